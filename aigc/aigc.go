@@ -1,16 +1,22 @@
 package aigc
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
-	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
-	"github.com/yaoapp/yao/openai"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/llm"
+	"github.com/yaoapp/yao/usage"
 )
 
+// Builtins the builtin variables of the prompt templates, content is the user message, user is the user id and session is the session data
+var Builtins = []string{"content", "user", "session"}
+
 // Autopilots the loaded autopilots
 var Autopilots = []string{}
 
@@ -27,10 +33,38 @@ func Select(id string) (*DSL, error) {
 
 // Call the AIGC
 func (ai *DSL) Call(content string, user string, option map[string]interface{}) (interface{}, *exception.Exception) {
+	return ai.CallWith(context.Background(), content, user, option)
+}
+
+// CallWith call the AIGC with the context, the token usage is recorded as the aigcs.<id> assistant
+func (ai *DSL) CallWith(ctx context.Context, content string, user string, option map[string]interface{}) (interface{}, *exception.Exception) {
+	return ai.CallWithVariables(ctx, map[string]interface{}{"content": content}, user, option)
+}
+
+// CallWithVariables call the AIGC with the variables of the prompt templates.
+// The content variable is sent as the user message, unless the templates use it.
+func (ai *DSL) CallWithVariables(ctx context.Context, variables map[string]interface{}, user string, option map[string]interface{}) (interface{}, *exception.Exception) {
+
+	data, err := ai.Variables.Bind(variables)
+	if err != nil {
+		return nil, exception.New("%s %s", 400, ai.ID, err.Error())
+	}
+	data["user"] = user
+	if data["session"] == nil {
+		data["session"] = map[string]interface{}{}
+	}
 
 	messages := []map[string]interface{}{}
-	for _, prompt := range ai.Prompts {
-		message := map[string]interface{}{"role": prompt.Role, "content": prompt.Content}
+	for i, prompt := range ai.Prompts {
+		content := prompt.Content
+		if ai.templates != nil && ai.templates[i] != nil {
+			content, err = ai.templates[i].Render(data)
+			if err != nil {
+				return nil, exception.New("%s prompts[%d] %s", 400, ai.ID, i, err.Error())
+			}
+		}
+
+		message := map[string]interface{}{"role": prompt.Role, "content": content}
 		if prompt.Name != "" {
 			message["name"] = prompt.Name
 		}
@@ -38,11 +72,20 @@ func (ai *DSL) Call(content string, user string, option map[string]interface{})
 	}
 
 	// add the user message
-	message := map[string]interface{}{"role": "user", "content": content}
-	if user != "" {
-		message["user"] = user
+	if content, ok := data["content"].(string); ok && content != "" && !ai.Uses("content") {
+		message := map[string]interface{}{"role": "user", "content": content}
+		if user != "" {
+			message["user"] = user
+		}
+		messages = append(messages, message)
+	}
+
+	// request the structured output, the JSON reply without the schema is parsed only for the process
+	structured := ai.Optional.Schema != nil || (ai.Optional.JSON && ai.Process != "")
+	if ai.Optional.Schema != nil {
+		messages = append([]map[string]interface{}{{"role": "system", "content": ai.schemaPrompt()}}, messages...)
+		option = ai.schemaOption(option)
 	}
-	messages = append(messages, message)
 
 	bytes, err := jsoniter.Marshal(messages)
 	if err != nil {
@@ -59,34 +102,100 @@ func (ai *DSL) Call(content string, user string, option map[string]interface{})
 	}
 
 	// call the AI
-	res, ex := ai.AI.ChatCompletions(messages, option, nil)
-	if ex != nil {
-		return nil, ex
+	ctx = usage.WithMeta(ctx, usage.Meta{Assistant: "aigcs." + ai.ID, Source: "aigc"})
+	if !structured {
+		resText, ex := ai.complete(ctx, messages, option)
+		if ex != nil {
+			return nil, ex
+		}
+
+		if ai.Process == "" {
+			return resText, nil
+		}
+		return ai.exec(ctx, resText)
 	}
 
-	resText, ex := ai.AI.GetContent(res)
+	param, ex := ai.structured(ctx, messages, option)
 	if ex != nil {
 		return nil, ex
 	}
 
 	if ai.Process == "" {
-		return resText, nil
+		return param, nil
+	}
+	return ai.exec(ctx, param)
+}
+
+// Uses check if the prompt templates use the variable
+func (ai *DSL) Uses(name string) bool {
+	for _, tmpl := range ai.templates {
+		if tmpl != nil && tmpl.Uses(name) {
+			return true
+		}
 	}
+	return false
+}
+
+// structured call the AI until the reply is valid JSON and matches the schema, the errors are sent back to the AI to correct the reply
+func (ai *DSL) structured(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}) (interface{}, *exception.Exception) {
+	retries := 2
+	if ai.Optional.Retries != nil {
+		retries = *ai.Optional.Retries
+	}
+
+	for i := 0; ; i++ {
+		resText, ex := ai.complete(ctx, messages, option)
+		if ex != nil && ai.rejected(ex, option) {
+			log.Warn("[AIGC] %s the connector %s does not support the structured output, the schema prompt is used: %s", ai.ID, ai.Connector, ex.Message)
+			formatless.Store(ai.Connector, true)
+			option = ai.schemaOption(option)
+			resText, ex = ai.complete(ctx, messages, option)
+		}
+		if ex != nil {
+			return nil, ex
+		}
 
-	var param interface{} = resText
-	if ai.Optional.JSON {
-		err = jsoniter.Unmarshal([]byte(resText), &param)
+		var errs []string
+		value, err := parseJSON(resText)
 		if err != nil {
-			return nil, exception.New("%s parse error: %s", 400, resText, err.Error())
+			errs = []string{fmt.Sprintf("the reply is not valid JSON, %s", err.Error())}
+		} else {
+			errs = validate(ai.Optional.Schema, value, "$")
+		}
+
+		if len(errs) == 0 {
+			return value, nil
 		}
+
+		if i >= retries {
+			return nil, exception.New("%s the reply is invalid: %s. %s", 400, ai.ID, strings.Join(errs, "; "), resText)
+		}
+
+		log.Warn("[AIGC] %s the reply is invalid, retry %d/%d: %s", ai.ID, i+1, retries, strings.Join(errs, "; "))
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": resText},
+			map[string]interface{}{"role": "user", "content": "The reply is invalid:\n- " + strings.Join(errs, "\n- ") + "\nCorrect it and reply with the JSON only."},
+		)
 	}
+}
 
+// complete call the AI and return the content of the reply
+func (ai *DSL) complete(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}) (string, *exception.Exception) {
+	res, ex := ai.AI.ChatCompletionsWith(ctx, messages, option, nil)
+	if ex != nil {
+		return "", ex
+	}
+	return ai.AI.GetContent(res)
+}
+
+// exec the process of the AIGC with the reply, the process runs in the context of the call
+func (ai *DSL) exec(ctx context.Context, param interface{}) (interface{}, *exception.Exception) {
 	p, err := process.Of(ai.Process, param)
 	if err != nil {
 		return nil, exception.New(err.Error(), 400)
 	}
 
-	resProcess, err := p.Exec()
+	resProcess, err := p.WithContext(ctx).Exec()
 	if err != nil {
 		return nil, exception.New(err.Error(), 500)
 	}
@@ -94,30 +203,57 @@ func (ai *DSL) Call(content string, user string, option map[string]interface{})
 	return resProcess, nil
 }
 
-// NewAI create a new AI
-func (ai *DSL) newAI() (AI, error) {
+// schemaPrompt the system prompt of the schema, the providers without the structured output follow it
+func (ai *DSL) schemaPrompt() string {
+	schema, _ := jsoniter.MarshalToString(ai.Optional.Schema)
+	return "Reply with a JSON value only, it should match the JSON Schema:\n" + schema
+}
 
-	if ai.Connector == "" || strings.HasPrefix(ai.Connector, "moapi") {
-		model := "gpt-3.5-turbo"
-		if strings.HasPrefix(ai.Connector, "moapi:") {
-			model = strings.TrimPrefix(ai.Connector, "moapi:")
-		}
+// formatless the connectors reject the response_format of the structured output, the schema prompt is used only. e.g. gpt-3.5-turbo
+var formatless sync.Map
 
-		mo, err := openai.NewMoapi(model)
-		if err != nil {
-			return nil, err
+// schemaOption the option of the structured output, the response_format is the OpenAI format, the connectors map it to their own.
+// The response_format is removed if the connector rejected it before.
+func (ai *DSL) schemaOption(option map[string]interface{}) map[string]interface{} {
+	res := map[string]interface{}{}
+	for key, value := range option {
+		res[key] = value
+	}
+
+	if _, has := formatless.Load(ai.Connector); has {
+		if format, ok := res["response_format"].(map[string]interface{}); ok && format["type"] == "json_schema" {
+			delete(res, "response_format")
 		}
-		return mo, nil
+		return res
 	}
 
-	conn, err := connector.Select(ai.Connector)
-	if err != nil {
-		return nil, err
+	if _, has := res["response_format"]; !has {
+		name := strings.NewReplacer(".", "_", "-", "_").Replace(ai.ID)
+		res["response_format"] = map[string]interface{}{
+			"type":        "json_schema",
+			"json_schema": map[string]interface{}{"name": name, "schema": ai.Optional.Schema},
+		}
 	}
+	return res
+}
 
-	if conn.Is(connector.OPENAI) {
-		return openai.New(ai.Connector)
+// rejected check if the request is rejected for the response_format of the structured output
+// {"error": {"message": "Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model."}}
+func (ai *DSL) rejected(ex *exception.Exception, option map[string]interface{}) bool {
+	format, ok := option["response_format"].(map[string]interface{})
+	if ex.Code != 400 || !ok || format["type"] != "json_schema" {
+		return false
 	}
 
-	return nil, fmt.Errorf("%s connector %s not support, should be a openai", ai.ID, ai.Connector)
+	message := strings.ToLower(ex.Message)
+	return strings.Contains(message, "response_format") || strings.Contains(message, "json_schema")
+}
+
+// NewAI create a new AI
+func (ai *DSL) newAI() (AI, error) {
+	api, err := llm.New(ai.Connector)
+	if err != nil {
+		return nil, fmt.Errorf("%s connector %s not support, %s", ai.ID, ai.Connector, err.Error())
+	}
+	return api, nil
 }
@@ -1,10 +1,12 @@
 package aigc
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/prompt"
 	"github.com/yaoapp/yao/test"
 )
 
@@ -49,6 +51,50 @@ func TestCallWithProcess(t *testing.T) {
 	assert.Equal(t, float64(256), data["width"])
 }
 
+func TestCallWithVariables(t *testing.T) {
+	dsl := &DSL{
+		ID: "reply",
+		Prompts: []Prompt{
+			{Role: "system", Content: "Reply to {{ customer.name }} in {{ language }}.{{ if customer.vip }} The customer is a VIP.{{ end }}", Template: true},
+			{Role: "user", Content: "{{ for item in items }}- {{ item }}\n{{ end }}", Template: true},
+		},
+		Variables: prompt.Variables{
+			"customer": {Type: "object", Required: true},
+			"language": {Type: "string", Default: "English"},
+			"items":    {Type: "array", Default: []interface{}{}},
+		},
+	}
+
+	var err error
+	dsl.templates, err = prompt.ParseAll(dsl.Prompts[0].Content, dsl.Prompts[1].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, dsl.Variables.Check(dsl.templates, Builtins...))
+
+	ai := &replies{texts: []string{"Hello Max"}}
+	dsl.AI = ai
+	res, ex := dsl.CallWithVariables(context.Background(), map[string]interface{}{
+		"customer": map[string]interface{}{"name": "Max", "vip": true},
+		"items":    []interface{}{"Apple", "Banana"},
+		"content":  "Where is my order?",
+	}, "", nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	assert.Equal(t, "Hello Max", res)
+	assert.Equal(t, []map[string]interface{}{
+		{"role": "system", "content": "Reply to Max in English. The customer is a VIP."},
+		{"role": "user", "content": "- Apple\n- Banana\n"},
+		{"role": "user", "content": "Where is my order?"},
+	}, ai.calls[0])
+
+	_, ex = dsl.CallWithVariables(context.Background(), map[string]interface{}{"content": "Hi"}, "", nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 400, ex.Code)
+}
+
 func prepare(t *testing.T) {
 	err := Load(config.Conf)
 	if err != nil {
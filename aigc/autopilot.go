@@ -0,0 +1,269 @@
+package aigc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/llm"
+	"github.com/yaoapp/yao/prompt"
+)
+
+// The status of the autopilot plan
+const (
+	PlanClarify = "clarify" // The command is ambiguous or the arguments are missing, the question should be answered
+	PlanConfirm = "confirm" // The plan is waiting for the confirmation
+	PlanDone    = "done"    // The AIGC is executed
+)
+
+// Threshold the default confidence threshold of the autopilot, the plan asks a question if the confidence is lower
+var Threshold = 0.6
+
+// Plan the decision of the autopilot, it can be reviewed before the AIGC is executed
+type Plan struct {
+	Command    string                 `json:"command"`
+	Status     string                 `json:"status"`               // clarify, confirm, done
+	AIGC       string                 `json:"aigc,omitempty"`       // The id of the chosen AIGC
+	Name       string                 `json:"name,omitempty"`       // The name of the chosen AIGC
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`  // The variables of the chosen AIGC
+	Confidence float64                `json:"confidence"`           // 0 - 1
+	Reason     string                 `json:"reason,omitempty"`     // Why the AIGC is chosen
+	Question   string                 `json:"question,omitempty"`   // The clarifying question when the status is clarify
+	Candidates []Candidate            `json:"candidates,omitempty"` // The autopilots the command is classified against
+	Result     interface{}            `json:"result,omitempty"`     // The result of the AIGC when the status is done
+}
+
+// Candidate the autopilot the command is classified against
+type Candidate struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   prompt.Variables `json:"arguments"`
+}
+
+// AutopilotOption the option of the autopilot
+type AutopilotOption struct {
+	Connector string  `json:"connector,omitempty"` // The connector classifies the command, the connector of the first autopilot is used if it is empty
+	Threshold float64 `json:"threshold,omitempty"` // The confidence threshold, the default value is 0.6
+	Confirm   bool    `json:"confirm,omitempty"`   // Return the plan for the confirmation instead of executing it
+	User      string  `json:"-"`
+	Sid       string  `json:"-"`
+}
+
+// Autopilot classify the command against the autopilots and execute the chosen AIGC.
+// The plan is returned without the execution if it should be clarified or confirmed.
+func Autopilot(ctx context.Context, command string, option AutopilotOption) (*Plan, *exception.Exception) {
+	plan, ex := Route(ctx, command, option)
+	if ex != nil {
+		return nil, ex
+	}
+
+	if plan.Status == PlanClarify || option.Confirm {
+		return plan, nil
+	}
+
+	ex = plan.Run(ctx, option)
+	if ex != nil {
+		return nil, ex
+	}
+	return plan, nil
+}
+
+// Route classify the command against the autopilots and extract the arguments, the plan is not executed
+func Route(ctx context.Context, command string, option AutopilotOption) (*Plan, *exception.Exception) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, exception.New("the command is required", 400)
+	}
+
+	candidates := autopilots()
+	if len(candidates) == 0 {
+		return nil, exception.New("no autopilot is loaded", 404)
+	}
+
+	router, ex := newRouter(candidates, option.Connector)
+	if ex != nil {
+		return nil, ex
+	}
+
+	res, ex := router.CallWith(ctx, command, option.User, nil)
+	if ex != nil {
+		return nil, ex
+	}
+
+	reply, _ := res.(map[string]interface{})
+	plan := &Plan{Command: command, Status: PlanConfirm, Candidates: []Candidate{}}
+	plan.AIGC, _ = reply["aigc"].(string)
+	plan.Confidence, _ = reply["confidence"].(float64)
+	plan.Reason, _ = reply["reason"].(string)
+	plan.Question, _ = reply["question"].(string)
+	plan.Arguments, _ = reply["arguments"].(map[string]interface{})
+	for _, ai := range candidates {
+		plan.Candidates = append(plan.Candidates, candidate(ai))
+	}
+
+	threshold := option.Threshold
+	if threshold <= 0 {
+		threshold = Threshold
+	}
+
+	// ambiguous, none of the autopilots matches or the confidence is too low
+	if plan.AIGC == "" || plan.Confidence < threshold {
+		plan.clarify("Which one do you mean? " + names(candidates))
+		return plan, nil
+	}
+
+	ai := AIGCs[plan.AIGC]
+	plan.Name = ai.Name
+	arguments, err := ai.Variables.Bind(plan.Arguments)
+	if err == nil && len(ai.Variables) == 0 && arguments["content"] == nil {
+		err = fmt.Errorf("the content is required")
+	}
+
+	if err != nil {
+		plan.clarify(fmt.Sprintf("Please tell me more about it, %s.", err.Error()))
+		return plan, nil
+	}
+
+	plan.Arguments = arguments
+	plan.Question = ""
+	return plan, nil
+}
+
+// Run execute the AIGC of the plan, the arguments may be edited after the review
+func (plan *Plan) Run(ctx context.Context, option AutopilotOption) *exception.Exception {
+	if plan.Status == PlanClarify {
+		return exception.New("the plan should be clarified, %s", 400, plan.Question)
+	}
+
+	if !isAutopilot(plan.AIGC) {
+		return exception.New("%s is not an autopilot", 400, plan.AIGC)
+	}
+
+	ai, err := Select(plan.AIGC)
+	if err != nil {
+		return exception.New(err.Error(), 404)
+	}
+
+	variables := map[string]interface{}{}
+	for name, value := range plan.Arguments {
+		variables[name] = value
+	}
+
+	if ai.Uses("session") {
+		variables["session"] = prompt.Session(option.Sid)
+	}
+
+	res, ex := ai.CallWithVariables(ctx, variables, option.User, nil)
+	if ex != nil {
+		return ex
+	}
+
+	plan.Result = res
+	plan.Status = PlanDone
+	return nil
+}
+
+func (plan *Plan) clarify(question string) {
+	plan.Status = PlanClarify
+	if plan.Question == "" {
+		plan.Question = question
+	}
+}
+
+// newRouter the AIGC classifies the command, the reply is validated with the ids of the autopilots
+func newRouter(candidates []*DSL, connector string) (*DSL, *exception.Exception) {
+	ids := []interface{}{""}
+	list := []Candidate{}
+	for _, ai := range candidates {
+		ids = append(ids, ai.ID)
+		list = append(list, candidate(ai))
+	}
+
+	tools, err := jsoniter.MarshalToString(list)
+	if err != nil {
+		return nil, exception.New(err.Error(), 500)
+	}
+
+	router := &DSL{
+		ID: "autopilot",
+		Prompts: []Prompt{{Role: "system", Content: "You route the command of the user to one of the AIGCs and extract its arguments.\n" +
+			"The AIGCs:\n" + tools + "\n" +
+			"Reply with aigc, the id of the AIGC, and arguments, the values of its arguments taken from the command. " +
+			"The confidence is 0 - 1, how sure you are that the AIGC is what the user wants. " +
+			"If the command matches none or more than one of the AIGCs, or the required arguments are missing, " +
+			"reply with an empty aigc or a low confidence and a short question to clarify it."}},
+		Optional: Optional{Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"aigc", "confidence", "arguments"},
+			"properties": map[string]interface{}{
+				"aigc":       map[string]interface{}{"type": "string", "enum": ids},
+				"confidence": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+				"arguments":  map[string]interface{}{"type": "object"},
+				"reason":     map[string]interface{}{"type": "string"},
+				"question":   map[string]interface{}{"type": "string"},
+			},
+		}},
+		AI: candidates[0].AI,
+	}
+
+	if connector != "" {
+		api, err := llm.New(connector)
+		if err != nil {
+			return nil, exception.New("autopilot connector %s not support, %s", 400, connector, err.Error())
+		}
+		router.AI = api
+	}
+	return router, nil
+}
+
+// autopilots the loaded autopilots, the reloaded AIGCs may turn off the autopilot
+func autopilots() []*DSL {
+	res := []*DSL{}
+	for _, id := range Autopilots {
+		if ai, has := AIGCs[id]; has && ai.Optional.Autopilot {
+			res = append(res, ai)
+		}
+	}
+	return res
+}
+
+func isAutopilot(id string) bool {
+	for _, ai := range autopilots() {
+		if ai.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// candidate the arguments are the variables, the content is the message sent to the AIGC
+func candidate(ai *DSL) Candidate {
+	arguments := prompt.Variables{}
+	for name, v := range ai.Variables {
+		arguments[name] = v
+	}
+
+	if _, has := arguments["content"]; !has {
+		arguments["content"] = prompt.Variable{
+			Type:        "string",
+			Required:    len(ai.Variables) == 0,
+			Description: "The message sent to the AIGC",
+		}
+	}
+	return Candidate{ID: ai.ID, Name: ai.Name, Description: ai.Description, Arguments: arguments}
+}
+
+func names(candidates []*DSL) string {
+	res := []string{}
+	for _, ai := range candidates {
+		name := ai.Name
+		if name == "" {
+			name = ai.ID
+		}
+		res = append(res, name)
+	}
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,88 @@
+package aigc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/prompt"
+)
+
+func TestAutopilot(t *testing.T) {
+	router := &replies{texts: []string{`{"aigc": "translate", "confidence": 0.9, "arguments": {"content": "hello"}, "reason": "translate the word"}`}}
+	translate := &DSL{
+		ID: "translate", Name: "Translate", Description: "Translate the content to the language",
+		Prompts:   []Prompt{{Role: "system", Content: "Translate to {{ language }}", Template: true}},
+		Variables: prompt.Variables{"language": {Type: "string", Default: "French"}},
+		Optional:  Optional{Autopilot: true},
+		AI:        router,
+	}
+	translate.templates, _ = prompt.ParseAll(translate.Prompts[0].Content)
+	draw := &DSL{
+		ID: "draw", Name: "Draw", Description: "Draw a picture",
+		Prompts:   []Prompt{{Role: "system", Content: "Draw a {{ subject }}", Template: true}},
+		Variables: prompt.Variables{"subject": {Type: "string", Required: true}},
+		Optional:  Optional{Autopilot: true},
+		AI:        &replies{texts: []string{"drawn"}},
+	}
+	draw.templates, _ = prompt.ParseAll(draw.Prompts[0].Content)
+
+	aigcs, autopilots := AIGCs, Autopilots
+	defer func() { AIGCs, Autopilots = aigcs, autopilots }()
+	AIGCs = map[string]*DSL{"translate": translate, "draw": draw}
+	Autopilots = []string{"translate", "draw"}
+
+	// The plan is returned for the confirmation
+	plan, ex := Autopilot(context.Background(), "Translate hello", AutopilotOption{Confirm: true})
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, PlanConfirm, plan.Status)
+	assert.Equal(t, "translate", plan.AIGC)
+	assert.Equal(t, map[string]interface{}{"content": "hello", "language": "French"}, plan.Arguments)
+	assert.Len(t, plan.Candidates, 2)
+	assert.Contains(t, router.calls[0][1]["content"], "Draw a picture")
+
+	// The reviewed plan is executed
+	router.texts = append(router.texts, "bonjour")
+	plan.Arguments["language"] = "French"
+	ex = plan.Run(context.Background(), AutopilotOption{})
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, PlanDone, plan.Status)
+	assert.Equal(t, "bonjour", plan.Result)
+	assert.Equal(t, "Translate to French", router.calls[1][0]["content"])
+
+	// The command is ambiguous
+	translate.AI = &replies{texts: []string{`{"aigc": "draw", "confidence": 0.3, "arguments": {}}`}}
+	plan, ex = Autopilot(context.Background(), "Do it", AutopilotOption{})
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, PlanClarify, plan.Status)
+	assert.Equal(t, "Which one do you mean? Translate, Draw", plan.Question)
+	assert.NotNil(t, plan.Run(context.Background(), AutopilotOption{}))
+
+	// The required argument is missing
+	translate.AI = &replies{texts: []string{`{"aigc": "draw", "confidence": 0.8, "arguments": {}}`}}
+	plan, ex = Route(context.Background(), "Draw something", AutopilotOption{})
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, PlanClarify, plan.Status)
+	assert.Contains(t, plan.Question, "the variable subject is required")
+
+	// The plan is executed without the confirmation
+	translate.AI = &replies{texts: []string{`{"aigc": "draw", "confidence": 0.8, "arguments": {"subject": "cat"}}`}}
+	plan, ex = Autopilot(context.Background(), "Draw a cat", AutopilotOption{})
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, PlanDone, plan.Status)
+	assert.Equal(t, "drawn", plan.Result)
+
+	// The AIGC not chosen by the router is rejected
+	plan = &Plan{AIGC: "unknown", Status: PlanConfirm}
+	assert.NotNil(t, plan.Run(context.Background(), AutopilotOption{}))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/prompt"
 	"github.com/yaoapp/yao/share"
 )
 
@@ -68,15 +69,51 @@ func LoadSource(data []byte, file, id string) (*DSL, error) {
 		return nil, fmt.Errorf("%s prompts is required", id)
 	}
 
+	// parse the prompt templates, the variables they use should be declared.
+	// The prompts without template: true are the literal text, the braces in them are kept
+	contents := []string{}
+	for _, p := range dsl.Prompts {
+		if !p.Template {
+			contents = append(contents, "")
+			continue
+		}
+		contents = append(contents, p.Content)
+	}
+
+	dsl.templates, err = prompt.ParseAll(contents...)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s", id, err.Error())
+	}
+
+	err = dsl.Variables.Check(dsl.templates, Builtins...)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s", id, err.Error())
+	}
+
+	err = checkSchema(dsl.Optional.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s", id, err.Error())
+	}
+
+	if dsl.Optional.Retries != nil && *dsl.Optional.Retries < 0 {
+		return nil, fmt.Errorf("%s retries should not be negative", id)
+	}
+
 	// create AI interface
 	dsl.AI, err = dsl.newAI()
 	if err != nil {
 		return nil, err
 	}
 
-	// add to autopilots
+	// add to autopilots, the reloaded AIGC is added once
 	if dsl.Optional.Autopilot {
-		Autopilots = append(Autopilots, id)
+		added := false
+		for _, autopilot := range Autopilots {
+			added = added || autopilot == id
+		}
+		if !added {
+			Autopilots = append(Autopilots, id)
+		}
 	}
 
 	// add to AIGCs
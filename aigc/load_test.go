@@ -1,6 +1,7 @@
 package aigc
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,30 @@ func TestLoad(t *testing.T) {
 	check(t)
 }
 
+func TestLoadLiteralPrompts(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	legacy := "Reply in JSON like {\"name\": \"{{ name }}\"}, keep {{ and }} as they are."
+	source := fmt.Sprintf(`{"name": "Legacy", "prompts": [{"role": "system", "content": %q}, {"role": "user", "content": "Hello {{ name }}", "template": true}], "variables": {"name": {"type": "string"}}}`, legacy)
+	dsl, err := LoadSource([]byte(source), "legacy.ai.yml", "unit.test.legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(AIGCs, "unit.test.legacy")
+
+	// The prompt without template: true is the literal text
+	assert.Nil(t, dsl.templates[0])
+	assert.NotNil(t, dsl.templates[1])
+
+	assert.Equal(t, legacy, dsl.Prompts[0].Content)
+	content, err := dsl.templates[1].Render(map[string]interface{}{"name": "Max"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Hello Max", content)
+}
+
 func check(t *testing.T) {
 	ids := map[string]bool{}
 	for id := range AIGCs {
@@ -1,15 +1,27 @@
 package aigc
 
 import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/prompt"
+	"github.com/yaoapp/yao/usage"
 )
 
 func init() {
 	process.Register("aigcs", processAigcs)
+	process.RegisterGroup("autopilot", map[string]process.Handler{
+		"run":     processAutopilotRun,
+		"plan":    processAutopilotPlan,
+		"execute": processAutopilotExecute,
+	})
 }
 
-// processScripts
+// processAigcs aigcs.<id>
+// args[0] the content, or the variables of the prompt templates. e.g. {"customer": {"name": "Max"}, "content": "Hello"}
+// args[1] the user id, args[2] the option
 func processAigcs(process *process.Process) interface{} {
 
 	process.ValidateArgNums(1)
@@ -19,9 +31,20 @@ func processAigcs(process *process.Process) interface{} {
 		return nil
 	}
 
-	content := process.ArgsString(0)
-	user := ""
+	variables := map[string]interface{}{}
+	if args, ok := process.Args[0].(map[string]interface{}); ok {
+		for name, value := range args {
+			variables[name] = value
+		}
+	} else {
+		variables["content"] = process.ArgsString(0)
+	}
+
+	if aigc.Uses("session") {
+		variables["session"] = prompt.Session(process.Sid)
+	}
 
+	user := ""
 	var option map[string]interface{} = nil
 	if process.NumOfArgs() > 1 {
 		user = process.ArgsString(1)
@@ -31,10 +54,85 @@ func processAigcs(process *process.Process) interface{} {
 		option = process.ArgsMap(2)
 	}
 
-	res, ex := aigc.Call(content, user, option)
+	res, ex := aigc.CallWithVariables(processContext(process), variables, user, option)
 	if ex != nil {
 		ex.Throw()
 	}
 
 	return res
 }
+
+// processAutopilotRun autopilot.run
+// args[0] the command, args[1] the option, args[2] the user id. e.g. {"confirm": true, "connector": "gpt-4o", "threshold": 0.6}
+// The plan is returned, the chosen AIGC is executed unless the plan should be clarified or confirmed.
+func processAutopilotRun(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	option := autopilotOption(process, 1)
+	plan, ex := Autopilot(processContext(process), process.ArgsString(0), option)
+	if ex != nil {
+		ex.Throw()
+	}
+	return plan
+}
+
+// processAutopilotPlan autopilot.plan
+// args[0] the command, args[1] the option, args[2] the user id. The plan is returned without the execution
+func processAutopilotPlan(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	option := autopilotOption(process, 1)
+	plan, ex := Route(processContext(process), process.ArgsString(0), option)
+	if ex != nil {
+		ex.Throw()
+	}
+	return plan
+}
+
+// processAutopilotExecute autopilot.execute
+// args[0] the reviewed plan, args[1] the option, args[2] the user id. The plan with the result is returned
+func processAutopilotExecute(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+
+	plan := Plan{}
+	data, err := jsoniter.Marshal(process.Args[0])
+	if err == nil {
+		err = jsoniter.Unmarshal(data, &plan)
+	}
+	if err != nil {
+		exception.New("the plan is invalid, %s", 400, err.Error()).Throw()
+	}
+
+	option := autopilotOption(process, 1)
+	ex := plan.Run(processContext(process), option)
+	if ex != nil {
+		ex.Throw()
+	}
+	return plan
+}
+
+func autopilotOption(process *process.Process, i int) AutopilotOption {
+	option := AutopilotOption{}
+	if process.NumOfArgs() > i {
+		data, err := jsoniter.Marshal(process.Args[i])
+		if err == nil {
+			err = jsoniter.Unmarshal(data, &option)
+		}
+		if err != nil {
+			exception.New("the option is invalid, %s", 400, err.Error()).Throw()
+		}
+	}
+
+	option.Sid = process.Sid
+	if process.NumOfArgs() > i+1 {
+		option.User = process.ArgsString(i + 1)
+	}
+	return option
+}
+
+// processContext the context of the process with the usage meta, the process may be canceled or traced by the caller
+func processContext(process *process.Process) context.Context {
+	ctx := process.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return usage.WithMeta(ctx, usage.Meta{Sid: process.Sid})
+}
@@ -1,12 +1,14 @@
 package aigc
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/test"
+	"github.com/yaoapp/yao/usage"
 )
 
 func TestProcessAigcs(t *testing.T) {
@@ -18,3 +20,16 @@ func TestProcessAigcs(t *testing.T) {
 	res := process.New("aigcs.translate", args...).Run()
 	assert.Contains(t, res, "Hello")
 }
+
+func TestProcessContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := processContext(&process.Process{Name: "aigcs.translate", Sid: "sid-1", Context: parent})
+	assert.Equal(t, "sid-1", usage.MetaOf(ctx).Sid)
+
+	// The call is canceled with the caller
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	ctx = processContext(&process.Process{Name: "aigcs.translate"})
+	assert.Nil(t, ctx.Err())
+}
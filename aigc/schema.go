@@ -0,0 +1,468 @@
+package aigc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// schemaTypes the types of the JSON Schema
+var schemaTypes = map[string]bool{"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true}
+
+// schemaKeywords the keywords supported by the validation, the annotations are accepted and ignored
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true, "minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"allOf": true, "anyOf": true, "oneOf": true, "not": true, "$ref": true, "$defs": true, "definitions": true, "format": true,
+	"title": true, "description": true, "default": true, "examples": true, "$schema": true, "$comment": true, "$id": true,
+}
+
+// schemaFormats the formats of the strings supported by the validation
+var schemaFormats = map[string]func(string) bool{
+	"date-time": func(v string) bool {
+		_, err := time.Parse(time.RFC3339, v)
+		return err == nil
+	},
+	"date": func(v string) bool {
+		_, err := time.Parse("2006-01-02", v)
+		return err == nil
+	},
+	"time": func(v string) bool { return timeFormat.MatchString(v) },
+	"email": func(v string) bool {
+		address, err := mail.ParseAddress(v)
+		return err == nil && address.Address == v
+	},
+	"uri": func(v string) bool {
+		u, err := url.Parse(v)
+		return err == nil && u.Scheme != ""
+	},
+	"uuid":     func(v string) bool { return uuidFormat.MatchString(v) },
+	"hostname": func(v string) bool { return len(v) <= 253 && hostnameFormat.MatchString(v) },
+	"ipv4": func(v string) bool {
+		ip := net.ParseIP(v)
+		return ip != nil && ip.To4() != nil && !strings.Contains(v, ":")
+	},
+	"ipv6": func(v string) bool { return net.ParseIP(v) != nil && strings.Contains(v, ":") },
+}
+
+var timeFormat = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]:([0-5][0-9]|60)(\.[0-9]+)?([Zz]|[+-]([01][0-9]|2[0-3]):[0-5][0-9])?$`)
+var uuidFormat = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+var hostnameFormat = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+
+// maxRefDepth the max depth of the nested $ref, the recursive schemas are stopped at the depth
+const maxRefDepth = 64
+
+var fenced = regexp.MustCompile("(?s)```(?:json)?\\s*(.*?)\\s*```")
+
+// checkSchema check the keywords, the types, the patterns, the formats and the references of the JSON Schema.
+// The keywords not supported by the validation are rejected, the values would be treated as valid.
+// The $ref should be a local reference, e.g. #/$defs/size, #/definitions/size.
+func checkSchema(schema map[string]interface{}) error {
+	return checkSubschema(schema, schema)
+}
+
+func checkSubschema(root map[string]interface{}, schema map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+
+	keys := []string{}
+	for key := range schema {
+		if !schemaKeywords[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return fmt.Errorf("schema the keywords %s are not supported", strings.Join(keys, ", "))
+	}
+
+	types, err := typesOf(schema["type"])
+	if err != nil {
+		return err
+	}
+	for _, typ := range types {
+		if !schemaTypes[typ] {
+			return fmt.Errorf("schema the type %s is invalid", typ)
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("schema the pattern %s is invalid, %s", pattern, err.Error())
+		}
+	}
+
+	if format, has := schema["format"]; has {
+		name, _ := format.(string)
+		if _, ok := schemaFormats[name]; !ok {
+			return fmt.Errorf("schema the format %v is not supported", format)
+		}
+	}
+
+	if ref, has := schema["$ref"]; has {
+		name, _ := ref.(string)
+		if _, err := resolveRef(root, name); err != nil {
+			return err
+		}
+	}
+
+	children := []interface{}{schema["items"], schema["additionalProperties"], schema["not"]}
+	for _, key := range []string{"properties", "$defs", "definitions"} {
+		if properties, ok := schema[key].(map[string]interface{}); ok {
+			for _, property := range properties {
+				children = append(children, property)
+			}
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if list, ok := schema[key].([]interface{}); ok {
+			children = append(children, list...)
+		}
+	}
+
+	for _, child := range children {
+		if child, ok := child.(map[string]interface{}); ok {
+			if err := checkSubschema(root, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validate the value with the JSON Schema, the errors are returned with the paths of the values. e.g. $.items[0].name is required
+// The keywords type, enum, const, properties, required, additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, format, minimum, maximum, exclusiveMinimum, exclusiveMaximum, allOf, anyOf, oneOf, not and the local $ref are supported.
+func validate(schema map[string]interface{}, value interface{}, path string) []string {
+	v := &validator{root: schema}
+	return v.validate(schema, value, path)
+}
+
+// validator the validation of a JSON Schema, the $ref are resolved in the root schema
+type validator struct {
+	root  map[string]interface{}
+	depth int // the depth of the nested $ref
+}
+
+func (v *validator) validate(schema map[string]interface{}, value interface{}, path string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	errs := []string{}
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := resolveRef(v.root, ref)
+		if err != nil {
+			return append(errs, fmt.Sprintf("%s %s", path, err.Error()))
+		}
+
+		if v.depth >= maxRefDepth {
+			return append(errs, fmt.Sprintf("%s the $ref %s is nested too deep", path, ref))
+		}
+
+		v.depth++
+		errs = append(errs, v.validate(target, value, path)...)
+		v.depth--
+	}
+
+	if types, _ := typesOf(schema["type"]); len(types) > 0 && !isType(value, types) {
+		return append(errs, fmt.Sprintf("%s should be %s", path, strings.Join(types, " or ")))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, option := range enum {
+			if equal(option, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s should be one of %s", path, dump(enum)))
+		}
+	}
+
+	if option, has := schema["const"]; has && !equal(option, value) {
+		errs = append(errs, fmt.Sprintf("%s should be %s", path, dump(option)))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, v.validateObject(schema, val, path)...)
+
+	case []interface{}:
+		if min, ok := number(schema["minItems"]); ok && float64(len(val)) < min {
+			errs = append(errs, fmt.Sprintf("%s should have at least %v items", path, min))
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(val)) > max {
+			errs = append(errs, fmt.Sprintf("%s should have at most %v items", path, max))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				errs = append(errs, v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case string:
+		length := float64(utf8.RuneCountInString(val))
+		if min, ok := number(schema["minLength"]); ok && length < min {
+			errs = append(errs, fmt.Sprintf("%s should have at least %v characters", path, min))
+		}
+		if max, ok := number(schema["maxLength"]); ok && length > max {
+			errs = append(errs, fmt.Sprintf("%s should have at most %v characters", path, max))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(val) {
+				errs = append(errs, fmt.Sprintf("%s should match the pattern %s", path, pattern))
+			}
+		}
+		if format, ok := schema["format"].(string); ok {
+			if check, has := schemaFormats[format]; has && !check(val) {
+				errs = append(errs, fmt.Sprintf("%s should be in the %s format", path, format))
+			}
+		}
+
+	case float64:
+		if min, ok := number(schema["minimum"]); ok && val < min {
+			errs = append(errs, fmt.Sprintf("%s should be >= %v", path, min))
+		}
+		if max, ok := number(schema["maximum"]); ok && val > max {
+			errs = append(errs, fmt.Sprintf("%s should be <= %v", path, max))
+		}
+		if min, ok := number(schema["exclusiveMinimum"]); ok && val <= min {
+			errs = append(errs, fmt.Sprintf("%s should be > %v", path, min))
+		}
+		if max, ok := number(schema["exclusiveMaximum"]); ok && val >= max {
+			errs = append(errs, fmt.Sprintf("%s should be < %v", path, max))
+		}
+	}
+
+	if list, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range list {
+			if sub, ok := sub.(map[string]interface{}); ok {
+				errs = append(errs, v.validate(sub, value, path)...)
+			}
+		}
+	}
+
+	if list, ok := schema["anyOf"].([]interface{}); ok && v.matches(list, value, path) == 0 {
+		errs = append(errs, fmt.Sprintf("%s should match any of the schemas", path))
+	}
+
+	if list, ok := schema["oneOf"].([]interface{}); ok && v.matches(list, value, path) != 1 {
+		errs = append(errs, fmt.Sprintf("%s should match exactly one of the schemas", path))
+	}
+
+	if not, ok := schema["not"].(map[string]interface{}); ok && len(v.validate(not, value, path)) == 0 {
+		errs = append(errs, fmt.Sprintf("%s should not match the schema", path))
+	}
+
+	return errs
+}
+
+func (v *validator) validateObject(schema map[string]interface{}, value map[string]interface{}, path string) []string {
+	errs := []string{}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, has := value[fmt.Sprintf("%v", name)]; !has {
+				errs = append(errs, fmt.Sprintf("%s.%v is required", path, name))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			errs = append(errs, v.validate(property, value[name], path+"."+name)...)
+			continue
+		}
+
+		if _, has := properties[name]; has {
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, fmt.Sprintf("%s.%s is not allowed", path, name))
+			}
+		case map[string]interface{}:
+			errs = append(errs, v.validate(additional, value[name], path+"."+name)...)
+		}
+	}
+	return errs
+}
+
+// matches the number of the schemas the value matches
+func (v *validator) matches(list []interface{}, value interface{}, path string) int {
+	n := 0
+	for _, sub := range list {
+		if sub, ok := sub.(map[string]interface{}); ok && len(v.validate(sub, value, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// resolveRef the schema of the local reference, the reference is a JSON pointer. e.g. #, #/$defs/size, #/definitions/size
+func resolveRef(root map[string]interface{}, ref string) (map[string]interface{}, error) {
+	if ref == "#" {
+		return root, nil
+	}
+
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("schema the $ref %s is not supported, the $ref should be a local reference like #/$defs/name", ref)
+	}
+
+	var current interface{} = root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[token]
+		default:
+			current = nil
+		}
+		if current == nil {
+			break
+		}
+	}
+
+	schema, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema the $ref %s is not found", ref)
+	}
+	return schema, nil
+}
+
+// parseJSON parse the reply of the model, the JSON is read from the markdown code block
+// or from the first { or [ to the last } or ] if the model added prose
+func parseJSON(text string) (interface{}, error) {
+	var value interface{}
+	text = strings.TrimSpace(text)
+	err := jsoniter.Unmarshal([]byte(text), &value)
+	if err == nil {
+		return value, nil
+	}
+
+	if match := fenced.FindStringSubmatch(text); match != nil {
+		if jsoniter.Unmarshal([]byte(match[1]), &value) == nil {
+			return value, nil
+		}
+	}
+
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start >= 0 && end > start {
+		if jsoniter.Unmarshal([]byte(text[start:end+1]), &value) == nil {
+			return value, nil
+		}
+	}
+	return nil, err
+}
+
+func typesOf(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		types := []string{}
+		for _, typ := range v {
+			name, ok := typ.(string)
+			if !ok {
+				return nil, fmt.Errorf("schema the type %v is invalid", typ)
+			}
+			types = append(types, name)
+		}
+		return types, nil
+	}
+	return nil, fmt.Errorf("schema the type %v is invalid", value)
+}
+
+func isType(value interface{}, types []string) bool {
+	for _, typ := range types {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if typ == "object" {
+				return true
+			}
+		case []interface{}:
+			if typ == "array" {
+				return true
+			}
+		case string:
+			if typ == "string" {
+				return true
+			}
+		case float64:
+			if typ == "number" || (typ == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case bool:
+			if typ == "boolean" {
+				return true
+			}
+		case nil:
+			if typ == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equal compare the values of the schema and the JSON value, the numbers of the schema may be integers
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return dump(a) == dump(b)
+}
+
+// dump the value as JSON, the keys of the maps are sorted
+func dump(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,215 @@
+package aigc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/exception"
+)
+
+var drawSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"width", "height"},
+	"properties": map[string]interface{}{
+		"width":  map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 1024},
+		"height": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 1024},
+		"color":  map[string]interface{}{"type": "string", "enum": []interface{}{"white", "black"}},
+		"tags":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "minLength": 1}, "maxItems": 2},
+	},
+	"additionalProperties": false,
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, validate(drawSchema, map[string]interface{}{"width": float64(256), "height": float64(256), "color": "white"}, "$"))
+
+	errs := validate(drawSchema, map[string]interface{}{
+		"width": 256.5,
+		"color": "red",
+		"tags":  []interface{}{"a", "", "c"},
+		"size":  float64(1),
+	}, "$")
+	assert.Equal(t, []string{
+		"$.height is required",
+		"$.color should be one of [\"white\",\"black\"]",
+		"$.size is not allowed",
+		"$.tags should have at most 2 items",
+		"$.tags[1] should have at least 1 characters",
+		"$.width should be integer",
+	}, errs)
+
+	assert.Equal(t, []string{"$ should be object"}, validate(drawSchema, "256x256", "$"))
+
+	oneOf := map[string]interface{}{"oneOf": []interface{}{
+		map[string]interface{}{"type": "string", "pattern": "^[0-9]+$"},
+		map[string]interface{}{"type": "number"},
+	}}
+	assert.Empty(t, validate(oneOf, "42", "$"))
+	assert.Equal(t, []string{"$ should match exactly one of the schemas"}, validate(oneOf, "forty-two", "$"))
+
+	assert.Nil(t, checkSchema(drawSchema))
+	assert.NotNil(t, checkSchema(map[string]interface{}{"type": "int"}))
+	assert.NotNil(t, checkSchema(map[string]interface{}{"properties": map[string]interface{}{"name": map[string]interface{}{"pattern": "(["}}}))
+
+	// The local references and the formats
+	refs := map[string]interface{}{
+		"$defs":       map[string]interface{}{"size": map[string]interface{}{"type": "integer", "minimum": 1}},
+		"definitions": map[string]interface{}{"node": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"children": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/definitions/node"}}}}},
+		"properties": map[string]interface{}{
+			"width": map[string]interface{}{"$ref": "#/$defs/size"},
+			"tree":  map[string]interface{}{"$ref": "#/definitions/node"},
+			"email": map[string]interface{}{"type": "string", "format": "email"},
+			"at":    map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+	}
+	assert.Nil(t, checkSchema(refs))
+	assert.Empty(t, validate(refs, map[string]interface{}{
+		"width": float64(2),
+		"tree":  map[string]interface{}{"children": []interface{}{map[string]interface{}{"children": []interface{}{}}}},
+		"email": "max@example.com",
+		"at":    "2024-01-02T03:04:05Z",
+	}, "$"))
+	assert.Equal(t, []string{
+		"$.at should be in the date-time format",
+		"$.email should be in the email format",
+		"$.tree.children[0] should be object",
+		"$.width should be >= 1",
+	}, validate(refs, map[string]interface{}{
+		"width": float64(0),
+		"tree":  map[string]interface{}{"children": []interface{}{"leaf"}},
+		"email": "max",
+		"at":    "yesterday",
+	}, "$"))
+
+	// The references not found, the remote references and the unknown formats are rejected
+	err := checkSchema(map[string]interface{}{"properties": map[string]interface{}{"width": map[string]interface{}{"$ref": "#/$defs/size"}}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	err = checkSchema(map[string]interface{}{"$ref": "https://example.com/schema.json"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "local reference")
+
+	err = checkSchema(map[string]interface{}{"properties": map[string]interface{}{"phone": map[string]interface{}{"type": "string", "format": "phone"}}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "format")
+
+	// The recursive references without values are stopped
+	loop := map[string]interface{}{"$defs": map[string]interface{}{"a": map[string]interface{}{"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}
+	assert.Nil(t, checkSchema(loop))
+	assert.Contains(t, validate(loop, "x", "$")[0], "nested too deep")
+}
+
+func TestParseJSON(t *testing.T) {
+	value, err := parseJSON(`{"width": 256}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"width": float64(256)}, value)
+
+	value, err = parseJSON("Here is the JSON:\n```json\n{\"width\": 256}\n```\nEnjoy!")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"width": float64(256)}, value)
+
+	value, err = parseJSON(`Sure! [1, 2] is the answer.`)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, value)
+
+	_, err = parseJSON(`No JSON here`)
+	assert.NotNil(t, err)
+}
+
+func TestCallWithSchema(t *testing.T) {
+	ai := &replies{texts: []string{
+		`The canvas is {"width": 256}`,
+		`{"width": 256, "height": 256}`,
+	}}
+
+	dsl := &DSL{ID: "draw", Prompts: []Prompt{{Role: "system", Content: "Draw"}}, Optional: Optional{Schema: drawSchema}, AI: ai}
+	res, ex := dsl.CallWith(context.Background(), "256x256", "", nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	assert.Equal(t, map[string]interface{}{"width": float64(256), "height": float64(256)}, res)
+	assert.Len(t, ai.calls, 2)
+
+	// The structured output is requested and the errors are sent back
+	format, _ := ai.options[0]["response_format"].(map[string]interface{})
+	assert.Equal(t, "json_schema", format["type"])
+	retry := ai.calls[1]
+	assert.Equal(t, "assistant", retry[len(retry)-2]["role"])
+	assert.Contains(t, retry[len(retry)-1]["content"], "$.height is required")
+
+	// The retries are spent
+	retries := 0
+	ai = &replies{texts: []string{`{"width": 256}`}}
+	dsl = &DSL{ID: "draw", Prompts: []Prompt{{Role: "system", Content: "Draw"}}, Optional: Optional{Schema: drawSchema, Retries: &retries}, AI: ai}
+	_, ex = dsl.CallWith(context.Background(), "256x256", "", nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 400, ex.Code)
+	assert.Len(t, ai.calls, 1)
+}
+
+func TestCallWithSchemaFormatless(t *testing.T) {
+	defer formatless.Delete("unit-test-formatless")
+
+	ai := &replies{texts: []string{`{"width": 256, "height": 256}`}, reject: true}
+	dsl := &DSL{ID: "draw", Connector: "unit-test-formatless", Prompts: []Prompt{{Role: "system", Content: "Draw"}}, Optional: Optional{Schema: drawSchema}, AI: ai}
+	res, ex := dsl.CallWith(context.Background(), "256x256", "", nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, map[string]interface{}{"width": float64(256), "height": float64(256)}, res)
+
+	// The rejected request is sent again with the schema prompt only
+	assert.Len(t, ai.calls, 2)
+	assert.NotNil(t, ai.options[0]["response_format"])
+	assert.Nil(t, ai.options[1]["response_format"])
+	assert.Contains(t, ai.calls[1][0]["content"], "JSON Schema")
+
+	// The connector is not requested with the response_format again
+	_, ex = dsl.CallWith(context.Background(), "256x256", "", nil)
+	assert.Nil(t, ex)
+	assert.Len(t, ai.calls, 3)
+	assert.Nil(t, ai.options[2]["response_format"])
+}
+
+// replies the AI replies the texts in order, the last one is repeated
+type replies struct {
+	texts   []string
+	calls   [][]map[string]interface{}
+	options []map[string]interface{}
+	reject  bool // reject the response_format of the structured output
+}
+
+func (ai *replies) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return ai.ChatCompletionsWith(context.Background(), messages, option, cb)
+}
+
+func (ai *replies) ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	n := len(ai.calls)
+	if n >= len(ai.texts) {
+		n = len(ai.texts) - 1
+	}
+	ai.calls = append(ai.calls, append([]map[string]interface{}{}, messages...))
+	ai.options = append(ai.options, option)
+	if _, has := option["response_format"]; has && ai.reject {
+		return nil, exception.New("Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model.", 400)
+	}
+	return ai.texts[n], nil
+}
+
+func (ai *replies) GetContent(response interface{}) (string, *exception.Exception) {
+	return response.(string), nil
+}
+
+func (ai *replies) Embeddings(input interface{}, user string) (interface{}, *exception.Exception) {
+	return nil, exception.New("not supported", 400)
+}
+
+func (ai *replies) Tiktoken(input string) (int, error) {
+	return len(input) / 4, nil
+}
+
+func (ai *replies) MaxToken() int {
+	return 4096
+}
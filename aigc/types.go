@@ -1,41 +1,39 @@
 package aigc
 
 import (
-	"context"
-
-	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/llm"
+	"github.com/yaoapp/yao/prompt"
 )
 
 // DSL the connector DSL
 type DSL struct {
-	ID        string   `json:"-" yaml:"-"`
-	Name      string   `json:"name,omitempty"`
-	Connector string   `json:"connector,omitempty"`
-	Process   string   `json:"process,omitempty"`
-	Prompts   []Prompt `json:"prompts"`
-	Optional  Optional `json:"optional,omitempty"`
-	AI        AI       `json:"-" yaml:"-"`
+	ID          string             `json:"-" yaml:"-"`
+	Name        string             `json:"name,omitempty"`
+	Description string             `json:"description,omitempty"` // What the AIGC does, the autopilot chooses the AIGC by the name and the description
+	Connector   string             `json:"connector,omitempty"`
+	Process     string             `json:"process,omitempty"`
+	Prompts     []Prompt           `json:"prompts"`
+	Variables   prompt.Variables   `json:"variables,omitempty"` // The input variables of the prompt templates
+	Optional    Optional           `json:"optional,omitempty"`
+	AI          AI                 `json:"-" yaml:"-"`
+	templates   []*prompt.Template // The templates of the prompts, nil if the prompt is static
 }
 
 // Prompt a prompt
 type Prompt struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-	Name    string `json:"name,omitempty"`
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Name     string `json:"name,omitempty"`
+	Template bool   `json:"template,omitempty"` // Render the content as a template, the content is the literal text if false
 }
 
 // Optional optional
 type Optional struct {
-	Autopilot bool `json:"autopilot,omitempty"`
-	JSON      bool `json:"json,omitempty"`
+	Autopilot bool                   `json:"autopilot,omitempty"`
+	JSON      bool                   `json:"json,omitempty"`
+	Schema    map[string]interface{} `json:"schema,omitempty"`  // The JSON Schema of the output, the output is validated before it is passed to the process
+	Retries   *int                   `json:"retries,omitempty"` // The retries when the output is not valid JSON or does not match the schema, the default value is 2
 }
 
-// AI the AI interface
-type AI interface {
-	ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception)
-	ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception)
-	GetContent(response interface{}) (string, *exception.Exception)
-	Embeddings(input interface{}, user string) (interface{}, *exception.Exception)
-	Tiktoken(input string) (int, error)
-	MaxToken() int
-}
+// AI the AI interface, the openai, anthropic and ollama connectors are supported
+type AI = llm.LLM
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"archive/zip"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -11,14 +12,22 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/spf13/cobra"
+	"github.com/yaoapp/gou/application/yaz/ciphers"
 	"github.com/yaoapp/gou/model"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
+	ymodel "github.com/yaoapp/yao/model"
+	"github.com/yaoapp/yao/share"
 )
 
 var dumpModel string
+var dumpWheres []string
+var dumpSince string
+var dumpSecret string
+var dumpNoData bool = false
 var dumpCmd = &cobra.Command{
 	Use:   "dump",
 	Short: L("Dump the application data"),
@@ -63,33 +72,58 @@ var dumpCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		option := ymodel.DumpOption{Models: []string{}, Wheres: []ymodel.Where{}}
 		if dumpModel != "" {
-			fmt.Println(color.YellowString(L("Not supported yet")))
-			os.Exit(1)
-			return
+			option.Models = strings.Split(dumpModel, ",")
 		}
 
-		// Export models
-		files := []string{}
-		for _, mod := range model.Models {
-
-			fmt.Printf("\r%s", color.GreenString(L("Export the models: %s (%s)"), mod.Name, mod.MetaData.Table.Name))
-			jsonfiles, err := mod.Export(5000, func(curr, total int) {
-				fmt.Printf("\r%s", strings.Repeat(" ", 80))
-				fmt.Printf("\r%s", color.GreenString(L("Export the models: %s (%s) %d/%d"), mod.Name, mod.MetaData.Table.Name, curr, total))
-			})
+		for _, input := range dumpWheres {
+			where, err := ymodel.ParseWhere(input)
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+			option.Wheres = append(option.Wheres, where)
+		}
 
+		// Incremental dump, the rows updated since the previous dump
+		if dumpSince != "" {
+			manifest, err := previousManifest(dumpSince, dumpSecret)
 			if err != nil {
 				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
 				os.Exit(1)
 			}
-			files = append(files, jsonfiles...)
+			option.Since = &manifest.CreatedAt
+		}
+
+		option.Dir, err = os.MkdirTemp("", "yao-dump-*")
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+		defer os.RemoveAll(option.Dir)
+
+		// Export models
+		manifest := ymodel.NewManifest(share.App.Name)
+		manifest.Encrypted = dumpSecret != ""
+		err = ymodel.Dump(manifest, option, func(mod *model.Model, curr, total int) {
+			fmt.Printf("\r%s", strings.Repeat(" ", 80))
+			fmt.Printf("\r%s", color.GreenString(L("Export the models: %s (%s) %d/%d"), mod.Name, mod.MetaData.Table.Name, curr, total))
+		})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
 		}
 		fmt.Printf("\r%s", strings.Repeat(" ", 80))
 		fmt.Printf("\r%s\n", color.GreenString(L("Export the models: ✨DONE✨")))
 
 		// Compress files
-		err = zipfiles(files, output, func(file string) {
+		archive := output
+		if dumpSecret != "" {
+			archive = filepath.Join(option.Dir, "dump.zip")
+		}
+
+		err = zipfiles(option.Dir, manifest, archive, !dumpNoData, func(file string) {
 			fmt.Printf("\r%s", strings.Repeat(" ", 80))
 			fmt.Printf("\r%s", color.GreenString(L("Compress the files: %s"), file))
 		})
@@ -101,16 +135,75 @@ var dumpCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Encrypt the dump
+		if dumpSecret != "" {
+			err = cryptFile(archive, output, dumpSecret, true)
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+		}
+
+		for id, info := range manifest.Models {
+			fmt.Println(color.WhiteString("  %s: %d rows", id, info.Rows))
+		}
 		fmt.Println(color.GreenString("File: %s", output))
 	},
 }
 
-// func init() {
-// 	// dumpCmd.PersistentFlags().StringVarP(&dumpModel, "name", "n", "", L("Model name"))
-// }
+func init() {
+	dumpCmd.PersistentFlags().StringVarP(&dumpModel, "name", "n", "", L("Model names or globs, separated by commas"))
+	dumpCmd.PersistentFlags().StringArrayVarP(&dumpWheres, "where", "w", []string{}, L("Model conditions. e.g. *:tenant_id=1"))
+	dumpCmd.PersistentFlags().StringVarP(&dumpSince, "since", "", "", L("The previous dump or manifest, dump the rows updated since then"))
+	dumpCmd.PersistentFlags().StringVarP(&dumpSecret, "secret", "", "", L("Encrypt the dump with the secret"))
+	dumpCmd.PersistentFlags().BoolVarP(&dumpNoData, "no-data", "", false, L("Do not dump the data directory"))
+}
+
+// previousManifest read the manifest of the previous dump, the file is a dump or a manifest.json
+func previousManifest(file string, secret string) (*ymodel.Manifest, error) {
+	if strings.HasSuffix(file, ".json") {
+		return ymodel.ReadManifest(filepath.Dir(file))
+	}
+
+	dst := unzipFile(file, secret, func(string) {})
+	defer os.RemoveAll(dst)
+
+	manifest, err := ymodel.ReadManifest(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%s has no manifest", file)
+	}
+	return manifest, nil
+}
+
+// cryptFile encrypt or decrypt the file with the cipher of the application package
+func cryptFile(src string, dst string, secret string, encrypt bool) error {
+	key := sha256.Sum256([]byte(secret))
+	cipher := ciphers.NewAES(key[:])
+
+	reader, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
 
-// gzipfiles
-func zipfiles(files []string, output string, process func(file string)) error {
+	writer, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	if encrypt {
+		return cipher.Encrypt(reader, writer)
+	}
+	return cipher.Decrypt(reader, writer)
+}
+
+// zipfiles compress the dumped files, the manifest and the data directory
+func zipfiles(dir string, manifest *ymodel.Manifest, output string, withData bool, process func(file string)) error {
 	outpath := filepath.Dir(output)
 	os.MkdirAll(outpath, 0755)
 
@@ -130,21 +223,35 @@ func zipfiles(files []string, output string, process func(file string)) error {
 		}
 	}()
 
-	for _, file := range files {
-		addFile(w, file, "model", process)
+	for _, info := range manifest.Models {
+		for _, name := range info.Files {
+			addFile(w, filepath.Join(dir, filepath.FromSlash(name)), "model", manifest, process)
+		}
 	}
 
 	// Add data path
 	dataPath := filepath.Join(config.Conf.Root, "data")
 	_, err = os.Stat(dataPath)
-	if err == nil {
-		addFolder(w, dataPath, "data", process)
+	if err == nil && withData {
+		addFolder(w, dataPath, "data", manifest, process)
+	}
+
+	// Add the manifest
+	data, err := jsoniter.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := w.Create(ymodel.ManifestFile)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	_, err = f.Write(data)
+	return err
 }
 
-func addFile(w *zip.Writer, file, baseInZip string, process func(file string)) {
+func addFile(w *zip.Writer, file, baseInZip string, manifest *ymodel.Manifest, process func(file string)) {
 	process(filepath.Join(baseInZip, filepath.Base(file)))
 	dat, err := ioutil.ReadFile(file)
 	if err != nil {
@@ -167,7 +274,7 @@ func addFile(w *zip.Writer, file, baseInZip string, process func(file string)) {
 	os.Remove(file)
 }
 
-func addFolder(w *zip.Writer, basePath, baseInZip string, process func(file string)) {
+func addFolder(w *zip.Writer, basePath, baseInZip string, manifest *ymodel.Manifest, process func(file string)) {
 
 	// Open the Directory
 	files, err := ioutil.ReadDir(basePath)
@@ -196,10 +303,11 @@ func addFolder(w *zip.Writer, basePath, baseInZip string, process func(file stri
 				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
 				os.Exit(1)
 			}
+			manifest.Sum(filepath.Join(baseInZip, file.Name()), dat)
 		} else if file.IsDir() {
 			// Recurse
 			newBase := filepath.Join(basePath, file.Name())
-			addFolder(w, newBase, filepath.Join(baseInZip, file.Name()), process)
+			addFolder(w, newBase, filepath.Join(baseInZip, file.Name()), manifest, process)
 		}
 	}
 }
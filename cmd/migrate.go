@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -11,12 +12,16 @@ import (
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
+	ymodel "github.com/yaoapp/yao/model"
 	"github.com/yaoapp/yao/share"
 )
 
 var name string
 var force bool = false
 var resetModel bool = false
+var migratePlan bool = false
+var migrateDryRun bool = false
+var migrateDown int = 0
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: L("Update database schema"),
@@ -31,7 +36,8 @@ var migrateCmd = &cobra.Command{
 
 		Boot()
 
-		if !force && config.Conf.Mode == "production" {
+		readonly := migratePlan || migrateDryRun
+		if !readonly && !force && config.Conf.Mode == "production" {
 			fmt.Println(color.WhiteString(L("TRY:")), color.GreenString("%s migrate --force", share.BUILDNAME))
 			exception.New(L("Migrate is not allowed on production mode."), 403).Throw()
 		}
@@ -43,34 +49,80 @@ var migrateCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		models := []*model.Model{}
 		if name != "" {
 			mod, has := model.Models[name]
 			if !has {
 				fmt.Println(color.RedString(L("Model: %s does not exits"), name))
 				return
 			}
+			models = append(models, mod)
+		} else {
+			for _, mod := range model.Models {
+				models = append(models, mod)
+			}
+		}
 
-			fmt.Printf(color.WhiteString(L("Update schema model: %s (%s) "), mod.Name, mod.MetaData.Table.Name) + "\t")
-			if resetModel {
-				err := mod.DropTable()
-				if err != nil {
-					fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
-					return
-				}
+		// The versioned migrations are applied when the whole app is migrated, -n migrates the model only
+		migrations := []*ymodel.Migration{}
+		if name == "" {
+			migrations, err = ymodel.Migrations()
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
 			}
+		}
 
-			err := mod.Migrate(false)
+		if name != "" && migrateDown > 0 {
+			fmt.Println(color.RedString(L("Fatal: %s"), L("--down rolls back the versioned migrations, it can not be used with --name")))
+			os.Exit(1)
+		}
+
+		migrator, err := ymodel.NewMigrator()
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		if migratePlan {
+			printMigratePlan(models, migrator, migrations)
+			return
+		}
+
+		if migrateDryRun {
+			printMigrateSteps(models, migrator, migrations)
+			return
+		}
+
+		// Hold the lock, the concurrent deploys should not migrate at the same time.
+		// The migrations table and the lock table are created only if there are versioned migrations.
+		if len(migrations) > 0 {
+			err = migrator.Prepare()
+			if err == nil {
+				err = migrator.Lock()
+			}
 			if err != nil {
-				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+			defer migrator.Unlock()
+		}
+
+		// Roll back the versioned migrations
+		if migrateDown > 0 {
+			if len(migrations) == 0 {
+				fmt.Println(color.WhiteString(L("No versioned migrations")))
 				return
 			}
 
-			fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
+			err = migrator.Down(migrations, migrateDown, printMigration("Rollback migration: %s (%s) "))
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			}
 			return
 		}
 
-		// Do Stuff Here
-		for _, mod := range model.Models {
+		for _, mod := range models {
 			fmt.Printf(color.WhiteString(L("Update schema model: %s (%s) "), mod.Name, mod.MetaData.Table.Name) + "\t")
 
 			if resetModel {
@@ -81,12 +133,38 @@ var migrateCmd = &cobra.Command{
 				}
 			}
 
+			// The model is not migrated if the plan has no changes
+			if plan, err := ymodel.PlanOf(mod); err == nil && len(plan.Changes) == 0 {
+				fmt.Printf(color.GreenString(L("NO CHANGES")) + "\n")
+				continue
+			}
+
 			err := mod.Migrate(false)
 			if err != nil {
 				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
 				continue
 			}
 			fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
+
+			// The differences the model migration does not apply, e.g. the dropped columns
+			if plan, err := ymodel.PlanOf(mod); err == nil {
+				for _, change := range plan.Changes {
+					fmt.Println(color.YellowString("  "+L("Not applied: %s"), strings.ReplaceAll(change.SQL, "\n", "\n  ")))
+				}
+			}
+		}
+
+		// The versioned migrations
+		if len(migrations) > 0 {
+			err = migrator.Up(migrations, printMigration("Apply migration: %s (%s) "))
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				return
+			}
+		}
+
+		if name != "" {
+			return
 		}
 
 		// After Migrate Hook
@@ -112,4 +190,162 @@ func init() {
 	migrateCmd.PersistentFlags().StringVarP(&name, "name", "n", "", L("Model name"))
 	migrateCmd.PersistentFlags().BoolVarP(&force, "force", "", false, L("Force migrate"))
 	migrateCmd.PersistentFlags().BoolVarP(&resetModel, "reset", "", false, L("Drop the table if exist"))
+	migrateCmd.PersistentFlags().BoolVarP(&migratePlan, "plan", "", false, L("Print the schema changes without executing"))
+	migrateCmd.PersistentFlags().BoolVarP(&migrateDryRun, "dry-run", "", false, L("Print the migrate steps without executing"))
+	migrateCmd.PersistentFlags().IntVarP(&migrateDown, "down", "", 0, L("Roll back the last n versioned migrations"))
+}
+
+func printMigration(format string) func(mig *ymodel.Migration, err error) {
+	return func(mig *ymodel.Migration, err error) {
+		fmt.Printf(color.WhiteString(L(format), mig.Version, mig.Name) + "\t")
+		if err != nil {
+			fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+			return
+		}
+		fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
+	}
+}
+
+// printMigratePlan print the schema differences between the models and the database, and the pending migrations
+func printMigratePlan(models []*model.Model, migrator *ymodel.Migrator, migrations []*ymodel.Migration) {
+	destructive := false
+	for _, mod := range models {
+		fmt.Println(color.WhiteString(L("Model: %s (%s)"), mod.ID, mod.MetaData.Table.Name))
+		plan, err := ymodel.PlanOf(mod)
+		if err != nil {
+			fmt.Println(color.RedString("  "+L("Fatal: %s"), err.Error()))
+			continue
+		}
+
+		if len(plan.Changes) == 0 {
+			fmt.Println(color.GreenString("  " + L("No changes")))
+			continue
+		}
+
+		for _, change := range plan.Changes {
+			if change.Destructive {
+				destructive = true
+				fmt.Println(color.RedString("  %s [DESTRUCTIVE]", describeChange(change)))
+				continue
+			}
+			fmt.Println(color.GreenString("  %s", describeChange(change)))
+		}
+	}
+
+	pending, err := migrator.Pending(migrations)
+	if err != nil {
+		fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+		return
+	}
+
+	for _, mig := range pending {
+		fmt.Println(color.WhiteString(L("Pending migration: %s (%s)"), mig.Version, mig.File))
+	}
+
+	if destructive {
+		fmt.Println(color.RedString(L("The plan has destructive changes, please backup the data before migrating.")))
+	}
+}
+
+// printMigrateSteps print the steps of the migrate command in the order of execution, nothing is executed
+func printMigrateSteps(models []*model.Model, migrator *ymodel.Migrator, migrations []*ymodel.Migration) {
+	step := 0
+	line := func(format string, args ...interface{}) {
+		step++
+		fmt.Println(color.WhiteString("%d. ", step) + fmt.Sprintf(format, args...))
+	}
+
+	if len(migrations) > 0 {
+		line(L("Create the tables %s and %s if not exists, acquire the lock"), ymodel.MigrationTable, ymodel.MigrationLockTable)
+	}
+
+	if migrateDown > 0 {
+		if len(migrations) == 0 {
+			line(L("No versioned migrations to roll back"))
+			return
+		}
+
+		applied, _, err := migrator.Applied()
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			return
+		}
+
+		n := migrateDown
+		for i := len(migrations) - 1; i >= 0 && n > 0; i-- {
+			mig := migrations[i]
+			if _, has := applied[mig.Version]; !has {
+				continue
+			}
+			n--
+			line(L("Roll back migration: %s (%s)"), mig.Version, mig.File)
+			fmt.Println(color.GreenString("   %s", strings.ReplaceAll(mig.Down.String(), "\n", "\n   ")))
+		}
+		line(L("Release the lock"))
+		return
+	}
+
+	for _, mod := range models {
+		table := mod.MetaData.Table.Name
+		if resetModel {
+			line(color.RedString("DROP TABLE %s; [DESTRUCTIVE]", table))
+			line(L("Create the table %s of the model %s"), table, mod.ID)
+			continue
+		}
+
+		plan, err := ymodel.PlanOf(mod)
+		if err != nil {
+			line(color.RedString(L("Model: %s (%s) %s"), mod.ID, table, err.Error()))
+			continue
+		}
+
+		if len(plan.Changes) == 0 {
+			line(L("Skip the model %s (%s), no changes"), mod.ID, table)
+			continue
+		}
+
+		if plan.Changes[0].Action == "create" {
+			line(L("Create the table %s of the model %s"), table, mod.ID)
+			continue
+		}
+		line(L("Upgrade the table %s of the model %s, %d differences (see --plan)"), table, mod.ID, len(plan.Changes))
+	}
+
+	pending, err := migrator.Pending(migrations)
+	if err != nil {
+		fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+		return
+	}
+
+	for _, mig := range pending {
+		line(L("Apply migration: %s (%s)"), mig.Version, mig.File)
+		fmt.Println(color.GreenString("   %s", strings.ReplaceAll(mig.Up.String(), "\n", "\n   ")))
+	}
+
+	if len(migrations) > 0 {
+		line(L("Release the lock"))
+	}
+
+	if name == "" && share.App.AfterMigrate != "" {
+		line(L("Run the AfterMigrate hook: %s"), share.App.AfterMigrate)
+	}
+}
+
+// describeChange the description of the schema change
+func describeChange(change ymodel.Change) string {
+	switch change.Action {
+	case "create":
+		return fmt.Sprintf(L("create table %s"), change.Table)
+	case "add":
+		return fmt.Sprintf(L("add column %s"), change.Name)
+	case "alter":
+		return fmt.Sprintf(L("alter column %s"), change.Name)
+	case "drop":
+		return fmt.Sprintf(L("drop column %s"), change.Name)
+	case "index.add":
+		return fmt.Sprintf(L("add index %s"), change.Name)
+	case "index.drop":
+		return fmt.Sprintf(L("drop index %s"), change.Name)
+	}
+	return fmt.Sprintf("%s %s", change.Action, change.Name)
 }
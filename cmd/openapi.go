@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/neo"
+	"github.com/yaoapp/yao/openapi"
+	"github.com/yaoapp/yao/share"
+)
+
+var openapiServers []string
+var openapiExtensions bool
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: L("Generate the OpenAPI document"),
+	Long:  L("Generate the OpenAPI document"),
+	Run: func(cmd *cobra.Command, args []string) {
+		defer func() {
+			err := exception.Catch(recover())
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			}
+		}()
+
+		Boot()
+
+		err := engine.Load(config.Conf, engine.LoadOption{Action: "openapi"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		// Collect the routes of the Neo API, they are not defined by the API DSLs
+		gin.SetMode(gin.ReleaseMode)
+		router := gin.New()
+		if neo.Neo != nil {
+			neo.Neo.API(router, "/api/__yao/neo")
+		}
+
+		servers := openapiServers
+		extensions := openapiExtensions
+		if setting := share.App.OpenAPI; setting != nil {
+			if len(servers) == 0 {
+				servers = setting.Servers
+			}
+			extensions = extensions || setting.Extensions
+		}
+
+		doc := openapi.Generate(openapi.Option{Servers: servers, Routes: router.Routes(), Extensions: extensions})
+		data, err := jsoniter.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		if len(args) == 0 {
+			fmt.Println(string(data))
+			return
+		}
+
+		err = os.WriteFile(args[0], data, 0644)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString("File: %s", args[0]))
+	},
+}
+
+func init() {
+	openapiCmd.PersistentFlags().StringArrayVarP(&openapiServers, "server", "s", []string{}, L("The server url of the document"))
+	openapiCmd.PersistentFlags().BoolVarP(&openapiExtensions, "extensions", "", false, L("Add the processes and the guards of the APIs"))
+}
@@ -0,0 +1,278 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Editor the line editor, supports the history, the tab completion and the basic emacs keys in the terminal
+type Editor struct {
+	in       *os.File
+	reader   *bufio.Reader
+	out      io.Writer
+	prompt   string
+	history  *History
+	complete func(word string) []string
+	tty      bool
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyTab       = 9
+	keyEnter     = 13
+	keyNewLine   = 10
+	keyEscape    = 27
+	keyBackspace = 127
+	keyCtrlH     = 8
+)
+
+// NewEditor create a line editor, the line is read without editing if the input is not a terminal
+func NewEditor(in *os.File, out io.Writer, prompt string, history *History, complete func(word string) []string) *Editor {
+	return &Editor{
+		in:       in,
+		reader:   bufio.NewReader(in),
+		out:      out,
+		prompt:   prompt,
+		history:  history,
+		complete: complete,
+		tty:      isTerminal(int(in.Fd())),
+	}
+}
+
+// ReadLine read a line, returns io.EOF when the input is closed or Ctrl-D on the empty line
+func (editor *Editor) ReadLine() (string, error) {
+	if !editor.tty {
+		fmt.Fprint(editor.out, editor.prompt)
+		line, err := editor.reader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			return line, nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	restore, err := makeRaw(int(editor.in.Fd()))
+	if err != nil {
+		editor.tty = false
+		return editor.ReadLine()
+	}
+	defer restore()
+
+	return editor.edit()
+}
+
+func (editor *Editor) edit() (string, error) {
+	line := []rune{}
+	pos := 0
+	lines := editor.history.Lines()
+	index := len(lines) // The history index, len(lines) is the current line
+	current := []rune{} // The current line before browsing the history
+
+	editor.refresh(line, pos)
+	for {
+		r, _, err := editor.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, keyNewLine:
+			fmt.Fprint(editor.out, "\r\n")
+			return string(line), nil
+
+		case keyCtrlC:
+			fmt.Fprint(editor.out, "^C\r\n")
+			line, pos = []rune{}, 0
+
+		case keyCtrlD:
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+
+		case keyCtrlA:
+			pos = 0
+
+		case keyCtrlE:
+			pos = len(line)
+
+		case keyCtrlK:
+			line = line[:pos]
+
+		case keyCtrlU:
+			line, pos = line[pos:], 0
+
+		case keyCtrlW:
+			start := pos
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line, pos = append(line[:start], line[pos:]...), start
+
+		case keyCtrlL:
+			fmt.Fprint(editor.out, "\x1b[H\x1b[2J")
+
+		case keyBackspace, keyCtrlH:
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+
+		case keyTab:
+			line, pos = editor.tab(line, pos)
+
+		case keyEscape:
+			seq := editor.escape()
+			switch seq {
+			case "[A": // Up
+				if index > 0 {
+					if index == len(lines) {
+						current = line
+					}
+					index--
+					line = []rune(lines[index])
+					pos = len(line)
+				}
+
+			case "[B": // Down
+				if index < len(lines) {
+					index++
+					if index == len(lines) {
+						line = current
+					} else {
+						line = []rune(lines[index])
+					}
+					pos = len(line)
+				}
+
+			case "[C": // Right
+				if pos < len(line) {
+					pos++
+				}
+
+			case "[D": // Left
+				if pos > 0 {
+					pos--
+				}
+
+			case "[H", "[1~", "OH":
+				pos = 0
+
+			case "[F", "[4~", "OF":
+				pos = len(line)
+
+			case "[3~": // Delete
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+
+		default:
+			if r < 32 {
+				continue
+			}
+			line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+			pos++
+		}
+
+		editor.refresh(line, pos)
+	}
+}
+
+// escape read the escape sequence. e.g. [A, [3~
+func (editor *Editor) escape() string {
+	seq := []rune{}
+	for {
+		r, _, err := editor.reader.ReadRune()
+		if err != nil {
+			return string(seq)
+		}
+
+		seq = append(seq, r)
+		if len(seq) == 1 && r != '[' && r != 'O' {
+			return string(seq)
+		}
+
+		if len(seq) > 1 && (r >= 'A' && r <= 'Z' || r == '~') {
+			return string(seq)
+		}
+
+		if len(seq) > 6 {
+			return string(seq)
+		}
+	}
+}
+
+// tab complete the word before the cursor, prints the candidates if there are more than one
+func (editor *Editor) tab(line []rune, pos int) ([]rune, int) {
+	if editor.complete == nil {
+		return line, pos
+	}
+
+	start := pos
+	for start > 0 && line[start-1] != ' ' {
+		start--
+	}
+
+	word := string(line[start:pos])
+	candidates := editor.complete(word)
+	if len(candidates) == 0 {
+		return line, pos
+	}
+
+	completion := candidates[0]
+	if len(candidates) > 1 {
+		completion = commonPrefix(candidates)
+		if len(completion) <= len(word) {
+			fmt.Fprint(editor.out, "\r\n")
+			max := 50
+			for i, candidate := range candidates {
+				if i == max {
+					fmt.Fprintf(editor.out, "... %d more\r\n", len(candidates)-max)
+					break
+				}
+				fmt.Fprintf(editor.out, "%s\r\n", candidate)
+			}
+			return line, pos
+		}
+	} else if !strings.HasSuffix(completion, " ") {
+		completion = completion + " "
+	}
+
+	replaced := append([]rune{}, line[:start]...)
+	replaced = append(replaced, []rune(completion)...)
+	newPos := len(replaced)
+	replaced = append(replaced, line[pos:]...)
+	return replaced, newPos
+}
+
+func (editor *Editor) refresh(line []rune, pos int) {
+	fmt.Fprintf(editor.out, "\r\x1b[K%s%s", editor.prompt, string(line))
+	if back := len(line) - pos; back > 0 {
+		fmt.Fprintf(editor.out, "\x1b[%dD", back)
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(strings.ToLower(word), strings.ToLower(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,72 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// History the command history of the REPL, the lines are saved to the file
+type History struct {
+	file  string
+	max   int
+	lines []string
+}
+
+// NewHistory load the history from the file, keeps the last max lines
+func NewHistory(file string, max int) *History {
+	history := &History{file: file, max: max, lines: []string{}}
+	if file == "" {
+		return history
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return history
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			history.lines = append(history.lines, line)
+		}
+	}
+
+	if len(history.lines) > max {
+		history.lines = history.lines[len(history.lines)-max:]
+	}
+	return history
+}
+
+// DefaultHistoryFile the history file in the home directory, empty if the home directory is unknown
+func DefaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".yao_history")
+}
+
+// Add append the line, the same line as the last one is ignored
+func (history *History) Add(line string) {
+	if len(history.lines) > 0 && history.lines[len(history.lines)-1] == line {
+		return
+	}
+
+	history.lines = append(history.lines, line)
+	if len(history.lines) > history.max {
+		history.lines = history.lines[len(history.lines)-history.max:]
+	}
+	history.save()
+}
+
+// Lines the history lines, the oldest first
+func (history *History) Lines() []string {
+	return history.lines
+}
+
+func (history *History) save() {
+	if history.file == "" {
+		return
+	}
+	os.WriteFile(history.file, []byte(strings.Join(history.lines, "\n")+"\n"), 0600)
+}
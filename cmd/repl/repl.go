@@ -0,0 +1,481 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/flow"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/exception"
+)
+
+// Prompt the prompt of the REPL
+var Prompt = "yao> "
+
+// REPL the interactive process runner, the engine is loaded once and the results are kept in the variables
+type REPL struct {
+	Vars    map[string]interface{} // The variables, $_ is the last result
+	Sid     string                 // The session id of the processes
+	History *History
+	out     io.Writer
+}
+
+var reAssign = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.+)$`)
+var reVar = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)((\.[A-Za-z0-9_\-]+)*)$`)
+
+// New create a REPL, the history is saved to the file if it is not empty
+func New(historyFile string, out io.Writer) *REPL {
+	return &REPL{
+		Vars:    map[string]interface{}{},
+		Sid:     uuid.NewString(),
+		History: NewHistory(historyFile, 1000),
+		out:     out,
+	}
+}
+
+// Run read and evaluate the lines until :exit or EOF
+func (r *REPL) Run(in *os.File) error {
+	fmt.Fprintln(r.out, color.WhiteString("Type :help for the commands, :exit to quit. Session: %s", r.Sid))
+	editor := NewEditor(in, r.out, Prompt, r.History, r.Complete)
+	for {
+		line, err := editor.ReadLine()
+		if err == io.EOF {
+			fmt.Fprintln(r.out)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r.History.Add(line)
+
+		res, exit, err := r.Eval(line)
+		if exit {
+			return nil
+		}
+
+		if err != nil {
+			fmt.Fprintln(r.out, color.RedString("%s", err.Error()))
+			continue
+		}
+
+		if res != nil {
+			r.print(res)
+		}
+	}
+}
+
+// Eval evaluate the line, the line is a command (:help), a process call or an assignment ($pet = models.pet.Find 1 ::{})
+func (r *REPL) Eval(line string) (res interface{}, exit bool, err error) {
+	if strings.HasPrefix(line, ":") {
+		return r.command(line)
+	}
+
+	name := ""
+	if matches := reAssign.FindStringSubmatch(line); matches != nil {
+		name = matches[1]
+		line = matches[2]
+	}
+
+	tokens, err := Tokenize(line)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Print the variable
+	if len(tokens) == 1 && strings.HasPrefix(tokens[0], "$") {
+		res, err = r.value(tokens[0])
+		if err == nil && name != "" {
+			r.Vars[name] = res
+		}
+		return res, false, err
+	}
+
+	args, err := r.Args(tokens[1:])
+	if err != nil {
+		return nil, false, err
+	}
+
+	res, err = r.Exec(tokens[0], args)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.Vars["_"] = res
+	if name != "" {
+		r.Vars[name] = res
+	}
+	return res, false, nil
+}
+
+// Exec execute the process with the session of the REPL
+func (r *REPL) Exec(name string, args []interface{}) (res interface{}, err error) {
+	defer func() {
+		if e := exception.Catch(recover()); e != nil {
+			err = e
+		}
+	}()
+
+	p := process.NewWithContext(context.Background(), name, args...)
+	p.Sid = r.Sid
+	res, err = p.Exec()
+	if err != nil {
+		return nil, fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "Exception|404:"))
+	}
+	return res, nil
+}
+
+// Args parse the arguments, the same syntax as yao run. The ::json is parsed as JSON, the $var is replaced with the variable
+func (r *REPL) Args(tokens []string) ([]interface{}, error) {
+	args := []interface{}{}
+	for _, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, "::"):
+			var v interface{}
+			err := jsoniter.Unmarshal([]byte(strings.TrimPrefix(token, "::")), &v)
+			if err != nil {
+				return nil, fmt.Errorf("Arguments: %s %s", token, err.Error())
+			}
+			args = append(args, v)
+
+		case strings.HasPrefix(token, "\\::"):
+			args = append(args, "::"+strings.TrimPrefix(token, "\\::"))
+
+		case strings.HasPrefix(token, "\\$"):
+			args = append(args, strings.TrimPrefix(token, "\\"))
+
+		case reVar.MatchString(token):
+			v, err := r.value(token)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+
+		default:
+			args = append(args, token)
+		}
+	}
+	return args, nil
+}
+
+// Complete the process names and the commands start with the word
+func (r *REPL) Complete(word string) []string {
+	candidates := []string{}
+	lower := strings.ToLower(word)
+	if strings.HasPrefix(word, ":") {
+		for _, cmd := range commands {
+			if strings.HasPrefix(cmd, lower) {
+				candidates = append(candidates, cmd)
+			}
+		}
+		return candidates
+	}
+
+	if strings.HasPrefix(word, "$") {
+		for name := range r.Vars {
+			if strings.HasPrefix("$"+name, word) {
+				candidates = append(candidates, "$"+name)
+			}
+		}
+		sort.Strings(candidates)
+		return candidates
+	}
+
+	for _, name := range Processes() {
+		if strings.HasPrefix(strings.ToLower(name), lower) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// Processes the process names of the registered handlers, the model and flow processes are expanded by the loaded ids
+func Processes() []string {
+	names := map[string]bool{}
+	for name := range process.Handlers {
+		switch {
+		case strings.HasPrefix(name, "models."):
+			method := strings.TrimPrefix(name, "models.")
+			for id := range model.Models {
+				names[fmt.Sprintf("models.%s.%s", id, method)] = true
+			}
+
+		case name == "flows":
+			for id := range flow.Flows {
+				names[fmt.Sprintf("flows.%s", id)] = true
+			}
+
+		default:
+			names[name] = true
+		}
+	}
+
+	res := []string{}
+	for name := range names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+var commands = []string{":exit", ":help", ":history", ":quit", ":session", ":sid", ":unset", ":user", ":vars"}
+
+func (r *REPL) command(line string) (interface{}, bool, error) {
+	tokens, err := Tokenize(line)
+	if err != nil {
+		return nil, false, err
+	}
+
+	args, err := r.Args(tokens[1:])
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch tokens[0] {
+	case ":exit", ":quit":
+		return nil, true, nil
+
+	case ":help":
+		fmt.Fprintln(r.out, help)
+		return nil, false, nil
+
+	case ":vars":
+		names := []string{}
+		for name := range r.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			data, _ := jsoniter.Marshal(r.Vars[name])
+			text := string(data)
+			if len(text) > 80 {
+				text = text[:77] + "..."
+			}
+			fmt.Fprintf(r.out, "%s %s\n", color.CyanString("$%s", name), text)
+		}
+		return nil, false, nil
+
+	case ":unset":
+		for _, name := range tokens[1:] {
+			delete(r.Vars, strings.TrimPrefix(name, "$"))
+		}
+		return nil, false, nil
+
+	case ":history":
+		for i, line := range r.History.Lines() {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, line)
+		}
+		return nil, false, nil
+
+	case ":sid":
+		if len(args) > 0 {
+			r.Sid = fmt.Sprintf("%v", args[0])
+			if r.Sid == "new" {
+				r.Sid = uuid.NewString()
+			}
+		}
+		return r.Sid, false, nil
+
+	case ":user":
+		if len(args) < 1 {
+			user, err := session.Global().ID(r.Sid).Get("user")
+			return user, false, err
+		}
+
+		user := args[0]
+		id := user
+		if data, ok := user.(map[string]interface{}); ok {
+			id = data["id"]
+		}
+
+		err := session.Global().ID(r.Sid).Set("user_id", id)
+		if err == nil {
+			err = session.Global().ID(r.Sid).Set("user", user)
+		}
+		return nil, false, err
+
+	case ":session":
+		if len(args) < 1 {
+			return nil, false, fmt.Errorf("Usage: :session <key> [value]")
+		}
+
+		key := fmt.Sprintf("%v", args[0])
+		if len(args) < 2 {
+			v, err := session.Global().ID(r.Sid).Get(key)
+			return v, false, err
+		}
+		return nil, false, session.Global().ID(r.Sid).Set(key, args[1])
+	}
+
+	return nil, false, fmt.Errorf("%s is not a command, type :help for the commands", tokens[0])
+}
+
+// value the variable value, the fields are accessed by the dot. e.g. $pet.name, $rows.0.id
+func (r *REPL) value(token string) (interface{}, error) {
+	matches := reVar.FindStringSubmatch(token)
+	if matches == nil {
+		return nil, fmt.Errorf("%s is not a variable", token)
+	}
+
+	v, has := r.Vars[matches[1]]
+	if !has {
+		return nil, fmt.Errorf("$%s is not defined", matches[1])
+	}
+
+	if matches[2] == "" {
+		return v, nil
+	}
+
+	for _, key := range strings.Split(strings.TrimPrefix(matches[2], "."), ".") {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			v = value[key]
+
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, fmt.Errorf("%s the index %s is out of range", token, key)
+			}
+			v = value[i]
+
+		default:
+			// Convert the value to the generic type. e.g. maps.MapStr, []map[string]interface{}
+			data, err := jsoniter.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+
+			var generic interface{}
+			err = jsoniter.Unmarshal(data, &generic)
+			if err != nil {
+				return nil, err
+			}
+
+			switch g := generic.(type) {
+			case map[string]interface{}:
+				v = g[key]
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(g) {
+					return nil, fmt.Errorf("%s the index %s is out of range", token, key)
+				}
+				v = g[i]
+			default:
+				return nil, fmt.Errorf("%s the %s is not an object", token, key)
+			}
+		}
+	}
+	return v, nil
+}
+
+func (r *REPL) print(res interface{}) {
+	if r.out == os.Stdout {
+		helper.Dump(res)
+		return
+	}
+
+	data, err := jsoniter.MarshalIndent(res, "", "  ")
+	if err != nil {
+		fmt.Fprintln(r.out, err.Error())
+		return
+	}
+	fmt.Fprintln(r.out, string(data))
+}
+
+// Tokenize split the line by the spaces, the quoted strings and the JSON of the ::json arguments are kept
+func Tokenize(line string) ([]string, error) {
+	tokens := []string{}
+	current := []rune{}
+	quote := rune(0)
+	depth := 0
+	started := false
+
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				if depth > 0 {
+					current = append(current, c)
+				}
+				continue
+			}
+			current = append(current, c)
+
+		case c == '"' || c == '\'':
+			quote = c
+			started = true
+			if depth > 0 {
+				current = append(current, c)
+			}
+
+		case c == '{' || c == '[':
+			depth++
+			started = true
+			current = append(current, c)
+
+		case c == '}' || c == ']':
+			depth--
+			current = append(current, c)
+
+		case (c == ' ' || c == '\t') && depth <= 0:
+			if started {
+				tokens = append(tokens, string(current))
+			}
+			current = []rune{}
+			started = false
+
+		default:
+			started = true
+			current = append(current, c)
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("the quote %c is not closed", quote)
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("the JSON argument is not closed")
+	}
+
+	if started {
+		tokens = append(tokens, string(current))
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("the process name is required")
+	}
+	return tokens, nil
+}
+
+const help = `Commands:
+  <process> [args...]          Execute the process. e.g. models.pet.Find 1 ::{}
+  $name = <process> [args...]  Execute the process and keep the result in $name
+  $name                        Print the variable, the fields are accessed by the dot. e.g. $pet.name
+  ::<json>                     The JSON argument. e.g. ::{"wheres":[{"column":"id","value":1}]}
+  $_                           The last result
+  :vars                        List the variables
+  :unset $name                 Remove the variables
+  :sid [id|new]                Print or set the session id of the processes
+  :user <id|::json>            Set the user of the session (user_id, user)
+  :session <key> [value]       Get or set the session data
+  :history                     Print the history
+  :exit                        Quit`
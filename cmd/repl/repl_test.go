@@ -0,0 +1,78 @@
+package repl
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize(`models.pet.Find 1 ::{"select": ["id", "name"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"models.pet.Find", "1", `::{"select": ["id", "name"]}`}, tokens)
+
+	tokens, err = Tokenize(`utils.str.Concat "hello world" 'foo bar' \::raw`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"utils.str.Concat", "hello world", "foo bar", `\::raw`}, tokens)
+
+	_, err = Tokenize(`utils.str.Concat "hello`)
+	assert.NotNil(t, err)
+
+	_, err = Tokenize(`models.pet.Get ::{"wheres": [`)
+	assert.NotNil(t, err)
+}
+
+func TestArgs(t *testing.T) {
+	r := New("", &bytes.Buffer{})
+	r.Vars["pet"] = map[string]interface{}{"id": float64(1), "tags": []interface{}{"cat", "dog"}}
+
+	args, err := r.Args([]string{"::{\"id\": 1}", "\\::raw", "$pet.id", "$pet.tags.1", "\\$pet", "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": float64(1)}, "::raw", float64(1), "dog", "$pet", "text"}, args)
+
+	_, err = r.Args([]string{"$unknown"})
+	assert.NotNil(t, err)
+
+	_, err = r.Args([]string{"$pet.tags.5"})
+	assert.NotNil(t, err)
+}
+
+func TestEvalVariable(t *testing.T) {
+	r := New("", &bytes.Buffer{})
+	r.Vars["pet"] = map[string]interface{}{"name": "Cookie"}
+
+	res, _, err := r.Eval("$name = $pet.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Cookie", res)
+	assert.Equal(t, "Cookie", r.Vars["name"])
+
+	_, exit, err := r.Eval(":exit")
+	assert.Nil(t, err)
+	assert.True(t, exit)
+
+	_, _, err = r.Eval(":unknown")
+	assert.NotNil(t, err)
+}
+
+func TestHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".yao_history")
+	history := NewHistory(file, 2)
+	history.Add("models.pet.Find 1 ::{}")
+	history.Add("models.pet.Find 1 ::{}")
+	history.Add("models.pet.Get ::{}")
+	history.Add("$_")
+	assert.Equal(t, []string{"models.pet.Get ::{}", "$_"}, history.Lines())
+
+	history = NewHistory(file, 10)
+	assert.Equal(t, []string{"models.pet.Get ::{}", "$_"}, history.Lines())
+}
@@ -17,11 +17,14 @@ import (
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
+	ymodel "github.com/yaoapp/yao/model"
 	"github.com/yaoapp/yao/share"
 )
 
 var restoreForce bool = false
 var migrateNoInsert bool = false
+var restoreOnly string
+var restoreSecret string
 var restoreCmd = &cobra.Command{
 	Use:   "restore",
 	Short: L("Restore the application data"),
@@ -54,10 +57,11 @@ var restoreCmd = &cobra.Command{
 		}
 
 		// Unzip files
-		dst := unzipFile(zipfile, func(file string) {
+		dst := unzipFile(zipfile, restoreSecret, func(file string) {
 			fmt.Printf("\r%s", strings.Repeat(" ", 80))
 			fmt.Printf("\r%s", color.GreenString(L("Unzip the file: %s"), file))
 		})
+		defer os.RemoveAll(dst)
 
 		// 加载数据模型
 		err = engine.Load(config.Conf, engine.LoadOption{Action: "restore"})
@@ -66,16 +70,58 @@ var restoreCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Restore models
-		restoreModels(filepath.Join(dst, "model"), []model.MigrateOption{
-			model.WithDonotInsertValues(migrateNoInsert),
-		})
+		migOpts := []model.MigrateOption{model.WithDonotInsertValues(migrateNoInsert)}
+		manifest, err := ymodel.ReadManifest(dst)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
 
-		// Restore Data
-		restoreData(filepath.Join(dst, "data"))
+		// The legacy dumps, the tables are recreated
+		if manifest == nil {
+			if restoreOnly != "" {
+				fmt.Println(color.RedString(L("Fatal: %s"), "--only requires a dump with the manifest"))
+				os.Exit(1)
+			}
 
-		// Clean
-		os.RemoveAll(dst)
+			restoreModels(filepath.Join(dst, "model"), migOpts)
+			restoreData(filepath.Join(dst, "data"))
+			fmt.Println(color.GreenString(L("✨DONE✨")))
+			return
+		}
+
+		fmt.Printf("\r%s", strings.Repeat(" ", 80))
+		err = manifest.Verify(dst)
+		if err != nil {
+			fmt.Println(color.RedString(L("\rVerify the manifest: %s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString(L("\rVerify the manifest: ✨DONE✨")))
+
+		// Upsert the rows
+		option := ymodel.RestoreOption{Only: []string{}, Migrate: migOpts, Dir: dst}
+		if restoreOnly != "" {
+			option.Only = strings.Split(restoreOnly, ",")
+		}
+
+		err = ymodel.Restore(manifest, option, func(mod *model.Model, curr, total int) {
+			fmt.Printf("\r%s", strings.Repeat(" ", 80))
+			fmt.Printf(color.GreenString(L("\rRestore model: %s (%s) %d/%d"), mod.Name, mod.MetaData.Table.Name, curr, total))
+		})
+		fmt.Println("")
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		// Merge the data directory
+		if restoreOnly == "" {
+			err = mergeData(filepath.Join(dst, "data"))
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+		}
 
 		fmt.Println(color.GreenString(L("✨DONE✨")))
 	},
@@ -84,6 +130,38 @@ var restoreCmd = &cobra.Command{
 func init() {
 	restoreCmd.PersistentFlags().BoolVarP(&restoreForce, "force", "", false, L("Force restore"))
 	restoreCmd.PersistentFlags().BoolVarP(&migrateNoInsert, "migrate-no-insert", "", false, L("Do not insert values when migrating"))
+	restoreCmd.PersistentFlags().StringVarP(&restoreOnly, "only", "", "", L("Model names or globs to restore, separated by commas"))
+	restoreCmd.PersistentFlags().StringVarP(&restoreSecret, "secret", "", "", L("Decrypt the dump with the secret"))
+}
+
+// mergeData copy the dumped files to the data directory, the existing files are overwritten
+func mergeData(basePath string) error {
+	if _, err := os.Stat(basePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	dataPath := filepath.Join(config.Conf.Root, "data")
+	return filepath.Walk(basePath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(basePath, file)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dataPath, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode())
+	})
 }
 
 func restoreData(basePath string) {
@@ -144,7 +222,7 @@ func restoreModels(basePath string, migOpts []model.MigrateOption) {
 	}
 }
 
-func unzipFile(file string, process func(file string)) string {
+func unzipFile(file string, secret string, process func(file string)) string {
 	_, err := os.Stat(file)
 
 	if errors.Is(err, os.ErrNotExist) {
@@ -160,6 +238,18 @@ func unzipFile(file string, process func(file string)) string {
 	dst := filepath.Join(os.TempDir(), fmt.Sprintf("%s-%s", filepath.Base(file), time.Now().Format("20060102150405")))
 	os.MkdirAll(dst, 0755)
 
+	// Decrypt the dump
+	if secret != "" {
+		decrypted := dst + ".zip"
+		err = cryptFile(file, decrypted, secret, false)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+		defer os.Remove(decrypted)
+		file = decrypted
+	}
+
 	archive, err := zip.OpenReader(file)
 	if err != nil {
 		fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
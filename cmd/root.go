@@ -137,6 +137,9 @@ func init() {
 	suiCmd.AddCommand(sui.WatchCmd)
 	suiCmd.AddCommand(sui.BuildCmd)
 	suiCmd.AddCommand(sui.TransCmd)
+	suiCmd.AddCommand(sui.ExportCmd)
+	suiCmd.AddCommand(sui.ImportCmd)
+	suiCmd.AddCommand(sui.ReportCmd)
 
 	rootCmd.AddCommand(
 		versionCmd,
@@ -144,9 +147,10 @@ func init() {
 		inspectCmd,
 		startCmd,
 		runCmd,
+		openapiCmd,
 		// getCmd,
-		// dumpCmd,
-		// restoreCmd,
+		dumpCmd,
+		restoreCmd,
 		// socketCmd,
 		// websocketCmd,
 		// packCmd,
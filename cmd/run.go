@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/fatih/color"
@@ -12,6 +13,7 @@ import (
 	"github.com/yaoapp/gou/plugin"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/cmd/repl"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
 	ischedule "github.com/yaoapp/yao/schedule"
@@ -20,6 +22,7 @@ import (
 )
 
 var runSilent = false
+var runInteractive = false
 
 var runCmd = &cobra.Command{
 	Use:   "run",
@@ -47,6 +50,11 @@ var runCmd = &cobra.Command{
 
 		cfg := config.Conf
 		cfg.Session.IsCLI = true
+		if runInteractive {
+			runREPL(cfg)
+			return
+		}
+
 		if len(args) < 1 {
 			if !runSilent {
 				color.Red(L("Not enough arguments\n"))
@@ -160,6 +168,29 @@ var runCmd = &cobra.Command{
 	},
 }
 
+// runREPL load the engine once and evaluate the processes interactively
+func runREPL(cfg config.Config) {
+	err := engine.Load(cfg, engine.LoadOption{Action: "run"})
+	if err != nil {
+		color.Red(L("Engine: %s\n"), err.Error())
+		return
+	}
+
+	// Start Tasks
+	itask.Start()
+	defer itask.Stop()
+
+	// Start Schedules
+	ischedule.Start()
+	defer ischedule.Stop()
+
+	err = repl.New(repl.DefaultHistoryFile(), os.Stdout).Run(os.Stdin)
+	if err != nil {
+		color.Red(L("Fatal: %s\n"), err.Error())
+	}
+}
+
 func init() {
 	runCmd.PersistentFlags().BoolVarP(&runSilent, "silent", "s", false, L("Silent mode"))
+	runCmd.PersistentFlags().BoolVarP(&runInteractive, "interactive", "i", false, L("Interactive mode, evaluate the processes in a REPL"))
 }
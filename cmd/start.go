@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -22,6 +23,7 @@ import (
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/health"
 	ischedule "github.com/yaoapp/yao/schedule"
 	"github.com/yaoapp/yao/service"
 	"github.com/yaoapp/yao/setup"
@@ -240,6 +242,7 @@ var startCmd = &cobra.Command{
 				case http.READY:
 					fmt.Println(color.GreenString(L("✨Server is up and running...")))
 					fmt.Println(color.GreenString("✨Ctrl+C to stop"))
+					go checkReadiness()
 					break
 
 				case http.CLOSED:
@@ -331,6 +334,16 @@ func printConnectors(silent bool) {
 	}
 }
 
+// checkReadiness print the failed readiness checks, the server is up but the dependencies may be unavailable
+func checkReadiness() {
+	res := health.Ready(context.Background())
+	for _, name := range res.Failures() {
+		message := fmt.Sprintf("[Health] %s is not ready: %s", name, res.Checks[name].Error)
+		fmt.Println(color.YellowString(message))
+		log.Warn(message)
+	}
+}
+
 func printStores(silent bool) {
 	if len(store.Pools) == 0 {
 		return
@@ -0,0 +1,238 @@
+package sui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/sui/core"
+)
+
+var exportFormat string
+var importFormat string
+var reportFormat string
+var output string
+
+// ExportCmd command
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: L("Export the locale messages of the template"),
+	Long:  L("Export the locale messages of the template to XLIFF 2.0 or gettext PO"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, color.RedString(L("yao sui export <sui> <template> [--format xliff|po] [--output dir] [--locales zh-cn,ja]")))
+			return
+		}
+
+		tmpl, err := localeTemplate("sui.export", args[0], args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+			return
+		}
+
+		ext := "xlf"
+		if strings.ToLower(exportFormat) == "po" {
+			ext = "po"
+		}
+
+		err = os.MkdirAll(output, os.ModePerm)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+			return
+		}
+
+		option := core.BuildOption{SSR: true}
+		for _, name := range localeNames(tmpl) {
+			catalog, err := tmpl.LocaleCatalog(name, &option)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+
+			raw, err := catalog.Export(exportFormat)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+
+			file := filepath.Join(output, fmt.Sprintf("%s.%s.%s", args[1], name, ext))
+			err = os.WriteFile(file, raw, 0644)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+
+			report := catalog.Report()
+			fmt.Println(color.WhiteString("  %s:\t%s (%d/%d translated)", name, file, report.Translated, report.Total))
+		}
+		fmt.Println(color.GreenString("Export succeeded"))
+	},
+}
+
+// ImportCmd command
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: L("Import the translated locale messages to the template"),
+	Long:  L("Import the translated XLIFF 2.0 or gettext PO files to the template locale files"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 3 {
+			fmt.Fprintln(os.Stderr, color.RedString(L("yao sui import <sui> <template> <file>... [--locales zh-cn]")))
+			return
+		}
+
+		tmpl, err := localeTemplate("sui.import", args[0], args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+			return
+		}
+
+		for _, file := range args[2:] {
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+
+			fileFormat := importFormat
+			if !cmd.Flags().Changed("format") {
+				fileFormat = strings.TrimPrefix(filepath.Ext(file), ".")
+			}
+
+			catalog, err := core.ParseLocaleCatalog(fileFormat, raw)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString("%s: %s", file, err.Error()))
+				return
+			}
+
+			if locales != "" {
+				catalog.Target = strings.ToLower(strings.TrimSpace(strings.Split(locales, ",")[0]))
+			}
+
+			warnings, err := tmpl.ImportLocaleCatalog(catalog)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString("%s: %s", file, err.Error()))
+				return
+			}
+
+			for _, warning := range warnings {
+				fmt.Println(color.YellowString("Warning: %s", warning))
+			}
+			fmt.Println(color.WhiteString("  %s:\t%s (%d messages)", catalog.Target, file, len(catalog.Entries)))
+		}
+		fmt.Println(color.GreenString("Import succeeded, run `yao sui build` to apply the translations"))
+	},
+}
+
+// ReportCmd command
+var ReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: L("Report the missing and stale locale messages of the template"),
+	Long:  L("Report the missing and stale locale messages of the template"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, color.RedString(L("yao sui report <sui> <template> [--locales zh-cn,ja] [--format json]")))
+			return
+		}
+
+		tmpl, err := localeTemplate("sui.report", args[0], args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+			return
+		}
+
+		option := core.BuildOption{SSR: true}
+		reports := []core.LocaleReport{}
+		for _, name := range localeNames(tmpl) {
+			catalog, err := tmpl.LocaleCatalog(name, &option)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+			reports = append(reports, catalog.Report())
+		}
+
+		if strings.ToLower(reportFormat) == "json" {
+			raw, err := jsoniter.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+			fmt.Println(string(raw))
+			return
+		}
+
+		for _, report := range reports {
+			fmt.Println(color.WhiteString("-----------------------"))
+			fmt.Println(color.GreenString("%s: %d/%d translated, %d drafts, %d missing, %d stale", report.Locale, report.Translated, report.Total, len(report.Drafts), len(report.Missing), len(report.Stale)))
+			fmt.Println(color.WhiteString("-----------------------"))
+			for _, entry := range report.Missing {
+				fmt.Println(color.YellowString("  missing\t%s\t[%s] %s", entry.Route, entry.Type, entry.Source))
+			}
+			for _, entry := range report.Drafts {
+				fmt.Println(color.CyanString("  draft\t%s\t[%s] %s", entry.Route, entry.Type, entry.Source))
+			}
+			for _, entry := range report.Stale {
+				fmt.Println(color.RedString("  stale\t%s\t%s", entry.Route, entry.Source))
+			}
+		}
+	},
+}
+
+// localeTemplate boot the engine and get the template
+func localeTemplate(action string, id string, name string) (core.ITemplate, error) {
+	Boot()
+
+	cfg := config.Conf
+	err := engine.Load(cfg, engine.LoadOption{Action: action})
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionData map[string]interface{}
+	err = jsoniter.UnmarshalFromString(strings.TrimPrefix(data, "::"), &sessionData)
+	if err != nil {
+		return nil, err
+	}
+
+	sid := uuid.New().String()
+	if sessionData != nil && len(sessionData) > 0 {
+		session.Global().ID(sid).SetMany(sessionData)
+	}
+
+	sui, has := core.SUIs[id]
+	if !has {
+		return nil, fmt.Errorf("the sui %s does not exist", id)
+	}
+	sui.WithSid(sid)
+	return sui.GetTemplate(name)
+}
+
+// localeNames get the locales to process, the default locale is ignored
+func localeNames(tmpl core.ITemplate) []string {
+	names := []string{}
+	if locales != "" {
+		for _, name := range strings.Split(locales, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	for _, locale := range tmpl.Locales() {
+		if locale.Default {
+			continue
+		}
+		names = append(names, locale.Value)
+	}
+	return names
+}
@@ -3,6 +3,8 @@ package sui
 var data string
 var locales string
 var debug bool
+var translate bool
+var connector string
 
 func init() {
 	WatchCmd.PersistentFlags().StringVarP(&data, "data", "d", "::{}", L("Session Data"))
@@ -11,4 +13,16 @@ func init() {
 	TransCmd.PersistentFlags().StringVarP(&data, "data", "d", "::{}", L("Session Data"))
 	TransCmd.PersistentFlags().BoolVarP(&debug, "debug", "D", false, L("Debug mode"))
 	TransCmd.PersistentFlags().StringVarP(&locales, "locales", "l", "", L("Locales, separated by commas"))
+	TransCmd.PersistentFlags().BoolVarP(&translate, "translate", "t", false, L("Machine translate the untranslated messages as drafts"))
+	TransCmd.PersistentFlags().StringVarP(&connector, "connector", "c", "", L("The AI connector used by the machine translation"))
+	ExportCmd.PersistentFlags().StringVarP(&data, "data", "d", "::{}", L("Session Data"))
+	ExportCmd.PersistentFlags().StringVarP(&locales, "locales", "l", "", L("Locales, separated by commas"))
+	ExportCmd.PersistentFlags().StringVarP(&exportFormat, "format", "F", "xliff", L("File format, xliff or po"))
+	ExportCmd.PersistentFlags().StringVarP(&output, "output", "o", ".", L("Output directory"))
+	ImportCmd.PersistentFlags().StringVarP(&data, "data", "d", "::{}", L("Session Data"))
+	ImportCmd.PersistentFlags().StringVarP(&locales, "locales", "l", "", L("Target locale, overrides the locale of the file"))
+	ImportCmd.PersistentFlags().StringVarP(&importFormat, "format", "F", "xliff", L("File format, xliff or po, detected by the file extension by default"))
+	ReportCmd.PersistentFlags().StringVarP(&data, "data", "d", "::{}", L("Session Data"))
+	ReportCmd.PersistentFlags().StringVarP(&locales, "locales", "l", "", L("Locales, separated by commas"))
+	ReportCmd.PersistentFlags().StringVarP(&reportFormat, "format", "F", "text", L("Output format, text or json"))
 }
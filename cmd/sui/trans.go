@@ -124,6 +124,28 @@ var TransCmd = &cobra.Command{
 			fmt.Println("")
 		}
 
+		// Machine translation
+		if translate {
+			translator, err := core.NewMachineTranslator(connector)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				return
+			}
+
+			for _, locale := range definedLocales {
+				if locale.Default {
+					translator.Source = locale.Value
+				}
+			}
+			option.Translator = translator
+
+			name := connector
+			if name == "" {
+				name = "moapi"
+			}
+			fmt.Println(color.WhiteString("Machine translate the untranslated messages by %s, the results are saved as drafts", name))
+		}
+
 		warnings, err := tmpl.Trans(&option)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
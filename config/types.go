@@ -25,6 +25,9 @@ type Config struct {
 	Session       Session  `json:"session,omitempty"`                                         // Session Config
 	Studio        Studio   `json:"studio,omitempty"`                                          // Studio config
 	Runtime       Runtime  `json:"runtime,omitempty"`                                         // Runtime config
+	Metrics       Metrics  `json:"metrics,omitempty"`                                         // Metrics config
+	Trace         Trace    `json:"trace,omitempty"`                                           // Tracing config
+	AccessLog     Access   `json:"access_log,omitempty"`                                      // Access log config
 }
 
 // Studio the studio config
@@ -54,6 +57,32 @@ type Session struct {
 	IsCLI    bool   `json:"iscli,omitempty" env:"YAO_SESSION_ISCLI" envDefault:"false"`   // Command Line Start
 }
 
+// Metrics the Prometheus metrics endpoint config
+type Metrics struct {
+	Enable bool   `json:"enable,omitempty" env:"YAO_METRICS" envDefault:"false"`       // Expose the metrics endpoint, the default value is false
+	Path   string `json:"path,omitempty" env:"YAO_METRICS_PATH" envDefault:"/metrics"` // The path of the metrics endpoint
+	Guard  string `json:"guard,omitempty" env:"YAO_METRICS_GUARD"`                     // The guards of the metrics endpoint, the separator is ",". e.g. bearer-jwt
+	Token  string `json:"token,omitempty" env:"YAO_METRICS_TOKEN"`                     // The bearer token for the scraper, checked when the guard is not set
+}
+
+// Trace the OpenTelemetry compatible tracing config
+type Trace struct {
+	Exporter string  `json:"exporter,omitempty" env:"YAO_TRACE_EXPORTER"`                                    // The exporter otlp/file, the tracing is disabled if it is empty
+	Endpoint string  `json:"endpoint,omitempty" env:"YAO_TRACE_ENDPOINT" envDefault:"http://127.0.0.1:4318"` // The OTLP/HTTP collector endpoint
+	Headers  string  `json:"headers,omitempty" env:"YAO_TRACE_HEADERS"`                                      // The headers of the OTLP requests, the separator is ",". e.g. Authorization=Bearer xxx
+	File     string  `json:"file,omitempty" env:"YAO_TRACE_FILE"`                                            // The file of the file exporter, the default is logs/trace.jsonl
+	Service  string  `json:"service,omitempty" env:"YAO_TRACE_SERVICE" envDefault:"yao"`                     // The service name of the spans
+	Sample   float64 `json:"sample,omitempty" env:"YAO_TRACE_SAMPLE" envDefault:"1"`                         // The sample ratio of the new traces, 0-1
+}
+
+// Access the access log config, the records are written to the log with the log mode
+type Access struct {
+	Disable       bool     `json:"disable,omitempty" env:"YAO_ACCESS_LOG_DISABLE" envDefault:"false"`                                                                             // Disable the access log
+	Headers       bool     `json:"headers,omitempty" env:"YAO_ACCESS_LOG_HEADERS" envDefault:"false"`                                                                             // Log the request headers
+	RedactHeaders []string `json:"redact_headers,omitempty" env:"YAO_ACCESS_LOG_REDACT_HEADERS" envSeparator:"," envDefault:"Authorization,Cookie,Proxy-Authorization,X-Api-Key"` // The headers are redacted
+	RedactQuery   []string `json:"redact_query,omitempty" env:"YAO_ACCESS_LOG_REDACT_QUERY" envSeparator:"," envDefault:"token,__tk,password,secret"`                             // The query keys are redacted
+}
+
 // Runtime Config
 type Runtime struct {
 	Mode              string `json:"mode,omitempty"  env:"YAO_RUNTIME_MODE" envDefault:"standard"`                        // the mode of the runtime, the default value is "standard" and the other value is "performance". "performance" mode need more memory but will run faster
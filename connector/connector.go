@@ -10,6 +10,15 @@ import (
 	"github.com/yaoapp/yao/share"
 )
 
+// Loader the loader of the connector types which are not supported by gou. e.g. the LLM providers
+type Loader struct {
+	Load   func(data []byte, file, id string) error
+	Unload func()
+}
+
+// Loaders the loaders of the connector types, registered by the packages of the types
+var Loaders = map[string]Loader{}
+
 // Load load store
 func Load(cfg config.Config) error {
 	exts := []string{"*.yao", "*.json", "*.jsonc"}
@@ -18,7 +27,7 @@ func Load(cfg config.Config) error {
 		if isdir {
 			return nil
 		}
-		_, err := connector.Load(file, share.ID(root, file))
+		err := LoadFile(file, share.ID(root, file))
 		if err != nil {
 			messages = append(messages, err.Error())
 		}
@@ -35,8 +44,37 @@ func Load(cfg config.Config) error {
 	return nil
 }
 
+// LoadFile load the connector, the types of the Loaders are loaded by the loaders, the others are loaded by gou
+func LoadFile(file string, id string) error {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	err = application.Parse(file, data, &head)
+	if err != nil {
+		return err
+	}
+
+	if loader, has := Loaders[strings.ToLower(head.Type)]; has {
+		return loader.Load(data, file, id)
+	}
+
+	_, err = connector.Load(file, id)
+	return err
+}
+
 // Unload Connector
 func Unload() error {
+	for _, loader := range Loaders {
+		if loader.Unload != nil {
+			loader.Unload()
+		}
+	}
+
 	messages := []string{}
 	for id, conn := range connector.Connectors {
 		err := conn.Close()
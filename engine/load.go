@@ -20,6 +20,7 @@ import (
 	"github.com/yaoapp/yao/fs"
 	"github.com/yaoapp/yao/i18n"
 	"github.com/yaoapp/yao/importer"
+	"github.com/yaoapp/yao/llm"
 	"github.com/yaoapp/yao/moapi"
 	"github.com/yaoapp/yao/model"
 	"github.com/yaoapp/yao/neo"
@@ -35,6 +36,7 @@ import (
 	"github.com/yaoapp/yao/store"
 	sui "github.com/yaoapp/yao/sui/api"
 	"github.com/yaoapp/yao/task"
+	"github.com/yaoapp/yao/usage"
 	"github.com/yaoapp/yao/websocket"
 	"github.com/yaoapp/yao/widget"
 	"github.com/yaoapp/yao/widgets"
@@ -66,6 +68,10 @@ func Load(cfg config.Config, options LoadOption) (err error) {
 	defer func() { err = exception.Catch(recover()) }()
 	exception.Mode = cfg.Mode
 
+	// The handlers are registered again by the loaders, they are hooked again after loading
+	share.UnhookProcesses()
+	defer share.HookProcesses()
+
 	// SET XGEN_BASE
 	adminRoot := "yao"
 	if share.App.Optional != nil {
@@ -197,6 +203,18 @@ func Load(cfg config.Config, options LoadOption) (err error) {
 		printErr(cfg.Mode, "Schedule", err)
 	}
 
+	// Load the token usage accounting of the LLM calls
+	err = usage.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Usage", err)
+	}
+
+	// Load the response cache of the LLM calls
+	err = llm.LoadCache(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "LLM Cache", err)
+	}
+
 	// Load AIGC
 	err = aigc.Load(cfg)
 	if err != nil {
@@ -308,6 +326,10 @@ func Reload(cfg config.Config, options LoadOption) (err error) {
 	defer func() { err = exception.Catch(recover()) }()
 	exception.Mode = cfg.Mode
 
+	// The handlers are registered again by the loaders, they are hooked again after loading
+	share.UnhookProcesses()
+	defer share.HookProcesses()
+
 	// SET XGEN_BASE
 	adminRoot := "yao"
 	if share.App.Optional != nil {
@@ -421,6 +443,18 @@ func Reload(cfg config.Config, options LoadOption) (err error) {
 		printErr(cfg.Mode, "Widget", err)
 	}
 
+	// Load the token usage accounting of the LLM calls
+	err = usage.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Usage", err)
+	}
+
+	// Load the response cache of the LLM calls
+	err = llm.LoadCache(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "LLM Cache", err)
+	}
+
 	// Load AIGC
 	err = aigc.Load(cfg)
 	if err != nil {
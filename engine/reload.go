@@ -0,0 +1,309 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/flow"
+	"github.com/yaoapp/gou/model"
+	v8 "github.com/yaoapp/gou/runtime/v8"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/aigc"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/pipe"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/widgets/chart"
+	"github.com/yaoapp/yao/widgets/dashboard"
+	"github.com/yaoapp/yao/widgets/form"
+	"github.com/yaoapp/yao/widgets/list"
+	"github.com/yaoapp/yao/widgets/table"
+)
+
+// Loader the loader of a DSL directory, used by the incremental reload
+type Loader struct {
+	Root       string                                   // The directory of the DSL files. e.g. models
+	Exts       []string                                 // The patterns of the file names
+	Load       func(root, file string) error            // Load or reload the file
+	Unload     func(id string)                          // Remove the DSL, nil if the DSL can not be removed
+	Dependents func(id string) []string                 // The files depend on the DSL, reloaded after the DSL
+	Routes     bool                                     // The API routes should be rebuilt after reloading
+	After      func(root, file string, r *ReloadResult) // Executed after reloading
+}
+
+// ReloadResult the result of the incremental reload
+type ReloadResult struct {
+	Full     bool     `json:"full"`     // The engine is fully reloaded
+	Routes   bool     `json:"routes"`   // The API routes should be rebuilt
+	Reloaded []string `json:"reloaded"` // The reloaded files
+	Removed  []string `json:"removed"`  // The removed DSL ids
+	Messages []string `json:"messages"` // The notices. e.g. run the migrate manually
+}
+
+// Loaders the loaders of the incremental reload, the key is the directory
+var Loaders = map[string]*Loader{}
+
+func init() {
+	Loaders = map[string]*Loader{
+		"models": {
+			Root: "models", Exts: []string{"*.mod.yao", "*.mod.json", "*.mod.jsonc"},
+			Load: func(root, file string) error {
+				_, err := model.Load(file, share.ID(root, file))
+				return err
+			},
+			Unload: func(id string) { delete(model.Models, id) },
+			Dependents: func(id string) []string {
+				return bindings(id, "model", "tables", "forms", "lists")
+			},
+			After: func(root, file string, r *ReloadResult) {
+				r.Messages = append(r.Messages, fmt.Sprintf("Model: %s changed (Please run yao migrate manually)", file))
+			},
+		},
+
+		"tables": {
+			Root: "tables", Exts: []string{"*.tab.yao", "*.tab.json", "*.tab.jsonc"},
+			Load:   table.LoadFileSync,
+			Unload: table.UnloadSync,
+			Dependents: func(id string) []string {
+				return bindings(id, "table", "forms", "lists")
+			},
+		},
+
+		"forms": {
+			Root: "forms", Exts: []string{"*.form.yao", "*.form.json", "*.form.jsonc"},
+			Load:   form.LoadFileSync,
+			Unload: form.UnloadSync,
+		},
+
+		"lists": {
+			Root: "lists", Exts: []string{"*.yao", "*.json", "*.jsonc"},
+			Load:   list.LoadFileSync,
+			Unload: list.UnloadSync,
+		},
+
+		"charts": {
+			Root: "charts", Exts: []string{"*.yao", "*.json", "*.jsonc"},
+			Load:   chart.LoadFileSync,
+			Unload: chart.UnloadSync,
+		},
+
+		"dashboards": {
+			Root: "dashboards", Exts: []string{"*.yao", "*.json", "*.jsonc"},
+			Load:   dashboard.LoadFileSync,
+			Unload: dashboard.UnloadSync,
+		},
+
+		"scripts": {
+			Root: "scripts", Exts: []string{"*.js", "*.ts"},
+			Load: func(root, file string) error {
+				v8.CLearModules()
+				_, err := v8.Load(file, share.ID(root, file))
+				return err
+			},
+			Unload: func(id string) {
+				v8.CLearModules()
+				delete(v8.Scripts, id)
+			},
+			Dependents: func(id string) []string { return importers("scripts", id) },
+		},
+
+		"services": {
+			Root: "services", Exts: []string{"*.js", "*.ts"},
+			Load: func(root, file string) error {
+				v8.CLearModules()
+				_, err := v8.Load(file, fmt.Sprintf("__yao_service.%s", share.ID(root, file)))
+				return err
+			},
+			Unload: func(id string) {
+				v8.CLearModules()
+				delete(v8.Scripts, fmt.Sprintf("__yao_service.%s", id))
+			},
+		},
+
+		"flows": {
+			Root: "flows", Exts: []string{"*.flow.yao", "*.flow.json", "*.flow.jsonc"},
+			Load: func(root, file string) error {
+				_, err := flow.Load(file, share.ID(root, file))
+				return err
+			},
+		},
+
+		"pipes": {
+			Root: "pipes", Exts: []string{"*.pip.yao", "*.pipe.yao"},
+			Load: func(root, file string) error {
+				p, err := pipe.NewFile(file, root)
+				if err != nil {
+					return err
+				}
+				pipe.Set(share.ID(root, file), p)
+				return nil
+			},
+			Unload: pipe.Remove,
+		},
+
+		"apis": {
+			Root: "apis", Exts: []string{"*.http.yao", "*.http.json", "*.http.jsonc"},
+			Load: func(root, file string) error {
+				_, err := api.Load(file, share.ID(root, file))
+				return err
+			},
+			Unload: func(id string) { delete(api.APIs, id) },
+			Routes: true,
+		},
+
+		"stores": {
+			Root: "stores", Exts: []string{"*.yao", "*.json", "*.jsonc"},
+			Load: func(root, file string) error {
+				_, err := store.Load(file, share.ID(root, file))
+				return err
+			},
+		},
+
+		"aigcs": {
+			Root: "aigcs", Exts: []string{"*.ai.yml", "*.ai.yaml"},
+			Load: func(root, file string) error {
+				_, err := aigc.LoadFile(file, share.ID(root, file))
+				return err
+			},
+		},
+	}
+}
+
+// reloadLock serializes the reloads, the DSL maps of gou (models, apis, scripts) are changed by one reload at a time
+var reloadLock sync.Mutex
+
+// ReloadFile reload the changed file and its dependents incrementally.
+// The engine is fully reloaded if the file is not handled by any of the loaders. e.g. app.yao, connectors
+func ReloadFile(cfg config.Config, name string) (res *ReloadResult, err error) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	defer func() {
+		if e := exception.Catch(recover()); e != nil {
+			err = e
+		}
+	}()
+	exception.Mode = cfg.Mode
+
+	file := strings.TrimPrefix(filepath.ToSlash(name), "/")
+	root := strings.Split(file, "/")[0]
+	loader, has := Loaders[root]
+	if !has || !loader.match(file) {
+		err = Reload(cfg, LoadOption{Action: "watch", IsReload: true})
+		return &ReloadResult{Full: true, Routes: true}, err
+	}
+
+	res = &ReloadResult{Reloaded: []string{}, Removed: []string{}, Messages: []string{}}
+	err = loader.reload(root, file, res, map[string]bool{})
+	return res, err
+}
+
+// reload the file and the dependents, the visited files are skipped
+func (loader *Loader) reload(root string, file string, res *ReloadResult, visited map[string]bool) error {
+	if visited[file] {
+		return nil
+	}
+	visited[file] = true
+
+	id := share.ID(root, file)
+	if exists, _ := application.App.Exists(file); !exists {
+		if loader.Unload != nil {
+			loader.Unload(id)
+			res.Removed = append(res.Removed, id)
+		}
+		res.Routes = res.Routes || loader.Routes
+		return nil
+	}
+
+	err := loader.Load(root, file)
+	if err != nil {
+		return fmt.Errorf("%s %s", file, err.Error())
+	}
+
+	res.Reloaded = append(res.Reloaded, file)
+	res.Routes = res.Routes || loader.Routes
+	if loader.After != nil {
+		loader.After(root, file, res)
+	}
+
+	if loader.Dependents == nil {
+		return nil
+	}
+
+	messages := []string{}
+	for _, dep := range loader.Dependents(id) {
+		depRoot := strings.Split(dep, "/")[0]
+		depLoader, has := Loaders[depRoot]
+		if !has {
+			continue
+		}
+
+		err := depLoader.reload(depRoot, dep, res, visited)
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf(strings.Join(messages, ";\n"))
+	}
+	return nil
+}
+
+func (loader *Loader) match(file string) bool {
+	base := filepath.Base(file)
+	for _, pattern := range loader.Exts {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// bindings the widget files bind the DSL. e.g. "bind": {"model": "pet"}
+func bindings(id string, kind string, roots ...string) []string {
+	re := regexp.MustCompile(fmt.Sprintf(`"%s"\s*:\s*"%s"`, kind, regexp.QuoteMeta(id)))
+	return search(re, roots...)
+}
+
+// importers the scripts import the script
+func importers(root string, id string) []string {
+	name := filepath.Base(strings.ReplaceAll(id, ".", "/"))
+	re := regexp.MustCompile(fmt.Sprintf(`(?m)^\s*import\s.*["'][^"']*\b%s(\.ts|\.js)?["']`, regexp.QuoteMeta(name)))
+	return search(re, root)
+}
+
+func search(re *regexp.Regexp, roots ...string) []string {
+	files := []string{}
+	for _, root := range roots {
+		loader, has := Loaders[root]
+		if !has {
+			continue
+		}
+
+		if exists, _ := application.App.Exists(root); !exists {
+			continue
+		}
+
+		application.App.Walk(root, func(root, file string, isdir bool) error {
+			if isdir {
+				return nil
+			}
+
+			source, err := application.App.Read(file)
+			if err != nil {
+				return nil
+			}
+
+			if re.Match(source) {
+				files = append(files, strings.TrimPrefix(filepath.ToSlash(file), "/"))
+			}
+			return nil
+		}, loader.Exts...)
+	}
+	return files
+}
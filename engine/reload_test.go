@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestReloadFile(t *testing.T) {
+	defer Unload()
+	err := Load(config.Conf, LoadOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ReloadFile(config.Conf, "/models/pet.mod.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, res.Full)
+	assert.False(t, res.Routes)
+	assert.Equal(t, "models/pet.mod.yao", res.Reloaded[0])
+	assert.Len(t, res.Messages, 1)
+	assert.NotNil(t, model.Models["pet"])
+
+	res, err = ReloadFile(config.Conf, "/apis/user.http.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, res.Full)
+	assert.True(t, res.Routes)
+
+	res, err = ReloadFile(config.Conf, "/app.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Full)
+	assert.True(t, res.Routes)
+}
+
+func TestReloadMatch(t *testing.T) {
+	assert.True(t, Loaders["models"].match("models/pet.mod.yao"))
+	assert.False(t, Loaders["models"].match("models/pet.yao"))
+	assert.True(t, Loaders["aigcs"].match("aigcs/translate.ai.yml"))
+}
+
+func TestReloadUnload(t *testing.T) {
+	for _, root := range []string{"models", "tables", "forms", "lists", "charts", "dashboards", "scripts", "services", "pipes", "apis"} {
+		assert.NotNil(t, Loaders[root].Unload, root)
+	}
+}
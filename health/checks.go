@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	v8 "github.com/yaoapp/gou/runtime/v8"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/runtime"
+)
+
+// the script for checking the V8 runtime
+var pingScript = []byte(`function Ping() { return "pong" }`)
+var pingCompiled *v8.Script
+var pingMutex sync.Mutex
+
+// builtins the checks of the database connections, the session store, the stores and the V8 runtime
+func builtins() map[string]Check {
+	res := map[string]Check{
+		"session":    checkSession,
+		"runtime.v8": checkRuntime,
+	}
+
+	if capsule.Global != nil {
+		capsule.Global.Connections.Range(func(key, value any) bool {
+			conn, ok := value.(*capsule.Connection)
+			if !ok {
+				return true
+			}
+			res[fmt.Sprintf("db.%v", key)] = checkDB(conn)
+			return true
+		})
+	}
+
+	for name := range store.Pools {
+		res[fmt.Sprintf("store.%s", name)] = checkStore(name)
+	}
+	return res
+}
+
+func checkDB(conn *capsule.Connection) Check {
+	return func(ctx context.Context) error {
+		if conn.DB == nil {
+			return fmt.Errorf("%s is not connected", conn.Config.Name)
+		}
+		return conn.PingContext(ctx)
+	}
+}
+
+// checkSession write and read a key of the session store
+func checkSession(ctx context.Context) error {
+	sid := fmt.Sprintf("__health_%s", uuid.NewString())
+	value := time.Now().UnixNano()
+	s := session.Global().Expire(10 * time.Second).ID(sid)
+	err := s.Set("ping", value)
+	if err != nil {
+		return fmt.Errorf("%s %s", session.Name, err.Error())
+	}
+
+	res, err := s.Get("ping")
+	if err != nil {
+		return fmt.Errorf("%s %s", session.Name, err.Error())
+	}
+
+	if fmt.Sprintf("%v", res) != fmt.Sprintf("%v", value) {
+		return fmt.Errorf("%s returns the unexpected value", session.Name)
+	}
+	return nil
+}
+
+// checkStore write, read and delete a key of the store
+func checkStore(name string) Check {
+	return func(ctx context.Context) error {
+		s, has := store.Pools[name]
+		if !has {
+			return fmt.Errorf("store %s is not loaded", name)
+		}
+
+		key := fmt.Sprintf("__health_%s", uuid.NewString())
+		err := s.Set(key, "pong", 10*time.Second)
+		if err != nil {
+			return err
+		}
+		defer s.Del(key)
+
+		if _, has := s.Get(key); !has {
+			return fmt.Errorf("store %s returns nothing", name)
+		}
+		return nil
+	}
+}
+
+// checkRuntime run the ping script with the V8 runtime, the script is compiled once and compiled again after a failure.
+// The script is not run if all the isolates of the pool are in use, the probe does not wait for the busy runtime.
+func checkRuntime(ctx context.Context) error {
+	if stats := runtime.Pool(); stats.MaxSize > 0 && stats.Available == 0 {
+		return nil
+	}
+
+	script, err := pingScriptOf()
+	if err != nil {
+		return err
+	}
+
+	release := runtime.Acquire()
+	defer release()
+
+	err = ping(script)
+	if err != nil {
+		pingMutex.Lock()
+		pingCompiled = nil
+		pingMutex.Unlock()
+	}
+	return err
+}
+
+// pingScriptOf the compiled ping script
+func pingScriptOf() (*v8.Script, error) {
+	pingMutex.Lock()
+	defer pingMutex.Unlock()
+	if pingCompiled != nil {
+		return pingCompiled, nil
+	}
+
+	script, err := v8.MakeScript(pingScript, "__health.js", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	pingCompiled = script
+	return script, nil
+}
+
+func ping(script *v8.Script) error {
+	scriptCtx, err := script.NewContext(uuid.NewString(), nil)
+	if err != nil {
+		return err
+	}
+	defer scriptCtx.Close()
+
+	res, err := scriptCtx.Call("Ping")
+	if err != nil {
+		return err
+	}
+
+	if res != "pong" {
+		return fmt.Errorf("the runtime returns the unexpected value %v", res)
+	}
+	return nil
+}
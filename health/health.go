@@ -0,0 +1,189 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/share"
+)
+
+// The status of the checks
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Timeout the timeout of each readiness check
+var Timeout = 5 * time.Second
+
+// Check the readiness check, returns the error if the dependency is unavailable
+type Check func(ctx context.Context) error
+
+// Result the result of the health check
+type Result struct {
+	Status  string                 `json:"status"`
+	Version string                 `json:"version,omitempty"`
+	Uptime  int64                  `json:"uptime,omitempty"` // seconds
+	Checks  map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult the result of a readiness check
+type CheckResult struct {
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration"` // milliseconds
+}
+
+var started = time.Now()
+var checks = map[string]Check{}
+var mutex sync.RWMutex
+
+// Register register a readiness check, the check with the same name is replaced
+func Register(name string, check Check) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	checks[name] = check
+}
+
+// Unregister remove the readiness check
+func Unregister(name string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	delete(checks, name)
+}
+
+// Live the liveness, the server is up if it can response
+func Live() Result {
+	return Result{Status: StatusUp, Version: share.VERSION, Uptime: int64(time.Since(started).Seconds())}
+}
+
+// Ready run the readiness checks concurrently, the status is down if any of the checks fails
+func Ready(ctx context.Context) Result {
+	all := map[string]Check{}
+	for name, check := range builtins() {
+		all[name] = check
+	}
+
+	mutex.RLock()
+	for name, check := range checks {
+		all[name] = check
+	}
+	mutex.RUnlock()
+
+	for name, check := range appChecks() {
+		all[name] = check
+	}
+
+	res := Result{Status: StatusUp, Version: share.VERSION, Uptime: int64(time.Since(started).Seconds()), Checks: map[string]CheckResult{}}
+	results := make(chan struct {
+		name   string
+		result CheckResult
+	}, len(all))
+
+	for name, check := range all {
+		go func(name string, check Check) {
+			results <- struct {
+				name   string
+				result CheckResult
+			}{name, run(ctx, check)}
+		}(name, check)
+	}
+
+	for range all {
+		r := <-results
+		res.Checks[r.name] = r.result
+		if r.result.Status != StatusUp {
+			res.Status = StatusDown
+		}
+	}
+	return res
+}
+
+// Failures the names of the failed checks, sorted by the name
+func (res Result) Failures() []string {
+	names := []string{}
+	for name, check := range res.Checks {
+		if check.Status != StatusUp {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LiveHandler the gin handler of the liveness endpoint
+func LiveHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, Live())
+}
+
+// ReadyHandler the gin handler of the readiness endpoint, responses 503 if any of the checks fails
+func ReadyHandler(c *gin.Context) {
+	res := Ready(c.Request.Context())
+	if res.Status != StatusUp {
+		c.JSON(http.StatusServiceUnavailable, res)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// run the check with the timeout, the panic is the failure
+func run(ctx context.Context, check Check) (res CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %s", Timeout)
+	}
+
+	res = CheckResult{Status: StatusUp, Duration: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// appChecks the checks defined in the app.yao, the check fails if the process throws an exception or returns false
+func appChecks() map[string]Check {
+	res := map[string]Check{}
+	for name, processName := range share.App.Health {
+		processName := processName
+		res[fmt.Sprintf("app.%s", name)] = func(ctx context.Context) error {
+			value, err := process.NewWithContext(ctx, processName).Exec()
+			if err != nil {
+				return err
+			}
+
+			switch v := value.(type) {
+			case bool:
+				if !v {
+					return fmt.Errorf("%s returns false", processName)
+				}
+			case error:
+				return v
+			}
+			return nil
+		}
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestReady(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	err := share.SessionStart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer share.SessionStop()
+
+	res := Ready(context.Background())
+	assert.Equal(t, StatusUp, res.Status, res.Failures())
+	assert.Equal(t, StatusUp, res.Checks["session"].Status)
+	assert.Equal(t, StatusUp, res.Checks["runtime.v8"].Status)
+	assert.Equal(t, StatusUp, res.Checks["db.primary-0"].Status)
+
+	// The ping script is compiled once
+	script := pingCompiled
+	assert.NotNil(t, script)
+	assert.Nil(t, checkRuntime(context.Background()))
+	assert.Same(t, script, pingCompiled)
+}
+
+func TestReadyFailures(t *testing.T) {
+	Register("unit.ok", func(ctx context.Context) error { return nil })
+	Register("unit.error", func(ctx context.Context) error { return fmt.Errorf("unavailable") })
+	Register("unit.panic", func(ctx context.Context) error { panic("boom") })
+	Register("unit.timeout", func(ctx context.Context) error { time.Sleep(time.Second); return nil })
+	defer func() {
+		for _, name := range []string{"unit.ok", "unit.error", "unit.panic", "unit.timeout"} {
+			Unregister(name)
+		}
+	}()
+
+	timeout := Timeout
+	Timeout = 100 * time.Millisecond
+	defer func() { Timeout = timeout }()
+
+	res := Ready(context.Background())
+	assert.Equal(t, StatusDown, res.Status)
+	assert.Equal(t, StatusUp, res.Checks["unit.ok"].Status)
+	assert.Equal(t, "unavailable", res.Checks["unit.error"].Error)
+	assert.Equal(t, "boom", res.Checks["unit.panic"].Error)
+	assert.Contains(t, res.Checks["unit.timeout"].Error, "timeout")
+	assert.Contains(t, res.Failures(), "unit.error")
+	assert.NotContains(t, res.Failures(), "unit.ok")
+}
+
+func TestHandlers(t *testing.T) {
+	Register("unit.error", func(ctx context.Context) error { return fmt.Errorf("unavailable") })
+	defer Unregister("unit.error")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", LiveHandler)
+	router.GET("/readyz", ReadyHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	live := Result{}
+	jsoniter.Unmarshal(w.Body.Bytes(), &live)
+	assert.Equal(t, StatusUp, live.Status)
+	assert.Equal(t, share.VERSION, live.Version)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	ready := Result{}
+	jsoniter.Unmarshal(w.Body.Bytes(), &ready)
+	assert.Equal(t, StatusDown, ready.Status)
+	assert.Equal(t, "unavailable", ready.Checks["unit.error"].Error)
+}
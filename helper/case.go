@@ -1,6 +1,8 @@
 package helper
 
 import (
+	"context"
+
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
@@ -8,17 +10,18 @@ import (
 
 // CaseParam 条件参数
 type CaseParam struct {
-	When    []Condition   `json:"when"`
-	Name    string        `json:"name"`
-	Process string        `json:"process"`
-	Args    []interface{} `json:"args"`
+	When    []Condition     `json:"when"`
+	Name    string          `json:"name"`
+	Process string          `json:"process"`
+	Args    []interface{}   `json:"args"`
+	Context context.Context `json:"-"` // The context of the caller, it carries the trace span to the process
 }
 
 // Case 条件判断
 func Case(params ...CaseParam) interface{} {
 	for _, param := range params {
 		if When(param.When) {
-			return process.New(param.Process, param.Args...).Run()
+			return newProcess(param.Context, param.Process, param.Args...).Run()
 		}
 	}
 	return nil
@@ -43,7 +46,9 @@ func ProcessCase(process *process.Process) interface{} {
 	process.ValidateArgNums(1)
 	params := []CaseParam{}
 	for _, v := range process.Args {
-		params = append(params, CaseParamOf(v))
+		param := CaseParamOf(v)
+		param.Context = process.Context
+		params = append(params, param)
 	}
 	return Case(params...)
 }
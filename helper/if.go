@@ -7,9 +7,9 @@ import (
 // IF 条件判断
 func IF(param CaseParam, paramElse ...CaseParam) interface{} {
 	if When(param.When) {
-		return process.New(param.Process, param.Args...).Run()
+		return newProcess(param.Context, param.Process, param.Args...).Run()
 	} else if len(paramElse) > 0 && When(paramElse[0].When) {
-		return process.New(paramElse[0].Process, paramElse[0].Args...).Run()
+		return newProcess(paramElse[0].Context, paramElse[0].Process, paramElse[0].Args...).Run()
 	}
 	return nil
 }
@@ -19,7 +19,9 @@ func ProcessIF(process *process.Process) interface{} {
 	process.ValidateArgNums(1)
 	params := []CaseParam{}
 	for _, v := range process.Args {
-		params = append(params, CaseParamOf(v))
+		param := CaseParamOf(v)
+		param.Context = process.Context
+		params = append(params, param)
 	}
 	if len(params) > 1 {
 		IF(params[0], params[1])
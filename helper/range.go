@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"context"
 	"reflect"
 	"regexp"
 
@@ -15,8 +16,9 @@ var reVar = regexp.MustCompile("::([a-z]+)") // ::key, ::value
 
 // Process 处理器参数
 type Process struct {
-	Process string        `json:"process"`
-	Args    []interface{} `json:"args,omitempty"`
+	Process string          `json:"process"`
+	Args    []interface{}   `json:"args,omitempty"`
+	Context context.Context `json:"-"` // The context of the caller, it carries the trace span to the process
 }
 
 // Range 过程控制
@@ -69,10 +71,18 @@ func For(from int, to int, p Process) {
 			"value": i,
 		}
 		args := bindArgs(p.Args, bindings)
-		process.New(p.Process, args...).Run()
+		newProcess(p.Context, p.Process, args...).Run()
 	}
 }
 
+// newProcess create the process with the context of the caller, the nested process is traced as the child of the caller
+func newProcess(ctx context.Context, name string, args ...interface{}) *process.Process {
+	if ctx == nil {
+		return process.New(name, args...)
+	}
+	return process.NewWithContext(ctx, name, args...)
+}
+
 func bindArgs(args []interface{}, bindings map[string]interface{}) []interface{} {
 	new := []interface{}{}
 	for i := range args {
@@ -89,7 +99,7 @@ func rangeString(v string, p Process) {
 			"value": value,
 		}
 		args := bindArgs(p.Args, bindings)
-		process.New(p.Process, args...).Run()
+		newProcess(p.Context, p.Process, args...).Run()
 	}
 
 }
@@ -101,7 +111,7 @@ func rangeMap(v map[string]interface{}, p Process) {
 			"value": value,
 		}
 		args := bindArgs(p.Args, bindings)
-		process.New(p.Process, args...).Run()
+		newProcess(p.Context, p.Process, args...).Run()
 	}
 }
 
@@ -112,7 +122,7 @@ func rangeArray(v []interface{}, p Process) {
 			"value": value,
 		}
 		args := bindArgs(p.Args, bindings)
-		process.New(p.Process, args...).Run()
+		newProcess(p.Context, p.Process, args...).Run()
 	}
 }
 
@@ -145,6 +155,7 @@ func ProcessEach(process *process.Process) interface{} {
 	process.ValidateArgNums(2)
 	v := process.Args[0]
 	p := ProcessOf(process.ArgsMap(1))
+	p.Context = process.Context
 	Range(v, p)
 	return nil
 }
@@ -155,6 +166,7 @@ func ProcessFor(process *process.Process) interface{} {
 	from := process.ArgsInt(0)
 	to := process.ArgsInt(1)
 	p := ProcessOf(process.ArgsMap(2))
+	p.Context = process.Context
 	For(from, to, p)
 	return nil
 }
@@ -0,0 +1,476 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/telemetry"
+	"github.com/yaoapp/yao/usage"
+)
+
+// Anthropic the Anthropic Messages API
+// https://docs.anthropic.com/en/api/messages
+type Anthropic struct {
+	connector string
+	key       string
+	model     string
+	host      string
+	version   string
+	maxToken  int // The context window
+	maxOutput int // The default max_tokens of the request, it is required by the API
+}
+
+// anthropicUsage the token usage of the stream events, the output tokens of the message_delta are cumulative
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicStopReasons the stop reasons mapped to the OpenAI finish reasons
+var anthropicStopReasons = map[string]string{
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"max_tokens":    "length",
+	"tool_use":      "tool_calls",
+}
+
+// NewAnthropic create a new Anthropic instance by setting
+func NewAnthropic(setting map[string]interface{}) (LLM, error) {
+	key := settingString(setting, "key", "")
+	if key == "" {
+		return nil, fmt.Errorf("The anthropic key is required")
+	}
+
+	return &Anthropic{
+		connector: settingString(setting, "id", ""),
+		key:       key,
+		model:     settingString(setting, "model", "claude-3-5-sonnet-latest"),
+		host:      strings.TrimSuffix(settingString(setting, "host", "https://api.anthropic.com"), "/"),
+		version:   settingString(setting, "version", "2023-06-01"),
+		maxToken:  settingInt(setting, "max_token", 200000),
+		maxOutput: settingInt(setting, "max_tokens", 4096),
+	}, nil
+}
+
+// ChatCompletions Creates a message for the given chat conversation.
+func (ai *Anthropic) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return ai.ChatCompletionsWith(context.Background(), messages, option, cb)
+}
+
+// ChatCompletionsWith Creates a message for the given chat conversation, the events are streamed to the cb if it is not nil
+func (ai *Anthropic) ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	payload := ai.payload(messages, option)
+	payload["stream"] = cb != nil
+
+	call, ex := usage.Begin(ctx, ai.connector, ai.model, messages, ai.Tiktoken)
+	if ex != nil {
+		return nil, ex
+	}
+
+	ctx, span := telemetry.Start(ctx, "anthropic.messages", telemetry.SpanKindClient)
+	span.SetAttribute("llm.provider", "anthropic")
+	span.SetAttribute("llm.model", ai.model)
+	defer span.Finish()
+
+	header := http.Header{
+		"X-Api-Key":         {ai.key},
+		"Anthropic-Version": {ai.version},
+	}
+	telemetry.Inject(ctx, header)
+
+	res, err := post(ctx, ai.host+"/v1/messages", header, payload)
+	if err != nil {
+		span.SetError(err)
+		return nil, unreachable("Anthropic", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		body, _ := io.ReadAll(res.Body)
+		ex := ai.error(res.StatusCode, body)
+		span.SetError(ex.Message)
+		return nil, ex
+	}
+
+	if cb == nil {
+		var data interface{}
+		err := jsoniter.NewDecoder(res.Body).Decode(&data)
+		if err != nil {
+			return nil, exception.New("Anthropic %s", 500, err.Error())
+		}
+		call.End(data)
+		return data, nil
+	}
+
+	ex = ai.stream(res.Body, call.Stream(cb))
+	call.End(nil)
+	if ex != nil {
+		span.SetError(ex.Message)
+	}
+	return nil, ex
+}
+
+// stream convert the server-sent events to the OpenAI chunks
+// https://docs.anthropic.com/en/api/messages-streaming
+func (ai *Anthropic) stream(body io.Reader, cb func(data []byte) int) *exception.Exception {
+	var ex *exception.Exception = nil
+	id := ""
+	tools := map[int]int{} // the content block index to the tool call index
+	finish := "stop"
+	prompt := 0
+	completion := 0
+	err := lines(body, func(line []byte) bool {
+		if !strings.HasPrefix(string(line), "data:") {
+			return true // the event: lines and the empty lines, the type is in the data
+		}
+
+		var event struct {
+			Type    string `json:"type"`
+			Index   int    `json:"index"`
+			Message struct {
+				ID    string         `json:"id"`
+				Usage anthropicUsage `json:"usage"`
+			} `json:"message"`
+			ContentBlock struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"content_block"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage anthropicUsage `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		err := jsoniter.Unmarshal(line[5:], &event)
+		if err != nil {
+			ex = exception.New("Anthropic %s", 500, err.Error())
+			cb(errorChunk("invalid_response", err.Error()))
+			return false
+		}
+
+		switch event.Type {
+		case "message_start":
+			id = event.Message.ID
+			prompt = event.Message.Usage.InputTokens
+
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				tools[event.Index] = len(tools)
+				call := map[string]interface{}{
+					"index":    tools[event.Index],
+					"id":       event.ContentBlock.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": event.ContentBlock.Name, "arguments": ""},
+				}
+				return cb(toolChunk(id, ai.model, call)) != 0
+			}
+
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				return cb(chunk(id, ai.model, event.Delta.Text, "")) != 0
+			}
+
+			if index, has := tools[event.Index]; has && event.Delta.Type == "input_json_delta" && event.Delta.PartialJSON != "" {
+				call := map[string]interface{}{"index": index, "function": map[string]interface{}{"arguments": event.Delta.PartialJSON}}
+				return cb(toolChunk(id, ai.model, call)) != 0
+			}
+
+		case "message_delta":
+			if reason, has := anthropicStopReasons[event.Delta.StopReason]; has {
+				finish = reason
+			}
+			completion = event.Usage.OutputTokens
+
+		case "message_stop":
+			if cb(last(id, ai.model, finish, prompt, completion)) == 0 {
+				return false
+			}
+			cb(done)
+			return false
+
+		case "error":
+			ex = exception.New("Anthropic %s %s", anthropicStatus(event.Error.Type), event.Error.Type, event.Error.Message)
+			cb(errorChunk(event.Error.Type, event.Error.Message))
+			return false
+		}
+		return true
+	})
+
+	if err != nil && ex == nil {
+		return unreachable("Anthropic", err)
+	}
+	return ex
+}
+
+// payload the system prompts are placed in the system field, the consecutive messages of the same role are merged.
+// The OpenAI tools, tool calls and tool messages are converted to the Anthropic tools, tool_use and tool_result blocks.
+func (ai *Anthropic) payload(messages []map[string]interface{}, option map[string]interface{}) map[string]interface{} {
+	system := []string{}
+	converted := []map[string]interface{}{}
+	for _, message := range messages {
+		role, _ := message["role"].(string)
+		var value interface{} = message["content"]
+		switch role {
+		case "system":
+			system = append(system, content(message["content"]))
+			continue
+
+		case "assistant":
+			if message["tool_calls"] != nil {
+				value = anthropicToolUses(message["content"], message["tool_calls"])
+			}
+
+		case "tool":
+			role = "user"
+			value = []interface{}{map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": message["tool_call_id"],
+				"content":     content(message["content"]),
+			}}
+
+		default:
+			role = "user" // the function messages
+		}
+
+		last := len(converted) - 1
+		if last < 0 || converted[last]["role"] != role {
+			converted = append(converted, map[string]interface{}{"role": role, "content": value})
+			continue
+		}
+
+		prev, isText := converted[last]["content"].(string)
+		text, ok := value.(string)
+		if isText && ok {
+			converted[last]["content"] = prev + "\n\n" + text
+			continue
+		}
+		converted[last]["content"] = append(anthropicBlocks(converted[last]["content"]), anthropicBlocks(value)...)
+	}
+
+	payload := map[string]interface{}{
+		"model":      ai.model,
+		"messages":   converted,
+		"max_tokens": ai.maxOutput,
+	}
+
+	if len(system) > 0 {
+		payload["system"] = strings.Join(system, "\n\n")
+	}
+
+	for key, value := range option {
+		switch key {
+		case "max_tokens", "temperature", "top_p", "top_k", "metadata", "stop_sequences":
+			payload[key] = value
+
+		case "tools", "functions":
+			if tools := anthropicTools(value); len(tools) > 0 {
+				payload["tools"] = tools
+			}
+
+		case "tool_choice", "function_call":
+			if choice := anthropicToolChoice(value); choice != nil {
+				payload["tool_choice"] = choice
+			}
+
+		case "stop":
+			if v, ok := value.(string); ok {
+				value = []string{v}
+			}
+			payload["stop_sequences"] = value
+
+		case "user":
+			payload["metadata"] = map[string]interface{}{"user_id": value}
+		}
+	}
+	return payload
+}
+
+// anthropicBlocks the content as the content blocks, the text is a text block
+func anthropicBlocks(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case nil:
+		return []interface{}{}
+	case string:
+		if v == "" {
+			return []interface{}{}
+		}
+		return []interface{}{map[string]interface{}{"type": "text", "text": v}}
+	}
+	return []interface{}{map[string]interface{}{"type": "text", "text": content(value)}}
+}
+
+// anthropicToolUses the text and the tool calls of the assistant message as the text and the tool_use blocks
+// {"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\": \"Paris\"}"}}
+func anthropicToolUses(text interface{}, value interface{}) []interface{} {
+	var calls []map[string]interface{}
+	data, err := jsoniter.Marshal(value)
+	if err != nil || jsoniter.Unmarshal(data, &calls) != nil {
+		return anthropicBlocks(text)
+	}
+
+	blocks := anthropicBlocks(text)
+	for _, call := range calls {
+
+		function, _ := call["function"].(map[string]interface{})
+		input := map[string]interface{}{}
+		switch arguments := function["arguments"].(type) {
+		case string:
+			if arguments != "" {
+				jsoniter.UnmarshalFromString(arguments, &input)
+			}
+		case map[string]interface{}:
+			input = arguments
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "tool_use", "id": call["id"], "name": function["name"], "input": input})
+	}
+	return blocks
+}
+
+// anthropicTools the OpenAI tools and functions as the Anthropic tools, the Anthropic tools are kept as they are
+func anthropicTools(value interface{}) []map[string]interface{} {
+	var list []map[string]interface{}
+	data, err := jsoniter.Marshal(value)
+	if err != nil || jsoniter.Unmarshal(data, &list) != nil {
+		return nil
+	}
+
+	tools := []map[string]interface{}{}
+	for _, tool := range list {
+		function, ok := tool["function"].(map[string]interface{})
+		if !ok {
+			if _, has := tool["parameters"]; !has {
+				tools = append(tools, tool)
+				continue
+			}
+			function = tool // the functions option
+		}
+
+		schema, ok := function["parameters"].(map[string]interface{})
+		if !ok {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+
+		converted := map[string]interface{}{"name": function["name"], "input_schema": schema}
+		if description, ok := function["description"].(string); ok && description != "" {
+			converted["description"] = description
+		}
+		tools = append(tools, converted)
+	}
+	return tools
+}
+
+// anthropicToolChoice the OpenAI tool choice as the Anthropic tool choice, nil if it is not supported.
+// auto, none, required and {"type": "function", "function": {"name": "weather"}}
+func anthropicToolChoice(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		switch v {
+		case "auto":
+			return map[string]interface{}{"type": "auto"}
+		case "none":
+			return map[string]interface{}{"type": "none"}
+		case "required", "any":
+			return map[string]interface{}{"type": "any"}
+		}
+		return nil
+
+	case map[string]interface{}:
+		if function, ok := v["function"].(map[string]interface{}); ok {
+			return map[string]interface{}{"type": "tool", "name": function["name"]}
+		}
+		if name, ok := v["name"].(string); ok && v["type"] == nil {
+			return map[string]interface{}{"type": "tool", "name": name} // the function_call option
+		}
+		return v
+	}
+	return nil
+}
+
+// toolChunk encode the tool call as an OpenAI chunk line
+func toolChunk(id, model string, call map[string]interface{}) []byte {
+	c := Chunk{ID: id, Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: model}
+	c.Choices = []ChunkChoice{{Index: 0, Delta: map[string]interface{}{"tool_calls": []interface{}{call}}}}
+	data, _ := jsoniter.Marshal(c)
+	return append([]byte("data: "), data...)
+}
+
+// error map the error response to the exception
+// {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+func (ai *Anthropic) error(status int, body []byte) *exception.Exception {
+	var res struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	err := jsoniter.Unmarshal(body, &res)
+	if err != nil || res.Error.Message == "" {
+		return exception.New("Anthropic Error %s", status, strings.TrimSpace(string(body)))
+	}
+	return exception.New("Anthropic %s %s", status, res.Error.Type, res.Error.Message)
+}
+
+// anthropicStatus the HTTP status of the error types in the stream
+func anthropicStatus(typ string) int {
+	switch typ {
+	case "invalid_request_error":
+		return 400
+	case "authentication_error":
+		return 401
+	case "permission_error":
+		return 403
+	case "not_found_error":
+		return 404
+	case "rate_limit_error":
+		return 429
+	case "overloaded_error":
+		return 529
+	}
+	return 500
+}
+
+// GetContent get the text of the message
+func (ai *Anthropic) GetContent(response interface{}) (string, *exception.Exception) {
+	data, ok := response.(map[string]interface{})
+	if !ok {
+		return "", exception.New("response format error, %#v", 500, response)
+	}
+
+	blocks, ok := data["content"].([]interface{})
+	if !ok {
+		return "", exception.New("response format error, %#v", 500, response)
+	}
+	return content(blocks), nil
+}
+
+// Embeddings the Anthropic API does not support the embeddings
+func (ai *Anthropic) Embeddings(input interface{}, user string) (interface{}, *exception.Exception) {
+	return nil, exception.New("Anthropic does not support the embeddings, use an openai or ollama connector", 400)
+}
+
+// Tiktoken get number of tokens, it is an estimate with the cl100k_base encoding
+func (ai *Anthropic) Tiktoken(input string) (int, error) {
+	return tokens(ai.model, input)
+}
+
+// MaxToken get max number of tokens
+func (ai *Anthropic) MaxToken() int {
+	return ai.maxToken
+}
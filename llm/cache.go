@@ -0,0 +1,292 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/metrics"
+	"github.com/yaoapp/yao/share"
+)
+
+// Cache the LLM replays the cached responses of the identical calls.
+// The calls with temperature 0 are cached unless the cache setting caches all,
+// the option {"cache": false} bypasses the cache and {"cache": true} caches the call whatever the temperature is.
+//
+//	"llmCache": { "store": "cache", "ttl": "24h", "connectors": ["gpt-4o", "ollama"] }
+type Cache struct {
+	LLM
+	connector string
+	provider  string
+	model     string
+}
+
+// CacheStore the store of the cached responses, the gou stores implement it
+type CacheStore interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration) error
+}
+
+// cacheEntry the cached response, the chunks are the lines of the stream
+type cacheEntry struct {
+	Response interface{} `json:"response,omitempty"`
+	Chunks   []string    `json:"chunks,omitempty"`
+}
+
+var cacheSetting *share.LLMCache
+var cacheStore CacheStore
+var cacheTTL time.Duration
+var cacheMutex sync.RWMutex
+
+var cacheRequests = metrics.Default.Counter("yao_llm_cache_requests_total", "The number of the LLM calls by the cache result, hit, miss or bypass", "connector", "result")
+
+// the option keys not sent to the provider or not changing the response
+var uncachedOptions = map[string]bool{"cache": true, "stream": true, "stream_options": true, "messages": true, "user": true}
+
+// LoadCache load the response cache of the app.yao, it should be called after the stores are loaded
+func LoadCache(cfg config.Config) error {
+	return SetupCache(share.App.LLMCache)
+}
+
+// SetupCache set the response cache, the cache is disabled if the setting is nil
+func SetupCache(setting *share.LLMCache) error {
+	if setting == nil {
+		setCache(nil, nil, 0)
+		return nil
+	}
+
+	if setting.Store == "" {
+		return fmt.Errorf("llmCache the store is required")
+	}
+
+	s, has := store.Pools[setting.Store]
+	if !has {
+		return fmt.Errorf("llmCache store %s does not load", setting.Store)
+	}
+
+	ttl := 24 * time.Hour
+	if setting.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(setting.TTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("llmCache the ttl %s is invalid", setting.TTL)
+		}
+	}
+
+	setCache(setting, s, ttl)
+	return nil
+}
+
+func setCache(setting *share.LLMCache, s CacheStore, ttl time.Duration) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	cacheSetting = setting
+	cacheStore = s
+	cacheTTL = ttl
+}
+
+// cached wrap the LLM with the cache if the cache of the connector is enabled
+func cached(ai LLM, connector, provider, model string) LLM {
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+	if cacheSetting == nil {
+		return ai
+	}
+
+	if len(cacheSetting.Connectors) > 0 {
+		has := false
+		for _, id := range cacheSetting.Connectors {
+			has = has || id == connector
+		}
+		if !has {
+			return ai
+		}
+	}
+
+	if model == "" {
+		model = connector
+	}
+	return &Cache{LLM: ai, connector: connector, provider: provider, model: model}
+}
+
+// ChatCompletions Creates a model response or replays the cached one
+func (cache *Cache) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return cache.ChatCompletionsWith(context.Background(), messages, option, cb)
+}
+
+// ChatCompletionsWith Creates a model response or replays the cached one, the cached stream is replayed to the cb line by line
+func (cache *Cache) ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	option, enabled := cache.option(option)
+
+	cacheMutex.RLock()
+	s, ttl := cacheStore, cacheTTL
+	cacheMutex.RUnlock()
+
+	if !enabled || s == nil {
+		cacheRequests.Inc(cache.connector, "bypass")
+		return cache.LLM.ChatCompletionsWith(ctx, messages, option, cb)
+	}
+
+	key, err := cache.key(messages, option, cb != nil)
+	if err != nil {
+		cacheRequests.Inc(cache.connector, "bypass")
+		return cache.LLM.ChatCompletionsWith(ctx, messages, option, cb)
+	}
+
+	if entry, has := cache.get(s, key); has {
+		cacheRequests.Inc(cache.connector, "hit")
+		for _, line := range entry.Chunks {
+			if cb([]byte(line)) == 0 {
+				break
+			}
+		}
+		return entry.Response, nil
+	}
+	cacheRequests.Inc(cache.connector, "miss")
+
+	if cb == nil {
+		res, ex := cache.LLM.ChatCompletionsWith(ctx, messages, option, nil)
+		if ex == nil && res != nil {
+			cache.set(s, key, cacheEntry{Response: res}, ttl)
+		}
+		return res, ex
+	}
+
+	// the stream is cached if it is complete, the errors and the breaks before the [DONE] line are not cached
+	chunks := []string{}
+	complete := true
+	res, ex := cache.LLM.ChatCompletionsWith(ctx, messages, option, func(data []byte) int {
+		line := string(data)
+		blank := strings.TrimSpace(line) == "" // the separators of the events
+		if !blank && (!strings.HasPrefix(line, "data:") || strings.HasPrefix(line, `data: {"error":`)) {
+			complete = false
+		}
+
+		if !blank {
+			chunks = append(chunks, line)
+		}
+
+		n := cb(data)
+		if n == 0 && line != string(done) {
+			complete = false
+		}
+		return n
+	})
+
+	if ex == nil && complete && len(chunks) > 0 {
+		cache.set(s, key, cacheEntry{Response: res, Chunks: chunks}, ttl)
+	}
+	return res, ex
+}
+
+// option copy the option without the cache flag, the call is cached if the temperature is 0 or the flag is true
+func (cache *Cache) option(option map[string]interface{}) (map[string]interface{}, bool) {
+	res := map[string]interface{}{}
+	for key, value := range option {
+		res[key] = value
+	}
+	delete(res, "cache")
+
+	if flag, ok := option["cache"].(bool); ok {
+		return res, flag
+	}
+
+	cacheMutex.RLock()
+	all := cacheSetting != nil && cacheSetting.All
+	cacheMutex.RUnlock()
+	if all {
+		return res, true
+	}
+
+	switch v := option["temperature"].(type) {
+	case int:
+		return res, v == 0
+	case int64:
+		return res, v == 0
+	case float64:
+		return res, v == 0
+	}
+	return res, false
+}
+
+// key the hash of the provider, the model, the normalized messages and the options
+func (cache *Cache) key(messages []map[string]interface{}, option map[string]interface{}, stream bool) (string, error) {
+	normalized := []map[string]interface{}{}
+	for _, message := range messages {
+		m := map[string]interface{}{}
+		for key, value := range message {
+			if key == "user" || value == nil {
+				continue
+			}
+			if text, ok := value.(string); ok {
+				value = strings.TrimSpace(text)
+			}
+			m[key] = value
+		}
+		normalized = append(normalized, m)
+	}
+
+	options := map[string]interface{}{}
+	for key, value := range option {
+		if !uncachedOptions[key] {
+			options[key] = value
+		}
+	}
+
+	// the map keys are sorted by the standard library config
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(map[string]interface{}{
+		"provider": cache.provider,
+		"model":    cache.model,
+		"messages": normalized,
+		"options":  options,
+		"stream":   stream,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return "llm:cache:" + hex.EncodeToString(sum[:]), nil
+}
+
+func (cache *Cache) get(s CacheStore, key string) (*cacheEntry, bool) {
+	value, has := s.Get(key)
+	if !has {
+		return nil, false
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, false
+	}
+
+	entry := &cacheEntry{}
+	err := jsoniter.UnmarshalFromString(text, entry)
+	if err != nil {
+		log.Warn("[LLM] the cached response %s is invalid, %s", key, err.Error())
+		return nil, false
+	}
+	return entry, true
+}
+
+func (cache *Cache) set(s CacheStore, key string, entry cacheEntry, ttl time.Duration) {
+	text, err := jsoniter.MarshalToString(entry)
+	if err != nil {
+		log.Warn("[LLM] the response of %s can not be cached, %s", cache.connector, err.Error())
+		return
+	}
+
+	err = s.Set(key, text, ttl)
+	if err != nil {
+		log.Warn("[LLM] the response of %s can not be cached, %s", cache.connector, err.Error())
+	}
+}
@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestCache(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer setCache(nil, nil, 0)
+
+	server, hits := counter(0, 200, ollamaMessage)
+	defer server.Close()
+
+	ai := prepareCache(t, server.URL)
+	deterministic := map[string]interface{}{"temperature": 0}
+	for i := 0; i < 2; i++ {
+		res, ex := ai.ChatCompletions(messages, deterministic, nil)
+		if ex != nil {
+			t.Fatal(ex.Message)
+		}
+
+		text, ex := ai.GetContent(res)
+		if ex != nil {
+			t.Fatal(ex.Message)
+		}
+		assert.Equal(t, "Hello!", text)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+
+	// The bypass flag
+	_, ex := ai.ChatCompletions(messages, map[string]interface{}{"temperature": 0, "cache": false}, nil)
+	assert.Nil(t, ex)
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+
+	// The calls with the temperature are not cached unless the flag is true
+	for i := 0; i < 2; i++ {
+		_, ex = ai.ChatCompletions(messages, map[string]interface{}{"temperature": 0.5}, nil)
+		assert.Nil(t, ex)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(hits))
+
+	for i := 0; i < 2; i++ {
+		_, ex = ai.ChatCompletions(messages, map[string]interface{}{"temperature": 0.5, "cache": true}, nil)
+		assert.Nil(t, ex)
+	}
+	assert.Equal(t, int32(5), atomic.LoadInt32(hits))
+
+	// The user and the spaces of the messages do not change the key
+	_, ex = ai.ChatCompletions([]map[string]interface{}{
+		{"role": "system", "content": "You are a helpful assistant.\n"},
+		{"role": "user", "content": "Hi", "type": "text", "name": "sid", "user": "u1"},
+		{"role": "system", "content": "Reply in English."},
+		{"role": "user", "content": " Say hello"},
+	}, deterministic, nil)
+	assert.Nil(t, ex)
+	assert.Equal(t, int32(5), atomic.LoadInt32(hits))
+}
+
+func TestCacheStream(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer setCache(nil, nil, 0)
+
+	server, hits := counter(0, 200, ollamaStream)
+	defer server.Close()
+
+	ai := prepareCache(t, server.URL)
+	setCache(&share.LLMCache{Store: "memory", All: true}, memoryStore{}, time.Minute)
+	for i := 0; i < 2; i++ {
+		text, finish, done := collect(t, ai)
+		assert.Equal(t, "Hello!", text)
+		assert.Equal(t, "stop", finish)
+		assert.True(t, done)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+
+	// The broken streams are not cached
+	broken, brokenHits := counter(0, 200, `{"message":{"role":"assistant","content":"Hel"},"done":false}`+"\n"+`{"error":"unexpected EOF"}`+"\n")
+	defer broken.Close()
+	ai = prepareCache(t, broken.URL)
+	for i := 0; i < 2; i++ {
+		ai.ChatCompletions(messages, nil, func(data []byte) int { return 1 })
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(brokenHits))
+}
+
+func TestCacheStreamBlankLines(t *testing.T) {
+	defer setCache(nil, nil, 0)
+	setCache(&share.LLMCache{Store: "memory", All: true}, memoryStore{}, time.Minute)
+
+	// The blank lines separate the events, they do not break the completeness
+	ai := &sse{lines: []string{`data: {"choices":[{"delta":{"content":"Hello!"}}]}`, "", "data: [DONE]", ""}}
+	cache := &Cache{LLM: ai, connector: "cache.blank", provider: "openai", model: "gpt-4o"}
+	for i := 0; i < 2; i++ {
+		lines := []string{}
+		_, ex := cache.ChatCompletions(messages, nil, func(data []byte) int {
+			lines = append(lines, string(data))
+			return 1
+		})
+		assert.Nil(t, ex)
+		assert.Contains(t, lines, "data: [DONE]")
+		if i > 0 {
+			assert.NotContains(t, lines, "")
+		}
+	}
+	assert.Equal(t, 1, ai.calls)
+}
+
+func prepareCache(t *testing.T, host string) LLM {
+	setCache(&share.LLMCache{Store: "memory"}, memoryStore{}, time.Minute)
+	_, err := LoadSource([]byte(`{"type": "ollama", "options": {"model": "llama3.2", "host": "`+host+`"}}`), "cache.test.conn.yao", "cache.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ai, err := New("cache.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IsType(t, &Cache{}, ai)
+	return ai
+}
+
+// memoryStore the cache store of the tests, the ttl is ignored
+type memoryStore map[string]interface{}
+
+func (s memoryStore) Get(key string) (interface{}, bool) {
+	value, has := s[key]
+	return value, has
+}
+
+func (s memoryStore) Set(key string, value interface{}, ttl time.Duration) error {
+	s[key] = value
+	return nil
+}
+
+// sse the LLM of the tests streams the lines
+type sse struct {
+	lines []string
+	calls int
+}
+
+func (ai *sse) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return ai.ChatCompletionsWith(context.Background(), messages, option, cb)
+}
+
+func (ai *sse) ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	ai.calls++
+	for _, line := range ai.lines {
+		if cb([]byte(line)) == 0 {
+			break
+		}
+	}
+	return nil, nil
+}
+
+func (ai *sse) GetContent(response interface{}) (string, *exception.Exception) {
+	return "", nil
+}
+
+func (ai *sse) Embeddings(input interface{}, user string) (interface{}, *exception.Exception) {
+	return nil, exception.New("not supported", 400)
+}
+
+func (ai *sse) Tiktoken(input string) (int, error) {
+	return len(input) / 4, nil
+}
+
+func (ai *sse) MaxToken() int {
+	return 4096
+}
@@ -0,0 +1,309 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/kun/exception"
+	iconnector "github.com/yaoapp/yao/connector"
+	"github.com/yaoapp/yao/openai"
+)
+
+// Drivers the LLM drivers of the connector types
+var Drivers = map[string]Driver{
+	"openai":    newOpenAI,
+	"anthropic": NewAnthropic,
+	"ollama":    NewOllama,
+	"pool":      NewPool,
+}
+
+// Connectors the loaded LLM connectors, the openai connectors are loaded by gou
+var Connectors = map[string]*Connector{}
+var connectorsMutex sync.RWMutex
+
+func init() {
+	for typ := range Drivers {
+		if Is(typ) {
+			iconnector.Loaders[typ] = iconnector.Loader{Load: load, Unload: Unload}
+		}
+	}
+}
+
+// Is check if the connector type is an LLM provider loaded by yao
+func Is(typ string) bool {
+	typ = strings.ToLower(typ)
+	_, has := Drivers[typ]
+	return has && typ != "openai"
+}
+
+// LoadSource load the LLM connector
+func LoadSource(data []byte, file, id string) (*Connector, error) {
+	conn := &Connector{ID: id, Options: map[string]interface{}{}}
+	err := application.Parse(file, data, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.Type = strings.ToLower(conn.Type)
+	if !Is(conn.Type) {
+		return nil, fmt.Errorf("%s the connector type %s is not an LLM provider", id, conn.Type)
+	}
+
+	connectorsMutex.Lock()
+	defer connectorsMutex.Unlock()
+	Connectors[id] = conn
+	return conn, nil
+}
+
+func load(data []byte, file, id string) error {
+	_, err := LoadSource(data, file, id)
+	return err
+}
+
+// Unload remove the LLM connectors
+func Unload() {
+	connectorsMutex.Lock()
+	defer connectorsMutex.Unlock()
+	Connectors = map[string]*Connector{}
+}
+
+// Select select the LLM connector
+func Select(id string) (*Connector, bool) {
+	connectorsMutex.RLock()
+	defer connectorsMutex.RUnlock()
+	conn, has := Connectors[id]
+	return conn, has
+}
+
+// IDs the ids of the LLM connectors, both the yao and the gou (openai, moapi) connectors
+func IDs() []string {
+	ids := []string{}
+	connectorsMutex.RLock()
+	for id := range Connectors {
+		ids = append(ids, id)
+	}
+	connectorsMutex.RUnlock()
+
+	for id, conn := range connector.Connectors {
+		if conn.Is(connector.OPENAI) || conn.Is(connector.MOAPI) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// New create the LLM by the connector id.
+// The id is empty or moapi:<model> for the moapi, a gou openai connector or a yao LLM connector (anthropic, ollama)
+func New(id string) (LLM, error) {
+	if id == "" || strings.HasPrefix(id, "moapi") {
+		model := "gpt-3.5-turbo"
+		if strings.HasPrefix(id, "moapi:") {
+			model = strings.TrimPrefix(id, "moapi:")
+		}
+		ai, err := openai.NewMoapi(model)
+		if err != nil {
+			return nil, err
+		}
+		return cached(ai, id, "moapi", model), nil
+	}
+
+	if conn, has := Select(id); has {
+		ai, err := conn.New()
+		if err != nil || conn.Type == "pool" {
+			return ai, err // the members of the pool are cached
+		}
+		return cached(ai, id, conn.Type, settingString(conn.Setting(), "model", "")), nil
+	}
+
+	conn, err := connector.Select(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !conn.Is(connector.OPENAI) && !conn.Is(connector.MOAPI) {
+		return nil, fmt.Errorf("The connector %s is not an LLM connector", id)
+	}
+	setting := map[string]interface{}{}
+	for key, value := range conn.Setting() {
+		setting[key] = value
+	}
+	setting["id"] = id // the usage is recorded by the connector id
+	ai, err := openai.NewOpenAI(setting)
+	if err != nil {
+		return nil, err
+	}
+	return cached(ai, id, "openai", settingString(setting, "model", "")), nil
+}
+
+// New create the LLM of the connector
+func (conn *Connector) New() (LLM, error) {
+	driver, has := Drivers[conn.Type]
+	if !has {
+		return nil, fmt.Errorf("%s the connector type %s is not supported", conn.ID, conn.Type)
+	}
+	return driver(conn.Setting())
+}
+
+// Setting the options of the connector, the $ENV.NAME values are replaced with the environment variables
+func (conn *Connector) Setting() map[string]interface{} {
+	setting := map[string]interface{}{}
+	for key, value := range conn.Options {
+		if v, ok := value.(string); ok && strings.HasPrefix(v, "$ENV.") {
+			value = os.Getenv(strings.TrimPrefix(v, "$ENV."))
+		}
+		setting[key] = value
+	}
+
+	setting["id"] = conn.ID
+	setting["type"] = conn.Type
+	setting["name"] = conn.Name
+	if conn.Label != "" {
+		setting["label"] = conn.Label
+	}
+	return setting
+}
+
+func newOpenAI(setting map[string]interface{}) (LLM, error) {
+	return openai.NewOpenAI(setting)
+}
+
+// settingString the string setting or the default value
+func settingString(setting map[string]interface{}, name string, value string) string {
+	if v, ok := setting[name].(string); ok && v != "" {
+		return v
+	}
+	return value
+}
+
+// settingInt the int setting or the default value, the numbers of the JSON are float64
+func settingInt(setting map[string]interface{}, name string, value int) int {
+	switch v := setting[name].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return value
+}
+
+// tokens the number of tokens, the cl100k_base encoding is used for the models unknown to tiktoken
+func tokens(model string, input string) (int, error) {
+	tkm, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		tkm, err = tiktoken.GetEncoding("cl100k_base")
+		if err != nil {
+			return len(input)/4 + 1, nil
+		}
+	}
+	return len(tkm.Encode(input, nil, nil)), nil
+}
+
+// content the text of the message content, the text parts are joined if the content is an array
+func content(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		texts := []string{}
+		for _, part := range v {
+			if p, ok := part.(map[string]interface{}); ok {
+				if text, ok := p["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// chunk encode the text as an OpenAI chunk line
+func chunk(id, model, text string, finish string) []byte {
+	c := Chunk{ID: id, Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: model}
+	choice := ChunkChoice{Index: 0, Delta: map[string]interface{}{}}
+	if text != "" {
+		choice.Delta["content"] = text
+	}
+	if finish != "" {
+		choice.FinishReason = &finish
+	}
+	c.Choices = []ChunkChoice{choice}
+
+	data, _ := jsoniter.Marshal(c)
+	return append([]byte("data: "), data...)
+}
+
+// last encode the finish reason and the token usage as the last OpenAI chunk line
+func last(id, model string, finish string, prompt int, completion int) []byte {
+	c := Chunk{ID: id, Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: model}
+	c.Choices = []ChunkChoice{{Index: 0, Delta: map[string]interface{}{}, FinishReason: &finish}}
+	if prompt > 0 || completion > 0 {
+		c.Usage = &ChunkUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+	}
+
+	data, _ := jsoniter.Marshal(c)
+	return append([]byte("data: "), data...)
+}
+
+// errorChunk encode the error as an OpenAI error line
+func errorChunk(typ, message string) []byte {
+	data, _ := jsoniter.Marshal(map[string]interface{}{"error": map[string]interface{}{"type": typ, "message": message}})
+	return append([]byte("data: "), data...)
+}
+
+// done the last line of the stream
+var done = []byte("data: [DONE]")
+
+// post send the JSON request, returns the response, the caller should close the body
+func post(ctx context.Context, url string, header http.Header, payload interface{}) (*http.Response, error) {
+	body, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return Client.Do(req)
+}
+
+// lines read the response body line by line, stops when the handler returns false
+func lines(reader io.Reader, handler func(line []byte) bool) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if !handler(scanner.Bytes()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// unreachable the exception of the network errors
+func unreachable(provider string, err error) *exception.Exception {
+	return exception.New("%s %s", 503, provider, err.Error())
+}
@@ -0,0 +1,459 @@
+package llm
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+// The recorded responses of the providers
+const anthropicStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_01XFDUDYJgAACzvnptvVoYEL","type":"message","role":"assistant","content":[],"model":"claude-3-5-sonnet-20241022","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type": "ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"!"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+const anthropicToolStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_014p7gG3wDgGV9EUtLvnow3U","type":"message","role":"assistant","content":[],"model":"claude-3-5-sonnet-20241022","usage":{"input_tokens":472,"output_tokens":2}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_01T1x1fJ34qAmk2tNTrN7Up6","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"location\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" \"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":89}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+const anthropicStreamError = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_01","type":"message","role":"assistant","content":[],"model":"claude-3-5-sonnet-20241022"}}
+
+event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+
+`
+
+const anthropicMessage = `{"id":"msg_01XFDUDYJgAACzvnptvVoYEL","type":"message","role":"assistant","content":[{"type":"text","text":"Hello!"}],"model":"claude-3-5-sonnet-20241022","stop_reason":"end_turn","usage":{"input_tokens":25,"output_tokens":3}}`
+
+const ollamaStream = `{"model":"llama3.2","created_at":"2024-11-05T08:52:11.123Z","message":{"role":"assistant","content":"Hello"},"done":false}
+{"model":"llama3.2","created_at":"2024-11-05T08:52:11.223Z","message":{"role":"assistant","content":"!"},"done":false}
+{"model":"llama3.2","created_at":"2024-11-05T08:52:11.323Z","message":{"role":"assistant","content":""},"done_reason":"stop","done":true,"total_duration":4883583458,"prompt_eval_count":26,"eval_count":2}
+`
+
+const ollamaMessage = `{"model":"llama3.2","created_at":"2024-11-05T08:52:11.323Z","message":{"role":"assistant","content":"Hello!"},"done_reason":"stop","done":true,"prompt_eval_count":26,"eval_count":3}`
+
+var messages = []map[string]interface{}{
+	{"role": "system", "content": "You are a helpful assistant."},
+	{"role": "user", "content": "Hi", "type": "text", "name": "sid"},
+	{"role": "system", "content": "Reply in English."},
+	{"role": "user", "content": "Say hello"},
+}
+
+func TestAnthropicChat(t *testing.T) {
+	var payload map[string]interface{}
+	var header http.Header
+	server := stub(t, 200, anthropicMessage, &payload, &header)
+	defer server.Close()
+
+	ai, err := NewAnthropic(map[string]interface{}{"key": "sk-ant-test", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ex := ai.ChatCompletions(messages, map[string]interface{}{"temperature": 0.5, "stop": "END", "presence_penalty": 1}, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	text, ex := ai.GetContent(res)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	assert.Equal(t, "Hello!", text)
+	assert.Equal(t, "sk-ant-test", header.Get("X-Api-Key"))
+	assert.Equal(t, "2023-06-01", header.Get("Anthropic-Version"))
+	assert.Equal(t, "You are a helpful assistant.\n\nReply in English.", payload["system"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"role": "user", "content": "Hi\n\nSay hello"}}, payload["messages"])
+	assert.Equal(t, []interface{}{"END"}, payload["stop_sequences"])
+	assert.Equal(t, float64(4096), payload["max_tokens"])
+	assert.Equal(t, false, payload["stream"])
+	assert.Nil(t, payload["presence_penalty"])
+}
+
+func TestAnthropicStream(t *testing.T) {
+	server := stub(t, 200, anthropicStream, nil, nil)
+	defer server.Close()
+
+	ai, err := NewAnthropic(map[string]interface{}{"key": "sk-ant-test", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, finish, done := collect(t, ai)
+	assert.Equal(t, "Hello!", text)
+	assert.Equal(t, "stop", finish)
+	assert.True(t, done)
+}
+
+func TestAnthropicTools(t *testing.T) {
+	ai, err := NewAnthropic(map[string]interface{}{"key": "sk-ant-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := ai.(*Anthropic).payload([]map[string]interface{}{
+		{"role": "user", "content": "What is the weather in Paris?"},
+		{"role": "assistant", "content": "Let me check.", "tool_calls": []map[string]interface{}{
+			{"id": "call_1", "type": "function", "function": map[string]interface{}{"name": "get_weather", "arguments": `{"location": "Paris"}`}},
+		}},
+		{"role": "tool", "tool_call_id": "call_1", "content": "Sunny"},
+		{"role": "user", "content": "Thanks"},
+	}, map[string]interface{}{
+		"tools": []interface{}{map[string]interface{}{"type": "function", "function": map[string]interface{}{
+			"name": "get_weather", "description": "Get the weather", "parameters": map[string]interface{}{"type": "object"},
+		}}},
+		"tool_choice": "required",
+	})
+
+	assert.Equal(t, []map[string]interface{}{{"name": "get_weather", "description": "Get the weather", "input_schema": map[string]interface{}{"type": "object"}}}, payload["tools"])
+	assert.Equal(t, map[string]interface{}{"type": "any"}, payload["tool_choice"])
+
+	converted := payload["messages"].([]map[string]interface{})
+	assert.Len(t, converted, 3)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "text", "text": "Let me check."},
+		map[string]interface{}{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": map[string]interface{}{"location": "Paris"}},
+	}, converted[1]["content"])
+
+	// The tool result and the next user message are merged into one user message
+	assert.Equal(t, "user", converted[2]["role"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "tool_result", "tool_use_id": "call_1", "content": "Sunny"},
+		map[string]interface{}{"type": "text", "text": "Thanks"},
+	}, converted[2]["content"])
+
+	assert.Equal(t, map[string]interface{}{"type": "tool", "name": "get_weather"}, anthropicToolChoice(map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "get_weather"}}))
+
+	// The tool_use blocks of the stream are the tool calls of the chunks
+	server := stub(t, 200, anthropicToolStream, nil, nil)
+	defer server.Close()
+
+	ai, err = NewAnthropic(map[string]interface{}{"key": "sk-ant-test", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []map[string]interface{}{}
+	finish := ""
+	_, ex := ai.ChatCompletions(messages, nil, func(data []byte) int {
+		if string(data) == "data: [DONE]" {
+			return 0
+		}
+
+		var chunk Chunk
+		if err := jsoniter.Unmarshal(data[6:], &chunk); err != nil {
+			t.Fatal(err)
+		}
+
+		if list, ok := chunk.Choices[0].Delta["tool_calls"].([]interface{}); ok {
+			calls = append(calls, list[0].(map[string]interface{}))
+		}
+		if chunk.Choices[0].FinishReason != nil {
+			finish = *chunk.Choices[0].FinishReason
+		}
+		return 1
+	})
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	assert.Equal(t, "tool_calls", finish)
+	assert.Len(t, calls, 3)
+	assert.Equal(t, "toolu_01T1x1fJ34qAmk2tNTrN7Up6", calls[0]["id"])
+	assert.Equal(t, "get_weather", calls[0]["function"].(map[string]interface{})["name"])
+	assert.Equal(t, float64(0), calls[1]["index"])
+	assert.Equal(t, `{"location": "Paris"}`, calls[1]["function"].(map[string]interface{})["arguments"].(string)+calls[2]["function"].(map[string]interface{})["arguments"].(string))
+}
+
+func TestAnthropicError(t *testing.T) {
+	server := stub(t, 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, nil, nil)
+	defer server.Close()
+
+	ai, err := NewAnthropic(map[string]interface{}{"key": "sk-ant-test", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ex := ai.ChatCompletions(messages, nil, nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 529, ex.Code)
+	assert.Contains(t, ex.Message, "overloaded_error Overloaded")
+
+	stream := stub(t, 200, anthropicStreamError, nil, nil)
+	defer stream.Close()
+
+	ai, _ = NewAnthropic(map[string]interface{}{"key": "sk-ant-test", "host": stream.URL})
+	errors := []string{}
+	_, ex = ai.ChatCompletions(messages, nil, func(data []byte) int {
+		if strings.HasPrefix(string(data), `data: {"error":`) {
+			errors = append(errors, jsoniter.Get(data[6:], "error", "message").ToString())
+		}
+		return 1
+	})
+	assert.NotNil(t, ex)
+	assert.Equal(t, 529, ex.Code)
+	assert.Equal(t, []string{"Overloaded"}, errors)
+
+	_, err = NewAnthropic(map[string]interface{}{})
+	assert.NotNil(t, err)
+}
+
+func TestOllamaChat(t *testing.T) {
+	var payload map[string]interface{}
+	server := stub(t, 200, ollamaMessage, &payload, nil)
+	defer server.Close()
+
+	ai, err := NewOllama(map[string]interface{}{"model": "llama3.2", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ex := ai.ChatCompletions(messages, map[string]interface{}{"temperature": 0.5, "max_tokens": 10, "response_format": map[string]interface{}{"type": "json_object"}}, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	text, ex := ai.GetContent(res)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	assert.Equal(t, "Hello!", text)
+	assert.Equal(t, "json", payload["format"])
+	assert.Equal(t, map[string]interface{}{"temperature": 0.5, "num_predict": float64(10)}, payload["options"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"role": "system", "content": "You are a helpful assistant.\n\nReply in English."},
+		map[string]interface{}{"role": "user", "content": "Hi"},
+		map[string]interface{}{"role": "user", "content": "Say hello"},
+	}, payload["messages"])
+
+	// The JSON schema of the structured output
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}}
+	_, ex = ai.ChatCompletions(messages, map[string]interface{}{"response_format": map[string]interface{}{"type": "json_schema", "json_schema": map[string]interface{}{"name": "reply", "schema": schema}}}, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, schema, payload["format"])
+}
+
+func TestOllamaStream(t *testing.T) {
+	server := stub(t, 200, ollamaStream, nil, nil)
+	defer server.Close()
+
+	ai, err := NewOllama(map[string]interface{}{"model": "llama3.2", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, finish, done := collect(t, ai)
+	assert.Equal(t, "Hello!", text)
+	assert.Equal(t, "stop", finish)
+	assert.True(t, done)
+}
+
+func TestOllamaError(t *testing.T) {
+	server := stub(t, 404, `{"error":"model \"llama3.2\" not found, try pulling it first"}`, nil, nil)
+	defer server.Close()
+
+	ai, err := NewOllama(map[string]interface{}{"model": "llama3.2", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ex := ai.ChatCompletions(messages, nil, nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 404, ex.Code)
+	assert.Contains(t, ex.Message, "not found, try pulling it first")
+
+	server.Close()
+	_, ex = ai.ChatCompletions(messages, nil, nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 503, ex.Code)
+}
+
+func TestOllamaEmbeddings(t *testing.T) {
+	server := stub(t, 200, `{"model":"all-minilm","embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":8}`, nil, nil)
+	defer server.Close()
+
+	ai, err := NewOllama(map[string]interface{}{"model": "all-minilm", "host": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, ex := ai.Embeddings([]string{"hello", "world"}, "")
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	data := res.(map[string]interface{})["data"].([]interface{})
+	assert.Len(t, data, 2)
+	assert.Equal(t, []float64{0.3, 0.4}, data[1].(map[string]interface{})["embedding"])
+}
+
+func TestStreamUsage(t *testing.T) {
+	anthropic := stub(t, 200, anthropicStream, nil, nil)
+	defer anthropic.Close()
+	ollama := stub(t, 200, ollamaStream, nil, nil)
+	defer ollama.Close()
+
+	claude, err := NewAnthropic(map[string]interface{}{"key": "sk-ant-test", "host": anthropic.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	llama, err := NewOllama(map[string]interface{}{"model": "llama3.2", "host": ollama.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for ai, expect := range map[LLM]ChunkUsage{
+		claude: {PromptTokens: 25, CompletionTokens: 15, TotalTokens: 40},
+		llama:  {PromptTokens: 26, CompletionTokens: 2, TotalTokens: 28},
+	} {
+		var usage *ChunkUsage
+		_, ex := ai.ChatCompletions(messages, nil, func(data []byte) int {
+			var chunk Chunk
+			if jsoniter.Unmarshal(data[6:], &chunk) == nil && chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			return 1
+		})
+		if ex != nil {
+			t.Fatal(ex.Message)
+		}
+		assert.Equal(t, &expect, usage)
+	}
+}
+
+func TestLoadSource(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+
+	t.Setenv("YAO_TEST_ANTHROPIC_KEY", "sk-ant-env")
+	conn, err := LoadSource([]byte(`{"type": "anthropic", "name": "Claude", "options": {"key": "$ENV.YAO_TEST_ANTHROPIC_KEY", "model": "claude-3-5-haiku-latest"}}`), "claude.conn.yao", "claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "sk-ant-env", conn.Setting()["key"])
+
+	ai, err := New("claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IsType(t, &Anthropic{}, ai)
+	assert.Contains(t, IDs(), "claude")
+
+	_, err = LoadSource([]byte(`{"type": "mysql", "options": {}}`), "db.conn.yao", "db")
+	assert.NotNil(t, err)
+}
+
+// stub the provider server, responds with the recorded body, the request payload and header are saved if not nil
+func stub(t *testing.T, status int, body string, payload *map[string]interface{}, header *http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if payload != nil {
+			jsoniter.Unmarshal(data, payload)
+		}
+
+		if header != nil {
+			*header = r.Header.Clone()
+		}
+
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+// collect the text of the OpenAI chunks
+func collect(t *testing.T, ai LLM) (string, string, bool) {
+	text := ""
+	finish := ""
+	done := false
+	_, ex := ai.ChatCompletions(messages, nil, func(data []byte) int {
+		if string(data) == "data: [DONE]" {
+			done = true
+			return 0
+		}
+
+		var chunk Chunk
+		err := jsoniter.Unmarshal(data[6:], &chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		text = text + content(chunk.Choices[0].Delta["content"])
+		if chunk.Choices[0].FinishReason != nil {
+			finish = *chunk.Choices[0].FinishReason
+		}
+		return 1
+	})
+
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	return text, finish, done
+}
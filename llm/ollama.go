@@ -0,0 +1,324 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/openai"
+	"github.com/yaoapp/yao/telemetry"
+	"github.com/yaoapp/yao/usage"
+)
+
+// Ollama the Ollama chat API, the local servers with the OpenAI compatible API (llama.cpp, vLLM) set the api option to openai
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+type Ollama struct {
+	connector string
+	key       string // Optional, the bearer token of the proxy
+	model     string
+	host      string
+	maxToken  int
+}
+
+// NewOllama create a new Ollama instance by setting
+func NewOllama(setting map[string]interface{}) (LLM, error) {
+	host := strings.TrimSuffix(settingString(setting, "host", "http://127.0.0.1:11434"), "/")
+
+	// The OpenAI compatible API. e.g. llama-server of llama.cpp
+	if settingString(setting, "api", "") == "openai" {
+		compatible := map[string]interface{}{}
+		for key, value := range setting {
+			compatible[key] = value
+		}
+		compatible["host"] = host
+		return openai.NewOpenAI(compatible)
+	}
+
+	model := settingString(setting, "model", "")
+	if model == "" {
+		return nil, fmt.Errorf("The ollama model is required")
+	}
+
+	return &Ollama{
+		connector: settingString(setting, "id", ""),
+		key:       settingString(setting, "key", ""),
+		model:     model,
+		host:      host,
+		maxToken:  settingInt(setting, "max_token", 8192),
+	}, nil
+}
+
+// ChatCompletions Generate the next message in the chat.
+func (ai *Ollama) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return ai.ChatCompletionsWith(context.Background(), messages, option, cb)
+}
+
+// ChatCompletionsWith Generate the next message in the chat, the lines are streamed to the cb if it is not nil
+func (ai *Ollama) ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	payload := ai.payload(messages, option)
+	payload["stream"] = cb != nil
+
+	call, ex := usage.Begin(ctx, ai.connector, ai.model, messages, ai.Tiktoken)
+	if ex != nil {
+		return nil, ex
+	}
+
+	ctx, span := telemetry.Start(ctx, "ollama.chat", telemetry.SpanKindClient)
+	span.SetAttribute("llm.provider", "ollama")
+	span.SetAttribute("llm.model", ai.model)
+	defer span.Finish()
+
+	res, err := post(ctx, ai.host+"/api/chat", ai.header(ctx), payload)
+	if err != nil {
+		span.SetError(err)
+		return nil, unreachable("Ollama", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		body, _ := io.ReadAll(res.Body)
+		ex := ai.error(res.StatusCode, body)
+		span.SetError(ex.Message)
+		return nil, ex
+	}
+
+	if cb == nil {
+		var data interface{}
+		err := jsoniter.NewDecoder(res.Body).Decode(&data)
+		if err != nil {
+			return nil, exception.New("Ollama %s", 500, err.Error())
+		}
+		call.End(data)
+		return data, nil
+	}
+
+	ex = ai.stream(res.Body, call.Stream(cb))
+	call.End(nil)
+	if ex != nil {
+		span.SetError(ex.Message)
+	}
+	return nil, ex
+}
+
+// stream convert the JSON lines to the OpenAI chunks
+func (ai *Ollama) stream(body io.Reader, cb func(data []byte) int) *exception.Exception {
+	var ex *exception.Exception = nil
+	err := lines(body, func(line []byte) bool {
+		if len(strings.TrimSpace(string(line))) == 0 {
+			return true
+		}
+
+		var event struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Done            bool   `json:"done"`
+			DoneReason      string `json:"done_reason"`
+			Error           string `json:"error"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			EvalCount       int    `json:"eval_count"`
+		}
+
+		err := jsoniter.Unmarshal(line, &event)
+		if err != nil {
+			ex = exception.New("Ollama %s", 500, err.Error())
+			cb(errorChunk("invalid_response", err.Error()))
+			return false
+		}
+
+		if event.Error != "" {
+			ex = exception.New("Ollama %s", 500, event.Error)
+			cb(errorChunk("server_error", event.Error))
+			return false
+		}
+
+		if event.Message.Content != "" {
+			if cb(chunk("", ai.model, event.Message.Content, "")) == 0 {
+				return false
+			}
+		}
+
+		if event.Done {
+			finish := "stop"
+			if event.DoneReason == "length" {
+				finish = "length"
+			}
+			if cb(last("", ai.model, finish, event.PromptEvalCount, event.EvalCount)) == 0 {
+				return false
+			}
+			cb(done)
+			return false
+		}
+		return true
+	})
+
+	if err != nil && ex == nil {
+		return unreachable("Ollama", err)
+	}
+	return ex
+}
+
+// payload the system prompts are merged and placed at the first, the OpenAI options are mapped to the model options
+func (ai *Ollama) payload(messages []map[string]interface{}, option map[string]interface{}) map[string]interface{} {
+	system := []string{}
+	converted := []map[string]interface{}{}
+	for _, message := range messages {
+		role, _ := message["role"].(string)
+		if role == "system" {
+			system = append(system, content(message["content"]))
+			continue
+		}
+
+		if role != "assistant" && role != "tool" {
+			role = "user"
+		}
+
+		msg := map[string]interface{}{"role": role, "content": content(message["content"])}
+		if images, ok := message["images"]; ok {
+			msg["images"] = images
+		}
+		converted = append(converted, msg)
+	}
+
+	if len(system) > 0 {
+		converted = append([]map[string]interface{}{{"role": "system", "content": strings.Join(system, "\n\n")}}, converted...)
+	}
+
+	options := map[string]interface{}{}
+	payload := map[string]interface{}{"model": ai.model, "messages": converted}
+	for key, value := range option {
+		switch key {
+		case "temperature", "top_p", "top_k", "seed", "num_ctx", "repeat_penalty", "presence_penalty", "frequency_penalty":
+			options[key] = value
+
+		case "max_tokens":
+			options["num_predict"] = value
+
+		case "stop":
+			if v, ok := value.(string); ok {
+				value = []string{v}
+			}
+			options["stop"] = value
+
+		case "response_format":
+			if v, ok := value.(map[string]interface{}); ok && v["type"] == "json_object" {
+				payload["format"] = "json"
+			} else if ok && v["type"] == "json_schema" {
+				if schema, ok := v["json_schema"].(map[string]interface{}); ok && schema["schema"] != nil {
+					payload["format"] = schema["schema"]
+				}
+			}
+
+		case "keep_alive", "format", "tools":
+			payload[key] = value
+
+		case "options":
+			if v, ok := value.(map[string]interface{}); ok {
+				for name, opt := range v {
+					options[name] = opt
+				}
+			}
+		}
+	}
+
+	if len(options) > 0 {
+		payload["options"] = options
+	}
+	return payload
+}
+
+func (ai *Ollama) header(ctx context.Context) http.Header {
+	header := http.Header{}
+	if ai.key != "" {
+		header.Set("Authorization", fmt.Sprintf("Bearer %s", ai.key))
+	}
+	telemetry.Inject(ctx, header)
+	return header
+}
+
+// error map the error response to the exception. {"error":"model \"llama3\" not found, try pulling it first"}
+func (ai *Ollama) error(status int, body []byte) *exception.Exception {
+	var res struct {
+		Error string `json:"error"`
+	}
+
+	err := jsoniter.Unmarshal(body, &res)
+	if err != nil || res.Error == "" {
+		return exception.New("Ollama Error %s", status, strings.TrimSpace(string(body)))
+	}
+	return exception.New("Ollama %s", status, res.Error)
+}
+
+// GetContent get the content of the message
+func (ai *Ollama) GetContent(response interface{}) (string, *exception.Exception) {
+	if data, ok := response.(map[string]interface{}); ok {
+		if message, ok := data["message"].(map[string]interface{}); ok {
+			if content, ok := message["content"].(string); ok {
+				return content, nil
+			}
+		}
+	}
+	return "", exception.New("response format error, %#v", 500, response)
+}
+
+// Embeddings Creates the embedding vectors, the response is the same format as the OpenAI embeddings
+func (ai *Ollama) Embeddings(input interface{}, user string) (interface{}, *exception.Exception) {
+	ctx := context.Background()
+	call, ex := usage.Begin(ctx, ai.connector, ai.model, nil, nil)
+	if ex != nil {
+		return nil, ex
+	}
+
+	res, err := post(ctx, ai.host+"/api/embed", ai.header(ctx), map[string]interface{}{"model": ai.model, "input": input})
+	if err != nil {
+		return nil, unreachable("Ollama", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, unreachable("Ollama", err)
+	}
+
+	if res.StatusCode != 200 {
+		return nil, ai.error(res.StatusCode, body)
+	}
+
+	var embed struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float64 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	err = jsoniter.Unmarshal(body, &embed)
+	if err != nil {
+		return nil, exception.New("Ollama %s", 500, err.Error())
+	}
+
+	data := []interface{}{}
+	for i, embedding := range embed.Embeddings {
+		data = append(data, map[string]interface{}{"object": "embedding", "index": i, "embedding": embedding})
+	}
+
+	response := map[string]interface{}{
+		"object": "list",
+		"model":  embed.Model,
+		"data":   data,
+		"usage":  map[string]interface{}{"prompt_tokens": embed.PromptEvalCount, "total_tokens": embed.PromptEvalCount},
+	}
+	call.End(response)
+	return response, nil
+}
+
+// Tiktoken get number of tokens, it is an estimate with the cl100k_base encoding
+func (ai *Ollama) Tiktoken(input string) (int, error) {
+	return tokens(ai.model, input)
+}
+
+// MaxToken get max number of tokens
+func (ai *Ollama) MaxToken() int {
+	return ai.maxToken
+}
@@ -0,0 +1,428 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+)
+
+// Pool the pool of the LLM connectors, the requests are balanced by the weights,
+// failed over to the other connectors on the 429/5xx errors before the first streamed chunk,
+// and the failing connectors are skipped by the circuit breakers until the cooldown expires.
+//
+//	{
+//	  "type": "pool",
+//	  "options": {
+//	    "connectors": [{ "connector": "gpt-4o", "weight": 3 }, { "connector": "claude", "weight": 1 }, "ollama"],
+//	    "retries": 2, "backoff": "200ms", "timeout": "60s",
+//	    "breaker": { "failures": 5, "cooldown": "30s" }
+//	  }
+//	}
+type Pool struct {
+	id       string
+	members  []*PoolMember
+	retries  int
+	backoff  time.Duration
+	timeout  time.Duration // The timeout of each attempt, the streams are timed out until the first chunk
+	failures int           // The consecutive failures to open the circuit breaker
+	cooldown time.Duration // The duration the circuit breaker keeps open
+}
+
+// PoolMember the connector of the pool
+type PoolMember struct {
+	Connector string `json:"connector"`
+	Weight    int    `json:"weight"`
+}
+
+// breaker the circuit breaker of a connector, shared by the pools
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool // half-open, one request is trying the connector
+}
+
+var breakers = map[string]*breaker{}
+var balancers = map[string]map[string]int{} // the current weights of the smooth weighted round-robin, by the pool id
+var poolMutex sync.Mutex
+
+// MaxBackoff the max duration to wait before the next attempt
+var MaxBackoff = 5 * time.Second
+
+// NewPool create a new pool by setting
+func NewPool(setting map[string]interface{}) (LLM, error) {
+	id := settingString(setting, "id", "")
+	pool := &Pool{
+		id:       id,
+		members:  []*PoolMember{},
+		retries:  settingInt(setting, "retries", 2),
+		backoff:  settingDuration(setting, "backoff", 200*time.Millisecond),
+		timeout:  settingDuration(setting, "timeout", 60*time.Second),
+		failures: 5,
+		cooldown: 30 * time.Second,
+	}
+
+	if cfg, ok := setting["breaker"].(map[string]interface{}); ok {
+		pool.failures = settingInt(cfg, "failures", pool.failures)
+		pool.cooldown = settingDuration(cfg, "cooldown", pool.cooldown)
+	}
+
+	connectors, _ := setting["connectors"].([]interface{})
+	for _, value := range connectors {
+		member := &PoolMember{Weight: 1}
+		switch v := value.(type) {
+		case string:
+			member.Connector = v
+		case map[string]interface{}:
+			member.Connector = settingString(v, "connector", "")
+			member.Weight = settingInt(v, "weight", 1)
+		}
+
+		if member.Connector == "" || member.Connector == id {
+			return nil, fmt.Errorf("pool %s the connector %v is invalid", id, value)
+		}
+
+		if member.Weight > 0 {
+			pool.members = append(pool.members, member)
+		}
+	}
+
+	if len(pool.members) == 0 {
+		return nil, fmt.Errorf("pool %s the connectors are required", id)
+	}
+	return pool, nil
+}
+
+// ChatCompletions Creates a model response by one of the connectors
+func (pool *Pool) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return pool.ChatCompletionsWith(context.Background(), messages, option, cb)
+}
+
+// ChatCompletionsWith Creates a model response by one of the connectors, retries the others on the 429/5xx errors
+func (pool *Pool) ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	return pool.do(ctx, func(ctx context.Context, ai LLM, started func() bool) (interface{}, *exception.Exception) {
+		if cb == nil {
+			return ai.ChatCompletionsWith(ctx, messages, copyOption(option), nil)
+		}
+
+		return ai.ChatCompletionsWith(ctx, messages, copyOption(option), func(data []byte) int {
+			if strings.HasPrefix(string(data), `data: {"error":`) {
+				return 1 // the error is returned as the exception, the next connector may be tried
+			}
+
+			if !started() {
+				return 0 // the attempt is abandoned
+			}
+			return cb(data)
+		})
+	})
+}
+
+// Embeddings Creates the embedding vectors by one of the connectors
+func (pool *Pool) Embeddings(input interface{}, user string) (interface{}, *exception.Exception) {
+	return pool.do(context.Background(), func(ctx context.Context, ai LLM, started func() bool) (interface{}, *exception.Exception) {
+		return ai.Embeddings(input, user)
+	})
+}
+
+// GetContent get the content of the response, the response is in the format of the connector which created it
+func (pool *Pool) GetContent(response interface{}) (string, *exception.Exception) {
+	var last *exception.Exception = exception.New("response format error, %#v", 500, response)
+	for _, member := range pool.members {
+		ai, err := New(member.Connector)
+		if err != nil {
+			continue
+		}
+
+		content, ex := ai.GetContent(response)
+		if ex == nil {
+			return content, nil
+		}
+		last = ex
+	}
+	return "", last
+}
+
+// Tiktoken get number of tokens by the first connector
+func (pool *Pool) Tiktoken(input string) (int, error) {
+	ai, err := New(pool.members[0].Connector)
+	if err != nil {
+		return 0, err
+	}
+	return ai.Tiktoken(input)
+}
+
+// MaxToken the smallest max number of tokens of the connectors, so the prompts fit any of them
+func (pool *Pool) MaxToken() int {
+	max := 0
+	for _, member := range pool.members {
+		ai, err := New(member.Connector)
+		if err != nil {
+			continue
+		}
+		if n := ai.MaxToken(); max == 0 || n < max {
+			max = n
+		}
+	}
+	return max
+}
+
+// do run the attempt on the selected connectors until it succeeds, a non-retryable error returns or the retries run out
+func (pool *Pool) do(ctx context.Context, attempt func(ctx context.Context, ai LLM, started func() bool) (interface{}, *exception.Exception)) (interface{}, *exception.Exception) {
+	tried := map[string]bool{}
+	var last *exception.Exception = nil
+
+	for i := 0; i <= pool.retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, exception.New("pool %s %s", 499, pool.id, ctx.Err().Error())
+			case <-time.After(pool.wait(i)):
+			}
+		}
+
+		member := pool.next(tried)
+		if member == nil {
+			break
+		}
+		tried[member.Connector] = true
+
+		ai, err := New(member.Connector)
+		if err != nil {
+			last = exception.New("pool %s %s", 500, pool.id, err.Error())
+			pool.report(member.Connector, false)
+			continue
+		}
+
+		res, ex, started := pool.try(ctx, ai, attempt)
+		if ex == nil || !Retryable(ex) {
+			pool.report(member.Connector, true) // the connector is available, the error is of the request
+			return res, ex
+		}
+
+		last = ex
+		pool.report(member.Connector, false)
+		if started {
+			return nil, ex // the chunks are sent to the client, it can not be retried
+		}
+		log.Warn("[LLM] pool %s connector %s failed (%d) %s, retry", pool.id, member.Connector, ex.Code, ex.Message)
+	}
+
+	if last == nil {
+		last = exception.New("pool %s all the connectors are unavailable", 503, pool.id)
+	}
+	return nil, last
+}
+
+// try run the attempt with the timeout, the timer of the streams stops at the first chunk.
+// The attempt is abandoned on the timeout, its chunks are dropped.
+func (pool *Pool) try(parent context.Context, ai LLM, attempt func(ctx context.Context, ai LLM, started func() bool) (interface{}, *exception.Exception)) (interface{}, *exception.Exception, bool) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	type result struct {
+		res interface{}
+		ex  *exception.Exception
+	}
+
+	var mutex sync.Mutex
+	isStarted := false
+	abandoned := false
+	first := make(chan bool)
+	results := make(chan result, 1)
+
+	started := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if abandoned {
+			return false
+		}
+		if !isStarted {
+			isStarted = true
+			close(first)
+		}
+		return true
+	}
+
+	abandon := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if isStarted {
+			return false
+		}
+		abandoned = true
+		return true
+	}
+
+	go func() {
+		res, ex := attempt(ctx, ai, started)
+		results <- result{res, ex}
+	}()
+
+	timer := time.NewTimer(pool.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case r := <-results:
+			mutex.Lock()
+			defer mutex.Unlock()
+			return r.res, r.ex, isStarted
+
+		case <-first:
+			first = nil // the stream started, waits for the result without the timeout
+			timer.Stop()
+
+		case <-timer.C:
+			if !abandon() {
+				continue
+			}
+			return nil, exception.New("pool %s the attempt timed out after %s", 504, pool.id, pool.timeout), false
+
+		case <-parent.Done():
+			ok := abandon()
+			return nil, exception.New("pool %s %s", 499, pool.id, parent.Err().Error()), !ok
+		}
+	}
+}
+
+// next select the connector by the smooth weighted round-robin, the open connectors are skipped.
+// The connectors not tried yet are preferred, the tried ones are selected again if the others are not available.
+func (pool *Pool) next(tried map[string]bool) *PoolMember {
+	poolMutex.Lock()
+	defer poolMutex.Unlock()
+
+	current, has := balancers[pool.id]
+	if !has {
+		current = map[string]int{}
+		balancers[pool.id] = current
+	}
+
+	now := time.Now()
+	candidates := []*PoolMember{}
+	for _, member := range pool.members {
+		if !tried[member.Connector] && pool.allow(member.Connector, now) {
+			candidates = append(candidates, member)
+		}
+	}
+
+	// All the available connectors are tried, retry them after the backoff
+	if len(candidates) == 0 {
+		for _, member := range pool.members {
+			if pool.allow(member.Connector, now) {
+				candidates = append(candidates, member)
+			}
+		}
+	}
+
+	total := 0
+	var selected *PoolMember = nil
+	for _, member := range candidates {
+		total = total + member.Weight
+		current[member.Connector] = current[member.Connector] + member.Weight
+		if selected == nil || current[member.Connector] > current[selected.Connector] {
+			selected = member
+		}
+	}
+
+	if selected == nil {
+		return nil
+	}
+
+	current[selected.Connector] = current[selected.Connector] - total
+	if b, has := breakers[selected.Connector]; has && !b.openUntil.IsZero() {
+		b.probing = true // half-open
+	}
+	return selected
+}
+
+// allow check the circuit breaker, the connector is tried by one request when the cooldown expires
+func (pool *Pool) allow(connector string, now time.Time) bool {
+	b, has := breakers[connector]
+	if !has || b.openUntil.IsZero() {
+		return true
+	}
+	return now.After(b.openUntil) && !b.probing
+}
+
+// report record the result of the connector, the circuit breaker opens after the consecutive failures
+func (pool *Pool) report(connector string, success bool) {
+	poolMutex.Lock()
+	defer poolMutex.Unlock()
+
+	b, has := breakers[connector]
+	if !has {
+		b = &breaker{}
+		breakers[connector] = b
+	}
+
+	if success {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.probing || b.failures >= pool.failures {
+		if b.openUntil.IsZero() || b.probing {
+			log.Warn("[LLM] the circuit breaker of the connector %s is open for %s", connector, pool.cooldown)
+		}
+		b.openUntil = time.Now().Add(pool.cooldown)
+		b.probing = false
+	}
+}
+
+// wait the exponential backoff with the jitter
+func (pool *Pool) wait(attempt int) time.Duration {
+	wait := pool.backoff << (attempt - 1)
+	if wait > MaxBackoff || wait <= 0 {
+		wait = MaxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// Retryable check if the request can be retried on the other connectors, the 429, 5xx and the network errors
+func Retryable(ex *exception.Exception) bool {
+	return ex.Code == 429 || ex.Code >= 500 || ex.Code == 408
+}
+
+// ResetBreakers close all the circuit breakers
+func ResetBreakers() {
+	poolMutex.Lock()
+	defer poolMutex.Unlock()
+	breakers = map[string]*breaker{}
+	balancers = map[string]map[string]int{}
+}
+
+// copyOption the adapters write the options (stream, model), each attempt uses a copy
+func copyOption(option map[string]interface{}) map[string]interface{} {
+	if option == nil {
+		return nil
+	}
+	res := map[string]interface{}{}
+	for key, value := range option {
+		res[key] = value
+	}
+	return res
+}
+
+// settingDuration the duration setting, the numbers are milliseconds. e.g. "30s", 500
+func settingDuration(setting map[string]interface{}, name string, value time.Duration) time.Duration {
+	switch v := setting[name].(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int:
+		return time.Duration(v) * time.Millisecond
+	case float64:
+		return time.Duration(v) * time.Millisecond
+	}
+	return value
+}
@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestPoolFailover(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer ResetBreakers()
+
+	primary, primaryHits := counter(0, 503, `{"error":"server overloaded"}`)
+	defer primary.Close()
+	secondary, secondaryHits := counter(0, 200, ollamaMessage)
+	defer secondary.Close()
+
+	ai := preparePool(t, `{"breaker": {"failures": 1, "cooldown": "1m"}}`, primary.URL, secondary.URL)
+	res, ex := ai.ChatCompletions(messages, nil, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	text, ex := ai.GetContent(res)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, "Hello!", text)
+	assert.Equal(t, int32(1), atomic.LoadInt32(primaryHits))
+	assert.Equal(t, int32(1), atomic.LoadInt32(secondaryHits))
+
+	// The circuit breaker of the primary is open
+	_, ex = ai.ChatCompletions(messages, nil, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(primaryHits))
+	assert.Equal(t, int32(2), atomic.LoadInt32(secondaryHits))
+}
+
+func TestPoolStream(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer ResetBreakers()
+
+	primary, primaryHits := counter(0, 429, `{"error":"too many requests"}`)
+	defer primary.Close()
+	secondary, _ := counter(0, 200, ollamaStream)
+	defer secondary.Close()
+
+	ai := preparePool(t, `{}`, primary.URL, secondary.URL)
+	text, finish, done := collect(t, ai)
+	assert.Equal(t, "Hello!", text)
+	assert.Equal(t, "stop", finish)
+	assert.True(t, done)
+	assert.Equal(t, int32(1), atomic.LoadInt32(primaryHits))
+
+	// The stream is not retried after the first chunk
+	broken, _ := counter(0, 200, `{"message":{"role":"assistant","content":"Hel"},"done":false}`+"\n"+`{"error":"unexpected EOF"}`+"\n")
+	defer broken.Close()
+	fallback, fallbackHits := counter(0, 200, ollamaStream)
+	defer fallback.Close()
+
+	ResetBreakers()
+	ai = preparePool(t, `{}`, broken.URL, fallback.URL)
+	text = ""
+	_, ex := ai.ChatCompletions(messages, nil, func(data []byte) int {
+		text = text + string(data)
+		return 1
+	})
+	assert.NotNil(t, ex)
+	assert.Contains(t, text, "Hel")
+	assert.Equal(t, int32(0), atomic.LoadInt32(fallbackHits))
+}
+
+func TestPoolRetrySameConnector(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer ResetBreakers()
+
+	// The only connector fails once, it is retried after the backoff
+	hits := int32(0)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"error":"server overloaded"}`))
+			return
+		}
+		w.Write([]byte(ollamaMessage))
+	}))
+	defer flaky.Close()
+
+	ai := preparePool(t, `{}`, flaky.URL)
+	_, ex := ai.ChatCompletions(messages, nil, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	// The circuit breaker still decides, the open connector is not retried
+	ResetBreakers()
+	atomic.StoreInt32(&hits, 0)
+	ai = preparePool(t, `{"breaker": {"failures": 1, "cooldown": "1m"}}`, flaky.URL)
+	_, ex = ai.ChatCompletions(messages, nil, nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 503, ex.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestPoolNotRetryable(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer ResetBreakers()
+
+	primary, _ := counter(0, 400, `{"error":"invalid options"}`)
+	defer primary.Close()
+	secondary, secondaryHits := counter(0, 200, ollamaMessage)
+	defer secondary.Close()
+
+	ai := preparePool(t, `{}`, primary.URL, secondary.URL)
+	_, ex := ai.ChatCompletions(messages, nil, nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 400, ex.Code)
+	assert.Equal(t, int32(0), atomic.LoadInt32(secondaryHits))
+}
+
+func TestPoolTimeout(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer ResetBreakers()
+
+	slow, _ := counter(500*time.Millisecond, 200, ollamaMessage)
+	defer slow.Close()
+	fast, fastHits := counter(0, 200, ollamaMessage)
+	defer fast.Close()
+
+	ai := preparePool(t, `{"timeout": "50ms"}`, slow.URL, fast.URL)
+	_, ex := ai.ChatCompletions(messages, nil, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(fastHits))
+}
+
+func TestPoolWeights(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer Unload()
+	defer ResetBreakers()
+
+	first, firstHits := counter(0, 200, ollamaMessage)
+	defer first.Close()
+	second, secondHits := counter(0, 200, ollamaMessage)
+	defer second.Close()
+
+	ai := preparePool(t, `{"weights": [3, 1]}`, first.URL, second.URL)
+	for i := 0; i < 8; i++ {
+		_, ex := ai.ChatCompletions(messages, nil, nil)
+		if ex != nil {
+			t.Fatal(ex.Message)
+		}
+	}
+	assert.Equal(t, int32(6), atomic.LoadInt32(firstHits))
+	assert.Equal(t, int32(2), atomic.LoadInt32(secondHits))
+}
+
+// preparePool load the ollama connectors of the hosts and the pool of them
+func preparePool(t *testing.T, option string, hosts ...string) LLM {
+	var extra struct {
+		Timeout string                 `json:"timeout"`
+		Breaker map[string]interface{} `json:"breaker"`
+		Weights []int                  `json:"weights"`
+	}
+	err := jsoniter.UnmarshalFromString(option, &extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := []interface{}{}
+	for i, host := range hosts {
+		id := fmt.Sprintf("pool.test.%d", i)
+		_, err := LoadSource([]byte(fmt.Sprintf(`{"type": "ollama", "options": {"model": "llama3.2", "host": %q}}`, host)), id+".conn.yao", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		weight := 1
+		if i < len(extra.Weights) {
+			weight = extra.Weights[i]
+		}
+		members = append(members, map[string]interface{}{"connector": id, "weight": weight})
+	}
+
+	setting := map[string]interface{}{"id": "pool.test", "connectors": members, "backoff": "1ms"}
+	if extra.Timeout != "" {
+		setting["timeout"] = extra.Timeout
+	}
+	if extra.Breaker != nil {
+		setting["breaker"] = extra.Breaker
+	}
+
+	ai, err := NewPool(setting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ai
+}
+
+// counter the stub server counts the requests, responds after the delay
+func counter(delay time.Duration, status int, body string) (*httptest.Server, *int32) {
+	hits := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	return server, &hits
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/yaoapp/kun/exception"
+)
+
+// LLM the common interface of the LLM providers.
+// The streaming chunks are converted to the OpenAI chunk format (data: {"choices":[{"delta":{"content":"..."}}]}),
+// so the consumers (aigc, neo, pipe) parse one format whatever the provider is.
+type LLM interface {
+	ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception)
+	ChatCompletionsWith(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception)
+	GetContent(response interface{}) (string, *exception.Exception)
+	Embeddings(input interface{}, user string) (interface{}, *exception.Exception)
+	Tiktoken(input string) (int, error)
+	MaxToken() int
+}
+
+// Driver create the LLM by the connector setting
+type Driver func(setting map[string]interface{}) (LLM, error)
+
+// Connector the connector of the LLM providers which are not the gou connector types. e.g. anthropic, ollama
+type Connector struct {
+	ID      string                 `json:"-"`
+	Type    string                 `json:"type"`
+	Name    string                 `json:"name,omitempty"`
+	Label   string                 `json:"label,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// Chunk the OpenAI chat completion chunk, the streaming events of the providers are converted to it
+type Chunk struct {
+	ID      string        `json:"id,omitempty"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model,omitempty"`
+	Choices []ChunkChoice `json:"choices"`
+	Usage   *ChunkUsage   `json:"usage,omitempty"` // The token usage, it is sent with the last chunk
+}
+
+// ChunkUsage the token usage of the stream
+type ChunkUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChunkChoice the choice of the chunk
+type ChunkChoice struct {
+	Index        int                    `json:"index"`
+	Delta        map[string]interface{} `json:"delta"`
+	FinishReason *string                `json:"finish_reason"`
+}
+
+// Client the HTTP client of the providers, replace it to set the proxy or the timeout
+var Client = &http.Client{}
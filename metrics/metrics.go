@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/schedule"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/runtime"
+	"github.com/yaoapp/yao/share"
+)
+
+// Default the registry of the Yao server
+var Default = NewRegistry()
+
+var (
+	httpRequests    = Default.Counter("yao_http_requests_total", "The number of the HTTP requests by the route", "method", "route", "status")
+	httpDuration    = Default.Histogram("yao_http_request_duration_seconds", "The latency of the HTTP requests by the route", nil, "method", "route")
+	processCalls    = Default.Counter("yao_process_calls_total", "The number of the process executions", "process")
+	processErrors   = Default.Counter("yao_process_errors_total", "The number of the failed process executions", "process")
+	processDuration = Default.Histogram("yao_process_duration_seconds", "The duration of the process executions", nil, "process")
+	runtimeInUse    = Default.Gauge("yao_runtime_v8_in_use", "The number of the isolates of the V8 runtime pool in use")
+	runtimeAvail    = Default.Gauge("yao_runtime_v8_available", "The number of the isolates of the V8 runtime pool can be acquired")
+	runtimeMinSize  = Default.Gauge("yao_runtime_v8_min_size", "The min size of the V8 runtime pool")
+	runtimeMaxSize  = Default.Gauge("yao_runtime_v8_max_size", "The max size of the V8 runtime pool")
+	dbConnections   = Default.Gauge("yao_db_connections", "The number of the database connections by the state", "connection", "state")
+	dbWaitCount     = Default.Gauge("yao_db_wait_count", "The total number of the connections waited for", "connection")
+	dbWaitDuration  = Default.Gauge("yao_db_wait_duration_seconds", "The total time blocked waiting for a new connection", "connection")
+	taskCalls       = Default.Counter("yao_task_calls_total", "The number of the task process calls", "task", "method")
+	scheduleRuns    = Default.Counter("yao_schedule_runs_total", "The number of the schedule runs", "schedule")
+)
+
+// the schedules by the process name, the schedule run is counted when the process is executed
+var schedules = map[string][]string{}
+
+var mutex sync.Mutex
+
+func init() {
+	Default.Collect(collectDB)
+	Default.Collect(collectRuntime)
+}
+
+// Start instrument the process handlers and the schedules
+// It should be called after the application is loaded, and again after the schedules are reloaded.
+func Start(cfg config.Config) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	schedules = map[string][]string{}
+	for name, sch := range schedule.Schedules {
+		if sch.Process != "" {
+			key := strings.ToLower(sch.Process)
+			schedules[key] = append(schedules[key], name)
+		}
+
+		if sch.TaskName != "" {
+			key := strings.ToLower(fmt.Sprintf("tasks.%s.add", sch.TaskName))
+			schedules[key] = append(schedules[key], name)
+		}
+	}
+
+	share.UseProcessHook("metrics", instrument)
+}
+
+// Middleware the gin middleware counts the HTTP requests
+// The route is the pattern of the gin route, "-" for the static files and the pages.
+func Middleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "-"
+	}
+
+	status := fmt.Sprintf("%d", c.Writer.Status())
+	httpRequests.Inc(c.Request.Method, route, status)
+	httpDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+}
+
+// Handler the gin handler writes the metrics
+func Handler(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	err := Default.Write(c.Writer)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// TokenGuard the guard checks the bearer token of the scraper
+func TokenGuard(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": 403, "message": "Not Authorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func instrument(handler process.Handler) process.Handler {
+	return func(p *process.Process) (res interface{}) {
+		name := strings.ToLower(p.Name)
+		start := time.Now()
+		defer func() {
+			processCalls.Inc(name)
+			processDuration.Observe(time.Since(start).Seconds(), name)
+			count(name)
+
+			if r := recover(); r != nil {
+				processErrors.Inc(name)
+				panic(r)
+			}
+
+			if _, ok := res.(error); ok {
+				processErrors.Inc(name)
+			}
+		}()
+
+		return handler(p)
+	}
+}
+
+// count the task calls and the schedule runs
+func count(name string) {
+	if strings.HasPrefix(name, "tasks.") {
+		parts := strings.Split(name, ".")
+		if len(parts) > 2 {
+			taskCalls.Inc(strings.Join(parts[1:len(parts)-1], "."), parts[len(parts)-1])
+		}
+	}
+
+	mutex.Lock()
+	names := schedules[name]
+	mutex.Unlock()
+	for _, sch := range names {
+		scheduleRuns.Inc(sch)
+	}
+}
+
+func collectRuntime(registry *Registry) {
+	stats := runtime.Pool()
+	runtimeInUse.Set(float64(stats.InUse))
+	runtimeAvail.Set(float64(stats.Available))
+	runtimeMinSize.Set(float64(stats.MinSize))
+	runtimeMaxSize.Set(float64(stats.MaxSize))
+}
+
+func collectDB(registry *Registry) {
+	dbConnections.Reset()
+	dbWaitCount.Reset()
+	dbWaitDuration.Reset()
+	if capsule.Global == nil {
+		return
+	}
+
+	capsule.Global.Connections.Range(func(key, value any) bool {
+		conn, ok := value.(*capsule.Connection)
+		if !ok || conn.DB == nil {
+			return true
+		}
+
+		name := fmt.Sprintf("%v", key)
+		stats := conn.Stats()
+		dbConnections.Set(float64(stats.OpenConnections), name, "open")
+		dbConnections.Set(float64(stats.InUse), name, "in_use")
+		dbConnections.Set(float64(stats.Idle), name, "idle")
+		dbConnections.Set(float64(stats.MaxOpenConnections), name, "max_open")
+		dbWaitCount.Set(float64(stats.WaitCount), name)
+		dbWaitDuration.Set(stats.WaitDuration.Seconds(), name)
+		return true
+	})
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareAndHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware)
+	router.GET("/api/user/:id", func(c *gin.Context) { c.String(200, "ok") })
+	router.GET("/metrics", TokenGuard("secret"), Handler)
+
+	before := httpRequests.Value("GET", "/api/user/:id", "200")
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/user/1", nil))
+		assert.Equal(t, 200, w.Code)
+	}
+	assert.Equal(t, before+2, httpRequests.Value("GET", "/api/user/:id", "200"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `yao_http_requests_total{method="GET",route="/api/user/:id",status="200"}`)
+	assert.Contains(t, w.Body.String(), "# TYPE yao_process_duration_seconds histogram")
+	assert.Contains(t, w.Body.String(), "# TYPE yao_runtime_v8_in_use gauge")
+	assert.Contains(t, w.Body.String(), "# TYPE yao_runtime_v8_available gauge")
+}
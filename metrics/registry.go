@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets the default buckets of the duration histograms in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// Registry the collection of the metrics, writes the Prometheus text exposition format
+type Registry struct {
+	metrics    []*Metric
+	collectors []func(registry *Registry)
+	mutex      sync.RWMutex
+}
+
+// Metric a metric family with the labels
+type Metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+	mutex   sync.Mutex
+}
+
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64 // the histogram bucket counts
+	count  uint64
+	sum    float64
+}
+
+// NewRegistry create a new registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: []*Metric{}, collectors: []func(registry *Registry){}}
+}
+
+// Counter register a counter
+func (registry *Registry) Counter(name, help string, labels ...string) *Metric {
+	return registry.register(&Metric{name: name, help: help, kind: kindCounter, labels: labels})
+}
+
+// Gauge register a gauge
+func (registry *Registry) Gauge(name, help string, labels ...string) *Metric {
+	return registry.register(&Metric{name: name, help: help, kind: kindGauge, labels: labels})
+}
+
+// Histogram register a histogram, the DefaultBuckets are used if the buckets is nil
+func (registry *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Metric {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return registry.register(&Metric{name: name, help: help, kind: kindHistogram, labels: labels, buckets: buckets})
+}
+
+// Collect register a collector, it is called before writing the metrics, use it to set the gauges
+func (registry *Registry) Collect(collector func(registry *Registry)) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.collectors = append(registry.collectors, collector)
+}
+
+func (registry *Registry) register(metric *Metric) *Metric {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	metric.series = map[string]*series{}
+	registry.metrics = append(registry.metrics, metric)
+	return metric
+}
+
+// Inc increase the counter or the gauge by 1
+func (metric *Metric) Inc(labels ...string) {
+	metric.Add(1, labels...)
+}
+
+// Dec decrease the gauge by 1
+func (metric *Metric) Dec(labels ...string) {
+	metric.Add(-1, labels...)
+}
+
+// Add add the value to the counter or the gauge
+func (metric *Metric) Add(value float64, labels ...string) {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+	metric.get(labels).value += value
+}
+
+// Set set the value of the gauge
+func (metric *Metric) Set(value float64, labels ...string) {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+	metric.get(labels).value = value
+}
+
+// Observe add the observation to the histogram
+func (metric *Metric) Observe(value float64, labels ...string) {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+	s := metric.get(labels)
+	for i, bucket := range metric.buckets {
+		if value <= bucket {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// Reset remove all the series, use it in the collectors for the gauges whose labels change
+func (metric *Metric) Reset() {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+	metric.series = map[string]*series{}
+}
+
+// Value get the value of the counter or the gauge, the count of the histogram
+func (metric *Metric) Value(labels ...string) float64 {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+	s, has := metric.series[strings.Join(labels, "\xff")]
+	if !has {
+		return 0
+	}
+
+	if metric.kind == kindHistogram {
+		return float64(s.count)
+	}
+	return s.value
+}
+
+func (metric *Metric) get(labels []string) *series {
+	if len(labels) != len(metric.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, %d given", metric.name, len(metric.labels), len(labels)))
+	}
+
+	key := strings.Join(labels, "\xff")
+	s, has := metric.series[key]
+	if !has {
+		s = &series{labels: append([]string{}, labels...)}
+		if metric.kind == kindHistogram {
+			s.counts = make([]uint64, len(metric.buckets))
+		}
+		metric.series[key] = s
+	}
+	return s
+}
+
+// Write write the metrics in the Prometheus text exposition format
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mutex.RLock()
+	collectors := registry.collectors
+	metrics := registry.metrics
+	registry.mutex.RUnlock()
+
+	for _, collector := range collectors {
+		collector(registry)
+	}
+
+	var sb strings.Builder
+	for _, metric := range metrics {
+		metric.write(&sb)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (metric *Metric) write(sb *strings.Builder) {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", metric.name, escapeHelp(metric.help)))
+	sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", metric.name, metric.kind))
+
+	keys := []string{}
+	for key := range metric.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := metric.series[key]
+		if metric.kind != kindHistogram {
+			sb.WriteString(fmt.Sprintf("%s%s %s\n", metric.name, formatLabels(metric.labels, s.labels, "", ""), formatValue(s.value)))
+			continue
+		}
+
+		for i, bucket := range metric.buckets {
+			sb.WriteString(fmt.Sprintf("%s_bucket%s %d\n", metric.name, formatLabels(metric.labels, s.labels, "le", formatValue(bucket)), s.counts[i]))
+		}
+		sb.WriteString(fmt.Sprintf("%s_bucket%s %d\n", metric.name, formatLabels(metric.labels, s.labels, "le", "+Inf"), s.count))
+		sb.WriteString(fmt.Sprintf("%s_sum%s %s\n", metric.name, formatLabels(metric.labels, s.labels, "", ""), formatValue(s.sum)))
+		sb.WriteString(fmt.Sprintf("%s_count%s %d\n", metric.name, formatLabels(metric.labels, s.labels, "", ""), s.count))
+	}
+}
+
+func formatLabels(names []string, values []string, extraName, extraValue string) string {
+	pairs := []string{}
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabel(values[i])))
+	}
+
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+func escapeHelp(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("test_requests_total", "The requests", "route")
+	gauge := registry.Gauge("test_in_use", "The in use")
+	histogram := registry.Histogram("test_duration_seconds", "The duration", []float64{0.1, 1}, "route")
+	registry.Collect(func(registry *Registry) { gauge.Set(3) })
+
+	counter.Inc("/api/user")
+	counter.Add(2, `/api/"quoted"`)
+	histogram.Observe(0.05, "/api/user")
+	histogram.Observe(0.5, "/api/user")
+	histogram.Observe(5, "/api/user")
+
+	var sb strings.Builder
+	err := registry.Write(&sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, `# HELP test_requests_total The requests
+# TYPE test_requests_total counter
+test_requests_total{route="/api/\"quoted\""} 2
+test_requests_total{route="/api/user"} 1
+# HELP test_in_use The in use
+# TYPE test_in_use gauge
+test_in_use 3
+# HELP test_duration_seconds The duration
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/api/user",le="0.1"} 1
+test_duration_seconds_bucket{route="/api/user",le="1"} 2
+test_duration_seconds_bucket{route="/api/user",le="+Inf"} 3
+test_duration_seconds_sum{route="/api/user"} 5.55
+test_duration_seconds_count{route="/api/user"} 3
+`, sb.String())
+
+	assert.Equal(t, float64(1), counter.Value("/api/user"))
+	assert.Equal(t, float64(3), histogram.Value("/api/user"))
+	assert.Panics(t, func() { counter.Inc() })
+}
@@ -0,0 +1,331 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// ManifestFile the manifest file name of the dump
+const ManifestFile = "manifest.json"
+
+// Manifest the manifest of the dump, records the models, the row counts and the checksums of the files
+type Manifest struct {
+	Version   int                       `json:"version"`
+	App       string                    `json:"app,omitempty"`
+	CreatedAt time.Time                 `json:"created_at"`
+	Since     *time.Time                `json:"since,omitempty"` // Incremental dump, the rows updated since the time
+	Encrypted bool                      `json:"encrypted,omitempty"`
+	Models    map[string]*ManifestModel `json:"models"`
+	Files     map[string]string         `json:"files"` // The sha256 of the files in the dump
+}
+
+// ManifestModel the dumped model
+type ManifestModel struct {
+	Table       string   `json:"table"`
+	PrimaryKey  string   `json:"primary_key"`
+	Rows        int      `json:"rows"`
+	Files       []string `json:"files"`
+	Wheres      []string `json:"wheres,omitempty"`
+	Incremental bool     `json:"incremental,omitempty"`
+}
+
+// DumpOption the dump option
+type DumpOption struct {
+	Models []string   // The model ids or globs. e.g. pet, user.*
+	Wheres []Where    // The conditions of the models
+	Since  *time.Time // Dump the rows created or updated since the time
+	Chunk  int        // The rows of each file, the default is 5000
+	Dir    string     // The output directory
+}
+
+// Where the condition of the dumped models, the format is <model>:<column><op><value>. e.g. *:tenant_id=1, pet:created_at>=2024-01-01
+type Where struct {
+	Model  string
+	Column string
+	OP     string
+	Value  string
+}
+
+var reWhere = regexp.MustCompile(`^([^:]+):([A-Za-z0-9_]+)\s*(>=|<=|!=|=|>|<|~)\s*(.*)$`)
+
+// ParseWhere parse the where condition. e.g. pet:status=checked
+func ParseWhere(input string) (Where, error) {
+	matches := reWhere.FindStringSubmatch(strings.TrimSpace(input))
+	if matches == nil {
+		return Where{}, fmt.Errorf("%s the where should be <model>:<column><op><value>", input)
+	}
+	return Where{Model: matches[1], Column: matches[2], OP: matches[3], Value: matches[4]}, nil
+}
+
+// String the where condition
+func (where Where) String() string {
+	return fmt.Sprintf("%s:%s%s%s", where.Model, where.Column, where.OP, where.Value)
+}
+
+// Glob the where condition matches the models by a glob. e.g. *, user.*
+func (where Where) Glob() bool {
+	return strings.ContainsAny(where.Model, "*?[")
+}
+
+// MatchModel check if the model id matches one of the patterns, all models are matched if the patterns are empty
+func MatchModel(patterns []string, id string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.TrimSpace(pattern), id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NewManifest create a manifest
+func NewManifest(app string) *Manifest {
+	return &Manifest{
+		Version:   1,
+		App:       app,
+		CreatedAt: time.Now(),
+		Models:    map[string]*ManifestModel{},
+		Files:     map[string]string{},
+	}
+}
+
+// Sum record the checksum of the file
+func (manifest *Manifest) Sum(name string, data []byte) {
+	sum := sha256.Sum256(data)
+	manifest.Files[filepath.ToSlash(name)] = hex.EncodeToString(sum[:])
+}
+
+// Verify verify the checksums of the files in the directory
+func (manifest *Manifest) Verify(dir string) error {
+	names := []string{}
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return fmt.Errorf("%s %s", name, err.Error())
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != manifest.Files[name] {
+			return fmt.Errorf("%s the checksum does not match", name)
+		}
+	}
+	return nil
+}
+
+// ReadManifest read the manifest of the dump directory, returns nil if the dump has no manifest (the legacy dumps)
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = jsoniter.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s", ManifestFile, err.Error())
+	}
+	return manifest, nil
+}
+
+// Dump export the rows of the models to the option.Dir/model/<model>.<n>.json files
+func Dump(manifest *Manifest, option DumpOption, progress func(mod *model.Model, curr, total int)) error {
+	if option.Chunk <= 0 {
+		option.Chunk = 5000
+	}
+
+	err := os.MkdirAll(filepath.Join(option.Dir, "model"), 0755)
+	if err != nil {
+		return err
+	}
+
+	// The where of a model named explicitly must be applied, a typo in the filter should not dump all the rows
+	for _, where := range option.Wheres {
+		if where.Glob() {
+			continue
+		}
+
+		mod, has := model.Models[where.Model]
+		if !has {
+			return fmt.Errorf("where %s: the model %s does not exist", where.String(), where.Model)
+		}
+
+		if !hasColumn(mod, where.Column) {
+			return fmt.Errorf("where %s: the model %s has no column %s", where.String(), where.Model, where.Column)
+		}
+	}
+
+	manifest.Since = option.Since
+	ids := []string{}
+	for id := range model.Models {
+		if MatchModel(option.Models, id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		err := dumpModel(manifest, model.Models[id], option, progress)
+		if err != nil {
+			return fmt.Errorf("%s %s", id, err.Error())
+		}
+	}
+	return nil
+}
+
+func dumpModel(manifest *Manifest, mod *model.Model, option DumpOption, progress func(mod *model.Model, curr, total int)) error {
+	_, qb, err := connection(mod.MetaData.Connector)
+	if err != nil {
+		return err
+	}
+
+	info := &ManifestModel{Table: mod.MetaData.Table.Name, PrimaryKey: mod.PrimaryKey, Files: []string{}, Wheres: []string{}}
+	newQuery := func() query.Query {
+		q := qb.New().Table(mod.MetaData.Table.Name)
+		for _, where := range option.Wheres {
+			if !MatchModel([]string{where.Model}, mod.ID) || !hasColumn(mod, where.Column) {
+				continue
+			}
+
+			switch where.OP {
+			case "~":
+				q.Where(where.Column, "like", where.Value)
+			default:
+				q.Where(where.Column, where.OP, where.Value)
+			}
+		}
+
+		if option.Since != nil && hasColumn(mod, "updated_at") && hasColumn(mod, "created_at") {
+			since := *option.Since
+			q.Where(func(q query.Query) {
+				q.Where("updated_at", ">=", since).OrWhere("created_at", ">=", since)
+			})
+		}
+		return q
+	}
+
+	for _, where := range option.Wheres {
+		if MatchModel([]string{where.Model}, mod.ID) && hasColumn(mod, where.Column) {
+			info.Wheres = append(info.Wheres, where.String())
+		}
+	}
+	info.Incremental = option.Since != nil && hasColumn(mod, "updated_at") && hasColumn(mod, "created_at")
+
+	total, err := newQuery().Count()
+	if err != nil {
+		return err
+	}
+
+	var last interface{} = nil
+	for page := 1; ; page++ {
+		q := newQuery().Limit(option.Chunk)
+		if mod.PrimaryKey != "" {
+			q.OrderBy(mod.PrimaryKey, "asc")
+			if last != nil {
+				q.Where(mod.PrimaryKey, ">", last)
+			}
+		} else {
+			q.Offset((page - 1) * option.Chunk)
+		}
+
+		rows, err := q.Get()
+		if err != nil {
+			return err
+		}
+
+		if len(rows) == 0 {
+			break
+		}
+
+		values := make([]map[string]interface{}, 0, len(rows))
+		for _, row := range rows {
+			value := map[string]interface{}{}
+			for k, v := range row {
+				value[k] = dumpValue(v)
+			}
+			values = append(values, value)
+		}
+
+		if mod.PrimaryKey != "" {
+			last = rows[len(rows)-1][mod.PrimaryKey]
+		}
+
+		data, err := jsoniter.Marshal(values)
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("model/%s.%d.json", mod.ID, page)
+		err = os.WriteFile(filepath.Join(option.Dir, filepath.FromSlash(name)), data, 0644)
+		if err != nil {
+			return err
+		}
+
+		manifest.Sum(name, data)
+		info.Files = append(info.Files, name)
+		info.Rows = info.Rows + len(rows)
+		if progress != nil {
+			progress(mod, info.Rows, int(total))
+		}
+
+		if len(rows) < option.Chunk {
+			break
+		}
+	}
+
+	manifest.Models[mod.ID] = info
+	return nil
+}
+
+func hasColumn(mod *model.Model, name string) bool {
+	if _, has := mod.Columns[name]; has {
+		return true
+	}
+
+	switch name {
+	case "created_at", "updated_at":
+		return mod.MetaData.Option.Timestamps
+	case "deleted_at":
+		return mod.MetaData.Option.SoftDeletes
+	}
+	return false
+}
+
+// dumpValue the times are formatted as RFC3339 with the timezone and the fractional seconds, see parseTimes
+func dumpValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case *time.Time:
+		if value == nil {
+			return nil
+		}
+		return value.Format(time.RFC3339Nano)
+	case []byte:
+		return string(value)
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWhere(t *testing.T) {
+	where, err := ParseWhere("*:tenant_id=1")
+	assert.Nil(t, err)
+	assert.Equal(t, Where{Model: "*", Column: "tenant_id", OP: "=", Value: "1"}, where)
+
+	where, err = ParseWhere("pet:created_at>=2024-01-01 00:00:00")
+	assert.Nil(t, err)
+	assert.Equal(t, ">=", where.OP)
+	assert.Equal(t, "2024-01-01 00:00:00", where.Value)
+	assert.Equal(t, "pet:created_at>=2024-01-01 00:00:00", where.String())
+
+	assert.True(t, Where{Model: "user.*"}.Glob())
+	assert.False(t, where.Glob())
+
+	_, err = ParseWhere("tenant_id=1")
+	assert.NotNil(t, err)
+}
+
+func TestDumpWhereModel(t *testing.T) {
+	where, err := ParseWhere("__unit_test_missing:tenant_id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Dump(NewManifest("unit-test"), DumpOption{Wheres: []Where{where}, Dir: t.TempDir()}, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+}
+
+func TestMatchModel(t *testing.T) {
+	assert.True(t, MatchModel([]string{}, "pet"))
+	assert.True(t, MatchModel([]string{"user", "pet*"}, "pet.tag"))
+	assert.False(t, MatchModel([]string{"user"}, "pet"))
+}
+
+func TestManifestVerify(t *testing.T) {
+	dir := t.TempDir()
+	manifest := NewManifest("demo")
+	data := []byte(`[{"id":1}]`)
+	err := os.MkdirAll(filepath.Join(dir, "model"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "model", "pet.1.json"), data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest.Sum("model/pet.1.json", data)
+	assert.Nil(t, manifest.Verify(dir))
+
+	err = os.WriteFile(filepath.Join(dir, "model", "pet.1.json"), []byte(`[]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, manifest.Verify(dir))
+}
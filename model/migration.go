@@ -0,0 +1,341 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// MigrationTable the table records the applied migrations
+const MigrationTable = "__yao_migrations"
+
+// MigrationLockTable the table holds the migrate lock
+const MigrationLockTable = "__yao_migrations_lock"
+
+// LockTimeout the lock held longer than the timeout is released, the deploy might crash
+var LockTimeout = 10 * time.Minute
+
+// Migration the versioned migration, the file name is <version>_<name>.mig.yao. e.g. migrations/20240601120000_pet_age.mig.yao
+type Migration struct {
+	Version   string `json:"-"`
+	Name      string `json:"-"`
+	File      string `json:"-"`
+	Connector string `json:"connector,omitempty"` // The connector of the SQL statements, the default is the default connection
+	Up        Step   `json:"up"`
+	Down      Step   `json:"down"`
+}
+
+// Step the up or down step of the migration, the SQL statements are executed before the process
+type Step struct {
+	SQL     []string      `json:"sql,omitempty"`
+	Process string        `json:"process,omitempty"`
+	Args    []interface{} `json:"args,omitempty"`
+}
+
+// Migrator run the versioned migrations
+type Migrator struct {
+	query  query.Query
+	schema schema.Schema
+	owner  string
+}
+
+var reMigration = regexp.MustCompile(`^(\d+)_([0-9A-Za-z_\-]+)\.mig\.(yao|json|jsonc)$`)
+
+// Migrations load the versioned migrations, ordered by the version
+func Migrations() ([]*Migration, error) {
+	migrations := []*Migration{}
+	if exists, _ := application.App.Exists("migrations"); !exists {
+		return migrations, nil
+	}
+
+	versions := map[string]string{}
+	exts := []string{"*.mig.yao", "*.mig.json", "*.mig.jsonc"}
+	err := application.App.Walk("migrations", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		matches := reMigration.FindStringSubmatch(filepath.Base(file))
+		if matches == nil {
+			return fmt.Errorf("%s the file name should be <version>_<name>.mig.yao", file)
+		}
+
+		if exists, has := versions[matches[1]]; has {
+			return fmt.Errorf("%s the version %s is used by %s", file, matches[1], exists)
+		}
+		versions[matches[1]] = file
+
+		source, err := application.App.Read(file)
+		if err != nil {
+			return err
+		}
+
+		mig := &Migration{}
+		err = application.Parse(file, source, mig)
+		if err != nil {
+			return err
+		}
+
+		mig.Version = matches[1]
+		mig.Name = matches[2]
+		mig.File = file
+		migrations = append(migrations, mig)
+		return nil
+	}, exts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return versionLess(migrations[i].Version, migrations[j].Version) })
+	return migrations, nil
+}
+
+// NewMigrator create a migrator on the default connection
+func NewMigrator() (*Migrator, error) {
+	sch, qb, err := connection("default")
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	return &Migrator{query: qb, schema: sch, owner: fmt.Sprintf("%s:%d", host, os.Getpid())}, nil
+}
+
+// Prepare create the migrations table and the lock table if they do not exist
+func (m *Migrator) Prepare() error {
+	has, err := m.schema.HasTable(MigrationTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = m.schema.CreateTable(MigrationTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("version", 100).Unique()
+			table.String("name", 200)
+			table.Integer("batch").Index()
+			table.TimestampTz("applied_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	has, err = m.schema.HasTable(MigrationLockTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		return m.schema.CreateTable(MigrationLockTable, func(table schema.Blueprint) {
+			table.String("name", 50).Unique()
+			table.String("owner", 200)
+			table.TimestampTz("locked_at").Index()
+		})
+	}
+	return nil
+}
+
+// Lock acquire the migrate lock, it returns an error if another deploy holds the lock
+func (m *Migrator) Lock() error {
+	_, err := m.query.New().Table(MigrationLockTable).Where("locked_at", "<", time.Now().Add(-LockTimeout)).Delete()
+	if err != nil {
+		return err
+	}
+
+	err = m.query.New().Table(MigrationLockTable).Insert(map[string]interface{}{
+		"name": "migrate", "owner": m.owner, "locked_at": time.Now(),
+	})
+	if err == nil {
+		return nil
+	}
+
+	row, e := m.query.New().Table(MigrationLockTable).Where("name", "migrate").First()
+	if e != nil || row == nil {
+		return err
+	}
+	return fmt.Errorf("migrate is locked by %v since %v", row["owner"], row["locked_at"])
+}
+
+// Unlock release the migrate lock
+func (m *Migrator) Unlock() error {
+	_, err := m.query.New().Table(MigrationLockTable).Where("name", "migrate").Where("owner", m.owner).Delete()
+	return err
+}
+
+// Applied the applied versions and the last batch
+func (m *Migrator) Applied() (map[string]int, int, error) {
+	versions := map[string]int{}
+	has, err := m.schema.HasTable(MigrationTable)
+	if err != nil || !has {
+		return versions, 0, err
+	}
+
+	rows, err := m.query.New().Table(MigrationTable).Select("version", "batch").Get()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	last := 0
+	for _, row := range rows {
+		batch, _ := strconv.Atoi(fmt.Sprintf("%v", row["batch"]))
+		versions[fmt.Sprintf("%v", row["version"])] = batch
+		if batch > last {
+			last = batch
+		}
+	}
+	return versions, last, nil
+}
+
+// Pending the migrations have not been applied
+func (m *Migrator) Pending(migrations []*Migration) ([]*Migration, error) {
+	applied, _, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*Migration{}
+	for _, mig := range migrations {
+		if _, has := applied[mig.Version]; !has {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up apply the pending migrations as a new batch, the lock should be held
+func (m *Migrator) Up(migrations []*Migration, handler func(mig *Migration, err error)) error {
+	pending, err := m.Pending(migrations)
+	if err != nil {
+		return err
+	}
+
+	_, last, err := m.Applied()
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range pending {
+		err := mig.Up.exec(mig.Connector)
+		if err == nil {
+			err = m.query.New().Table(MigrationTable).Insert(map[string]interface{}{
+				"version": mig.Version, "name": mig.Name, "batch": last + 1, "applied_at": time.Now(),
+			})
+		}
+
+		if handler != nil {
+			handler(mig, err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s %s", mig.File, err.Error())
+		}
+	}
+	return nil
+}
+
+// Down roll back the last n applied migrations, the lock should be held
+func (m *Migrator) Down(migrations []*Migration, n int, handler func(mig *Migration, err error)) error {
+	applied, _, err := m.Applied()
+	if err != nil {
+		return err
+	}
+
+	files := map[string]*Migration{}
+	for _, mig := range migrations {
+		files[mig.Version] = mig
+	}
+
+	versions := []string{}
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[j], versions[i]) })
+
+	if n < len(versions) {
+		versions = versions[:n]
+	}
+
+	for _, version := range versions {
+		mig, has := files[version]
+		if !has {
+			return fmt.Errorf("the migration file of the version %s does not exist", version)
+		}
+
+		err := mig.Down.exec(mig.Connector)
+		if err == nil {
+			_, err = m.query.New().Table(MigrationTable).Where("version", version).Delete()
+		}
+
+		if handler != nil {
+			handler(mig, err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s %s", mig.File, err.Error())
+		}
+	}
+	return nil
+}
+
+// String the preview of the step
+func (step Step) String() string {
+	lines := []string{}
+	for _, sql := range step.SQL {
+		sql = strings.TrimSpace(sql)
+		if !strings.HasSuffix(sql, ";") {
+			sql = sql + ";"
+		}
+		lines = append(lines, sql)
+	}
+
+	if step.Process != "" {
+		lines = append(lines, fmt.Sprintf("-- process %s", step.Process))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (step Step) exec(connector string) error {
+	if len(step.SQL) > 0 {
+		_, qb, err := connection(connector)
+		if err != nil {
+			return err
+		}
+
+		db := qb.DB(true)
+		for _, sql := range step.SQL {
+			if _, err := db.Exec(sql); err != nil {
+				return err
+			}
+		}
+	}
+
+	if step.Process != "" {
+		_, err := process.New(step.Process, step.Args...).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionLess compare the versions as numbers, the versions may have different lengths
+func versionLess(a, b string) bool {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
@@ -0,0 +1,508 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// Change the schema change of the migration plan
+type Change struct {
+	Action      string `json:"action"`      // create, add, alter, drop, index.add, index.drop
+	Table       string `json:"table"`       // The table name
+	Name        string `json:"name"`        // The column or the index name
+	SQL         string `json:"sql"`         // The DDL statement, for preview only
+	Destructive bool   `json:"destructive"` // The change may lose data
+}
+
+// Plan the migration plan of a model
+type Plan struct {
+	Model   string   `json:"model"`
+	Table   string   `json:"table"`
+	Driver  string   `json:"driver"`
+	Changes []Change `json:"changes"`
+}
+
+// Schema the table schema snapshot, used to compare the model with the database
+type Schema struct {
+	Table   string
+	Columns []Column
+	Indexes []Index
+}
+
+// Column the column of the schema snapshot
+type Column struct {
+	Name     string
+	Type     string
+	Length   int
+	Nullable bool
+	Primary  bool
+	Option   []string
+}
+
+// Index the index of the schema snapshot
+type Index struct {
+	Name    string
+	Type    string // index, unique, primary
+	Columns []string
+}
+
+// PlanOf diff the model schema against the live database
+func PlanOf(mod *model.Model) (*Plan, error) {
+	sch, qb, err := connection(mod.MetaData.Connector)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := qb.DB().DriverName()
+	plan := &Plan{Model: mod.ID, Table: mod.MetaData.Table.Name, Driver: driver}
+	want := SchemaOf(mod)
+
+	has, err := sch.HasTable(want.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		plan.Changes = Diff(driver, want, nil)
+		return plan, nil
+	}
+
+	got, err := liveSchema(sch, want.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Changes = Diff(driver, want, got)
+	return plan, nil
+}
+
+// Destructive check if the plan has destructive changes
+func (plan *Plan) Destructive() bool {
+	for _, change := range plan.Changes {
+		if change.Destructive {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaOf the schema snapshot of the model
+func SchemaOf(mod *model.Model) Schema {
+	res := Schema{Table: mod.MetaData.Table.Name, Columns: []Column{}, Indexes: []Index{}}
+	for _, col := range mod.MetaData.Columns {
+		typ := strings.ToLower(col.Type)
+		res.Columns = append(res.Columns, Column{
+			Name:     col.Name,
+			Type:     typ,
+			Length:   col.Length,
+			Nullable: col.Nullable,
+			Primary:  col.Primary || isIncrements(typ),
+			Option:   col.Option,
+		})
+
+		if col.Unique {
+			res.Indexes = append(res.Indexes, Index{Name: fmt.Sprintf("%s_unique", col.Name), Type: "unique", Columns: []string{col.Name}})
+		} else if col.Index {
+			res.Indexes = append(res.Indexes, Index{Name: fmt.Sprintf("%s_index", col.Name), Type: "index", Columns: []string{col.Name}})
+		}
+	}
+
+	if mod.MetaData.Option.Timestamps {
+		res.Columns = append(res.Columns,
+			Column{Name: "created_at", Type: "timestamp", Nullable: true},
+			Column{Name: "updated_at", Type: "timestamp", Nullable: true},
+		)
+	}
+
+	if mod.MetaData.Option.SoftDeletes {
+		res.Columns = append(res.Columns, Column{Name: "deleted_at", Type: "timestamp", Nullable: true})
+	}
+
+	for _, idx := range mod.MetaData.Indexes {
+		typ := strings.ToLower(idx.Type)
+		if typ == "" {
+			typ = "index"
+		}
+		res.Indexes = append(res.Indexes, Index{Name: idx.Name, Type: typ, Columns: idx.Columns})
+	}
+
+	return res
+}
+
+// Diff the changes to migrate the got schema to the want schema, the table is created if got is nil
+func Diff(driver string, want Schema, got *Schema) []Change {
+	changes := []Change{}
+	table := want.Table
+	if got == nil {
+		changes = append(changes, Change{Action: "create", Table: table, Name: table, SQL: createTableSQL(driver, want)})
+		for _, idx := range want.Indexes {
+			if idx.Type == "primary" {
+				continue
+			}
+			changes = append(changes, Change{Action: "index.add", Table: table, Name: idx.Name, SQL: createIndexSQL(driver, table, idx)})
+		}
+		return changes
+	}
+
+	columns := map[string]Column{}
+	for _, col := range got.Columns {
+		columns[strings.ToLower(col.Name)] = col
+	}
+
+	// Add and alter the columns
+	names := map[string]bool{}
+	for _, col := range want.Columns {
+		names[strings.ToLower(col.Name)] = true
+		live, has := columns[strings.ToLower(col.Name)]
+		if !has {
+			changes = append(changes, Change{
+				Action: "add", Table: table, Name: col.Name,
+				SQL: fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", quote(driver, table), columnSQL(driver, col)),
+			})
+			continue
+		}
+
+		if col.Primary || live.Primary {
+			continue
+		}
+
+		typeChanged := driver != "sqlite3" && normalizeType(col.Type) != normalizeType(live.Type)
+		lengthChanged := hasLength(col.Type) && col.Length > 0 && live.Length > 0 && col.Length != live.Length
+		nullChanged := col.Nullable != live.Nullable
+		if !typeChanged && !lengthChanged && !nullChanged {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Action: "alter", Table: table, Name: col.Name,
+			SQL:         alterColumnSQL(driver, table, col),
+			Destructive: typeChanged || (lengthChanged && col.Length < live.Length) || (nullChanged && !col.Nullable),
+		})
+	}
+
+	// Drop the columns
+	for _, col := range got.Columns {
+		if names[strings.ToLower(col.Name)] || col.Primary {
+			continue
+		}
+		changes = append(changes, Change{
+			Action: "drop", Table: table, Name: col.Name,
+			SQL:         fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", quote(driver, table), quote(driver, col.Name)),
+			Destructive: true,
+		})
+	}
+
+	// The indexes are matched by the type and the columns, the names are generated differently by the drivers
+	matched := map[int]bool{}
+	for _, idx := range want.Indexes {
+		if idx.Type == "primary" {
+			continue
+		}
+
+		found := false
+		for i, live := range got.Indexes {
+			if !matched[i] && sameIndex(idx, live) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			changes = append(changes, Change{Action: "index.add", Table: table, Name: idx.Name, SQL: createIndexSQL(driver, table, idx)})
+		}
+	}
+
+	for i, live := range got.Indexes {
+		if matched[i] || live.Type == "primary" {
+			continue
+		}
+		changes = append(changes, Change{
+			Action: "index.drop", Table: table, Name: live.Name,
+			SQL:         dropIndexSQL(driver, table, live.Name),
+			Destructive: live.Type == "unique",
+		})
+	}
+
+	return changes
+}
+
+// connection the schema and the query builder of the connector
+func connection(name string) (schema.Schema, query.Query, error) {
+	if name == "" || name == "default" {
+		return capsule.Global.Schema(), capsule.Global.Query(), nil
+	}
+
+	conn, err := connector.Select(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sch, err := conn.Schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	qb, err := conn.Query()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sch, qb, nil
+}
+
+// liveSchema the schema snapshot of the table in the database
+func liveSchema(sch schema.Schema, name string) (*Schema, error) {
+	tab, err := sch.GetTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Schema{Table: name, Columns: []Column{}, Indexes: []Index{}}
+	primary := map[string]bool{}
+	for _, idx := range tab.GetIndexes() {
+		index := Index{Name: idx.Name, Type: strings.ToLower(idx.Type), Columns: []string{}}
+		if strings.EqualFold(idx.Name, "primary") {
+			index.Type = "primary"
+		}
+
+		for _, col := range idx.Columns {
+			index.Columns = append(index.Columns, col.Name)
+			if index.Type == "primary" {
+				primary[col.Name] = true
+			}
+		}
+		res.Indexes = append(res.Indexes, index)
+	}
+
+	for _, col := range tab.GetColumns() {
+		column := Column{Name: col.Name, Type: strings.ToLower(col.Type), Nullable: col.Nullable, Primary: primary[col.Name]}
+		if col.Length != nil {
+			column.Length = *col.Length
+		}
+		res.Columns = append(res.Columns, column)
+	}
+
+	sort.Slice(res.Columns, func(i, j int) bool { return res.Columns[i].Name < res.Columns[j].Name })
+	sort.Slice(res.Indexes, func(i, j int) bool { return res.Indexes[i].Name < res.Indexes[j].Name })
+	return res, nil
+}
+
+func sameIndex(want Index, got Index) bool {
+	if (want.Type == "unique") != (got.Type == "unique") || len(want.Columns) != len(got.Columns) {
+		return false
+	}
+
+	for i := range want.Columns {
+		if !strings.EqualFold(want.Columns[i], got.Columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIncrements(typ string) bool {
+	return typ == "id" || strings.HasSuffix(typ, "increments")
+}
+
+func hasLength(typ string) bool {
+	switch strings.ToLower(typ) {
+	case "string", "char", "binary":
+		return true
+	}
+	return false
+}
+
+// normalizeType the types read from the database may differ from the model types. e.g. jsonb, unsignedInteger
+func normalizeType(typ string) string {
+	typ = strings.TrimPrefix(strings.ToLower(typ), "unsigned")
+	switch typ {
+	case "jsonb":
+		return "json"
+	case "timestamptz":
+		return "timestamp"
+	case "datetimetz":
+		return "datetime"
+	case "enum":
+		return "string"
+	}
+	return typ
+}
+
+func quote(driver string, name string) string {
+	if driver == "mysql" {
+		return fmt.Sprintf("`%s`", name)
+	}
+	return fmt.Sprintf(`"%s"`, name)
+}
+
+func createTableSQL(driver string, sch Schema) string {
+	lines := []string{}
+	primary := []string{}
+	for _, col := range sch.Columns {
+		lines = append(lines, "  "+columnSQL(driver, col))
+		if col.Primary && !isIncrements(col.Type) {
+			primary = append(primary, quote(driver, col.Name))
+		}
+	}
+
+	if len(primary) > 0 {
+		lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", strings.Join(primary, ", ")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", quote(driver, sch.Table), strings.Join(lines, ",\n"))
+}
+
+func createIndexSQL(driver string, table string, idx Index) string {
+	columns := []string{}
+	for _, name := range idx.Columns {
+		columns = append(columns, quote(driver, name))
+	}
+
+	unique := ""
+	if idx.Type == "unique" {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, quote(driver, idx.Name), quote(driver, table), strings.Join(columns, ", "))
+}
+
+func dropIndexSQL(driver string, table string, name string) string {
+	if driver == "mysql" {
+		return fmt.Sprintf("DROP INDEX %s ON %s;", quote(driver, name), quote(driver, table))
+	}
+	return fmt.Sprintf("DROP INDEX %s;", quote(driver, name))
+}
+
+func alterColumnSQL(driver string, table string, col Column) string {
+	switch driver {
+	case "mysql":
+		return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", quote(driver, table), columnSQL(driver, col))
+
+	case "postgres", "pgx":
+		null := "DROP NOT NULL"
+		if !col.Nullable {
+			null = "SET NOT NULL"
+		}
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s, ALTER COLUMN %s %s;",
+			quote(driver, table), quote(driver, col.Name), sqlType(driver, col), quote(driver, col.Name), null)
+	}
+
+	// SQLite does not support altering columns, the table is rebuilt
+	return fmt.Sprintf("-- rebuild %s to alter the column %s", quote(driver, table), columnSQL(driver, col))
+}
+
+func columnSQL(driver string, col Column) string {
+	def := fmt.Sprintf("%s %s", quote(driver, col.Name), sqlType(driver, col))
+	if isIncrements(col.Type) {
+		return def
+	}
+
+	if col.Nullable {
+		return def + " NULL"
+	}
+	return def + " NOT NULL"
+}
+
+// sqlType the column type of the driver, for preview only
+func sqlType(driver string, col Column) string {
+	typ := strings.ToLower(col.Type)
+	unsigned := strings.HasPrefix(typ, "unsigned")
+	typ = strings.TrimPrefix(typ, "unsigned")
+
+	length := func(def int) int {
+		if col.Length > 0 {
+			return col.Length
+		}
+		return def
+	}
+
+	pick := func(mysql, postgres, sqlite string) string {
+		switch driver {
+		case "mysql":
+			if unsigned {
+				return mysql + " UNSIGNED"
+			}
+			return mysql
+		case "postgres", "pgx":
+			return postgres
+		}
+		return sqlite
+	}
+
+	switch typ {
+	case "id", "bigincrements":
+		return pick("BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY", "BIGSERIAL PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT")
+	case "increments", "smallincrements", "tinyincrements", "mediumincrements":
+		return pick("INT UNSIGNED AUTO_INCREMENT PRIMARY KEY", "SERIAL PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT")
+	case "string":
+		return fmt.Sprintf("VARCHAR(%d)", length(200))
+	case "char":
+		return fmt.Sprintf("CHAR(%d)", length(200))
+	case "text":
+		return "TEXT"
+	case "mediumtext":
+		return pick("MEDIUMTEXT", "TEXT", "TEXT")
+	case "longtext":
+		return pick("LONGTEXT", "TEXT", "TEXT")
+	case "binary":
+		return pick(fmt.Sprintf("VARBINARY(%d)", length(255)), "BYTEA", "BLOB")
+	case "tinyinteger":
+		return pick("TINYINT", "SMALLINT", "INTEGER")
+	case "smallinteger":
+		return pick("SMALLINT", "SMALLINT", "INTEGER")
+	case "mediuminteger":
+		return pick("MEDIUMINT", "INTEGER", "INTEGER")
+	case "integer":
+		return pick("INT", "INTEGER", "INTEGER")
+	case "biginteger":
+		return pick("BIGINT", "BIGINT", "INTEGER")
+	case "decimal":
+		return "DECIMAL(10,2)"
+	case "float":
+		return pick("FLOAT", "REAL", "REAL")
+	case "double":
+		return pick("DOUBLE", "DOUBLE PRECISION", "REAL")
+	case "boolean":
+		return pick("TINYINT(1)", "BOOLEAN", "BOOLEAN")
+	case "date":
+		return "DATE"
+	case "datetime":
+		return pick("DATETIME", "TIMESTAMP", "DATETIME")
+	case "datetimetz":
+		return pick("DATETIME", "TIMESTAMPTZ", "DATETIME")
+	case "time":
+		return "TIME"
+	case "timetz":
+		return pick("TIME", "TIMETZ", "TIME")
+	case "timestamp":
+		return "TIMESTAMP"
+	case "timestamptz":
+		return pick("TIMESTAMP", "TIMESTAMPTZ", "TIMESTAMP")
+	case "json":
+		return pick("JSON", "JSON", "TEXT")
+	case "jsonb":
+		return pick("JSON", "JSONB", "TEXT")
+	case "uuid":
+		return pick("CHAR(36)", "UUID", "CHAR(36)")
+	case "year":
+		return pick("YEAR", "SMALLINT", "INTEGER")
+	case "ipaddress":
+		return pick("VARCHAR(45)", "INET", "VARCHAR(45)")
+	case "macaddress":
+		return pick("VARCHAR(17)", "MACADDR", "VARCHAR(17)")
+	case "enum":
+		if driver == "mysql" && len(col.Option) > 0 {
+			return fmt.Sprintf("ENUM('%s')", strings.Join(col.Option, "','"))
+		}
+		return fmt.Sprintf("VARCHAR(%d)", length(200))
+	}
+
+	return strings.ToUpper(typ)
+}
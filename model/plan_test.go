@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestPlanOf(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	Load(config.Conf)
+	plan, err := PlanOf(model.Models["pet"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "pet", plan.Model)
+	assert.NotEmpty(t, plan.Driver)
+}
+
+func TestDiff(t *testing.T) {
+	want := Schema{
+		Table: "pet",
+		Columns: []Column{
+			{Name: "id", Type: "id", Primary: true},
+			{Name: "name", Type: "string", Length: 80},
+			{Name: "age", Type: "integer", Nullable: true},
+		},
+		Indexes: []Index{{Name: "name_unique", Type: "unique", Columns: []string{"name"}}},
+	}
+
+	// Create the table
+	changes := Diff("mysql", want, nil)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, "create", changes[0].Action)
+	assert.Contains(t, changes[0].SQL, "`name` VARCHAR(80) NOT NULL")
+	assert.Equal(t, "CREATE UNIQUE INDEX `name_unique` ON `pet` (`name`);", changes[1].SQL)
+
+	// Alter the table
+	got := &Schema{
+		Table: "pet",
+		Columns: []Column{
+			{Name: "id", Type: "bigInteger", Primary: true},
+			{Name: "name", Type: "string", Length: 200},
+			{Name: "mode", Type: "string", Nullable: true},
+		},
+		Indexes: []Index{
+			{Name: "PRIMARY", Type: "primary", Columns: []string{"id"}},
+			{Name: "pet_name_unique", Type: "unique", Columns: []string{"name"}},
+			{Name: "mode_index", Type: "index", Columns: []string{"mode"}},
+		},
+	}
+
+	changes = Diff("postgres", want, got)
+	actions := map[string]Change{}
+	for _, change := range changes {
+		actions[change.Action+":"+change.Name] = change
+	}
+	assert.Len(t, changes, 4)
+	assert.False(t, actions["add:age"].Destructive)
+	assert.Equal(t, `ALTER TABLE "pet" ADD COLUMN "age" INTEGER NULL;`, actions["add:age"].SQL)
+	assert.True(t, actions["alter:name"].Destructive)
+	assert.True(t, actions["drop:mode"].Destructive)
+	assert.False(t, actions["index.drop:mode_index"].Destructive)
+}
+
+func TestVersionLess(t *testing.T) {
+	assert.True(t, versionLess("9", "10"))
+	assert.True(t, versionLess("20240101", "20240102"))
+	assert.False(t, versionLess("002", "1"))
+}
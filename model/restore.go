@@ -0,0 +1,202 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/model"
+)
+
+// RestoreOption the restore option
+type RestoreOption struct {
+	Only    []string              // The model ids or globs to restore, all models are restored if empty
+	Migrate []model.MigrateOption // The options of the model migration
+	Dir     string                // The dump directory
+}
+
+// Restore upsert the rows of the dump by the primary key, the tables are created or upgraded before restoring
+func Restore(manifest *Manifest, option RestoreOption, progress func(mod *model.Model, curr, total int)) error {
+	ids := []string{}
+	for id := range manifest.Models {
+		if MatchModel(option.Only, id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		mod, has := model.Models[id]
+		if !has {
+			return fmt.Errorf("model %s does not load", id)
+		}
+
+		err := mod.Migrate(false, option.Migrate...)
+		if err != nil {
+			return fmt.Errorf("%s %s", id, err.Error())
+		}
+
+		err = restoreModel(mod, manifest.Models[id], option.Dir, progress)
+		if err != nil {
+			return fmt.Errorf("%s %s", id, err.Error())
+		}
+	}
+	return nil
+}
+
+func restoreModel(mod *model.Model, info *ManifestModel, dir string, progress func(mod *model.Model, curr, total int)) error {
+	_, qb, err := connection(mod.MetaData.Connector)
+	if err != nil {
+		return err
+	}
+
+	pk := info.PrimaryKey
+	if pk == "" {
+		pk = mod.PrimaryKey
+	}
+
+	times := timeColumns(mod)
+	curr := 0
+	for _, name := range info.Files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+
+		rows, err := decodeRows(data)
+		if err != nil {
+			return fmt.Errorf("%s %s", name, err.Error())
+		}
+		parseTimes(rows, times)
+
+		if len(rows) == 0 {
+			continue
+		}
+
+		// Insert the rows when the model has no primary key
+		if pk == "" {
+			err = qb.New().Table(info.Table).Insert(rows)
+			if err != nil {
+				return err
+			}
+			curr = curr + len(rows)
+			if progress != nil {
+				progress(mod, curr, info.Rows)
+			}
+			continue
+		}
+
+		ids := []interface{}{}
+		for _, row := range rows {
+			ids = append(ids, row[pk])
+		}
+
+		existing, err := qb.New().Table(info.Table).Select(pk).WhereIn(pk, ids).Get()
+		if err != nil {
+			return err
+		}
+
+		exists := map[string]bool{}
+		for _, row := range existing {
+			exists[rowKey(row[pk])] = true
+		}
+
+		inserts := []map[string]interface{}{}
+		for _, row := range rows {
+			id := row[pk]
+			if !exists[rowKey(id)] {
+				inserts = append(inserts, row)
+				continue
+			}
+
+			values := map[string]interface{}{}
+			for k, v := range row {
+				if k != pk {
+					values[k] = v
+				}
+			}
+
+			_, err := qb.New().Table(info.Table).Where(pk, id).Update(values)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(inserts) > 0 {
+			err = qb.New().Table(info.Table).Insert(inserts)
+			if err != nil {
+				return err
+			}
+		}
+
+		curr = curr + len(rows)
+		if progress != nil {
+			progress(mod, curr, info.Rows)
+		}
+	}
+
+	return nil
+}
+
+// decodeRows decode the rows of the dump file, the numbers are kept as json.Number, so the large ids are not changed to floats
+func decodeRows(data []byte) ([]map[string]interface{}, error) {
+	rows := []map[string]interface{}{}
+	decoder := jsoniter.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// rowKey the canonical string of the primary key, the same id of the dump and the database has the same key
+func rowKey(v interface{}) string {
+	switch value := v.(type) {
+	case json.Number:
+		return value.String()
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// timeColumns the date and time columns of the model
+func timeColumns(mod *model.Model) []string {
+	columns := []string{}
+	for _, col := range SchemaOf(mod).Columns {
+		if strings.Contains(col.Type, "date") || strings.Contains(col.Type, "time") {
+			columns = append(columns, col.Name)
+		}
+	}
+	return columns
+}
+
+// parseTimes parse the times of the dump, they are formatted as RFC3339 with the timezone and the fractional seconds.
+// The values of the legacy dumps are kept as is, they are the datetime strings of the database.
+func parseTimes(rows []map[string]interface{}, columns []string) {
+	for _, row := range rows {
+		for _, name := range columns {
+			value, ok := row[name].(string)
+			if !ok {
+				continue
+			}
+
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err == nil {
+				row[name] = t
+			}
+		}
+	}
+}
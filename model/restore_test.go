@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestRowKey(t *testing.T) {
+	rows, err := decodeRows([]byte(`[{"id": 1234567}, {"id": 9007199254740993}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, rowKey(int64(1234567)), rowKey(rows[0]["id"]))
+	assert.Equal(t, "1234567", rowKey(float64(1234567)))
+	assert.Equal(t, rowKey(int64(9007199254740993)), rowKey(rows[1]["id"]))
+	assert.Equal(t, "1234567", rowKey([]byte("1234567")))
+}
+
+func TestDumpValueTime(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.FixedZone("CST", 8*3600))
+	rows := []map[string]interface{}{{"created_at": dumpValue(at), "name": "2024-01-02T03:04:05Z"}, {"created_at": "2024-01-02 03:04:05"}}
+	parseTimes(rows, []string{"created_at"})
+
+	value, ok := rows[0]["created_at"].(time.Time)
+	assert.True(t, ok)
+	assert.True(t, at.Equal(value))
+	assert.Equal(t, "2024-01-02T03:04:05Z", rows[0]["name"])
+	assert.Equal(t, "2024-01-02 03:04:05", rows[1]["created_at"])
+}
+
+func TestRestoreLargeIDs(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	table := "__unit_test_restore"
+	source := fmt.Sprintf(`{
+		"name": "Restore",
+		"table": {"name": "%s"},
+		"columns": [{"name": "id", "type": "ID"}, {"name": "name", "type": "string", "length": 80}],
+		"option": {"timestamps": false, "soft_deletes": false}
+	}`, table)
+
+	mod, err := model.LoadSource([]byte(source), table, "<unit-test>.mod.yao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(model.Models, table)
+	defer mod.DropTable()
+
+	err = mod.Migrate(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, qb, err := connection("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = qb.New().Table(table).Insert([]map[string]interface{}{{"id": 1234567, "name": "old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "rows.json"), []byte(`[{"id": 1234567, "name": "new"}, {"id": 2345678, "name": "added"}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing row is updated instead of inserted again
+	info := &ManifestModel{Table: table, PrimaryKey: "id", Rows: 2, Files: []string{"rows.json"}}
+	err = restoreModel(mod, info, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := qb.New().Table(table).OrderBy("id").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "new", rows[0]["name"])
+	assert.Equal(t, "added", rows[1]["name"])
+}
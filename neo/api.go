@@ -14,9 +14,14 @@ import (
 	"github.com/yaoapp/gou/api"
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/yao/aigc"
 	"github.com/yaoapp/yao/helper"
+	"github.com/yaoapp/yao/llm"
 	"github.com/yaoapp/yao/neo/conversation"
 	"github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/ratelimit"
+	"github.com/yaoapp/yao/usage"
 )
 
 // API registers the Neo API endpoints
@@ -50,8 +55,8 @@ func (neo *DSL) API(router *gin.Engine, path string) error {
 	// curl -X POST 'http://localhost:5099/api/__yao/neo' \
 	//   -H 'Content-Type: application/json' \
 	//   -d '{"content": "Hello", "chat_id": "chat_123", "context": "previous_context", "token": "xxx"}'
-	router.GET(path, append(middlewares, neo.handleChat)...)
-	router.POST(path, append(middlewares, neo.handleChat)...)
+	router.GET(path, append(middlewares, ratelimit.NeoQuota, neo.handleChat)...)
+	router.POST(path, append(middlewares, ratelimit.NeoQuota, neo.handleChat)...)
 
 	// Status check endpoint
 	// Example:
@@ -142,6 +147,17 @@ func (neo *DSL) API(router *gin.Engine, path string) error {
 	router.GET(path+"/generate/prompts", append(middlewares, neo.handleGeneratePrompts)...)
 	router.POST(path+"/generate/prompts", append(middlewares, neo.handleGeneratePrompts)...)
 
+	// Autopilot endpoint, the command is routed to the autopilot AIGCs
+	// Run example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/autopilot' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"command": "Translate hello to French", "confirm": true, "token": "xxx"}'
+	// Execute the reviewed plan example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/autopilot' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"plan": {"aigc": "translate", "arguments": {"content": "hello"}}, "token": "xxx"}'
+	router.POST(path+"/autopilot", append(middlewares, neo.handleAutopilot)...)
+
 	// Utility endpoints
 	// List connectors example:
 	// curl -X GET 'http://localhost:5099/api/__yao/neo/utility/connectors?token=xxx'
@@ -837,6 +853,58 @@ func (neo *DSL) handleGenerateCustom(c *gin.Context) {
 	resp.send("result")
 }
 
+// handleAutopilot handles routing the command to the autopilots, or executing the reviewed plan
+func (neo *DSL) handleAutopilot(c *gin.Context) {
+	var body struct {
+		Command   string     `json:"command"`
+		Plan      *aigc.Plan `json:"plan"`
+		Confirm   bool       `json:"confirm"`
+		Connector string     `json:"connector"`
+		Threshold float64    `json:"threshold"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+		return
+	}
+
+	// the user is the signed in user of the session
+	sid := c.GetString("__sid")
+	user := ""
+	if id, err := session.Global().ID(sid).Get("user_id"); err == nil && id != nil {
+		user = fmt.Sprintf("%v", id)
+	}
+
+	option := aigc.AutopilotOption{
+		Connector: body.Connector,
+		Threshold: body.Threshold,
+		Confirm:   body.Confirm,
+		User:      user,
+		Sid:       sid,
+	}
+
+	ctx := usage.WithMeta(c.Request.Context(), usage.Meta{Sid: sid, Source: "neo"})
+	if body.Plan != nil {
+		if ex := body.Plan.Run(ctx, option); ex != nil {
+			c.JSON(ex.Code, gin.H{"message": ex.Message, "code": ex.Code})
+			return
+		}
+		c.JSON(200, gin.H{"data": body.Plan})
+		return
+	}
+
+	if body.Command == "" {
+		c.JSON(400, gin.H{"message": "command is required", "code": 400})
+		return
+	}
+
+	plan, ex := aigc.Autopilot(ctx, body.Command, option)
+	if ex != nil {
+		c.JSON(ex.Code, gin.H{"message": ex.Message, "code": ex.Code})
+		return
+	}
+	c.JSON(200, gin.H{"data": plan})
+}
+
 // handleAssistantList handles listing assistants
 func (neo *DSL) handleAssistantList(c *gin.Context) {
 	// Parse filter parameters
@@ -1020,6 +1088,21 @@ func (neo *DSL) handleConnectors(c *gin.Context) {
 		}
 	}
 
+	// LLM connectors of yao. e.g. anthropic, ollama
+	for id, conn := range llm.Connectors {
+		label := conn.Label
+		if label == "" {
+			label = conn.Name
+		}
+		if label == "" {
+			label = id
+		}
+		options = append(options, map[string]interface{}{
+			"label": label,
+			"value": id,
+		})
+	}
+
 	c.JSON(200, gin.H{"data": options})
 	c.Done()
 }
@@ -0,0 +1,27 @@
+package assistant
+
+import "context"
+
+type chatKey struct{}
+
+type chat struct {
+	sid string
+	id  string
+}
+
+// WithChat returns a copy of the context with the session and the chat id, the remote assistants map the chat to their thread
+func WithChat(ctx context.Context, sid string, chatID string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, chatKey{}, chat{sid: sid, id: chatID})
+}
+
+// ChatOf the session and the chat id of the context
+func ChatOf(ctx context.Context) (string, string) {
+	if ctx == nil {
+		return "", ""
+	}
+	c, _ := ctx.Value(chatKey{}).(chat)
+	return c.sid, c.id
+}
@@ -8,13 +8,19 @@ import (
 // Chat the chat
 func (ast *Local) Chat(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) error {
 
-	if ast.openai == nil {
+	if ast.ai == nil {
 		return fmt.Errorf("api is not initialized")
 	}
 
-	_, ext := ast.openai.ChatCompletionsWith(ctx, messages, option, cb)
+	// Retrieve the knowledge and send the citations
+	messages, citations := ast.retrieve(ctx, messages)
+	if citations != nil && cb(citations) == 0 {
+		return nil
+	}
+
+	_, ext := ast.ai.ChatCompletionsWith(ctx, messages, option, cb)
 	if ext != nil {
-		return fmt.Errorf("openai chat completions with error: %s", ext.Message)
+		return fmt.Errorf("chat completions with error: %s", ext.Message)
 	}
 
 	return nil
@@ -12,6 +12,7 @@ import (
 
 	"github.com/yaoapp/gou/fs"
 	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/rag"
 )
 
 // AllowedFileTypes the allowed file types
@@ -60,6 +61,9 @@ func (ast *Local) Upload(ctx context.Context, file *multipart.FileHeader, reader
 		return nil, err
 	}
 
+	// Ingest the file into the knowledge base
+	ast.ingest(ctx, rag.File{ID: filename, Filename: file.Filename, ContentType: contentType})
+
 	return &assistant.File{
 		ID:          filename,
 		Filename:    filename,
@@ -86,6 +90,22 @@ func (ast *Local) allowed(contentType string) bool {
 	return false
 }
 
+// FileDelete delete the uploaded file and remove it from the knowledge base
+func (ast *Local) FileDelete(ctx context.Context, fileID string) error {
+	data, err := fs.Get("data")
+	if err != nil {
+		return err
+	}
+
+	if exists, _ := data.Exists(fileID); exists {
+		err = data.Remove(fileID)
+		if err != nil {
+			return err
+		}
+	}
+	return ast.remove(ctx, fileID)
+}
+
 // Download downloads a file
 func (ast *Local) Download(ctx context.Context, fileID string) (*assistant.FileResponse, error) {
 
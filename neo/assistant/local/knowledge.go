@@ -0,0 +1,200 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/fs"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/rag"
+)
+
+// Scopes of the uploaded files in the knowledge base
+const (
+	ScopeUser      = "user"      // the files are retrieved in the chats of the user who uploaded them, the default scope
+	ScopeChat      = "chat"      // the files are retrieved in the chat they are uploaded to
+	ScopeAssistant = "assistant" // the files are retrieved in all the chats of the assistant
+)
+
+// ingest the uploaded file into the knowledge base in the background, the collection is chosen by the scope of the assistant
+func (ast *Local) ingest(ctx context.Context, file rag.File) {
+	knowledge := rag.Default()
+	if knowledge == nil {
+		return
+	}
+
+	collection := ast.collection(ctx)
+	if collection == "" {
+		log.Trace("[Neo] the file %s is not ingested, the upload has no session", file.Filename)
+		return
+	}
+
+	go func() {
+		data, err := fs.Get("data")
+		if err != nil {
+			log.Error("[Neo] ingest the file %s %s", file.ID, err.Error())
+			return
+		}
+
+		content, err := data.ReadFile(file.ID)
+		if err != nil {
+			log.Error("[Neo] ingest the file %s %s", file.ID, err.Error())
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		n, err := knowledge.Ingest(ctx, collection, file, content)
+		if errors.Is(err, rag.ErrUnsupported) {
+			log.Trace("[Neo] the file %s is not ingested, %s", file.Filename, err.Error())
+			return
+		}
+
+		if err != nil {
+			log.Error("[Neo] ingest the file %s %s", file.Filename, err.Error())
+			return
+		}
+		log.Trace("[Neo] the file %s is ingested into %s, %d chunks", file.Filename, collection, n)
+	}()
+}
+
+// retrieve the chunks relevant to the last user message, the chunks are inserted before it as a system message.
+// The citations of the chunks are returned as the actions chunk of the stream.
+func (ast *Local) retrieve(ctx context.Context, messages []map[string]interface{}) ([]map[string]interface{}, []byte) {
+	knowledge := rag.Default()
+	if knowledge == nil || len(messages) == 0 {
+		return messages, nil
+	}
+
+	last := messages[len(messages)-1]
+	query, ok := last["content"].(string)
+	if !ok || last["role"] != "user" {
+		return messages, nil
+	}
+
+	collections := ast.collections()
+	if collection := ast.collection(ctx); collection != "" && collection != collections[0] {
+		collections = append([]string{collection}, collections...)
+	}
+
+	results, err := knowledge.Retrieve(ctx, collections, query)
+	if err != nil {
+		log.Error("[Neo] retrieve the knowledge of %s %s", ast.ID, err.Error())
+		return messages, nil
+	}
+	results = ast.exists(knowledge, results)
+
+	if len(results) == 0 {
+		return messages, nil
+	}
+
+	res := make([]map[string]interface{}, 0, len(messages)+1)
+	res = append(res, messages[:len(messages)-1]...)
+	res = append(res, rag.Prompt(results), last)
+
+	actions := []map[string]interface{}{}
+	for _, citation := range rag.Citations(results) {
+		actions = append(actions, map[string]interface{}{
+			"name":    fmt.Sprintf("[%v] %v", citation["index"], citation["filename"]),
+			"type":    "citation",
+			"payload": citation,
+		})
+	}
+
+	chunk, err := jsoniter.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		log.Error("[Neo] the citations of %s %s", ast.ID, err.Error())
+		return res, nil
+	}
+	return res, append([]byte("data: "), chunk...)
+}
+
+// remove the chunks of the file from the collection of the uploads
+func (ast *Local) remove(ctx context.Context, fileID string) error {
+	knowledge := rag.Default()
+	collection := ast.collection(ctx)
+	if knowledge == nil || collection == "" {
+		return nil
+	}
+	return knowledge.Remove(collection, fileID)
+}
+
+// exists the chunks of the files still in the data filesystem, the chunks of the deleted files are removed
+func (ast *Local) exists(knowledge *rag.Knowledge, results []rag.Result) []rag.Result {
+	data, err := fs.Get("data")
+	if err != nil {
+		return results
+	}
+
+	res := []rag.Result{}
+	removed := map[string]bool{}
+	for _, result := range results {
+		if removed[result.FileID] {
+			continue
+		}
+
+		// the chunks of the other sources, e.g. the documents ingested by the processes
+		if !strings.HasPrefix(result.FileID, "/__assistants/") {
+			res = append(res, result)
+			continue
+		}
+
+		if has, _ := data.Exists(result.FileID); has {
+			res = append(res, result)
+			continue
+		}
+
+		removed[result.FileID] = true
+		err := knowledge.Remove(result.Collection, result.FileID)
+		if err != nil {
+			log.Error("[Neo] remove the file %s from %s %s", result.FileID, result.Collection, err.Error())
+		}
+	}
+	return res
+}
+
+// collection the collection of the uploaded files, the first collection of the assistant scoped by the user or the chat.
+// Returns empty if the scope requires the session and the context has not.
+func (ast *Local) collection(ctx context.Context) string {
+	base := ast.collections()[0]
+	sid, chatID := assistant.ChatOf(ctx)
+	switch ast.Scope {
+	case ScopeAssistant:
+		return base
+
+	case ScopeChat:
+		if sid == "" || chatID == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s@%s/%s", base, sid, chatID)
+	}
+
+	if sid == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s@%s", base, owner(sid))
+}
+
+// owner the user id of the session, the session id is used if the user is not signed in
+func owner(sid string) string {
+	id, err := session.Global().ID(sid).Get("user_id")
+	if err != nil || id == nil || id == "" {
+		return sid
+	}
+	return fmt.Sprintf("%v", id)
+}
+
+// collections the collections of the knowledge base attached to the assistant, the default value is the assistant id
+func (ast *Local) collections() []string {
+	if len(ast.Collections) > 0 {
+		return ast.Collections
+	}
+	return []string{ast.ID}
+}
@@ -3,29 +3,28 @@ package local
 import (
 	"context"
 
-	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/yao/llm"
 	"github.com/yaoapp/yao/neo/assistant"
-	"github.com/yaoapp/yao/openai"
 )
 
 // Local the local assistant
 type Local struct {
-	ID        string              `json:"assistant_id"`
-	Prompts   []assistant.Prompt  `json:"prompts,omitempty"`
-	Connector connector.Connector `json:"-" yaml:"-"`
-	openai    *openai.OpenAI
+	ID          string             `json:"assistant_id"`
+	Prompts     []assistant.Prompt `json:"prompts,omitempty"`
+	Connector   string             `json:"connector,omitempty"`
+	Collections []string           `json:"collections,omitempty"` // The collections of the knowledge base, the default value is the assistant id
+	Scope       string             `json:"scope,omitempty"`       // The scope of the uploaded files: user (default), chat or assistant
+	ai          llm.LLM
 }
 
-// New create a new local assistant
-func New(connector connector.Connector, prompts []assistant.Prompt, id string) (*Local, error) {
-
-	setting := connector.Setting()
-	api, err := openai.NewOpenAI(setting)
+// New create a new local assistant, the connector is an LLM connector (openai, anthropic, ollama, moapi)
+func New(connector string, prompts []assistant.Prompt, id string) (*Local, error) {
+	ai, err := llm.New(connector)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Local{Connector: connector, ID: id, Prompts: prompts, openai: api}, nil
+	return &Local{Connector: connector, ID: id, Prompts: prompts, ai: ai}, nil
 }
 
 // List list all assistants
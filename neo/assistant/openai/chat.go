@@ -3,28 +3,302 @@ package openai
 import (
 	"context"
 	"fmt"
-)
-
-// Chat the chat struct
-type Chat struct {
-	ID       string `json:"chat_id"`
-	ThreadID string `json:"thread_id"`
-}
+	"net/url"
+	"strings"
+	"time"
 
-// NewChat create a new chat
-func (ast *OpenAI) NewChat() {}
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/llm"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/usage"
+)
 
-// Chat the chat
+// Chat the chat, the message is added to the thread of the chat and the run events are streamed as the OpenAI chunks.
+// The chat completions are used if the assistant is not synced to a remote assistant.
 func (ast *OpenAI) Chat(ctx context.Context, messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) error {
 
 	if ast.openai == nil {
 		return fmt.Errorf("openai is not initialized")
 	}
 
-	_, ext := ast.openai.ChatCompletionsWith(ctx, messages, option, cb)
-	if ext != nil {
-		return fmt.Errorf("openai chat completions with error: %s", ext.Message)
+	if ast.RemoteID == "" {
+		_, ext := ast.openai.ChatCompletionsWith(ctx, messages, option, cb)
+		if ext != nil {
+			return fmt.Errorf("openai chat completions with error: %s", ext.Message)
+		}
+		return nil
+	}
+
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if role, _ := messages[i]["role"].(string); role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return fmt.Errorf("the user message is required")
+	}
+
+	call, ex := usage.Begin(ctx, ast.connector, ast.model, messages, ast.openai.Tiktoken)
+	if ex != nil {
+		return fmt.Errorf("%s", ex.Message)
+	}
+	cb = call.Stream(cb)
+	defer call.End(nil)
+
+	// the history is added to the new thread, the thread of the chat has it already
+	sid, chatID := assistant.ChatOf(ctx)
+	threadID, err := ast.threadOf(sid, chatID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	if threadID == "" {
+		thread, err := ast.ThreadCreate(ctx, sid, chatID, messages[:last])
+		if err != nil {
+			return err
+		}
+		threadID = thread.ID
+	}
+
+	message := map[string]interface{}{"role": "user", "content": text(messages[last]["content"])}
+	if files := takeAttachments(sid, chatID); len(files) > 0 {
+		attachments := []map[string]interface{}{}
+		for _, id := range files {
+			attachments = append(attachments, map[string]interface{}{"file_id": id, "tools": []map[string]interface{}{{"type": "file_search"}}})
+		}
+		message["attachments"] = attachments
+	}
+
+	err = ast.request(ctx, "POST", "/v1/threads/"+url.PathEscape(threadID)+"/messages", message, nil)
+	if err != nil {
+		return err
+	}
+
+	// the system messages of the chat are the additional instructions of the run, the prompts synced as the instructions are skipped
+	instructions := []string{}
+	for _, message := range messages {
+		if role, _ := message["role"].(string); role == "system" {
+			if content := text(message["content"]); content != "" && !ast.instructions[content] {
+				instructions = append(instructions, content)
+			}
+		}
+	}
+
+	payload := map[string]interface{}{"assistant_id": ast.RemoteID, "stream": true}
+	if len(instructions) > 0 {
+		payload["additional_instructions"] = strings.Join(instructions, "\n\n")
+	}
+	for _, name := range []string{"model", "temperature", "top_p", "max_completion_tokens"} {
+		if value, has := option[name]; has {
+			payload[name] = value
+		}
+	}
+
+	return ast.run(ctx, threadID, payload, cb)
+}
+
+// maxToolRounds the max rounds of the tool outputs submitted in one run
+const maxToolRounds = 10
+
+// toolCall the function call of the run requires action
+type toolCall struct {
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// run create the run of the thread and convert the run events to the OpenAI chunks.
+// The function calls of the run are run as the processes, the outputs are submitted and the run is streamed again.
+// The run step events are ignored, the messages of the steps are streamed as the message deltas.
+func (ast *OpenAI) run(ctx context.Context, threadID string, payload map[string]interface{}, cb func(data []byte) int) error {
+	var failed error = nil
+	var calls []toolCall
+	id := ""
+	handler := func(event string, data []byte) bool {
+		switch event {
+		case "thread.run.created":
+			var run struct {
+				ID string `json:"id"`
+			}
+			jsoniter.Unmarshal(data, &run)
+			id = run.ID
+
+		case "thread.message.delta":
+			var delta struct {
+				Delta struct {
+					Content []struct {
+						Type string `json:"type"`
+						Text struct {
+							Value string `json:"value"`
+						} `json:"text"`
+					} `json:"content"`
+				} `json:"delta"`
+			}
+			err := jsoniter.Unmarshal(data, &delta)
+			if err != nil {
+				failed = fmt.Errorf("OpenAI %s", err.Error())
+				cb(errorChunk("invalid_response", err.Error()))
+				return false
+			}
+
+			for _, content := range delta.Delta.Content {
+				if content.Type == "text" && content.Text.Value != "" {
+					if cb(chunk(id, ast.model, content.Text.Value, "", nil)) == 0 {
+						return false
+					}
+				}
+			}
+
+		case "thread.run.completed":
+			var run struct {
+				Usage *llm.ChunkUsage `json:"usage"`
+			}
+			jsoniter.Unmarshal(data, &run)
+			if cb(chunk(id, ast.model, "", "stop", run.Usage)) == 0 {
+				return false
+			}
+			cb([]byte("data: [DONE]"))
+			return false
+
+		case "thread.run.requires_action":
+			var run struct {
+				ID             string `json:"id"`
+				RequiredAction struct {
+					SubmitToolOutputs struct {
+						ToolCalls []toolCall `json:"tool_calls"`
+					} `json:"submit_tool_outputs"`
+				} `json:"required_action"`
+			}
+			err := jsoniter.Unmarshal(data, &run)
+			if err != nil {
+				failed = fmt.Errorf("OpenAI %s", err.Error())
+				cb(errorChunk("invalid_response", err.Error()))
+				return false
+			}
+			if run.ID != "" {
+				id = run.ID
+			}
+			calls = run.RequiredAction.SubmitToolOutputs.ToolCalls
+			return false
+
+		case "thread.run.failed", "thread.run.cancelled", "thread.run.expired", "thread.run.incomplete", "error":
+			var run struct {
+				LastError *struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"last_error"`
+				Message string `json:"message"`
+			}
+			jsoniter.Unmarshal(data, &run)
+
+			message := strings.TrimPrefix(event, "thread.run.")
+			if run.LastError != nil {
+				message = run.LastError.Message
+			} else if run.Message != "" {
+				message = run.Message
+			}
+			failed = fmt.Errorf("OpenAI the run is %s", message)
+			cb(errorChunk("server_error", message))
+			return false
+		}
+		return true
+	}
+
+	path := "/v1/threads/" + url.PathEscape(threadID) + "/runs"
+	for round := 0; ; round++ {
+		calls = nil
+		err := ast.stream(ctx, path, payload, handler)
+		if err != nil {
+			return err
+		}
+		if failed != nil || len(calls) == 0 {
+			return failed
+		}
+
+		runPath := "/v1/threads/" + url.PathEscape(threadID) + "/runs/" + url.PathEscape(id)
+		if round >= maxToolRounds {
+			ast.request(ctx, "POST", runPath+"/cancel", map[string]interface{}{}, nil)
+			failed = fmt.Errorf("OpenAI the run %s requires the action more than %d times", id, maxToolRounds)
+			cb(errorChunk("requires_action", failed.Error()))
+			return failed
+		}
+
+		outputs, err := ast.callTools(ctx, calls)
+		if err != nil {
+			ast.request(ctx, "POST", runPath+"/cancel", map[string]interface{}{}, nil)
+			failed = fmt.Errorf("OpenAI the run %s requires the action, %s", id, err.Error())
+			cb(errorChunk("requires_action", failed.Error()))
+			return failed
+		}
+
+		path = runPath + "/submit_tool_outputs"
+		payload = map[string]interface{}{"tool_outputs": outputs, "stream": true}
+	}
+}
+
+// callTools run the processes of the function calls, the output is the string returned or the JSON of the value
+func (ast *OpenAI) callTools(ctx context.Context, calls []toolCall) ([]map[string]interface{}, error) {
+	sid, _ := assistant.ChatOf(ctx)
+	outputs := []map[string]interface{}{}
+	for _, call := range calls {
+		name, has := ast.processes[call.Function.Name]
+		if !has {
+			return nil, fmt.Errorf("the function %s has no process", call.Function.Name)
+		}
+
+		args := map[string]interface{}{}
+		if call.Function.Arguments != "" {
+			err := jsoniter.UnmarshalFromString(call.Function.Arguments, &args)
+			if err != nil {
+				return nil, fmt.Errorf("the arguments of the function %s are invalid, %s", call.Function.Name, err.Error())
+			}
+		}
+
+		p, err := process.Of(name, args)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.WithSID(sid).WithContext(ctx).Exec()
+		if err != nil {
+			return nil, fmt.Errorf("the function %s is failed, %s", call.Function.Name, err.Error())
+		}
+
+		output, ok := value.(string)
+		if !ok {
+			output, err = jsoniter.MarshalToString(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		outputs = append(outputs, map[string]interface{}{"tool_call_id": call.ID, "output": output})
+	}
+	return outputs, nil
+}
+
+// chunk encode the text as an OpenAI chunk line, the usage is sent with the last chunk
+func chunk(id, model, text string, finish string, usage *llm.ChunkUsage) []byte {
+	c := llm.Chunk{ID: id, Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: model, Usage: usage}
+	choice := llm.ChunkChoice{Index: 0, Delta: map[string]interface{}{}}
+	if text != "" {
+		choice.Delta["content"] = text
+	}
+	if finish != "" {
+		choice.FinishReason = &finish
+	}
+	c.Choices = []llm.ChunkChoice{choice}
+
+	data, _ := jsoniter.Marshal(c)
+	return append([]byte("data: "), data...)
+}
+
+// errorChunk encode the error as an OpenAI error line
+func errorChunk(typ, message string) []byte {
+	data, _ := jsoniter.Marshal(map[string]interface{}{"error": map[string]interface{}{"type": typ, "message": message}})
+	return append([]byte("data: "), data...)
 }
@@ -0,0 +1,172 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Client the HTTP client of the assistants API, replace it to set the proxy or the timeout
+var Client = &http.Client{}
+
+// request send the JSON request, the response is decoded into the res if it is not nil
+func (ast *OpenAI) request(ctx context.Context, method string, path string, payload interface{}, res interface{}) error {
+	var body io.Reader = nil
+	if payload != nil {
+		data, err := jsoniter.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, ast.host+path, body)
+	if err != nil {
+		return err
+	}
+	ast.header(req)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI %s", err.Error())
+	}
+	defer resp.Body.Close()
+	return decode(resp, res)
+}
+
+// upload send the file as the multipart form, the purpose of the assistants files is assistants
+func (ast *OpenAI) upload(ctx context.Context, filename string, contentType string, reader io.Reader, res interface{}) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	err := writer.WriteField("purpose", "assistants")
+	if err != nil {
+		return err
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, reader)
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ast.host+"/v1/files", &buf)
+	if err != nil {
+		return err
+	}
+	ast.header(req)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI %s", err.Error())
+	}
+	defer resp.Body.Close()
+	return decode(resp, res)
+}
+
+// stream send the JSON request and read the server-sent events, stops when the handler returns false
+func (ast *OpenAI) stream(ctx context.Context, path string, payload interface{}, handler func(event string, data []byte) bool) error {
+	data, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ast.host+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	ast.header(req)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decode(resp, nil)
+	}
+
+	event := ""
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+
+		case strings.HasPrefix(line, "data:"):
+			if !handler(event, []byte(strings.TrimSpace(strings.TrimPrefix(line, "data:")))) {
+				return nil
+			}
+			event = ""
+		}
+	}
+	return scanner.Err()
+}
+
+func (ast *OpenAI) header(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+ast.key)
+	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	if ast.organization != "" {
+		req.Header.Set("OpenAI-Organization", ast.organization)
+	}
+}
+
+// decode the response, the error message of the API is returned as the error
+// {"error": {"message": "No assistant found with id 'asst_abc'.", "type": "invalid_request_error"}}
+func decode(resp *http.Response, res interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var message struct {
+			Error struct {
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+		if err := jsoniter.Unmarshal(body, &message); err == nil && message.Error.Message != "" {
+			return fmt.Errorf("OpenAI %d %s", resp.StatusCode, message.Error.Message)
+		}
+		return fmt.Errorf("OpenAI %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	if raw, ok := res.(*[]byte); ok {
+		*raw = body
+		return nil
+	}
+	return jsoniter.Unmarshal(body, res)
+}
@@ -1,13 +1,15 @@
 package openai
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/yaoapp/gou/fs"
@@ -30,7 +32,12 @@ var AllowedFileTypes = map[string]string{
 // MaxSize 20M max file size
 var MaxSize int64 = 20 * 1024 * 1024
 
-// Upload the file
+// attachments the files uploaded to the chats, they are attached to the next message of the chat. the key is the sid and the chat id
+var attachments = map[string][]string{}
+var attachmentMutex sync.Mutex
+
+// Upload the file to the remote file store, the file is kept in the data filesystem to download it.
+// The file is attached to the next message of the chat.
 func (ast *OpenAI) Upload(ctx context.Context, file *multipart.FileHeader, reader io.Reader, option map[string]interface{}) (*assistant.File, error) {
 
 	// check file size
@@ -43,36 +50,80 @@ func (ast *OpenAI) Upload(ctx context.Context, file *multipart.FileHeader, reade
 		return nil, fmt.Errorf("file type %s not allowed", contentType)
 	}
 
-	data, err := fs.Get("data")
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var remote RemoteFile
+	err = ast.upload(ctx, file.Filename, contentType, bytes.NewReader(content), &remote)
 	if err != nil {
 		return nil, err
 	}
 
-	ext := filepath.Ext(file.Filename)
-	id, err := ast.id(file.Filename, ext)
+	data, err := fs.Get("data")
 	if err != nil {
 		return nil, err
 	}
 
-	filename := id
-	_, err = data.Write(filename, reader, 0644)
+	// the remote id is the name of the local file
+	filename := ast.path(remote.ID, filepath.Ext(file.Filename))
+	_, err = data.WriteFile(filename, content, 0644)
 	if err != nil {
 		return nil, err
 	}
 
+	if sid, chatID := assistant.ChatOf(ctx); chatID != "" {
+		key := sid + "/" + chatID
+		attachmentMutex.Lock()
+		attachments[key] = append(attachments[key], remote.ID)
+		attachmentMutex.Unlock()
+	}
+
 	return &assistant.File{
 		ID:          filename,
-		Filename:    filename,
+		Filename:    file.Filename,
 		ContentType: contentType,
 		Bytes:       int(file.Size),
 		CreatedAt:   int(time.Now().Unix()),
 	}, nil
 }
 
-func (ast *OpenAI) id(temp string, ext string) (string, error) {
+// RemoteFile the file of the remote file store
+type RemoteFile struct {
+	ID        string `json:"id"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int    `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose,omitempty"`
+}
+
+// takeAttachments the files uploaded to the chat since the last message
+func takeAttachments(sid string, chatID string) []string {
+	if chatID == "" {
+		return nil
+	}
+
+	key := sid + "/" + chatID
+	attachmentMutex.Lock()
+	defer attachmentMutex.Unlock()
+	files := attachments[key]
+	delete(attachments, key)
+	return files
+}
+
+func (ast *OpenAI) path(id string, ext string) string {
 	date := time.Now().Format("20060102")
-	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(temp)))[:8]
-	return fmt.Sprintf("/__assistants/%s/%s/%s%s", ast.ID, date, hash, ext), nil
+	return fmt.Sprintf("/__assistants/%s/%s/%s%s", ast.ID, date, id, ext)
+}
+
+// remoteFile the remote id of the file, the id of the uploaded file is the local path
+func remoteFile(fileID string) string {
+	if !strings.HasPrefix(fileID, "/") {
+		return fileID
+	}
+	base := filepath.Base(fileID)
+	return strings.TrimSuffix(base, filepath.Ext(base))
 }
 
 func (ast *OpenAI) allowed(contentType string) bool {
@@ -86,21 +137,89 @@ func (ast *OpenAI) allowed(contentType string) bool {
 	return false
 }
 
-// FileLists list all files
-func (ast *OpenAI) FileLists() {}
+// FileLists list the files of the remote file store
+func (ast *OpenAI) FileLists(ctx context.Context) ([]assistant.File, error) {
+	var res struct {
+		Data []RemoteFile `json:"data"`
+	}
+	err := ast.request(ctx, "GET", "/v1/files?purpose=assistants", nil, &res)
+	if err != nil {
+		return nil, err
+	}
 
-// FileDelete delete a file
-func (ast *OpenAI) FileDelete() {}
+	files := []assistant.File{}
+	for _, file := range res.Data {
+		files = append(files, assistant.File{ID: file.ID, Bytes: file.Bytes, CreatedAt: file.CreatedAt, Filename: file.Filename})
+	}
+	return files, nil
+}
 
-// FileContent get the content of a file
-func (ast *OpenAI) FileContent() {}
+// FileDelete delete the file of the remote file store and the local copy
+func (ast *OpenAI) FileDelete(ctx context.Context, fileID string) error {
+	err := ast.request(ctx, "DELETE", "/v1/files/"+url.PathEscape(remoteFile(fileID)), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(fileID, "/") {
+		return nil
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		return err
+	}
+	if exists, _ := data.Exists(fileID); exists {
+		return data.Remove(fileID)
+	}
+	return nil
+}
 
-// FileInfo get the information of a file
-func (ast *OpenAI) FileInfo() {}
+// FileContent get the content of the file, the local copy is read if it exists
+func (ast *OpenAI) FileContent(ctx context.Context, fileID string) ([]byte, error) {
+	if strings.HasPrefix(fileID, "/") {
+		data, err := fs.Get("data")
+		if err != nil {
+			return nil, err
+		}
+		if exists, _ := data.Exists(fileID); exists {
+			return data.ReadFile(fileID)
+		}
+	}
 
-// Download downloads a file
+	var content []byte
+	err := ast.request(ctx, "GET", "/v1/files/"+url.PathEscape(remoteFile(fileID))+"/content", nil, &content)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// FileInfo get the information of the file of the remote file store
+func (ast *OpenAI) FileInfo(ctx context.Context, fileID string) (*assistant.File, error) {
+	var file RemoteFile
+	err := ast.request(ctx, "GET", "/v1/files/"+url.PathEscape(remoteFile(fileID)), nil, &file)
+	if err != nil {
+		return nil, err
+	}
+	return &assistant.File{ID: file.ID, Bytes: file.Bytes, CreatedAt: file.CreatedAt, Filename: file.Filename}, nil
+}
+
+// Download downloads a file, the local copy of the uploaded file or the file of the remote file store
 func (ast *OpenAI) Download(ctx context.Context, fileID string) (*assistant.FileResponse, error) {
 
+	// The files of the remote file store. e.g. the files created by the code interpreter
+	if !strings.HasPrefix(fileID, "/") {
+		content, err := ast.FileContent(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		return &assistant.FileResponse{
+			Reader:      io.NopCloser(bytes.NewReader(content)),
+			ContentType: "application/octet-stream",
+		}, nil
+	}
+
 	// Get the data filesystem
 	data, err := fs.Get("data")
 	if err != nil {
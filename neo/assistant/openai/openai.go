@@ -2,17 +2,66 @@ package openai
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/conversation"
 	api "github.com/yaoapp/yao/openai"
 )
 
-// OpenAI the openai assistant
+// OpenAI the openai assistant, the Neo assistants with the remote option are synced to the remote assistants and the chats are mapped to the threads.
+// The chat completions are used if the assistant is not synced. e.g. the default assistant of the connector
 type OpenAI struct {
-	ID        string              `json:"assistant_id"` // the assistant id
-	Connector connector.Connector `json:"-" yaml:"-"`
-	openai    *api.OpenAI
+	ID           string                    `json:"assistant_id"`        // the assistant id
+	RemoteID     string                    `json:"remote_id,omitempty"` // the id of the remote assistant, asst_xxx
+	Connector    connector.Connector       `json:"-" yaml:"-"`
+	Conversation conversation.Conversation `json:"-" yaml:"-"` // the threads of the chats are stored with the chats
+	openai       *api.OpenAI
+	connector    string
+	host         string
+	key          string
+	organization string
+	model        string
+	instructions map[string]bool   // the prompts synced as the instructions of the remote assistant
+	processes    map[string]string // the processes of the function tools, the key is the function name
+}
+
+// Remote the remote assistant of the assistants API
+type Remote struct {
+	ID           string                   `json:"id,omitempty"`
+	Name         string                   `json:"name,omitempty"`
+	Description  string                   `json:"description,omitempty"`
+	Model        string                   `json:"model,omitempty"`
+	Instructions string                   `json:"instructions,omitempty"`
+	Tools        []map[string]interface{} `json:"tools,omitempty"`
+	Metadata     map[string]string        `json:"metadata,omitempty"`
+	Temperature  *float64                 `json:"temperature,omitempty"`
+	CreatedAt    int64                    `json:"created_at,omitempty"`
+}
+
+// MetadataKey the metadata key of the remote assistants, the value is the Neo assistant id
+const MetadataKey = "neo_assistant_id"
+
+// synced the remote assistants synced, the key is the connector and the Neo assistant id
+var synced = map[string]syncState{}
+var syncMutex sync.Mutex
+
+type syncState struct {
+	id   string // the remote id
+	hash string // the hash of the synced payload
+}
+
+// Enabled the assistant runs on the remote assistant, set the remote option to true. e.g. {"option": {"remote": true}}
+// The OpenAI compatible hosts do not provide the assistants API usually.
+func Enabled(neo assistant.Assistant) bool {
+	remote, _ := neo.Option["remote"].(bool)
+	return remote
 }
 
 // New create a new openai assistant
@@ -24,7 +73,28 @@ func New(connector connector.Connector, id string) (*OpenAI, error) {
 		return nil, err
 	}
 
-	return &OpenAI{ID: id, Connector: connector, openai: openai}, nil
+	ast := &OpenAI{
+		ID:        id,
+		Connector: connector,
+		openai:    openai,
+		connector: id,
+		host:      "https://api.openai.com",
+		model:     "gpt-3.5-turbo",
+	}
+
+	if v, ok := setting["host"].(string); ok && v != "" {
+		ast.host = strings.TrimSuffix(v, "/")
+	}
+	if v, ok := setting["key"].(string); ok {
+		ast.key = v
+	}
+	if v, ok := setting["organization"].(string); ok {
+		ast.organization = v
+	}
+	if v, ok := setting["model"].(string); ok && v != "" {
+		ast.model = v
+	}
+	return ast, nil
 }
 
 // Current set the current assistant
@@ -33,19 +103,267 @@ func (ast *OpenAI) Current(id string) *OpenAI {
 	return ast
 }
 
-// List list all assistants
+// List list the remote assistants
 func (ast *OpenAI) List(ctx context.Context, param assistant.QueryParam) ([]assistant.Assistant, error) {
-	return nil, nil
+	remotes, _, err := ast.list(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []assistant.Assistant{}
+	for _, remote := range remotes {
+		res = append(res, ast.assistant(remote))
+	}
+	return res, nil
+}
+
+// Create create a new remote assistant, returns the remote id
+func (ast *OpenAI) Create(ctx context.Context, neo assistant.Assistant) (string, error) {
+	var remote Remote
+	err := ast.request(ctx, "POST", "/v1/assistants", ast.remote(neo), &remote)
+	if err != nil {
+		return "", err
+	}
+	return remote.ID, nil
+}
+
+// Delete delete the remote assistant
+func (ast *OpenAI) Delete(ctx context.Context, id string) error {
+	err := ast.request(ctx, "DELETE", "/v1/assistants/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	syncMutex.Lock()
+	defer syncMutex.Unlock()
+	for key, state := range synced {
+		if state.id == id {
+			delete(synced, key)
+		}
+	}
+	return nil
+}
+
+// Update update the remote assistant
+func (ast *OpenAI) Update(ctx context.Context, id string, neo assistant.Assistant) error {
+	return ast.request(ctx, "POST", "/v1/assistants/"+url.PathEscape(id), ast.remote(neo), nil)
+}
+
+// Get get the remote assistant
+func (ast *OpenAI) Get(ctx context.Context, id string) (*assistant.Assistant, error) {
+	var remote Remote
+	err := ast.request(ctx, "GET", "/v1/assistants/"+url.PathEscape(id), nil, &remote)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ast.assistant(remote)
+	return &res, nil
+}
+
+// Sync create or update the remote assistant of the Neo assistant, the remote assistant is found by the metadata.
+// The remote assistant is not updated if the assistant is not changed since the last sync.
+func (ast *OpenAI) Sync(ctx context.Context, neo assistant.Assistant) (string, error) {
+	ast.bind(neo)
+	payload := ast.remote(neo)
+	data, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	key := ast.connector + "/" + neo.ID
+	syncMutex.Lock()
+	state, has := synced[key]
+	syncMutex.Unlock()
+	if has && state.hash == hash {
+		ast.RemoteID = state.id
+		return state.id, nil
+	}
+
+	id := state.id
+	if id == "" {
+		id, err = ast.find(ctx, neo.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if id == "" {
+		id, err = ast.Create(ctx, neo)
+	} else {
+		err = ast.Update(ctx, id, neo)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	syncMutex.Lock()
+	synced[key] = syncState{id: id, hash: hash}
+	syncMutex.Unlock()
+	ast.RemoteID = id
+	return id, nil
+}
+
+// find the remote assistant of the Neo assistant by the metadata
+func (ast *OpenAI) find(ctx context.Context, id string) (string, error) {
+	param := assistant.QueryParam{Limit: 100}
+	for {
+		remotes, more, err := ast.list(ctx, param)
+		if err != nil {
+			return "", err
+		}
+
+		for _, remote := range remotes {
+			if remote.Metadata[MetadataKey] == id {
+				return remote.ID, nil
+			}
+		}
+
+		if !more || len(remotes) == 0 {
+			return "", nil
+		}
+		param.After = remotes[len(remotes)-1].ID
+	}
+}
+
+func (ast *OpenAI) list(ctx context.Context, param assistant.QueryParam) ([]Remote, bool, error) {
+	query := url.Values{}
+	if param.Limit > 0 {
+		query.Set("limit", fmt.Sprintf("%d", param.Limit))
+	}
+	if param.Order != "" {
+		query.Set("order", param.Order)
+	}
+	if param.After != "" {
+		query.Set("after", param.After)
+	}
+	if param.Before != "" {
+		query.Set("before", param.Before)
+	}
+
+	path := "/v1/assistants"
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+
+	var res struct {
+		Data    []Remote `json:"data"`
+		HasMore bool     `json:"has_more"`
+	}
+	err := ast.request(ctx, "GET", path, nil, &res)
+	if err != nil {
+		return nil, false, err
+	}
+	return res.Data, res.HasMore, nil
+}
+
+// remote the remote assistant of the Neo assistant, the prompts are the instructions.
+// The model, the tools and the temperature are read from the option, the file_search tool is used by default.
+func (ast *OpenAI) remote(neo assistant.Assistant) Remote {
+	instructions := []string{}
+	for _, prompt := range neo.Prompts {
+		if prompt.Content != "" {
+			instructions = append(instructions, prompt.Content)
+		}
+	}
+
+	remote := Remote{
+		Name:         neo.Name,
+		Description:  neo.Description,
+		Model:        ast.model,
+		Instructions: strings.Join(instructions, "\n\n"),
+		Tools:        []map[string]interface{}{{"type": "file_search"}},
+		Metadata:     map[string]string{MetadataKey: neo.ID},
+	}
+
+	if model, ok := neo.Option["model"].(string); ok && model != "" {
+		remote.Model = model
+	}
+
+	// the process of the function tool is run by the Neo, it is not sent to the remote assistant
+	if tools, ok := neo.Option["tools"].([]interface{}); ok {
+		remote.Tools = []map[string]interface{}{}
+		for _, tool := range tools {
+			if tool, ok := tool.(map[string]interface{}); ok {
+				copied := map[string]interface{}{}
+				for key, value := range tool {
+					if key != "process" {
+						copied[key] = value
+					}
+				}
+				remote.Tools = append(remote.Tools, copied)
+			}
+		}
+	}
+
+	switch v := neo.Option["temperature"].(type) {
+	case float64:
+		remote.Temperature = &v
+	case int:
+		temperature := float64(v)
+		remote.Temperature = &temperature
+	}
+	return remote
 }
 
-// Create create a new assistant
-func (ast *OpenAI) Create() {}
+// bind the prompts and the function processes of the Neo assistant to the remote assistant
+// The function tool runs the process. e.g. {"type": "function", "function": {"name": "weather", ...}, "process": "scripts.weather.Get"}
+func (ast *OpenAI) bind(neo assistant.Assistant) {
+	ast.instructions = map[string]bool{}
+	for _, prompt := range neo.Prompts {
+		if prompt.Content != "" {
+			ast.instructions[prompt.Content] = true
+		}
+	}
 
-// Delete delete an assistant
-func (ast *OpenAI) Delete() {}
+	ast.processes = map[string]string{}
+	tools, _ := neo.Option["tools"].([]interface{})
+	for _, tool := range tools {
+		tool, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := ""
+		if function, ok := tool["function"].(map[string]interface{}); ok {
+			name, _ = function["name"].(string)
+		}
+		if process, ok := tool["process"].(string); ok && name != "" && process != "" {
+			ast.processes[name] = process
+		}
+	}
+}
 
-// Update update an assistant
-func (ast *OpenAI) Update() {}
+// assistant the Neo assistant of the remote assistant
+func (ast *OpenAI) assistant(remote Remote) assistant.Assistant {
+	id := remote.Metadata[MetadataKey]
+	if id == "" {
+		id = remote.ID
+	}
 
-// Get get an assistant
-func (ast *OpenAI) Get() {}
+	option := map[string]interface{}{"model": remote.Model, "remote_id": remote.ID}
+	if len(remote.Tools) > 0 {
+		tools := []interface{}{}
+		for _, tool := range remote.Tools {
+			tools = append(tools, tool)
+		}
+		option["tools"] = tools
+	}
+	if remote.Temperature != nil {
+		option["temperature"] = *remote.Temperature
+	}
+
+	res := assistant.Assistant{
+		ID:          id,
+		Type:        "assistant",
+		Name:        remote.Name,
+		Description: remote.Description,
+		Connector:   ast.connector,
+		Option:      option,
+		API:         ast,
+	}
+	if remote.Instructions != "" {
+		res.Prompts = []assistant.Prompt{{Role: "system", Content: remote.Instructions}}
+	}
+	return res
+}
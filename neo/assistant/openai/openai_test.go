@@ -0,0 +1,434 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/conversation"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestSync(t *testing.T) {
+	remote := newStub()
+	defer remote.Close()
+	ast := prepare(t, remote.URL)
+
+	neo := assistant.Assistant{
+		ID:      "writer",
+		Name:    "Writer",
+		Prompts: []assistant.Prompt{{Role: "system", Content: "You are a writer."}},
+		Option:  map[string]interface{}{"model": "gpt-4o-mini", "temperature": 0.2},
+	}
+
+	id, err := ast.Sync(context.Background(), neo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "asst_1", id)
+	assert.Equal(t, []string{"GET /v1/assistants?limit=100", "POST /v1/assistants"}, remote.take())
+	assert.Equal(t, "assistants=v2", remote.beta)
+
+	// The remote assistant is not changed
+	_, err = ast.Sync(context.Background(), neo)
+	assert.Nil(t, err)
+	assert.Empty(t, remote.take())
+
+	neo.Name = "Writer 2"
+	_, err = ast.Sync(context.Background(), neo)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"POST /v1/assistants/asst_1"}, remote.take())
+
+	res, err := ast.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "writer", res.ID)
+	assert.Equal(t, "Writer 2", res.Name)
+	assert.Equal(t, "gpt-4o-mini", res.Option["model"])
+	assert.Equal(t, 0.2, res.Option["temperature"])
+	assert.Equal(t, []assistant.Prompt{{Role: "system", Content: "You are a writer."}}, res.Prompts)
+
+	list, err := ast.List(context.Background(), assistant.QueryParam{Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	// The remote assistant is created again after it is deleted
+	assert.Nil(t, ast.Delete(context.Background(), id))
+	remote.take()
+	id, err = ast.Sync(context.Background(), neo)
+	assert.Nil(t, err)
+	assert.Equal(t, "asst_2", id)
+
+	// The remote assistant is opt-in
+	assert.False(t, Enabled(neo))
+	neo.Option["remote"] = true
+	assert.True(t, Enabled(neo))
+}
+
+func TestChat(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	defer capsule.Schema().DropTableIfExists("__unit_test_assistants_history")
+	defer capsule.Schema().DropTableIfExists("__unit_test_assistants_chat")
+	capsule.Schema().DropTableIfExists("__unit_test_assistants_history")
+	capsule.Schema().DropTableIfExists("__unit_test_assistants_chat")
+
+	remote := newStub()
+	defer remote.Close()
+	ast := prepare(t, remote.URL)
+
+	conv, err := conversation.NewXun(conversation.Setting{Connector: "default", Table: "__unit_test_assistants"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast.Conversation = conv
+
+	_, err = ast.Sync(context.Background(), assistant.Assistant{ID: "writer", Name: "Writer", Prompts: []assistant.Prompt{{Role: "system", Content: "You are a writer."}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.take()
+
+	// The prompts synced as the instructions are not the additional instructions
+	ctx := assistant.WithChat(context.Background(), "sid", "chat-1")
+	messages := []map[string]interface{}{
+		{"role": "system", "content": "You are a writer."},
+		{"role": "system", "content": "Reply in English."},
+		{"role": "user", "content": "Hi"},
+		{"role": "assistant", "content": "Hello!"},
+		{"role": "user", "content": "Say hello", "name": "sid"},
+	}
+
+	text, done := collect(t, ast, ctx, messages)
+	assert.Equal(t, "Hello world!", text)
+	assert.True(t, done)
+	assert.Equal(t, []string{"POST /v1/threads", "POST /v1/threads/thread_1/messages", "POST /v1/threads/thread_1/runs"}, remote.take())
+	assert.Equal(t, "Reply in English.", remote.run["additional_instructions"])
+	assert.Equal(t, "asst_1", remote.run["assistant_id"])
+
+	// The uploaded file is attached to the next message of the chat
+	file, err := ast.Upload(ctx, header("notes.txt", 5), strings.NewReader("notes"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasSuffix(file.ID, "/file-1.txt"))
+	assert.Equal(t, "notes", remote.files["file-1"])
+	remote.take()
+
+	messages = append(messages, map[string]interface{}{"role": "assistant", "content": "Hello world!"}, map[string]interface{}{"role": "user", "content": "Read the notes"})
+	text, _ = collect(t, ast, ctx, messages)
+	assert.Equal(t, "Hello world!", text)
+	assert.Equal(t, []string{"POST /v1/threads/thread_1/messages", "POST /v1/threads/thread_1/runs"}, remote.take())
+
+	thread := remote.threads["thread_1"]
+	assert.Len(t, thread, 6)
+	assert.Equal(t, "file-1", thread[4]["attachments"].([]interface{})[0].(map[string]interface{})["file_id"])
+
+	// The thread is stored with the chat and scoped by the session
+	threads, err := ast.ThreadList("sid")
+	assert.Nil(t, err)
+	assert.Equal(t, []Thread{{ID: "thread_1", ChatID: "chat-1"}}, threads)
+
+	threads, err = ast.ThreadList("other")
+	assert.Nil(t, err)
+	assert.Empty(t, threads)
+
+	// The thread history is mapped to the chat
+	info, err := ast.ChatInfo(context.Background(), "sid", "chat-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "thread_1", info.Chat["thread_id"])
+	assert.Len(t, info.History, 6)
+	assert.Equal(t, "user", info.History[0]["role"])
+	assert.Equal(t, "Hi", info.History[0]["content"])
+	assert.Equal(t, "Read the notes", info.History[4]["content"])
+
+	// The failed run returns the error
+	remote.fail = true
+	err = ast.Chat(ctx, messages, nil, func(data []byte) int { return 1 })
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Rate limit reached")
+}
+
+func TestChatTools(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	defer capsule.Schema().DropTableIfExists("__unit_test_assistants_tools_history")
+	defer capsule.Schema().DropTableIfExists("__unit_test_assistants_tools_chat")
+	capsule.Schema().DropTableIfExists("__unit_test_assistants_tools_history")
+	capsule.Schema().DropTableIfExists("__unit_test_assistants_tools_chat")
+
+	process.Register("unit.neo.weather", func(process *process.Process) interface{} {
+		args := process.ArgsMap(0)
+		return map[string]interface{}{"city": args["city"], "sid": process.Sid, "temperature": 20}
+	})
+
+	remote := newStub()
+	defer remote.Close()
+	ast := prepare(t, remote.URL)
+
+	conv, err := conversation.NewXun(conversation.Setting{Connector: "default", Table: "__unit_test_assistants_tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast.Conversation = conv
+
+	weather := map[string]interface{}{
+		"type":     "function",
+		"function": map[string]interface{}{"name": "weather", "parameters": map[string]interface{}{"type": "object"}},
+		"process":  "unit.neo.weather",
+	}
+	_, err = ast.Sync(context.Background(), assistant.Assistant{ID: "weather", Name: "Weather", Option: map[string]interface{}{"tools": []interface{}{weather}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The process of the function is not sent to the remote assistant
+	tool := remote.assistants["asst_1"]["tools"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "function", tool["type"])
+	assert.Nil(t, tool["process"])
+	remote.take()
+
+	// The function call runs the process and the output is submitted
+	remote.tool = "weather"
+	ctx := assistant.WithChat(context.Background(), "sid", "chat-1")
+	text, done := collect(t, ast, ctx, []map[string]interface{}{{"role": "user", "content": "The weather of Paris"}})
+	assert.Equal(t, "Hello world!", text)
+	assert.True(t, done)
+	assert.Equal(t, []string{
+		"POST /v1/threads", "POST /v1/threads/thread_1/messages", "POST /v1/threads/thread_1/runs",
+		"POST /v1/threads/thread_1/runs/run_1/submit_tool_outputs",
+	}, remote.take())
+
+	outputs := remote.run["tool_outputs"].([]interface{})
+	assert.Len(t, outputs, 1)
+	assert.Equal(t, "call_1", outputs[0].(map[string]interface{})["tool_call_id"])
+	assert.JSONEq(t, `{"city": "Paris", "sid": "sid", "temperature": 20}`, outputs[0].(map[string]interface{})["output"].(string))
+
+	// The run is cancelled if the function has no process
+	remote.tool = "unknown"
+	err = ast.Chat(ctx, []map[string]interface{}{{"role": "user", "content": "The weather of Rome"}}, nil, func(data []byte) int { return 1 })
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the function unknown has no process")
+	assert.Contains(t, remote.take(), "POST /v1/threads/thread_1/runs/run_1/cancel")
+}
+
+func prepare(t *testing.T, host string) *OpenAI {
+	conn, err := connector.New("openai", "test.assistants", []byte(fmt.Sprintf(`{"name": "Assistants", "options": {"model": "gpt-4o", "key": "sk-test", "host": %q}}`, host)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ast, err := New(conn, "test.assistants")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncMutex.Lock()
+	synced = map[string]syncState{}
+	syncMutex.Unlock()
+	attachmentMutex.Lock()
+	attachments = map[string][]string{}
+	attachmentMutex.Unlock()
+	return ast
+}
+
+// collect the text of the OpenAI chunks
+func collect(t *testing.T, ast *OpenAI, ctx context.Context, messages []map[string]interface{}) (string, bool) {
+	text := ""
+	done := false
+	err := ast.Chat(ctx, messages, nil, func(data []byte) int {
+		if string(data) == "data: [DONE]" {
+			done = true
+			return 0
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta map[string]interface{} `json:"delta"`
+			} `json:"choices"`
+		}
+		err := jsoniter.Unmarshal(data[6:], &chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, ok := chunk.Choices[0].Delta["content"].(string); ok {
+			text = text + content
+		}
+		return 1
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	return text, done
+}
+
+func header(filename string, size int64) *multipart.FileHeader {
+	return &multipart.FileHeader{Filename: filename, Size: size, Header: textproto.MIMEHeader{"Content-Type": {"text/plain"}}}
+}
+
+// stub the stub of the assistants API
+type stub struct {
+	*httptest.Server
+	assistants map[string]map[string]interface{}
+	threads    map[string][]map[string]interface{}
+	files      map[string]string
+	requests   []string
+	run        map[string]interface{}
+	beta       string
+	tool       string // the function called by the run
+	fail       bool
+	count      int
+	mutex      sync.Mutex
+}
+
+func newStub() *stub {
+	r := &stub{assistants: map[string]map[string]interface{}{}, threads: map[string][]map[string]interface{}{}, files: map[string]string{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+func (r *stub) take() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	requests := r.requests
+	r.requests = []string{}
+	return requests
+}
+
+func (r *stub) serve(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requests = append(r.requests, req.Method+" "+req.URL.RequestURI())
+	r.beta = req.Header.Get("OpenAI-Beta")
+
+	payload := map[string]interface{}{}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		body, _ := io.ReadAll(req.Body)
+		jsoniter.Unmarshal(body, &payload)
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")[1:]
+	switch {
+	case parts[0] == "assistants" && len(parts) == 1 && req.Method == "GET":
+		data := []interface{}{}
+		for _, ast := range r.assistants {
+			data = append(data, ast)
+		}
+		r.json(w, 200, map[string]interface{}{"object": "list", "data": data, "has_more": false})
+
+	case parts[0] == "assistants" && len(parts) == 1:
+		r.count++
+		payload["id"] = fmt.Sprintf("asst_%d", r.count)
+		r.assistants[payload["id"].(string)] = payload
+		r.json(w, 200, payload)
+
+	case parts[0] == "assistants" && r.assistants[parts[1]] == nil:
+		r.json(w, 404, map[string]interface{}{"error": map[string]interface{}{"message": "No assistant found"}})
+
+	case parts[0] == "assistants" && req.Method == "GET":
+		r.json(w, 200, r.assistants[parts[1]])
+
+	case parts[0] == "assistants" && req.Method == "DELETE":
+		delete(r.assistants, parts[1])
+		r.json(w, 200, map[string]interface{}{"id": parts[1], "deleted": true})
+
+	case parts[0] == "assistants":
+		payload["id"] = parts[1]
+		r.assistants[parts[1]] = payload
+		r.json(w, 200, payload)
+
+	case parts[0] == "threads" && len(parts) == 1:
+		id := fmt.Sprintf("thread_%d", len(r.threads)+1)
+		r.threads[id] = []map[string]interface{}{}
+		if messages, ok := payload["messages"].([]interface{}); ok {
+			for _, message := range messages {
+				r.threads[id] = append(r.threads[id], message.(map[string]interface{}))
+			}
+		}
+		r.json(w, 200, map[string]interface{}{"id": id, "created_at": 1730793600, "metadata": payload["metadata"]})
+
+	case parts[0] == "threads" && len(parts) == 3 && parts[2] == "messages" && req.Method == "GET":
+		data := []interface{}{}
+		for i, message := range r.threads[parts[1]] {
+			data = append(data, map[string]interface{}{
+				"id": fmt.Sprintf("msg_%d", i), "role": message["role"], "created_at": 1730793600,
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": map[string]interface{}{"value": message["content"]}}},
+			})
+		}
+		r.json(w, 200, map[string]interface{}{"data": data, "has_more": false})
+
+	case parts[0] == "threads" && len(parts) == 3 && parts[2] == "messages":
+		r.threads[parts[1]] = append(r.threads[parts[1]], payload)
+		r.json(w, 200, map[string]interface{}{"id": "msg"})
+
+	case parts[0] == "threads" && len(parts) == 5 && parts[4] == "cancel":
+		r.json(w, 200, map[string]interface{}{"id": parts[3], "status": "cancelling"})
+
+	case parts[0] == "threads" && len(parts) == 3 && parts[2] == "runs" && r.tool != "":
+		r.run = payload
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(200)
+		w.Write([]byte("event: thread.run.created\ndata: {\"id\":\"run_1\",\"status\":\"queued\"}\n\n"))
+		w.Write([]byte("event: thread.run.step.created\ndata: {\"id\":\"step_1\",\"type\":\"tool_calls\"}\n\n"))
+		w.Write([]byte("event: thread.run.requires_action\ndata: {\"id\":\"run_1\",\"status\":\"requires_action\",\"required_action\":{\"type\":\"submit_tool_outputs\",\"submit_tool_outputs\":{\"tool_calls\":[{\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"" + r.tool + "\",\"arguments\":\"{\\\"city\\\":\\\"Paris\\\"}\"}}]}}}\n\n"))
+		w.Write([]byte("event: done\ndata: [DONE]\n\n"))
+
+	case parts[0] == "threads" && (len(parts) == 3 && parts[2] == "runs" || len(parts) == 5 && parts[4] == "submit_tool_outputs"):
+		r.run = payload
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(200)
+		w.Write([]byte("event: thread.run.created\ndata: {\"id\":\"run_1\",\"status\":\"queued\"}\n\n"))
+		if r.fail {
+			w.Write([]byte("event: thread.run.failed\ndata: {\"id\":\"run_1\",\"status\":\"failed\",\"last_error\":{\"code\":\"rate_limit_exceeded\",\"message\":\"Rate limit reached\"}}\n\n"))
+			return
+		}
+		w.Write([]byte("event: thread.message.delta\ndata: {\"id\":\"msg_1\",\"delta\":{\"content\":[{\"index\":0,\"type\":\"text\",\"text\":{\"value\":\"Hello\"}}]}}\n\n"))
+		w.Write([]byte("event: thread.message.delta\ndata: {\"id\":\"msg_1\",\"delta\":{\"content\":[{\"index\":0,\"type\":\"text\",\"text\":{\"value\":\" world!\"}}]}}\n\n"))
+		w.Write([]byte("event: thread.run.completed\ndata: {\"id\":\"run_1\",\"status\":\"completed\",\"usage\":{\"prompt_tokens\":20,\"completion_tokens\":3,\"total_tokens\":23}}\n\n"))
+		w.Write([]byte("event: done\ndata: [DONE]\n\n"))
+		r.threads[parts[1]] = append(r.threads[parts[1]], map[string]interface{}{"role": "assistant", "content": "Hello world!"})
+
+	case parts[0] == "files" && req.Method == "POST":
+		file, _, err := req.FormFile("file")
+		if err != nil || req.FormValue("purpose") != "assistants" {
+			r.json(w, 400, map[string]interface{}{"error": map[string]interface{}{"message": "invalid file"}})
+			return
+		}
+		var buf bytes.Buffer
+		io.Copy(&buf, file)
+		id := fmt.Sprintf("file-%d", len(r.files)+1)
+		r.files[id] = buf.String()
+		r.json(w, 200, map[string]interface{}{"id": id, "bytes": buf.Len(), "filename": "notes.txt", "purpose": "assistants"})
+
+	default:
+		r.json(w, 404, map[string]interface{}{"error": map[string]interface{}{"message": "not found"}})
+	}
+}
+
+func (r *stub) json(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body, _ := jsoniter.Marshal(data)
+	w.Write(body)
+}
@@ -1,21 +1,228 @@
 package openai
 
-// Thread the thread struct
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/yao/neo/conversation"
+)
+
+// Thread the thread of the chat
 type Thread struct {
-	ID string `json:"thread_id"`
+	ID        string            `json:"thread_id"`
+	ChatID    string            `json:"chat_id,omitempty"`
+	CreatedAt int64             `json:"created_at,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// threadOf the thread of the chat, the thread id is stored with the chat in the conversation
+func (ast *OpenAI) threadOf(sid string, chatID string) (string, error) {
+	if ast.Conversation == nil || chatID == "" {
+		return "", nil
+	}
+	return ast.Conversation.GetThread(sid, chatID)
+}
+
+// ThreadList list the threads of the chats of the session, the assistants API does not list the threads
+func (ast *OpenAI) ThreadList(sid string) ([]Thread, error) {
+	res := []Thread{}
+	if ast.Conversation == nil {
+		return res, nil
+	}
+
+	threads, err := ast.Conversation.GetThreads(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	for chatID, id := range threads {
+		res = append(res, Thread{ID: id, ChatID: chatID})
+	}
+	return res, nil
+}
+
+// ThreadCreate create a new thread of the chat, the user and the assistant messages are added to the thread
+func (ast *OpenAI) ThreadCreate(ctx context.Context, sid string, chatID string, messages []map[string]interface{}) (*Thread, error) {
+	payload := map[string]interface{}{}
+	if history := threadMessages(messages); len(history) > 0 {
+		payload["messages"] = history
+	}
+	if chatID != "" {
+		payload["metadata"] = map[string]string{"chat_id": chatID}
+	}
+
+	var res struct {
+		ID        string            `json:"id"`
+		CreatedAt int64             `json:"created_at"`
+		Metadata  map[string]string `json:"metadata"`
+	}
+	err := ast.request(ctx, "POST", "/v1/threads", payload, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &Thread{ID: res.ID, ChatID: chatID, CreatedAt: res.CreatedAt, Metadata: res.Metadata}
+	if ast.Conversation != nil && chatID != "" {
+		err = ast.Conversation.SaveThread(sid, chatID, thread.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return thread, nil
+}
+
+// ThreadGet get the thread
+func (ast *OpenAI) ThreadGet(ctx context.Context, id string) (*Thread, error) {
+	var res struct {
+		ID        string            `json:"id"`
+		CreatedAt int64             `json:"created_at"`
+		Metadata  map[string]string `json:"metadata"`
+	}
+	err := ast.request(ctx, "GET", "/v1/threads/"+url.PathEscape(id), nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &Thread{ID: res.ID, ChatID: res.Metadata["chat_id"], CreatedAt: res.CreatedAt, Metadata: res.Metadata}, nil
+}
+
+// ThreadDelete delete the thread of the chat, the chat gets a new thread in the next chat
+func (ast *OpenAI) ThreadDelete(ctx context.Context, sid string, chatID string) error {
+	id, err := ast.threadOf(sid, chatID)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return nil
+	}
+
+	err = ast.request(ctx, "DELETE", "/v1/threads/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return err
+	}
+	return ast.Conversation.SaveThread(sid, chatID, "")
+}
+
+// ThreadUpdate update the metadata of the thread
+func (ast *OpenAI) ThreadUpdate(ctx context.Context, id string, metadata map[string]string) error {
+	return ast.request(ctx, "POST", "/v1/threads/"+url.PathEscape(id), map[string]interface{}{"metadata": metadata}, nil)
 }
 
-// ThreadList list all threads
-func (ast *OpenAI) ThreadList() {}
+// ThreadMessages the messages of the thread in the order of the creation, in the format of the Neo history
+func (ast *OpenAI) ThreadMessages(ctx context.Context, id string) ([]map[string]interface{}, error) {
+	history := []map[string]interface{}{}
+	after := ""
+	for {
+		query := url.Values{"order": {"asc"}, "limit": {"100"}}
+		if after != "" {
+			query.Set("after", after)
+		}
 
-// ThreadCreate create a new thread
-func (ast *OpenAI) ThreadCreate() {}
+		var res struct {
+			Data []struct {
+				ID          string `json:"id"`
+				Role        string `json:"role"`
+				CreatedAt   int64  `json:"created_at"`
+				AssistantID string `json:"assistant_id"`
+				Content     []struct {
+					Type string `json:"type"`
+					Text struct {
+						Value string `json:"value"`
+					} `json:"text"`
+				} `json:"content"`
+			} `json:"data"`
+			HasMore bool `json:"has_more"`
+		}
 
-// ThreadGet get a thread
-func (ast *OpenAI) ThreadGet(id string) {}
+		err := ast.request(ctx, "GET", fmt.Sprintf("/v1/threads/%s/messages?%s", url.PathEscape(id), query.Encode()), nil, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range res.Data {
+			texts := []string{}
+			for _, content := range message.Content {
+				if content.Type == "text" {
+					texts = append(texts, content.Text.Value)
+				}
+			}
+
+			item := map[string]interface{}{
+				"role":       message.Role,
+				"content":    strings.Join(texts, "\n"),
+				"created_at": time.Unix(message.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+			}
+			if message.AssistantID != "" {
+				item["assistant_id"] = message.AssistantID
+			}
+			history = append(history, item)
+		}
+
+		if !res.HasMore || len(res.Data) == 0 {
+			return history, nil
+		}
+		after = res.Data[len(res.Data)-1].ID
+	}
+}
 
-// ThreadDelete delete a thread
-func (ast *OpenAI) ThreadDelete() {}
+// ChatInfo the chat of the thread, the messages of the thread are the history
+func (ast *OpenAI) ChatInfo(ctx context.Context, sid string, chatID string) (*conversation.ChatInfo, error) {
+	id, err := ast.threadOf(sid, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, fmt.Errorf("the chat %s has no thread", chatID)
+	}
 
-// ThreadUpdate update a thread
-func (ast *OpenAI) ThreadUpdate() {}
+	history, err := ast.ThreadMessages(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversation.ChatInfo{
+		Chat:    map[string]interface{}{"chat_id": chatID, "thread_id": id, "assistant_id": ast.RemoteID},
+		History: history,
+	}, nil
+}
+
+// threadMessages the user and the assistant messages, the other roles are not supported by the threads
+func threadMessages(messages []map[string]interface{}) []map[string]interface{} {
+	res := []map[string]interface{}{}
+	for _, message := range messages {
+		role, _ := message["role"].(string)
+		if role != "user" && role != "assistant" {
+			continue
+		}
+
+		content := text(message["content"])
+		if content == "" {
+			continue
+		}
+		res = append(res, map[string]interface{}{"role": role, "content": content})
+	}
+	return res
+}
+
+// text the text of the message content, the text parts are joined if the content is an array
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		texts := []string{}
+		for _, part := range v {
+			if p, ok := part.(map[string]interface{}); ok {
+				if t, ok := p["text"].(string); ok {
+					texts = append(texts, t)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
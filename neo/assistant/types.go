@@ -15,9 +15,10 @@ type API interface {
 
 // Prompt a prompt
 type Prompt struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-	Name    string `json:"name,omitempty"`
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Name     string `json:"name,omitempty"`
+	Template bool   `json:"template,omitempty"` // Render the content as a template, the content is the literal text if false
 }
 
 // QueryParam the assistant query param
@@ -39,6 +40,8 @@ type Assistant struct {
 	Option      map[string]interface{}   `json:"option,omitempty"`      // AI Option
 	Prompts     []Prompt                 `json:"prompts,omitempty"`     // AI Prompts
 	Flows       []map[string]interface{} `json:"flows,omitempty"`       // Assistant Flows
+	Collections []string                 `json:"collections,omitempty"` // The collections of the knowledge base, the default value is the assistant id
+	Scope       string                   `json:"scope,omitempty"`       // The scope of the uploaded files in the knowledge base: user (default), chat or assistant
 	API         API                      `json:"-" yaml:"-"`            // Assistant API
 }
 
@@ -28,6 +28,31 @@ func (m *Mongo) SaveHistory(sid string, messages []map[string]interface{}, cid s
 	return nil
 }
 
+// GetSummary retrieves the rolling summary of a chat
+func (m *Mongo) GetSummary(sid string, cid string) (*Summary, error) {
+	return nil, nil
+}
+
+// SaveSummary saves the rolling summary of a chat
+func (m *Mongo) SaveSummary(sid string, cid string, summary Summary) error {
+	return nil
+}
+
+// GetThread retrieves the remote thread of a chat
+func (m *Mongo) GetThread(sid string, cid string) (string, error) {
+	return "", nil
+}
+
+// SaveThread saves the remote thread of a chat
+func (m *Mongo) SaveThread(sid string, cid string, threadID string) error {
+	return nil
+}
+
+// GetThreads retrieves the remote threads of the chats of a session
+func (m *Mongo) GetThreads(sid string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
 // DeleteChat deletes a single chat
 func (m *Mongo) DeleteChat(sid string, cid string) error {
 	return nil
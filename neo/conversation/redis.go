@@ -28,6 +28,31 @@ func (r *Redis) SaveHistory(sid string, messages []map[string]interface{}, cid s
 	return nil
 }
 
+// GetSummary retrieves the rolling summary of a chat
+func (r *Redis) GetSummary(sid string, cid string) (*Summary, error) {
+	return nil, nil
+}
+
+// SaveSummary saves the rolling summary of a chat
+func (r *Redis) SaveSummary(sid string, cid string, summary Summary) error {
+	return nil
+}
+
+// GetThread retrieves the remote thread of a chat
+func (r *Redis) GetThread(sid string, cid string) (string, error) {
+	return "", nil
+}
+
+// SaveThread saves the remote thread of a chat
+func (r *Redis) SaveThread(sid string, cid string, threadID string) error {
+	return nil
+}
+
+// GetThreads retrieves the remote threads of the chats of a session
+func (r *Redis) GetThreads(sid string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
 // DeleteChat deletes a single chat
 func (r *Redis) DeleteChat(sid string, cid string) error {
 	return nil
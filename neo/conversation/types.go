@@ -1,5 +1,7 @@
 package conversation
 
+import "time"
+
 // Setting represents the conversation configuration structure
 // Used to configure basic conversation parameters including connector, user field, table name, etc.
 type Setting struct {
@@ -17,6 +19,13 @@ type ChatInfo struct {
 	History []map[string]interface{} `json:"history"` // Chat history records
 }
 
+// Summary represents the rolling summary of a chat
+// The history messages created before or at Until are replaced by the summary
+type Summary struct {
+	Content string    `json:"content"` // The summary of the earlier messages
+	Until   time.Time `json:"until"`   // The created_at of the last summarized message
+}
+
 // ChatFilter represents the chat filter structure
 // Used for filtering and pagination when retrieving chat lists
 type ChatFilter struct {
@@ -98,6 +107,37 @@ type Conversation interface {
 	// Returns: Potential error
 	SaveHistory(sid string, messages []map[string]interface{}, cid string, context map[string]interface{}) error
 
+	// GetSummary retrieves the rolling summary of a chat
+	// sid: Session ID
+	// cid: Chat ID
+	// Returns: The summary, nil if the chat is not summarized, and potential error
+	GetSummary(sid string, cid string) (*Summary, error)
+
+	// SaveSummary saves the rolling summary of a chat
+	// sid: Session ID
+	// cid: Chat ID
+	// summary: The summary
+	// Returns: Potential error
+	SaveSummary(sid string, cid string, summary Summary) error
+
+	// GetThread retrieves the remote thread of a chat
+	// sid: Session ID
+	// cid: Chat ID
+	// Returns: The thread id, empty if the chat has no thread, and potential error
+	GetThread(sid string, cid string) (string, error)
+
+	// SaveThread saves the remote thread of a chat, the chat is created if it does not exist
+	// sid: Session ID
+	// cid: Chat ID
+	// threadID: The thread id, empty to unbind the thread
+	// Returns: Potential error
+	SaveThread(sid string, cid string, threadID string) error
+
+	// GetThreads retrieves the remote threads of the chats of a session
+	// sid: Session ID
+	// Returns: The thread ids keyed by the chat id and potential error
+	GetThreads(sid string) (map[string]string, error)
+
 	// DeleteChat deletes a single chat
 	// sid: Session ID
 	// cid: Chat ID
@@ -28,6 +28,31 @@ func (w *Weaviate) SaveHistory(sid string, messages []map[string]interface{}, ci
 	return nil
 }
 
+// GetSummary retrieves the rolling summary of a chat
+func (w *Weaviate) GetSummary(sid string, cid string) (*Summary, error) {
+	return nil, nil
+}
+
+// SaveSummary saves the rolling summary of a chat
+func (w *Weaviate) SaveSummary(sid string, cid string, summary Summary) error {
+	return nil
+}
+
+// GetThread retrieves the remote thread of a chat
+func (w *Weaviate) GetThread(sid string, cid string) (string, error) {
+	return "", nil
+}
+
+// SaveThread saves the remote thread of a chat
+func (w *Weaviate) SaveThread(sid string, cid string, threadID string) error {
+	return nil
+}
+
+// GetThreads retrieves the remote threads of the chats of a session
+func (w *Weaviate) GetThreads(sid string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
 // DeleteChat deletes a single chat
 func (w *Weaviate) DeleteChat(sid string, cid string) error {
 	return nil
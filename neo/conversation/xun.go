@@ -181,6 +181,9 @@ func (conv *Xun) initChatTable() error {
 			table.String("chat_id", 200).Unique().Index()
 			table.String("title", 200).Null()
 			table.String("sid", 255).Index()
+			table.Text("summary").Null()
+			table.TimestampTz("summarized_at").Null()
+			table.String("thread_id", 200).Null().Index()
 			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
 			table.TimestampTz("updated_at").Null().Index()
 		})
@@ -204,6 +207,33 @@ func (conv *Xun) initChatTable() error {
 		}
 	}
 
+	// Add the summary columns to the chat tables created by the earlier versions
+	if !tab.HasColumn("summary") || !tab.HasColumn("summarized_at") {
+		err = conv.schema.AlterTable(chatTable, func(table schema.Blueprint) {
+			if !tab.HasColumn("summary") {
+				table.Text("summary").Null()
+			}
+			if !tab.HasColumn("summarized_at") {
+				table.TimestampTz("summarized_at").Null()
+			}
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Add the summary columns to the chat table: %s", chatTable)
+	}
+
+	// Add the thread column to the chat tables created by the earlier versions
+	if !tab.HasColumn("thread_id") {
+		err = conv.schema.AlterTable(chatTable, func(table schema.Blueprint) {
+			table.String("thread_id", 200).Null().Index()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Add the thread column to the chat table: %s", chatTable)
+	}
+
 	return nil
 }
 
@@ -575,6 +605,139 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 	return nil
 }
 
+// GetSummary get the rolling summary of the chat
+func (conv *Xun) GetSummary(sid string, cid string) (*Summary, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := conv.newQueryChat().
+		Select("summary", "summarized_at").
+		Where("sid", userID).
+		Where("chat_id", cid).
+		First()
+	if err != nil {
+		return nil, err
+	}
+
+	content, ok := row.Get("summary").(string)
+	if !ok || content == "" {
+		return nil, nil
+	}
+
+	until, ok := parseTime(row.Get("summarized_at"))
+	if !ok {
+		return nil, nil
+	}
+
+	return &Summary{Content: content, Until: until}, nil
+}
+
+// SaveSummary save the rolling summary of the chat
+func (conv *Xun) SaveSummary(sid string, cid string, summary Summary) error {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	_, err = conv.newQueryChat().
+		Where("sid", userID).
+		Where("chat_id", cid).
+		Update(map[string]interface{}{
+			"summary":       summary.Content,
+			"summarized_at": summary.Until,
+			"updated_at":    time.Now(),
+		})
+	return err
+}
+
+// GetThread get the remote thread of the chat
+func (conv *Xun) GetThread(sid string, cid string) (string, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return "", err
+	}
+
+	row, err := conv.newQueryChat().
+		Select("thread_id").
+		Where("sid", userID).
+		Where("chat_id", cid).
+		First()
+	if err != nil {
+		return "", err
+	}
+
+	threadID, _ := row.Get("thread_id").(string)
+	return threadID, nil
+}
+
+// SaveThread save the remote thread of the chat, the chat is created if it does not exist
+func (conv *Xun) SaveThread(sid string, cid string, threadID string) error {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	exists, err := conv.newQueryChat().
+		Where("chat_id", cid).
+		Where("sid", userID).
+		Exists()
+	if err != nil {
+		return err
+	}
+
+	var value interface{} = threadID
+	if threadID == "" {
+		value = nil
+	}
+
+	if !exists {
+		return conv.newQueryChat().
+			Insert(map[string]interface{}{
+				"chat_id":    cid,
+				"sid":        userID,
+				"thread_id":  value,
+				"created_at": time.Now(),
+			})
+	}
+
+	_, err = conv.newQueryChat().
+		Where("sid", userID).
+		Where("chat_id", cid).
+		Update(map[string]interface{}{
+			"thread_id":  value,
+			"updated_at": time.Now(),
+		})
+	return err
+}
+
+// GetThreads get the remote threads of the chats of the session
+func (conv *Xun) GetThreads(sid string) (map[string]string, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conv.newQueryChat().
+		Select("chat_id", "thread_id").
+		Where("sid", userID).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	threads := map[string]string{}
+	for _, row := range rows {
+		chatID, _ := row.Get("chat_id").(string)
+		threadID, _ := row.Get("thread_id").(string)
+		if chatID != "" && threadID != "" {
+			threads[chatID] = threadID
+		}
+	}
+	return threads, nil
+}
+
 // GetChat get the chat info and its history
 func (conv *Xun) GetChat(sid string, cid string) (*ChatInfo, error) {
 	userID, err := conv.getUserID(sid)
@@ -923,3 +1086,18 @@ func (conv *Xun) GetAssistants(filter AssistantFilter) (*AssistantResponse, erro
 		Total:    total,
 	}, nil
 }
+
+// parseTime parse the timestamp column, the drivers return the time or the string
+func parseTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -231,6 +231,109 @@ func TestXunSaveAndGetHistory(t *testing.T) {
 	assert.Equal(t, 2, len(data))
 }
 
+func TestXunSummary(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_history")
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_chat")
+
+	err := capsule.Schema().DropTableIfExists("__unit_test_conversation_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = capsule.Schema().DropTableIfExists("__unit_test_conversation_chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conv, err := NewXun(Setting{
+		Connector: "default",
+		Table:     "__unit_test_conversation",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cid := "summary-chat"
+	err = conv.SaveHistory("123456", []map[string]interface{}{
+		{"role": "user", "content": "My name is Max"},
+		{"role": "assistant", "content": "Nice to meet you, Max"},
+	}, cid, nil)
+	assert.Nil(t, err)
+
+	// not summarized
+	summary, err := conv.GetSummary("123456", cid)
+	assert.Nil(t, err)
+	assert.Nil(t, summary)
+
+	until := time.Now().Truncate(time.Second)
+	err = conv.SaveSummary("123456", cid, Summary{Content: "The user is Max.", Until: until})
+	assert.Nil(t, err)
+
+	summary, err = conv.GetSummary("123456", cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, summary)
+	assert.Equal(t, "The user is Max.", summary.Content)
+	assert.True(t, until.Equal(summary.Until))
+}
+
+func TestXunThread(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_history")
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_chat")
+
+	err := capsule.Schema().DropTableIfExists("__unit_test_conversation_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = capsule.Schema().DropTableIfExists("__unit_test_conversation_chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conv, err := NewXun(Setting{
+		Connector: "default",
+		Table:     "__unit_test_conversation",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no thread
+	threadID, err := conv.GetThread("123456", "thread-chat")
+	assert.Nil(t, err)
+	assert.Empty(t, threadID)
+
+	// the chat is created with the thread
+	err = conv.SaveThread("123456", "thread-chat", "thread_abc")
+	assert.Nil(t, err)
+
+	threadID, err = conv.GetThread("123456", "thread-chat")
+	assert.Nil(t, err)
+	assert.Equal(t, "thread_abc", threadID)
+
+	// the threads are scoped by the session
+	err = conv.SaveThread("654321", "other-chat", "thread_xyz")
+	assert.Nil(t, err)
+
+	threads, err := conv.GetThreads("123456")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"thread-chat": "thread_abc"}, threads)
+
+	// unbind the thread
+	err = conv.SaveThread("123456", "thread-chat", "")
+	assert.Nil(t, err)
+
+	threadID, err = conv.GetThread("123456", "thread-chat")
+	assert.Nil(t, err)
+	assert.Empty(t, threadID)
+}
+
 func TestXunSaveAndGetHistoryWithCID(t *testing.T) {
 	test.Prepare(t, config.Conf)
 	defer test.Clean()
@@ -0,0 +1,346 @@
+package neo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/llm"
+	"github.com/yaoapp/yao/neo/conversation"
+	"github.com/yaoapp/yao/neo/rag"
+	"github.com/yaoapp/yao/usage"
+)
+
+// History the strategy of the conversation history sent to the assistant
+//
+//	history:
+//	  strategy: summary # all|last|tokens|summary, the default value is all
+//	  messages: 20      # last: the max number of the history messages
+//	  max_tokens: 6000  # tokens, summary: the token budget of the messages, the default value is the max token of the connector minus the reserve
+//	  reserve: 1024     # tokens, summary: the tokens reserved for the reply
+//	  keep: 6           # summary: the recent messages kept verbatim, the older ones are summarized
+type History struct {
+	Strategy  string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Messages  int    `json:"messages,omitempty" yaml:"messages,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	Reserve   int    `json:"reserve,omitempty" yaml:"reserve,omitempty"`
+	Keep      int    `json:"keep,omitempty" yaml:"keep,omitempty"`
+	Connector string `json:"connector,omitempty" yaml:"connector,omitempty"` // The connector to summarize, the default value is the connector of neo
+	Prompt    string `json:"prompt,omitempty" yaml:"prompt,omitempty"`       // summary: the system prompt of the summarization
+}
+
+// defaultSummaryPrompt the system prompt of the summarization
+const defaultSummaryPrompt = "Summarize the conversation between the user and the assistant. " +
+	"Merge the previous summary with the new messages, keep the facts, the decisions, the names and the numbers the assistant needs to continue the conversation. " +
+	"Reply with the summary only, in the language of the conversation."
+
+// summarizing the chats being summarized, the chat is summarized by one goroutine at a time
+var summarizing = sync.Map{}
+
+// validate the history setting
+func (h History) validate() error {
+	switch h.Strategy {
+	case "", "all", "last", "tokens", "summary":
+	default:
+		return fmt.Errorf("history the strategy %s is invalid, all, last, tokens or summary", h.Strategy)
+	}
+
+	if h.Messages < 0 || h.MaxTokens < 0 || h.Reserve < 0 || h.Keep < 0 {
+		return fmt.Errorf("history the messages, max_tokens, reserve and keep should not be negative")
+	}
+	return nil
+}
+
+// boundMessages bound the history of the messages by the strategy, the last message is the user input and it is always kept.
+// The prompts are sent before the messages, they and the knowledge message of the assistant are counted in the token budget.
+// The tokens are counted by the connector of the selected assistant, see assistantConnector.
+func (neo *DSL) boundMessages(ctx Context, connector string, prompts []map[string]interface{}, messages []map[string]interface{}) []map[string]interface{} {
+	if len(messages) == 0 {
+		return messages
+	}
+
+	history := messages[:len(messages)-1]
+	input := messages[len(messages)-1]
+
+	switch neo.History.Strategy {
+	case "last":
+		max := neo.History.Messages
+		if max <= 0 {
+			max = 20
+		}
+		history = lastMessages(history, max)
+
+	case "tokens":
+		count, budget := neo.tokenBudget(connector)
+		budget = budget - messageTokens(input, count) - reservedTokens(prompts, count)
+		history = trimMessages(history, budget, count)
+
+	case "summary":
+		var prefix []map[string]interface{}
+		summary, err := neo.Conversation.GetSummary(ctx.Sid, ctx.ChatID)
+		if err != nil {
+			log.Error("[Neo] get the summary of the chat %s %s", ctx.ChatID, err.Error())
+		}
+
+		if summary != nil {
+			history = after(history, summary.Until)
+			prefix = []map[string]interface{}{{"role": "system", "content": "The summary of the earlier conversation:\n" + summary.Content}}
+		}
+
+		count, budget := neo.tokenBudget(connector)
+		budget = budget - messageTokens(input, count) - reservedTokens(prompts, count)
+		for _, message := range prefix {
+			budget = budget - messageTokens(message, count)
+		}
+		history = append(prefix, trimMessages(history, budget, count)...)
+
+	default:
+		return messages
+	}
+
+	res := make([]map[string]interface{}, 0, len(history)+1)
+	res = append(res, history...)
+	return append(res, input)
+}
+
+// summarize compress the history messages except the recent ones when there are too many, it is called in the background after the chat
+func (neo *DSL) summarize(sid string, cid string) {
+	if neo.History.Strategy != "summary" || sid == "" || cid == "" {
+		return
+	}
+
+	if _, running := summarizing.LoadOrStore(cid, true); running {
+		return
+	}
+	defer summarizing.Delete(cid)
+
+	keep := neo.History.Keep
+	if keep <= 0 {
+		keep = 6
+	}
+
+	history, err := neo.Conversation.GetHistory(sid, cid)
+	if err != nil {
+		log.Error("[Neo] summarize the chat %s %s", cid, err.Error())
+		return
+	}
+
+	summary, err := neo.Conversation.GetSummary(sid, cid)
+	if err != nil {
+		log.Error("[Neo] summarize the chat %s %s", cid, err.Error())
+		return
+	}
+
+	previous := ""
+	if summary != nil {
+		previous = summary.Content
+		history = after(history, summary.Until)
+	}
+
+	// Summarize when the messages are twice the kept ones, so the summary is not called on every message
+	if len(history) <= keep*2 {
+		return
+	}
+
+	older, until, ok := olderMessages(history, keep)
+	if !ok {
+		return
+	}
+
+	lines := []string{}
+	if previous != "" {
+		lines = append(lines, "The previous summary:", previous, "", "The new messages:")
+	}
+	for _, message := range older {
+		lines = append(lines, fmt.Sprintf("%v: %v", message["role"], message["content"]))
+	}
+
+	prompt := neo.History.Prompt
+	if prompt == "" {
+		prompt = defaultSummaryPrompt
+	}
+
+	ai, err := llm.New(neo.historyConnector())
+	if err != nil {
+		log.Error("[Neo] summarize the chat %s %s", cid, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	ctx = usage.WithMeta(ctx, usage.Meta{Sid: sid, Assistant: neo.ID, Source: "neo"})
+
+	res, ex := ai.ChatCompletionsWith(ctx, []map[string]interface{}{
+		{"role": "system", "content": prompt},
+		{"role": "user", "content": strings.Join(lines, "\n")},
+	}, nil, nil)
+	if ex != nil {
+		log.Error("[Neo] summarize the chat %s %s", cid, ex.Message)
+		return
+	}
+
+	content, ex := ai.GetContent(res)
+	if ex != nil {
+		log.Error("[Neo] summarize the chat %s %s", cid, ex.Message)
+		return
+	}
+
+	err = neo.Conversation.SaveSummary(sid, cid, conversation.Summary{Content: strings.TrimSpace(content), Until: until})
+	if err != nil {
+		log.Error("[Neo] save the summary of the chat %s %s", cid, err.Error())
+	}
+}
+
+// tokenBudget the token counter and the token budget of the history and the input, the connector is the one the chat is sent to
+func (neo *DSL) tokenBudget(connector string) (func(string) int, int) {
+	reserve := neo.History.Reserve
+	if reserve <= 0 {
+		reserve = 1024
+	}
+
+	count := func(input string) int { return len(input)/4 + 1 }
+	max := 4096
+	ai, err := llm.New(connector)
+	if err != nil {
+		log.Warn("[Neo] the history tokens are estimated, %s", err.Error())
+	} else {
+		max = ai.MaxToken()
+		count = func(input string) int {
+			n, err := ai.Tiktoken(input)
+			if err != nil {
+				return len(input)/4 + 1
+			}
+			return n
+		}
+	}
+
+	if neo.History.MaxTokens > 0 {
+		return count, neo.History.MaxTokens
+	}
+	return count, max - reserve
+}
+
+// assistantConnector the connector of the selected assistant, the assistant id is a connector if it is not an assistant of neo
+func (neo *DSL) assistantConnector(assistantID string) string {
+	if assistantID == "" {
+		return neo.Connector
+	}
+
+	if ast, has := neo.AssistantMaps[assistantID]; has {
+		return ast.Connector
+	}
+	return assistantID
+}
+
+func (neo *DSL) historyConnector() string {
+	if neo.History.Connector != "" {
+		return neo.History.Connector
+	}
+	return neo.Connector
+}
+
+// lastMessages the last n messages, the leading assistant messages are dropped so the history starts with the user
+func lastMessages(messages []map[string]interface{}, n int) []map[string]interface{} {
+	if len(messages) > n {
+		messages = messages[len(messages)-n:]
+	}
+	return leadingUser(messages)
+}
+
+// trimMessages the latest messages fit the token budget, the older ones are dropped
+func trimMessages(messages []map[string]interface{}, budget int, count func(string) int) []map[string]interface{} {
+	start := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		budget = budget - messageTokens(messages[i], count)
+		if budget < 0 {
+			break
+		}
+		start = i
+	}
+	return leadingUser(messages[start:])
+}
+
+// olderMessages the messages except the kept ones, the messages created at the same time as the last older one are included.
+// The user input and the reply are saved at the same time, they are summarized together.
+func olderMessages(messages []map[string]interface{}, keep int) ([]map[string]interface{}, time.Time, bool) {
+	end := len(messages) - keep
+	if end <= 0 {
+		return nil, time.Time{}, false
+	}
+
+	until, ok := createdAt(messages[end-1])
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	for end < len(messages) {
+		t, ok := createdAt(messages[end])
+		if !ok || t.After(until) {
+			break
+		}
+		end++
+	}
+	return messages[:end], until, true
+}
+
+// after the messages created after the time
+func after(messages []map[string]interface{}, until time.Time) []map[string]interface{} {
+	res := []map[string]interface{}{}
+	for _, message := range messages {
+		if t, ok := createdAt(message); ok && !t.After(until) {
+			continue
+		}
+		res = append(res, message)
+	}
+	return res
+}
+
+// leadingUser drop the leading assistant messages
+func leadingUser(messages []map[string]interface{}) []map[string]interface{} {
+	for len(messages) > 0 && messages[0]["role"] == "assistant" {
+		messages = messages[1:]
+	}
+	return messages
+}
+
+// reservedTokens the tokens of the prompts and the knowledge message inserted before the user input.
+// The knowledge chunks are counted at their max size, 4 characters a token.
+func reservedTokens(prompts []map[string]interface{}, count func(string) int) int {
+	tokens := 0
+	for _, prompt := range prompts {
+		tokens = tokens + messageTokens(prompt, count)
+	}
+
+	knowledge := rag.Default()
+	if knowledge == nil {
+		return tokens
+	}
+	return tokens + messageTokens(rag.Prompt(nil), count) + knowledge.TopK*(knowledge.ChunkSize/4+16) // the chunk, the number and the filename
+}
+
+// messageTokens the tokens of the message, each message takes 4 more tokens
+func messageTokens(message map[string]interface{}, count func(string) int) int {
+	content, ok := message["content"].(string)
+	if !ok {
+		content = fmt.Sprintf("%v", message["content"])
+	}
+	return count(content) + 4
+}
+
+// createdAt the created_at of the history message
+func createdAt(message map[string]interface{}) (time.Time, bool) {
+	switch v := message["created_at"].(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
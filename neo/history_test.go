@@ -0,0 +1,96 @@
+package neo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/conversation"
+)
+
+func TestHistoryLast(t *testing.T) {
+	history := append([]map[string]interface{}{{"role": "assistant", "content": "hello!"}}, historyMessages(6)...)
+	input := map[string]interface{}{"role": "user", "content": "Hi"}
+
+	neo := &DSL{History: History{Strategy: "last", Messages: 3}}
+	messages := neo.boundMessages(Context{}, "", nil, append(history, input))
+	assert.Len(t, messages, 3)
+	assert.Equal(t, "user", messages[0]["role"]) // the leading assistant message is dropped
+	assert.Equal(t, "Hi", messages[2]["content"])
+
+	neo = &DSL{}
+	assert.Len(t, neo.boundMessages(Context{}, "", nil, append(history, input)), 8)
+}
+
+func TestHistoryTokens(t *testing.T) {
+	count := func(input string) int { return len(input) }
+	history := historyMessages(6)
+
+	// each message takes 10 tokens (6 characters and 4 more)
+	trimmed := trimMessages(history, 35, count)
+	assert.Len(t, trimmed, 2)
+	assert.Equal(t, "user", trimmed[0]["role"])
+	assert.Equal(t, history[4]["content"], trimmed[0]["content"])
+
+	assert.Len(t, trimMessages(history, 5, count), 0)
+	assert.Len(t, trimMessages(history, 1000, count), 6)
+
+	// The prompts are counted in the budget
+	input := map[string]interface{}{"role": "user", "content": "Hi"}
+	prompts := []map[string]interface{}{{"role": "system", "content": strings.Repeat("p", 440)}}
+	neo := &DSL{History: History{Strategy: "tokens", MaxTokens: 140}}
+	assert.Len(t, neo.boundMessages(Context{}, "not-found", nil, append(history, input)), 7)
+	assert.Len(t, neo.boundMessages(Context{}, "not-found", prompts, append(history, input)), 3)
+}
+
+func TestAssistantConnector(t *testing.T) {
+	neo := &DSL{Connector: "gpt-4o", AssistantMaps: map[string]assistant.Assistant{"writer": {ID: "writer", Connector: "claude"}}}
+	assert.Equal(t, "gpt-4o", neo.assistantConnector(""))
+	assert.Equal(t, "claude", neo.assistantConnector("writer"))
+	assert.Equal(t, "ollama", neo.assistantConnector("ollama"))
+}
+
+func TestHistorySummary(t *testing.T) {
+	history := historyMessages(8)
+	older, until, ok := olderMessages(history, 3)
+	assert.True(t, ok)
+
+	// the user input and the reply are created at the same time, they are summarized together
+	assert.Len(t, older, 6)
+	assert.Equal(t, history[5]["created_at"], until)
+
+	recent := after(history, until)
+	assert.Len(t, recent, 2)
+	assert.Equal(t, history[6]["content"], recent[0]["content"])
+
+	_, _, ok = olderMessages(history, 8)
+	assert.False(t, ok)
+
+	// the chat is not summarized yet, the messages fit the budget
+	neo := &DSL{History: History{Strategy: "summary", MaxTokens: 1000}, Conversation: conversation.NewRedis()}
+	messages := neo.boundMessages(Context{Sid: "sid", ChatID: "chat"}, "not-found", nil, append(history, map[string]interface{}{"role": "user", "content": "Hi"}))
+	assert.Len(t, messages, 9)
+
+	assert.Nil(t, History{Strategy: "summary"}.validate())
+	assert.NotNil(t, History{Strategy: "window"}.validate())
+}
+
+// historyMessages the user and the assistant messages, each pair is created at the same time
+func historyMessages(n int) []map[string]interface{} {
+	start := time.Date(2024, 11, 1, 8, 0, 0, 0, time.UTC)
+	messages := []map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, map[string]interface{}{
+			"role":       role,
+			"content":    "text-" + string(rune('a'+i)),
+			"created_at": start.Add(time.Duration(i/2) * time.Minute),
+		})
+	}
+	return messages
+}
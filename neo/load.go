@@ -10,6 +10,7 @@ import (
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/neo/assistant"
 	"github.com/yaoapp/yao/neo/conversation"
+	"github.com/yaoapp/yao/neo/rag"
 )
 
 // Neo the neo AI assistant
@@ -43,6 +44,21 @@ func Load(cfg config.Config) error {
 		setting.ConversationSetting.MaxSize = 100
 	}
 
+	err = setting.History.validate()
+	if err != nil {
+		return err
+	}
+
+	err = setting.parsePrompts()
+	if err != nil {
+		return err
+	}
+
+	err = rag.Load(setting.Knowledge, setting.Connector)
+	if err != nil {
+		return err
+	}
+
 	Neo = &setting
 
 	// Conversation Setting
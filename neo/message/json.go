@@ -46,11 +46,35 @@ func NewOpenAI(data []byte) *JSON {
 		}
 		break
 
+	case strings.HasPrefix(text, `data: {"error":`):
+		var message openai.ErrorMessage
+		err := jsoniter.Unmarshal(data, &message)
+		if err != nil {
+			msg.Text = err.Error()
+		} else {
+			msg.Text = message.Error.Message
+		}
+		msg.Type = "error"
+		break
+
+	case strings.HasPrefix(text, `data: {"actions":`):
+		var message struct {
+			Actions []Action `json:"actions"`
+		}
+		err := jsoniter.Unmarshal(data, &message)
+		if err != nil {
+			msg.Text = err.Error()
+			msg.Type = "error"
+			return &JSON{msg}
+		}
+		msg.Actions = message.Actions
+		break
+
 	case strings.Contains(text, `[DONE]`):
 		msg.Done = true
 		break
 
-	case strings.Contains(text, `"finish_reason":"stop"`):
+	case strings.Contains(text, `"finish_reason":"stop"`), strings.Contains(text, `"finish_reason":"length"`):
 		msg.Done = true
 		break
 
@@ -6,16 +6,20 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/llm"
 	"github.com/yaoapp/yao/neo/assistant"
 	"github.com/yaoapp/yao/neo/assistant/local"
 	"github.com/yaoapp/yao/neo/assistant/openai"
 	"github.com/yaoapp/yao/neo/conversation"
 	"github.com/yaoapp/yao/neo/message"
 	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/telemetry"
+	"github.com/yaoapp/yao/usage"
 )
 
 // Lock the assistant list
@@ -44,7 +48,23 @@ func (neo *DSL) Answer(ctx Context, question string, c *gin.Context) error {
 		return err
 	}
 
+	// Render the prompts, bound the history and prepare the messages
+	prompts, err := neo.prompts(ctx)
+	if err != nil {
+		msg := message.New().Error(err).Done()
+		msg.Write(c.Writer)
+		return err
+	}
+
+	messages, err = neo.HookPrepare(ctx, append(prompts, neo.boundMessages(ctx, neo.assistantConnector(res.AssistantID), prompts, messages)...))
+	if err != nil {
+		msg := message.New().Error(err).Done()
+		msg.Write(c.Writer)
+		return err
+	}
+
 	// Chat with AI
+	ctx.AssistantID = res.AssistantID
 	return neo.chat(ast, ctx, messages, c)
 }
 
@@ -129,7 +149,7 @@ func (neo *DSL) GenerateWithAI(ctx Context, input string, messageType string, sy
 
 	// Chat with AI in background
 	go func() {
-		err := ast.Chat(c.Request.Context(), messages, neo.Option, func(data []byte) int {
+		err := ast.Chat(neo.usageContext(c.Request.Context(), ctx, res.AssistantID), messages, neo.Option, func(data []byte) int {
 			select {
 			case <-clientBreak:
 				return 0 // break
@@ -146,6 +166,14 @@ func (neo *DSL) GenerateWithAI(ctx Context, input string, messageType string, sy
 					return 0 // break
 				}
 
+				// Send the actions, e.g. the citations of the knowledge
+				if len(msg.Message.Actions) > 0 {
+					if !silent {
+						msg.Write(c.Writer)
+					}
+					return 1 // continue
+				}
+
 				// Append content and send message
 				content = msg.Append(content)
 
@@ -173,7 +201,7 @@ func (neo *DSL) GenerateWithAI(ctx Context, input string, messageType string, sy
 		})
 
 		if err != nil {
-			log.Error("Chat error: %s", err.Error())
+			log.With(share.LogFields(c.Request.Context())).Error("Chat error: %s", err.Error())
 			if !silent {
 				message.New().Error(err).Done().Write(c.Writer)
 			}
@@ -238,7 +266,7 @@ func (neo *DSL) Upload(ctx Context, c *gin.Context) (*assistant.File, error) {
 		return nil, err
 	}
 
-	return ast.Upload(ctx, tmpfile, reader, option)
+	return ast.Upload(assistant.WithChat(ctx.Context, ctx.Sid, ctx.ChatID), tmpfile, reader, option)
 }
 
 // Download downloads a file
@@ -278,9 +306,14 @@ func (neo *DSL) chat(ast assistant.API, ctx Context, messages []map[string]inter
 	done := make(chan bool, 1)
 	content := []byte{}
 
+	spanCtx, span := telemetry.Start(c.Request.Context(), "neo.chat", telemetry.SpanKindInternal)
+	span.SetAttribute("neo.chat_id", ctx.ChatID)
+	span.SetAttribute("neo.messages", len(messages))
+
 	// Chat with AI in background
 	go func() {
-		err := ast.Chat(c.Request.Context(), messages, neo.Option, func(data []byte) int {
+		defer span.Finish()
+		err := ast.Chat(assistant.WithChat(neo.usageContext(spanCtx, ctx, ctx.AssistantID), ctx.Sid, ctx.ChatID), messages, neo.Option, func(data []byte) int {
 			select {
 			case <-clientBreak:
 				return 0 // break
@@ -297,6 +330,12 @@ func (neo *DSL) chat(ast assistant.API, ctx Context, messages []map[string]inter
 					return 0 // break
 				}
 
+				// Send the actions, e.g. the citations of the knowledge
+				if len(msg.Message.Actions) > 0 {
+					msg.Write(c.Writer)
+					return 1 // continue
+				}
+
 				// Append content and send message
 				content = msg.Append(content)
 				if msg.Message != nil && msg.Message.Text != "" {
@@ -322,13 +361,15 @@ func (neo *DSL) chat(ast assistant.API, ctx Context, messages []map[string]inter
 		})
 
 		if err != nil {
-			log.Error("Chat error: %s", err.Error())
+			log.With(share.LogFields(c.Request.Context())).Error("Chat error: %s", err.Error())
 			message.New().Error(err).Done().Write(c.Writer)
+			span.SetError(err)
 		}
 
-		// Save chat history
+		// Save chat history, the older messages are summarized in the background
 		if len(content) > 0 {
 			neo.saveHistory(ctx.Sid, ctx.ChatID, content, messages)
+			go neo.summarize(ctx.Sid, ctx.ChatID)
 		}
 
 		done <- true
@@ -344,6 +385,14 @@ func (neo *DSL) chat(ast assistant.API, ctx Context, messages []map[string]inter
 	}
 }
 
+// usageContext the token usage of the chat is recorded by the session and the assistant, the default assistant is recorded as the neo id
+func (neo *DSL) usageContext(parent context.Context, ctx Context, assistantID string) context.Context {
+	if assistantID == "" {
+		assistantID = neo.ID
+	}
+	return usage.WithMeta(parent, usage.Meta{Sid: ctx.Sid, Assistant: assistantID, Source: "neo"})
+}
+
 // updateAssistantList update the assistant list
 func (neo *DSL) updateAssistantList(list []assistant.Assistant) {
 	lock.Lock()
@@ -392,7 +441,33 @@ func (neo *DSL) newAssistant(id string) (assistant.API, error) {
 
 // newAssistantByConfig create a new assistant from assistant configuration
 func (neo *DSL) newAssistantByConfig(ast *assistant.Assistant) (assistant.API, error) {
-	return neo.newAssistantByConnector(ast.Connector)
+	api, err := neo.newAssistantByConnector(ast.Connector)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attach the collections of the knowledge base, the default collection is the assistant id
+	if api, ok := api.(*local.Local); ok {
+		api.Collections = ast.Collections
+		if len(api.Collections) == 0 {
+			api.Collections = []string{ast.ID}
+		}
+		api.Scope = ast.Scope
+	}
+
+	// Sync the remote assistant if the remote option is set, the chats of the assistant run on the threads.
+	// The chat completions are used if the sync fails. e.g. the OpenAI compatible hosts without the assistants API
+	if api, ok := api.(*openai.OpenAI); ok && openai.Enabled(*ast) {
+		api.Current(ast.ID)
+		api.Conversation = neo.Conversation
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, err := api.Sync(ctx, *ast)
+		if err != nil {
+			log.Error("Sync openai assistant %s error: %s, the chat completions are used", ast.ID, err.Error())
+		}
+	}
+	return api, nil
 }
 
 // newAssistantByConnector create a new assistant from connector id
@@ -402,6 +477,15 @@ func (neo *DSL) newAssistantByConnector(id string) (assistant.API, error) {
 		return neo.newMoapiAssistant(id)
 	}
 
+	// LLM connectors of yao. e.g. anthropic, ollama
+	if _, has := llm.Select(id); has {
+		api, err := local.New(id, neo.Prompts, id)
+		if err != nil {
+			return nil, fmt.Errorf("Create local assistant error: %s", err.Error())
+		}
+		return api, nil
+	}
+
 	// Other connector
 	conn, err := connector.Select(id)
 	if err != nil {
@@ -417,7 +501,7 @@ func (neo *DSL) newAssistantByConnector(id string) (assistant.API, error) {
 	}
 
 	// Base on the assistant list hook
-	api, err := local.New(conn, neo.Prompts, id)
+	api, err := local.New(id, neo.Prompts, id)
 	if err != nil {
 		return nil, fmt.Errorf("Create local assistant error: %s", err.Error())
 	}
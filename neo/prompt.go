@@ -0,0 +1,101 @@
+package neo
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/yao/prompt"
+)
+
+// Builtins the builtin variables of the prompt templates, they are read from the context
+var Builtins = []string{"session", "formdata", "field", "namespace", "path", "config"}
+
+// parsePrompts parse the prompt templates, the variables they use should be declared.
+// The prompts without template: true are the literal text, the braces in them are kept
+func (neo *DSL) parsePrompts() error {
+	contents := []string{}
+	for _, p := range neo.Prompts {
+		if !p.Template {
+			contents = append(contents, "")
+			continue
+		}
+		contents = append(contents, p.Content)
+	}
+
+	templates, err := prompt.ParseAll(contents...)
+	if err != nil {
+		return fmt.Errorf("%s %s", neo.ID, err.Error())
+	}
+
+	err = neo.Variables.Check(templates, Builtins...)
+	if err != nil {
+		return fmt.Errorf("%s %s", neo.ID, err.Error())
+	}
+
+	neo.templates = templates
+	return nil
+}
+
+// prompts the messages of the prompts, the templates are rendered with the form data, the session and the context
+func (neo *DSL) prompts(ctx Context) ([]map[string]interface{}, error) {
+	if len(neo.Prompts) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+
+	formdata := ctx.FormData
+	if formdata == nil {
+		formdata = map[string]interface{}{}
+	}
+
+	data, err := neo.Variables.Bind(formdata)
+	if err != nil {
+		return nil, err
+	}
+
+	field := map[string]interface{}{}
+	if ctx.Field != nil {
+		field = map[string]interface{}{"name": ctx.Field.Name, "bind": ctx.Field.Bind}
+	}
+
+	config := ctx.Config
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	data["formdata"] = formdata
+	data["field"] = field
+	data["namespace"] = ctx.Namespace
+	data["path"] = ctx.Path
+	data["config"] = config
+	data["session"] = map[string]interface{}{}
+	if neo.uses("session") {
+		data["session"] = prompt.Session(ctx.Sid)
+	}
+
+	messages := []map[string]interface{}{}
+	for i, p := range neo.Prompts {
+		content := p.Content
+		if i < len(neo.templates) && neo.templates[i] != nil {
+			content, err = neo.templates[i].Render(data)
+			if err != nil {
+				return nil, fmt.Errorf("prompts[%d] %s", i, err.Error())
+			}
+		}
+
+		message := map[string]interface{}{"role": p.Role, "content": content}
+		if p.Name != "" {
+			message["name"] = p.Name
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// uses check if the prompt templates use the variable
+func (neo *DSL) uses(name string) bool {
+	for _, tmpl := range neo.templates {
+		if tmpl != nil && tmpl.Uses(name) {
+			return true
+		}
+	}
+	return false
+}
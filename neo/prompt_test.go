@@ -0,0 +1,61 @@
+package neo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/prompt"
+)
+
+func TestPrompts(t *testing.T) {
+	neo := &DSL{
+		ID: "neo",
+		Prompts: []assistant.Prompt{
+			{Role: "system", Content: "You are the assistant of {{ customer.name }}.{{ if field.name }} The user is editing {{ field.name }}.{{ end }}", Template: true},
+			{Role: "system", Content: "Reply in English.", Name: "language"},
+		},
+		Variables: prompt.Variables{"customer": {Type: "object", Default: map[string]interface{}{"name": "the guest"}}},
+	}
+
+	err := neo.parsePrompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := neo.prompts(Context{FormData: map[string]interface{}{"customer": map[string]interface{}{"name": "Max"}}, Field: &Field{Name: "title"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"role": "system", "content": "You are the assistant of Max. The user is editing title."},
+		{"role": "system", "content": "Reply in English.", "name": "language"},
+	}, messages)
+
+	messages, err = neo.prompts(Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "You are the assistant of the guest.", messages[0]["content"])
+
+	// The variables of the templates should be declared
+	neo.Prompts = append(neo.Prompts, assistant.Prompt{Role: "system", Content: "{{ order.id }}", Template: true})
+	assert.NotNil(t, neo.parsePrompts())
+}
+
+func TestPromptsLiteral(t *testing.T) {
+	legacy := "Reply in JSON like {\"name\": \"{{ name }}\"}, keep {{ and }} as they are."
+	neo := &DSL{ID: "neo", Prompts: []assistant.Prompt{{Role: "system", Content: legacy}}}
+
+	// The prompt is not a template unless template: true, the variables are not checked
+	err := neo.parsePrompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := neo.prompts(Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, legacy, messages[0]["content"])
+}
@@ -0,0 +1,306 @@
+package rag
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrUnsupported the text of the file type could not be extracted
+var ErrUnsupported = errors.New("the file type is not supported")
+
+// Extractors the text extractors of the file extensions
+var Extractors = map[string]func(content []byte) (string, error){
+	"txt":  plain,
+	"md":   plain,
+	"csv":  plain,
+	"json": plain,
+	"pdf":  extractPDF,
+	"docx": extractDOCX,
+	"pptx": extractPPTX,
+	"odt":  extractODT,
+	"xlsx": extractXLSX,
+}
+
+var slideName = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+var pdfStream = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\nendstream`)
+var pdfText = regexp.MustCompile(`(?s)\[(.*?)\]\s*TJ|\(((?:\\.|[^\\)])*)\)\s*(?:Tj|'|")|(T\*|Td|TD|ET)`)
+var pdfString = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)`)
+
+// Extract the text of the file by the extension, text/* files are read as the plain text.
+// ErrUnsupported returns if the file type could not be extracted (doc, xls, ppt, images, audios and videos).
+func Extract(filename string, contentType string, content []byte) (string, error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if extract, has := Extractors[ext]; has {
+		return extract(content)
+	}
+
+	if strings.HasPrefix(contentType, "text/") {
+		return plain(content)
+	}
+	return "", ErrUnsupported
+}
+
+func plain(content []byte) (string, error) {
+	return string(content), nil
+}
+
+// extractDOCX the text of the word/document.xml
+func extractDOCX(content []byte) (string, error) {
+	files, err := unzip(content)
+	if err != nil {
+		return "", err
+	}
+
+	document, has := files["word/document.xml"]
+	if !has {
+		return "", fmt.Errorf("docx word/document.xml not found")
+	}
+	return xmlText(document, []string{"t"}, []string{"p", "br"})
+}
+
+// extractPPTX the text of the slides, in the order of the slides
+func extractPPTX(content []byte) (string, error) {
+	files, err := unzip(content)
+	if err != nil {
+		return "", err
+	}
+
+	slides := []int{}
+	for name := range files {
+		if match := slideName.FindStringSubmatch(name); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			slides = append(slides, n)
+		}
+	}
+	sort.Ints(slides)
+
+	texts := []string{}
+	for _, n := range slides {
+		text, err := xmlText(files[fmt.Sprintf("ppt/slides/slide%d.xml", n)], []string{"t"}, []string{"p"})
+		if err != nil {
+			return "", err
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// extractODT the text of the content.xml
+func extractODT(content []byte) (string, error) {
+	files, err := unzip(content)
+	if err != nil {
+		return "", err
+	}
+
+	document, has := files["content.xml"]
+	if !has {
+		return "", fmt.Errorf("odt content.xml not found")
+	}
+	return xmlText(document, []string{"p", "h"}, []string{"p", "h", "line-break"})
+}
+
+// extractXLSX the rows of the sheets, the cells are separated by tabs
+func extractXLSX(content []byte) (string, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	for _, sheet := range file.GetSheetList() {
+		rows, err := file.GetRows(sheet)
+		if err != nil {
+			return "", err
+		}
+
+		lines = append(lines, "# "+sheet)
+		for _, row := range rows {
+			if line := strings.TrimSpace(strings.Join(row, "\t")); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// extractPDF the text of the PDF content streams. It is the best effort, the strings shown by the text operators
+// (Tj, TJ, ' and ") are read, the streams are inflated if they are compressed. The fonts with the custom encodings are not decoded,
+// ErrUnsupported returns if the text is not readable, e.g. the scanned documents and the CID fonts, so the garbage is not indexed.
+func extractPDF(content []byte) (string, error) {
+	if !bytes.HasPrefix(content, []byte("%PDF")) {
+		return "", fmt.Errorf("pdf the file is not a PDF document")
+	}
+
+	var text strings.Builder
+	for _, match := range pdfStream.FindAllSubmatch(content, -1) {
+		stream := match[1]
+		if reader, err := zlib.NewReader(bytes.NewReader(stream)); err == nil {
+			if inflated, err := io.ReadAll(reader); err == nil || len(inflated) > 0 {
+				stream = inflated
+			}
+			reader.Close()
+		}
+
+		for _, op := range pdfText.FindAllSubmatch(stream, -1) {
+			switch {
+			case op[1] != nil:
+				for _, s := range pdfString.FindAllSubmatch(op[1], -1) {
+					text.WriteString(pdfUnescape(s[1]))
+				}
+			case op[2] != nil:
+				text.WriteString(pdfUnescape(op[2]))
+			default:
+				text.WriteString("\n")
+			}
+		}
+	}
+
+	if !readable(text.String()) {
+		return "", fmt.Errorf("pdf %w, the text could not be extracted", ErrUnsupported)
+	}
+	return text.String(), nil
+}
+
+// readable check if the text is the valid UTF-8 and at least 90% of the characters are printable, the spaces are not counted
+func readable(text string) bool {
+	if !utf8.ValidString(text) {
+		return false
+	}
+
+	total, printable := 0, 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsPrint(r) {
+			printable++
+		}
+	}
+	return total > 0 && printable*10 >= total*9
+}
+
+// pdfUnescape the escape sequences of the PDF string
+func pdfUnescape(s []byte) string {
+	var res strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			res.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			res.WriteByte('\n')
+		case 'r':
+			res.WriteByte('\r')
+		case 't':
+			res.WriteByte('\t')
+		case 'b', 'f':
+		case '\n':
+		default:
+			if c >= '0' && c <= '7' {
+				end := i
+				for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+					end++
+				}
+				n, _ := strconv.ParseUint(string(s[i:end]), 8, 8)
+				res.WriteByte(byte(n))
+				i = end - 1
+				continue
+			}
+			res.WriteByte(c)
+		}
+	}
+	return res.String()
+}
+
+// unzip the files of the office document
+func unzip(content []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		if !strings.HasSuffix(file.Name, ".xml") {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[file.Name] = data
+	}
+	return files, nil
+}
+
+// xmlText the character data inside the text elements, a new line is added at the end of the break elements.
+// The elements are matched by the local names, e.g. w:t and a:t are t.
+func xmlText(data []byte, texts []string, breaks []string) (string, error) {
+	isText := map[string]bool{}
+	for _, name := range texts {
+		isText[name] = true
+	}
+
+	isBreak := map[string]bool{}
+	for _, name := range breaks {
+		isBreak[name] = true
+	}
+
+	var res strings.Builder
+	depth := 0
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if isText[t.Name.Local] {
+				depth++
+			}
+		case xml.EndElement:
+			if isText[t.Name.Local] && depth > 0 {
+				depth--
+			}
+			if isBreak[t.Name.Local] {
+				res.WriteString("\n")
+			}
+		case xml.CharData:
+			if depth > 0 {
+				res.Write(t)
+			}
+		}
+	}
+	return res.String(), nil
+}
@@ -0,0 +1,156 @@
+package rag
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/fs"
+)
+
+// Local the embedded vector index, the chunks are kept in memory and searched by the cosine similarity.
+// The index is saved in the data filesystem if the path is given.
+type Local struct {
+	path        string
+	collections map[string][]Chunk
+	mutex       sync.RWMutex
+}
+
+// NewLocal create a new local index. option: {"path": "/__knowledge/index.json"}
+func NewLocal(option map[string]interface{}) (Index, error) {
+	local := &Local{collections: map[string][]Chunk{}}
+	if option != nil {
+		if path, ok := option["path"].(string); ok {
+			local.path = path
+		}
+	}
+
+	err := local.load()
+	if err != nil {
+		return nil, err
+	}
+	return local, nil
+}
+
+// Upsert the chunks of the collection, the chunks of the same files are replaced
+func (local *Local) Upsert(collection string, chunks []Chunk) error {
+	local.mutex.Lock()
+	defer local.mutex.Unlock()
+
+	files := map[string]bool{}
+	for _, chunk := range chunks {
+		files[chunk.FileID] = true
+	}
+
+	kept := []Chunk{}
+	for _, chunk := range local.collections[collection] {
+		if !files[chunk.FileID] {
+			kept = append(kept, chunk)
+		}
+	}
+
+	local.collections[collection] = append(kept, chunks...)
+	return local.save()
+}
+
+// Search the chunks of the collections, the most similar first
+func (local *Local) Search(collections []string, vector []float64, topK int) ([]Result, error) {
+	local.mutex.RLock()
+	defer local.mutex.RUnlock()
+
+	results := []Result{}
+	for _, collection := range collections {
+		for _, chunk := range local.collections[collection] {
+			results = append(results, Result{Chunk: chunk, Collection: collection, Score: cosine(vector, chunk.Vector)})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Remove the chunks of the file from the collection
+func (local *Local) Remove(collection string, fileID string) error {
+	local.mutex.Lock()
+	defer local.mutex.Unlock()
+
+	kept := []Chunk{}
+	for _, chunk := range local.collections[collection] {
+		if chunk.FileID != fileID {
+			kept = append(kept, chunk)
+		}
+	}
+
+	local.collections[collection] = kept
+	return local.save()
+}
+
+func (local *Local) load() error {
+	if local.path == "" {
+		return nil
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		return err
+	}
+
+	exists, err := data.Exists(local.path)
+	if err != nil || !exists {
+		return err
+	}
+
+	content, err := data.ReadFile(local.path)
+	if err != nil {
+		return err
+	}
+
+	err = jsoniter.Unmarshal(content, &local.collections)
+	if err != nil {
+		return fmt.Errorf("local index %s %s", local.path, err.Error())
+	}
+	return nil
+}
+
+func (local *Local) save() error {
+	if local.path == "" {
+		return nil
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		return err
+	}
+
+	content, err := jsoniter.Marshal(local.collections)
+	if err != nil {
+		return err
+	}
+
+	_, err = data.WriteFile(local.path, content, 0644)
+	return err
+}
+
+// cosine the cosine similarity of the vectors, 0 returns if the dimensions are different
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
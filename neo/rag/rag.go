@@ -0,0 +1,249 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/yao/llm"
+)
+
+// Drivers the drivers of the vector index
+var Drivers = map[string]Driver{
+	"local": NewLocal,
+}
+
+// BatchSize the max number of the chunks embedded in one request
+var BatchSize = 16
+
+var knowledge *Knowledge
+var mutex sync.RWMutex
+
+// Load the knowledge base of neo, the knowledge base is disabled if the setting is nil.
+// The embeddings are created by the connector of the setting, the default value is the connector given.
+func Load(setting *Setting, connector string) error {
+	if setting == nil {
+		mutex.Lock()
+		knowledge = nil
+		mutex.Unlock()
+		return nil
+	}
+
+	if setting.Connector == "" {
+		setting.Connector = connector
+	}
+
+	ai, err := llm.New(setting.Connector)
+	if err != nil {
+		return fmt.Errorf("knowledge %s", err.Error())
+	}
+
+	k, err := New(*setting, ai)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	knowledge = k
+	mutex.Unlock()
+	return nil
+}
+
+// Default the knowledge base of neo, nil returns if it is disabled
+func Default() *Knowledge {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return knowledge
+}
+
+// New create a new knowledge base
+func New(setting Setting, embedder Embedder) (*Knowledge, error) {
+	if setting.Index == "" {
+		setting.Index = "local"
+	}
+
+	if setting.ChunkSize <= 0 {
+		setting.ChunkSize = 1000
+	}
+
+	if setting.Overlap == 0 {
+		setting.Overlap = setting.ChunkSize / 5
+	} else if setting.Overlap < 0 {
+		setting.Overlap = 0
+	}
+
+	if setting.Overlap >= setting.ChunkSize {
+		return nil, fmt.Errorf("knowledge the overlap should be less than the chunk size %d", setting.ChunkSize)
+	}
+
+	if setting.TopK <= 0 {
+		setting.TopK = 4
+	}
+
+	driver, has := Drivers[setting.Index]
+	if !has {
+		return nil, fmt.Errorf("knowledge the index %s not support", setting.Index)
+	}
+
+	index, err := driver(setting.Option)
+	if err != nil {
+		return nil, fmt.Errorf("knowledge %s", err.Error())
+	}
+
+	return &Knowledge{Setting: setting, index: index, embedder: embedder}, nil
+}
+
+// Ingest extract the text of the file, split it into chunks, embed the chunks and save them in the collection.
+// The chunks of the file ingested before are replaced, the number of the chunks returns.
+func (k *Knowledge) Ingest(ctx context.Context, collection string, file File, content []byte) (int, error) {
+	text, err := Extract(file.Filename, file.ContentType, content)
+	if err != nil {
+		return 0, err
+	}
+
+	texts := Split(text, k.ChunkSize, k.Overlap)
+	if len(texts) == 0 {
+		return 0, nil
+	}
+
+	chunks := make([]Chunk, 0, len(texts))
+	for start := 0; start < len(texts); start += BatchSize {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		end := start + BatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		vectors, err := k.embed(texts[start:end])
+		if err != nil {
+			return 0, err
+		}
+
+		for i, vector := range vectors {
+			n := start + i
+			chunks = append(chunks, Chunk{
+				ID:       fmt.Sprintf("%s#%d", file.ID, n),
+				FileID:   file.ID,
+				Filename: file.Filename,
+				Index:    n,
+				Text:     texts[n],
+				Vector:   vector,
+			})
+		}
+	}
+
+	err = k.index.Upsert(collection, chunks)
+	if err != nil {
+		return 0, err
+	}
+	return len(chunks), nil
+}
+
+// Retrieve the chunks of the collections relevant to the query, the most relevant first
+func (k *Knowledge) Retrieve(ctx context.Context, collections []string, query string) ([]Result, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || len(collections) == 0 {
+		return []Result{}, nil
+	}
+
+	vectors, err := k.embed([]string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results, err := k.index.Search(collections, vectors[0], k.TopK)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []Result{}
+	for _, result := range results {
+		if result.Score < k.MinScore {
+			continue
+		}
+		res = append(res, result)
+	}
+	return res, nil
+}
+
+// Remove the chunks of the file from the collection
+func (k *Knowledge) Remove(collection string, fileID string) error {
+	return k.index.Remove(collection, fileID)
+}
+
+// Prompt the system message of the retrieved chunks, the chunks are numbered to be cited as [n]
+func Prompt(results []Result) map[string]interface{} {
+	lines := []string{
+		"Answer with the following knowledge when it is relevant to the question. " +
+			"Cite the sources with their numbers, e.g. [1], and do not make up the sources.",
+	}
+	for i, result := range results {
+		lines = append(lines, "", fmt.Sprintf("[%d] %s", i+1, result.Filename), result.Text)
+	}
+	return map[string]interface{}{"role": "system", "content": strings.Join(lines, "\n")}
+}
+
+// Citations the citations of the retrieved chunks, the index is the number cited in the answer
+func Citations(results []Result) []map[string]interface{} {
+	citations := []map[string]interface{}{}
+	for i, result := range results {
+		citations = append(citations, map[string]interface{}{
+			"index":      i + 1,
+			"file_id":    result.FileID,
+			"filename":   result.Filename,
+			"collection": result.Collection,
+			"chunk":      result.Index,
+			"score":      result.Score,
+			"text":       result.Text,
+		})
+	}
+	return citations
+}
+
+// embed the texts, the vectors are in the order of the texts
+func (k *Knowledge) embed(texts []string) ([][]float64, error) {
+	res, ex := k.embedder.Embeddings(texts, "")
+	if ex != nil {
+		return nil, fmt.Errorf("knowledge embeddings %s", ex.Message)
+	}
+
+	raw, err := jsoniter.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("knowledge embeddings %s", err.Error())
+	}
+
+	var embeddings struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	err = jsoniter.Unmarshal(raw, &embeddings)
+	if err != nil {
+		return nil, fmt.Errorf("knowledge embeddings %s", err.Error())
+	}
+
+	if len(embeddings.Data) != len(texts) {
+		return nil, fmt.Errorf("knowledge embeddings %d vectors returned, %d expected", len(embeddings.Data), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, data := range embeddings.Data {
+		index := data.Index
+		if index < 0 || index >= len(texts) || vectors[index] != nil {
+			index = i
+		}
+		vectors[index] = data.Embedding
+	}
+	return vectors, nil
+}
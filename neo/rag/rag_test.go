@@ -0,0 +1,198 @@
+package rag
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"github.com/yaoapp/kun/exception"
+)
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{}, Split(" \n\n ", 10, 2))
+	assert.Equal(t, []string{"Hello\nWorld"}, Split("Hello\n\nWorld", 100, 10))
+
+	chunks := Split("0123456789abcdefghij\nklmno", 10, 3)
+	assert.Equal(t, []string{"0123456789", "789abcdefg", "efghij", "hij\nklmno"}, chunks)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len([]rune(chunk)), 10)
+	}
+
+	chunks = Split("The first paragraph.\nThe second paragraph.\nThe third paragraph.", 45, 0)
+	assert.Equal(t, []string{"The first paragraph.\nThe second paragraph.", "The third paragraph."}, chunks)
+}
+
+func TestExtract(t *testing.T) {
+	docx := officeFile(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:t xml:space="preserve"> Yao</w:t></w:r></w:p><w:p><w:r><w:t>Neo</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	text, err := Extract("hello.docx", "", docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Hello Yao\nNeo\n", text)
+
+	pptx := officeFile(t, map[string]string{
+		"ppt/slides/slide10.xml": `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Ten</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Two</a:t></a:r></a:p></p:sld>`,
+	})
+	text, err = Extract("hello.pptx", "", pptx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Two\n\nTen\n", text)
+
+	odt := officeFile(t, map[string]string{
+		"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><text:h>Title</text:h><text:p>Hello <text:span>Neo</text:span></text:p></office:body></office:document-content>`,
+	})
+	text, err = Extract("hello.odt", "", odt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Title\nHello Neo\n", text)
+
+	xlsx := excelize.NewFile()
+	xlsx.SetCellValue("Sheet1", "A1", "Name")
+	xlsx.SetCellValue("Sheet1", "B1", "Price")
+	xlsx.SetCellValue("Sheet1", "A2", "Apple")
+	xlsx.SetCellValue("Sheet1", "B2", 5)
+	buffer, err := xlsx.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err = Extract("prices.xlsx", "", buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "# Sheet1\nName\tPrice\nApple\t5", text)
+
+	var stream bytes.Buffer
+	w := zlib.NewWriter(&stream)
+	w.Write([]byte(`BT /F1 12 Tf 72 712 Td (Hello \(PDF\)) Tj 0 -14 Td [(Wor) -20 (ld)] TJ ET`))
+	w.Close()
+	pdf := []byte("%PDF-1.4\n4 0 obj\n<< /Filter /FlateDecode >>\nstream\n" + stream.String() + "\nendstream\nendobj\n")
+	text, err = Extract("hello.pdf", "application/pdf", pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Hello (PDF)\nWorld\n", strings.TrimPrefix(text, "\n"))
+
+	// The glyph ids of the custom encodings are not the text, the PDF is not indexed
+	pdf = []byte("%PDF-1.4\n4 0 obj\n<< >>\nstream\nBT /F1 12 Tf (\\001\\002\\003\\004\\377\\376) Tj ET\nendstream\nendobj\n")
+	_, err = Extract("glyphs.pdf", "application/pdf", pdf)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+
+	pdf = []byte("%PDF-1.4\n4 0 obj\n<< >>\nstream\nq 0 0 612 792 re W n Q\nendstream\nendobj\n")
+	_, err = Extract("scanned.pdf", "application/pdf", pdf)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+
+	text, err = Extract("notes", "text/plain", []byte("Hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello", text)
+
+	_, err = Extract("hello.doc", "application/msword", []byte{})
+	assert.Equal(t, ErrUnsupported, err)
+}
+
+func TestKnowledge(t *testing.T) {
+	k, err := New(Setting{ChunkSize: 60, Overlap: -1, TopK: 2}, embedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "The apple price is five dollars.\nThe banana price is two dollars.\nYao is a low code engine."
+	n, err := k.Ingest(context.Background(), "fruits", File{ID: "/__assistants/neo/1.txt", Filename: "fruits.txt"}, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, n)
+
+	_, err = k.Ingest(context.Background(), "other", File{ID: "/__assistants/neo/2.txt", Filename: "apple.txt"}, []byte("The apple price is unknown."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := k.Retrieve(context.Background(), []string{"fruits"}, "What is the apple price?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, results, 2)
+	assert.Equal(t, "fruits.txt", results[0].Filename)
+	assert.Equal(t, 0, results[0].Index)
+	assert.Contains(t, results[0].Text, "apple price")
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	// The chunks of the file are replaced
+	n, err = k.Ingest(context.Background(), "fruits", File{ID: "/__assistants/neo/1.txt", Filename: "fruits.txt"}, []byte("Yao is a low code engine."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, n)
+
+	results, err = k.Retrieve(context.Background(), []string{"fruits", "other"}, "What is the apple price?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, results, 2)
+	assert.Equal(t, "other", results[0].Collection)
+
+	prompt := Prompt(results)
+	assert.Equal(t, "system", prompt["role"])
+	assert.Contains(t, prompt["content"], "[1] apple.txt\nThe apple price is unknown.")
+
+	citations := Citations(results)
+	assert.Equal(t, 1, citations[0]["index"])
+	assert.Equal(t, "/__assistants/neo/2.txt", citations[0]["file_id"])
+
+	err = k.Remove("other", "/__assistants/neo/2.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = k.Retrieve(context.Background(), []string{"other"}, "apple")
+	assert.Nil(t, err)
+	assert.Len(t, results, 0)
+}
+
+// embedder the words are hashed into the dimensions of the vectors
+type embedder struct{}
+
+func (embedder) Embeddings(input interface{}, user string) (interface{}, *exception.Exception) {
+	texts, ok := input.([]string)
+	if !ok {
+		return nil, exception.New("the input is invalid", 400)
+	}
+
+	data := []interface{}{}
+	for i, text := range texts {
+		vector := make([]float64, 64)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return r < 'a' || r > 'z' }) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%64]++
+		}
+		data = append(data, map[string]interface{}{"index": i, "embedding": vector})
+	}
+	return map[string]interface{}{"data": data}, nil
+}
+
+func officeFile(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(f, content)
+	}
+	w.Close()
+	return buffer.Bytes()
+}
@@ -0,0 +1,64 @@
+package rag
+
+import (
+	"strings"
+)
+
+// Split the text into chunks of the size (in characters), the paragraphs are kept together if they fit.
+// The adjacent chunks share the overlap characters, so the sentences cut at the boundary are not lost.
+func Split(text string, size int, overlap int) []string {
+	if size <= 0 {
+		size = 1000
+	}
+
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	chunks := []string{}
+	current := []rune{}
+	fresh := 0 // The characters added since the last chunk
+
+	flush := func() {
+		if chunk := strings.TrimSpace(string(current)); chunk != "" && fresh > 0 {
+			chunks = append(chunks, chunk)
+		}
+
+		if overlap > 0 && len(current) > overlap {
+			current = append([]rune{}, current[len(current)-overlap:]...)
+		} else if overlap == 0 {
+			current = []rune{}
+		}
+		fresh = 0
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		paragraph := []rune(strings.Join(strings.Fields(line), " "))
+		if len(paragraph) == 0 {
+			continue
+		}
+
+		if len(current) > 0 {
+			paragraph = append([]rune{'\n'}, paragraph...)
+		}
+
+		if fresh > 0 && len(current)+len(paragraph) > size {
+			flush()
+		}
+
+		// The long paragraph is cut at the size
+		for len(current)+len(paragraph) > size {
+			room := size - len(current)
+			current = append(current, paragraph[:room]...)
+			paragraph = paragraph[room:]
+			fresh += room
+			flush()
+		}
+
+		current = append(current, paragraph...)
+		fresh += len(paragraph)
+	}
+
+	flush()
+	return chunks
+}
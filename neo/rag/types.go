@@ -0,0 +1,71 @@
+package rag
+
+import (
+	"github.com/yaoapp/kun/exception"
+)
+
+// Setting the knowledge base setting of neo
+//
+//	knowledge:
+//	  connector: openai   # The connector of the embeddings, the default value is the connector of neo
+//	  index: local        # The vector index, the default value is local
+//	  option:             # The option of the index. local: {"path": "/__knowledge/index.json"} the index is saved in the data filesystem
+//	  chunk_size: 1000    # The max characters of a chunk
+//	  overlap: 200        # The characters shared by the adjacent chunks, -1 disables the overlap
+//	  top_k: 4            # The max number of the chunks retrieved
+//	  min_score: 0.3      # The min cosine similarity of the chunks retrieved
+type Setting struct {
+	Connector string                 `json:"connector,omitempty" yaml:"connector,omitempty"`
+	Index     string                 `json:"index,omitempty" yaml:"index,omitempty"`
+	Option    map[string]interface{} `json:"option,omitempty" yaml:"option,omitempty"`
+	ChunkSize int                    `json:"chunk_size,omitempty" yaml:"chunk_size,omitempty"`
+	Overlap   int                    `json:"overlap,omitempty" yaml:"overlap,omitempty"`
+	TopK      int                    `json:"top_k,omitempty" yaml:"top_k,omitempty"`
+	MinScore  float64                `json:"min_score,omitempty" yaml:"min_score,omitempty"`
+}
+
+// Knowledge the knowledge base, the files are split into chunks, embedded and saved in the index
+type Knowledge struct {
+	Setting
+	index    Index
+	embedder Embedder
+}
+
+// Embedder creates the embedding vectors, the response is the same format as the OpenAI embeddings. llm.LLM is an embedder.
+type Embedder interface {
+	Embeddings(input interface{}, user string) (interface{}, *exception.Exception)
+}
+
+// Index the vector index of the chunks
+type Index interface {
+	Upsert(collection string, chunks []Chunk) error
+	Search(collections []string, vector []float64, topK int) ([]Result, error)
+	Remove(collection string, fileID string) error
+}
+
+// Driver creates the index with the option
+type Driver func(option map[string]interface{}) (Index, error)
+
+// File the file ingested
+type File struct {
+	ID          string `json:"file_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Chunk a chunk of the file
+type Chunk struct {
+	ID       string    `json:"id"`
+	FileID   string    `json:"file_id"`
+	Filename string    `json:"filename"`
+	Index    int       `json:"index"`
+	Text     string    `json:"text"`
+	Vector   []float64 `json:"vector"`
+}
+
+// Result a chunk retrieved
+type Result struct {
+	Chunk
+	Collection string  `json:"collection"`
+	Score      float64 `json:"score"`
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yaoapp/yao/neo/assistant"
 	"github.com/yaoapp/yao/neo/conversation"
+	"github.com/yaoapp/yao/neo/rag"
+	"github.com/yaoapp/yao/prompt"
 )
 
 // DSL AI assistant
@@ -16,6 +18,8 @@ type DSL struct {
 	Guard               string                         `json:"guard,omitempty" yaml:"guard,omitempty"`
 	Connector           string                         `json:"connector" yaml:"connector"`
 	ConversationSetting conversation.Setting           `json:"conversation" yaml:"conversation"`
+	History             History                        `json:"history,omitempty" yaml:"history,omitempty"`     // The strategy of the history sent to the assistant
+	Knowledge           *rag.Setting                   `json:"knowledge,omitempty" yaml:"knowledge,omitempty"` // The knowledge base of the uploaded files, it is disabled if it is not set
 	Option              map[string]interface{}         `json:"option" yaml:"option"`
 	Prepare             string                         `json:"prepare,omitempty" yaml:"prepare,omitempty"`
 	Create              string                         `json:"create,omitempty" yaml:"create,omitempty"`
@@ -23,12 +27,14 @@ type DSL struct {
 	AssistantListHook   string                         `json:"assistants,omitempty" yaml:"assistants,omitempty"` // Get the assistant list from the hook
 	MentionHook         string                         `json:"mentions,omitempty"`                               // Get the mention list from the hook
 	Prompts             []assistant.Prompt             `json:"prompts,omitempty" yaml:"prompts,omitempty"`
+	Variables           prompt.Variables               `json:"variables,omitempty" yaml:"variables,omitempty"` // The input variables of the prompt templates, they are read from the form data
 	Allows              []string                       `json:"allows,omitempty" yaml:"allows,omitempty"`
 	Assistant           assistant.API                  `json:"-" yaml:"-"` // The default assistant
 	Conversation        conversation.Conversation      `json:"-" yaml:"-"`
 	GuardHandlers       []gin.HandlerFunc              `json:"-" yaml:"-"`
 	AssistantList       []assistant.Assistant          `json:"-" yaml:"-"`
 	AssistantMaps       map[string]assistant.Assistant `json:"-" yaml:"-"`
+	templates           []*prompt.Template             // The templates of the prompts, nil if the prompt is static
 }
 
 // Mention list
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"strings"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/http"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/telemetry"
+	"github.com/yaoapp/yao/usage"
 )
 
 // Tiktoken get number of tokens
@@ -25,6 +28,7 @@ func Tiktoken(model string, input string) (int, error) {
 
 // OpenAI struct
 type OpenAI struct {
+	connector    string // The connector id, the usage is recorded by it
 	key          string
 	model        string
 	host         string
@@ -53,8 +57,12 @@ func New(id string) (*OpenAI, error) {
 		return nil, fmt.Errorf("The connector %s is not a OpenAI connector", id)
 	}
 
-	setting := c.Setting()
-	return NewOpenAI(setting)
+	ai, err := NewOpenAI(c.Setting())
+	if err != nil {
+		return nil, err
+	}
+	ai.connector = id
+	return ai, nil
 }
 
 // NewOpenAI create a new OpenAI instance by setting
@@ -85,7 +93,13 @@ func NewOpenAI(setting map[string]interface{}) (*OpenAI, error) {
 		maxToken = v
 	}
 
+	connector := ""
+	if v, ok := setting["id"].(string); ok {
+		connector = v
+	}
+
 	return &OpenAI{
+		connector:    connector,
 		key:          key,
 		model:        model,
 		host:         host,
@@ -119,6 +133,7 @@ func NewMoapi(model string) (*OpenAI, error) {
 	}
 
 	return &OpenAI{
+		connector:    "moapi",
 		key:          key,
 		model:        model,
 		host:         url,
@@ -164,18 +179,7 @@ func (openai OpenAI) CompletionsWith(ctx context.Context, prompt interface{}, op
 // ChatCompletions Creates a model response for the given chat conversation.
 // https://platform.openai.com/docs/api-reference/chat/create
 func (openai OpenAI) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
-	if option == nil {
-		option = map[string]interface{}{}
-	}
-	option["messages"] = messages
-
-	if cb != nil {
-		option["stream"] = true
-		return nil, openai.stream(context.Background(), "/v1/chat/completions", option, cb)
-	}
-
-	option["stream"] = false
-	return openai.post("/v1/chat/completions", option)
+	return openai.ChatCompletionsWith(context.Background(), messages, option, cb)
 }
 
 // ChatCompletionsWith Creates a model response for the given chat conversation.
@@ -186,13 +190,24 @@ func (openai OpenAI) ChatCompletionsWith(ctx context.Context, messages []map[str
 	}
 	option["messages"] = messages
 
+	call, ex := usage.Begin(ctx, openai.connector, openai.model, messages, openai.Tiktoken)
+	if ex != nil {
+		return nil, ex
+	}
+
 	if cb != nil {
 		option["stream"] = true
-		return nil, openai.stream(ctx, "/v1/chat/completions", option, cb)
+		ex := openai.stream(ctx, "/v1/chat/completions", option, call.Stream(cb))
+		call.End(nil)
+		return nil, ex
 	}
 
 	option["stream"] = false
-	return openai.post("/v1/chat/completions", option)
+	res, ex := openai.post("/v1/chat/completions", option)
+	if ex == nil {
+		call.End(res)
+	}
+	return res, ex
 }
 
 // Edits Creates a new edit for the provided input, instruction, and parameters.
@@ -212,7 +227,17 @@ func (openai OpenAI) Embeddings(input interface{}, user string) (interface{}, *e
 	if user != "" {
 		payload["user"] = user
 	}
-	return openai.post("/v1/embeddings", payload)
+
+	call, ex := usage.Begin(context.Background(), openai.connector, openai.model, nil, nil)
+	if ex != nil {
+		return nil, ex
+	}
+
+	res, ex := openai.post("/v1/embeddings", payload)
+	if ex == nil {
+		call.End(res)
+	}
+	return res, ex
 }
 
 // AudioTranscriptions Transcribes audio into the input language.
@@ -431,20 +456,77 @@ func (openai OpenAI) stream(ctx context.Context, path string, payload map[string
 	url := fmt.Sprintf("%s%s", openai.host, path)
 	key := fmt.Sprintf("Bearer %s", openai.key)
 	payload["model"] = openai.model
+
+	ctx, span := telemetry.Start(ctx, "openai.stream", telemetry.SpanKindClient)
+	span.SetAttribute("openai.model", openai.model)
+	span.SetAttribute("openai.path", path)
+	defer span.Finish()
+
+	header := map[string][]string{
+		"Content-Type":  {"application/json; charset=utf-8"},
+		"Authorization": {key},
+	}
+	telemetry.Inject(ctx, header)
+
+	// The lines before the first data line are the error response, they are returned as the exception
+	body := []byte{}
+	started := false
 	req := http.New(url)
-	err := req.
-		WithHeader(map[string][]string{
-			"Content-Type":  {"application/json; charset=utf-8"},
-			"Authorization": {key},
-		}).
-		Stream(ctx, "POST", payload, cb)
+	err := req.WithHeader(header).Stream(ctx, "POST", payload, func(data []byte) int {
+		if !started {
+			line := strings.TrimSpace(string(data))
+			if !strings.HasPrefix(line, "data:") {
+				body = append(body, data...)
+				return 1
+			}
+			started = true
+		}
+		return cb(data)
+	})
+
+	if !started && len(strings.TrimSpace(string(body))) > 0 {
+		ex := openai.streamError(body)
+		span.SetError(ex.Message)
+		return ex
+	}
 
 	if err != nil {
-		return exception.New(err.Error(), 500)
+		span.SetError(err)
+		if ctx.Err() == context.DeadlineExceeded {
+			return exception.New("OpenAI %s", 504, err.Error())
+		}
+		return exception.New("OpenAI %s", 503, err.Error())
 	}
 	return nil
 }
 
+// streamError map the error response of the stream to the exception, the status is inferred by the error code
+// {"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}
+func (openai OpenAI) streamError(body []byte) *exception.Exception {
+	var res ErrorMessage
+	err := jsoniter.Unmarshal(body, &res)
+	if err != nil || res.Error.Message == "" {
+		return exception.New("OpenAI Error %s", 500, strings.TrimSpace(string(body)))
+	}
+
+	status := 400
+	switch {
+	case res.Error.Code == "rate_limit_exceeded" || res.Error.Code == "insufficient_quota" || res.Error.Type == "requests" || res.Error.Type == "tokens":
+		status = 429
+	case res.Error.Code == "invalid_api_key" || res.Error.Type == "authentication_error":
+		status = 401
+	case res.Error.Code == "model_not_found":
+		status = 404
+	case res.Error.Type == "server_error" || res.Error.Type == "service_unavailable":
+		status = 503
+	}
+
+	if res.Error.Code != "" {
+		return exception.New("OpenAI %s %s", status, res.Error.Code, res.Error.Message)
+	}
+	return exception.New("OpenAI %s", status, res.Error.Message)
+}
+
 func (openai OpenAI) isError(res *http.Response) *exception.Exception {
 
 	if res.Status != 200 {
@@ -8,6 +8,7 @@ import (
 	"github.com/yaoapp/gou/runtime/v8/bridge"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/usage"
 )
 
 func init() {
@@ -81,7 +82,12 @@ func ProcessAudioTranscriptions(process *process.Process) interface{} {
 func ProcessChatCompletions(process *process.Process) interface{} {
 
 	process.ValidateArgNums(2)
-	ctx, cancel := context.WithCancel(context.Background())
+	parent := process.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(usage.WithMeta(parent, usage.Meta{Sid: process.Sid, Source: "openai"}))
 	defer cancel()
 
 	model := process.ArgsString(0)
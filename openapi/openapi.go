@@ -0,0 +1,610 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/widgets/action"
+	"github.com/yaoapp/yao/widgets/chart"
+	"github.com/yaoapp/yao/widgets/dashboard"
+	"github.com/yaoapp/yao/widgets/form"
+	"github.com/yaoapp/yao/widgets/list"
+	"github.com/yaoapp/yao/widgets/table"
+)
+
+// Option the option of the document
+type Option struct {
+	Servers    []string       // The server urls. e.g. https://api.example.com
+	Routes     gin.RoutesInfo // The routes registered without the API DSLs. e.g. the Neo API
+	Extensions bool           // Add the x-yao-process and x-yao-guard extensions, they show the processes and the guards of the APIs
+}
+
+// SecuritySchemes the security schemes of the built-in guards
+var SecuritySchemes = map[string]SecurityScheme{
+	"bearer-jwt":   {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "The JWT token in the Authorization header"},
+	"query-jwt":    {Type: "apiKey", In: "query", Name: "__tk", Description: "The JWT token in the query string"},
+	"cookie-jwt":   {Type: "apiKey", In: "cookie", Name: "__tk", Description: "The JWT token in the cookie"},
+	"cookie-trace": {Type: "apiKey", In: "cookie", Name: "sid", Description: "The session id in the cookie"},
+}
+
+// widget the widget instance of the widget APIs
+type widget struct {
+	ID     string
+	Model  string
+	Action interface{} // The action DSL of the widget
+}
+
+var reModelProcess = regexp.MustCompile(`(?i)^models\.(.+)\.([a-z]+)$`)
+var reOperationID = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// Generate generate the OpenAPI document from the loaded APIs, widgets and models
+func Generate(option Option) *Document {
+	doc := New(option.Servers...)
+	doc.extensions = option.Extensions
+
+	ids := []string{}
+	for id := range model.Models {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		doc.Components.Schemas[id] = ModelSchema(model.Models[id])
+	}
+
+	ids = []string{}
+	for id := range api.APIs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	expanded := []string{} // The widget routes are expanded by the widget ids
+	for _, id := range ids {
+		inst := api.APIs[id]
+		if inst.Type != "" && inst.Type != "http" {
+			continue
+		}
+
+		if strings.HasPrefix(id, "widgets.") {
+			kind := strings.TrimPrefix(id, "widgets.")
+			expanded = append(expanded, fullpath(inst.HTTP.Group, "/"))
+			for _, w := range widgets(kind) {
+				doc.addWidget(kind, w, inst.HTTP)
+			}
+			continue
+		}
+
+		tag := inst.HTTP.Name
+		if tag == "" {
+			tag = id
+		}
+
+		doc.addTag(tag, inst.HTTP.Description)
+		for _, path := range inst.HTTP.Paths {
+			guard := path.Guard
+			if guard == "" {
+				guard = inst.HTTP.Guard
+			}
+			doc.AddPath(tag, fullpath(inst.HTTP.Group, path.Path), path, guard, "", "")
+		}
+	}
+
+	for _, route := range option.Routes {
+		if hasPrefix(route.Path, expanded) {
+			continue
+		}
+		doc.AddRoute(route.Method, route.Path)
+	}
+
+	return doc
+}
+
+// New create an empty document
+func New(servers ...string) *Document {
+	title := share.App.Name
+	if title == "" {
+		title = "Yao"
+	}
+
+	version := share.App.Version
+	if version == "" {
+		version = share.VERSION
+	}
+
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version, Description: share.App.Description},
+		Servers:    []Server{},
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}, SecuritySchemes: map[string]SecurityScheme{}},
+		Tags:       []Tag{},
+	}
+
+	for _, url := range servers {
+		doc.Servers = append(doc.Servers, Server{URL: url})
+	}
+	return doc
+}
+
+// AddPath add the operation of the API path, the model and the kind (find, search, save ...) are used for the request and the response schemas
+func (doc *Document) AddPath(tag string, path string, p api.Path, guard string, modelID string, kind string) {
+	if modelID == "" {
+		if matches := reModelProcess.FindStringSubmatch(p.Process); matches != nil {
+			modelID = matches[1]
+			kind = matches[2]
+		}
+	}
+
+	if _, has := doc.Components.Schemas[modelID]; !has {
+		modelID = ""
+	}
+
+	op := &Operation{
+		Tags:        []string{tag},
+		Summary:     p.Label,
+		Description: p.Description,
+		OperationID: operationID(p.Method, path),
+		Parameters:  []Parameter{},
+		Responses:   map[string]Response{},
+	}
+
+	if doc.extensions {
+		op.Process = p.Process
+		op.Guard = guard
+	}
+
+	doc.setParameters(op, path, p.In, modelID, kind)
+	doc.setSecurity(op, guard)
+
+	status := p.Out.Status
+	if status == 0 {
+		status = 200
+	}
+
+	typ := p.Out.Type
+	if typ == "" {
+		typ = "application/json"
+	}
+
+	if contentType, has := p.Out.Headers["Content-Type"]; has && strings.Contains(contentType, "{{") {
+		typ = "application/octet-stream"
+	}
+
+	res := Response{Description: "OK", Content: map[string]MediaType{}}
+	switch {
+	case strings.Contains(typ, "json"):
+		res.Content[typ] = MediaType{Schema: responseSchema(modelID, kind)}
+	case strings.HasPrefix(typ, "text/"):
+		res.Content[typ] = MediaType{Schema: &Schema{Type: "string"}}
+	default:
+		res.Content[typ] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	}
+
+	op.Responses[fmt.Sprintf("%d", status)] = res
+	doc.setOperation(path, p.Method, op)
+}
+
+// AddRoute add the route without the API DSL, the existing operations are not overwritten
+func (doc *Document) AddRoute(method string, path string) {
+	if method == "OPTIONS" || method == "HEAD" {
+		return
+	}
+
+	path = convertPath(path)
+	if item, has := doc.Paths[path]; has {
+		if _, has := item[strings.ToLower(method)]; has {
+			return
+		}
+	}
+
+	tag := "routes"
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 2 && parts[0] == "api" && parts[1] == "__yao" {
+		tag = parts[2]
+	}
+	doc.addTag(tag, "")
+
+	op := &Operation{
+		Tags:        []string{tag},
+		OperationID: operationID(method, path),
+		Parameters:  pathParameters(path),
+		Responses:   map[string]Response{"200": {Description: "OK"}},
+	}
+	doc.setOperation(path, method, op)
+}
+
+// ModelSchema the JSON schema of the model
+func ModelSchema(mod *model.Model) *Schema {
+	schema := &Schema{
+		Type:        "object",
+		Title:       mod.MetaData.Name,
+		Description: mod.MetaData.Table.Comment,
+		Properties:  map[string]*Schema{},
+		Required:    []string{},
+	}
+
+	for _, col := range mod.MetaData.Columns {
+		prop := columnSchema(strings.ToLower(col.Type), col.Option, col.Nullable)
+		prop.Title = col.Label
+		prop.Description = col.Comment
+		if col.Length > 0 && (col.Type == "string" || col.Type == "char") {
+			prop.MaxLength = col.Length
+		}
+
+		if col.Default != nil {
+			prop.Default = col.Default
+		}
+
+		schema.Properties[col.Name] = prop
+		if !col.Nullable && col.Default == nil && col.DefaultRaw == "" && !col.Primary && strings.ToLower(col.Type) != "id" && !strings.HasSuffix(strings.ToLower(col.Type), "increments") {
+			schema.Required = append(schema.Required, col.Name)
+		}
+	}
+
+	if mod.MetaData.Option.Timestamps {
+		schema.Properties["created_at"] = columnSchema("timestamp", nil, true)
+		schema.Properties["updated_at"] = columnSchema("timestamp", nil, true)
+	}
+
+	if mod.MetaData.Option.SoftDeletes {
+		schema.Properties["deleted_at"] = columnSchema("timestamp", nil, true)
+	}
+
+	return schema
+}
+
+func (doc *Document) addWidget(kind string, w widget, http api.HTTP) {
+	tag := fmt.Sprintf("%s.%s", kind, w.ID)
+	doc.addTag(tag, fmt.Sprintf("The %s widget %s", kind, w.ID))
+
+	for _, path := range http.Paths {
+		key := actionKey(path.Path)
+		act := actionOf(w.Action, key)
+		if act == nil || act.Disable {
+			continue
+		}
+
+		guard := act.Guard
+		if guard == "" {
+			guard = "bearer-jwt"
+		}
+
+		// The widget id is a part of the path
+		path.In = withoutArg(path.In, "$param.id")
+		route := fullpath(http.Group, strings.Replace(path.Path, "/:id", "/"+w.ID, 1))
+		doc.AddPath(tag, route, path, guard, w.Model, key)
+	}
+}
+
+func (doc *Document) setParameters(op *Operation, path string, in []interface{}, modelID string, kind string) {
+	op.Parameters = pathParameters(path)
+	body := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	payload := false
+
+	for _, arg := range in {
+		name, ok := arg.(string)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(name, "$query."):
+			op.Parameters = append(op.Parameters, Parameter{Name: strings.TrimPrefix(name, "$query."), In: "query", Schema: &Schema{Type: "string"}})
+
+		case strings.HasPrefix(name, "$header."):
+			op.Parameters = append(op.Parameters, Parameter{Name: strings.TrimPrefix(name, "$header."), In: "header", Schema: &Schema{Type: "string"}})
+
+		case strings.HasPrefix(name, "$payload."):
+			body.Properties[strings.TrimPrefix(name, "$payload.")] = &Schema{}
+
+		case strings.HasPrefix(name, "$form."):
+			form.Properties[strings.TrimPrefix(name, "$form.")] = &Schema{Type: "string"}
+
+		case strings.HasPrefix(name, "$file."):
+			form.Properties[strings.TrimPrefix(name, "$file.")] = &Schema{Type: "string", Format: "binary"}
+
+		case name == ":query" || name == ":query-param":
+			explode := true
+			description := "The query string"
+			if name == ":query-param" {
+				description = "The query params. e.g. select=id,name&where.name.match=yao&order=id.desc"
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: "query", In: "query", Description: description, Style: "form", Explode: &explode,
+				Schema: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			})
+
+		case name == ":payload" || name == ":body":
+			payload = true
+		}
+	}
+
+	content := map[string]MediaType{}
+	if payload {
+		schema := &Schema{Type: "object", AdditionalProperties: true}
+		if modelID != "" && requestModel(kind) {
+			schema = &Schema{Ref: ref(modelID)}
+		}
+		content["application/json"] = MediaType{Schema: schema}
+	} else if len(body.Properties) > 0 {
+		content["application/json"] = MediaType{Schema: body}
+	}
+
+	if len(form.Properties) > 0 {
+		content["multipart/form-data"] = MediaType{Schema: form}
+	}
+
+	if len(content) > 0 {
+		op.RequestBody = &RequestBody{Required: payload, Content: content}
+	}
+}
+
+func (doc *Document) setSecurity(op *Operation, guard string) {
+	requirement := map[string][]string{}
+	for _, name := range strings.Split(guard, ",") {
+		name = strings.TrimSpace(name)
+		if name == "-" {
+			op.Security = []map[string][]string{{}}
+			return
+		}
+
+		scheme, has := SecuritySchemes[name]
+		if !has || name == "cookie-trace" {
+			continue
+		}
+		doc.Components.SecuritySchemes[name] = scheme
+		requirement[name] = []string{}
+	}
+
+	if len(requirement) > 0 {
+		op.Security = []map[string][]string{requirement}
+	}
+}
+
+func (doc *Document) setOperation(path string, method string, op *Operation) {
+	item, has := doc.Paths[path]
+	if !has {
+		item = PathItem{}
+		doc.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+func (doc *Document) addTag(name string, description string) {
+	for _, tag := range doc.Tags {
+		if tag.Name == name {
+			return
+		}
+	}
+	doc.Tags = append(doc.Tags, Tag{Name: name, Description: description})
+}
+
+// widgets the loaded widgets of the kind
+func widgets(kind string) []widget {
+	res := []widget{}
+	switch kind {
+	case "table":
+		for id, dsl := range table.Tables {
+			w := widget{ID: id, Action: dsl.Action}
+			if dsl.Action != nil && dsl.Action.Bind != nil {
+				w.Model = dsl.Action.Bind.Model
+			}
+			res = append(res, w)
+		}
+
+	case "form":
+		for id, dsl := range form.Forms {
+			w := widget{ID: id, Action: dsl.Action}
+			if dsl.Action != nil && dsl.Action.Bind != nil {
+				w.Model = dsl.Action.Bind.Model
+			}
+			res = append(res, w)
+		}
+
+	case "list":
+		for id, dsl := range list.Lists {
+			w := widget{ID: id, Action: dsl.Action}
+			if dsl.Action != nil && dsl.Action.Bind != nil {
+				w.Model = dsl.Action.Bind.Model
+			}
+			res = append(res, w)
+		}
+
+	case "chart":
+		for id, dsl := range chart.Charts {
+			res = append(res, widget{ID: id, Action: dsl.Action})
+		}
+
+	case "dashboard":
+		for id, dsl := range dashboard.Dashboards {
+			res = append(res, widget{ID: id, Action: dsl.Action})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// actionKey the action of the widget path. e.g. /:id/update/in -> update-in, /:id/find/:primary -> find
+func actionKey(path string) string {
+	parts := []string{}
+	for _, part := range strings.Split(strings.TrimPrefix(path, "/:id"), "/") {
+		if part == "" || strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "-")
+}
+
+// actionOf the action process of the widget action DSL, matched by the json tag or the field name
+func actionOf(actions interface{}, key string) *action.Process {
+	value := reflect.ValueOf(actions)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return nil
+	}
+
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag != key && strings.ToLower(field.Name) != strings.ReplaceAll(key, "-", "") {
+			continue
+		}
+
+		if act, ok := value.Field(i).Interface().(*action.Process); ok {
+			return act
+		}
+	}
+	return nil
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutArg(in []interface{}, name string) []interface{} {
+	res := []interface{}{}
+	for _, arg := range in {
+		if arg != name {
+			res = append(res, arg)
+		}
+	}
+	return res
+}
+
+// requestModel the methods use the model as the request body
+func requestModel(kind string) bool {
+	switch strings.ToLower(kind) {
+	case "create", "save", "update", "update-in", "update-where", "updatewhere":
+		return true
+	}
+	return false
+}
+
+// responseSchema the response schema of the model methods. e.g. Search, Find
+func responseSchema(modelID string, kind string) *Schema {
+	if modelID == "" {
+		return &Schema{}
+	}
+
+	switch strings.ToLower(kind) {
+	case "find":
+		return &Schema{Ref: ref(modelID)}
+
+	case "get":
+		return &Schema{Type: "array", Items: &Schema{Ref: ref(modelID)}}
+
+	case "search", "paginate":
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":     {Type: "array", Items: &Schema{Ref: ref(modelID)}},
+				"total":    {Type: "integer"},
+				"page":     {Type: "integer"},
+				"pagesize": {Type: "integer"},
+				"pagecnt":  {Type: "integer"},
+				"next":     {Type: "integer"},
+				"prev":     {Type: "integer"},
+			},
+		}
+
+	case "create", "save":
+		return &Schema{Type: "integer", Description: "The primary key"}
+	}
+	return &Schema{}
+}
+
+func columnSchema(typ string, option []string, nullable bool) *Schema {
+	schema := &Schema{}
+	name := "string"
+	switch strings.TrimPrefix(typ, "unsigned") {
+	case "id", "increments", "bigincrements", "smallincrements", "tinyincrements", "mediumincrements",
+		"tinyinteger", "smallinteger", "mediuminteger", "integer", "biginteger", "year":
+		name = "integer"
+	case "float", "double", "decimal":
+		name = "number"
+	case "boolean":
+		name = "boolean"
+	case "json", "jsonb":
+		name = ""
+	case "date":
+		schema.Format = "date"
+	case "datetime", "datetimetz", "timestamp", "timestamptz":
+		schema.Format = "date-time"
+	case "time", "timetz":
+		schema.Format = "time"
+	case "uuid":
+		schema.Format = "uuid"
+	case "ipaddress":
+		schema.Format = "ipv4"
+	case "enum":
+		for _, opt := range option {
+			schema.Enum = append(schema.Enum, opt)
+		}
+	}
+
+	if name == "" {
+		return schema
+	}
+
+	schema.Type = name
+	if nullable {
+		schema.Type = []string{name, "null"}
+	}
+	return schema
+}
+
+func ref(modelID string) string {
+	return fmt.Sprintf("#/components/schemas/%s", modelID)
+}
+
+func fullpath(group string, path string) string {
+	group = strings.Trim(group, "/")
+	if group == "" {
+		return convertPath("/api" + path)
+	}
+	return convertPath(fmt.Sprintf("/api/%s%s", group, path))
+}
+
+// convertPath convert the gin path to the OpenAPI path. e.g. /api/pet/:id -> /api/pet/{id}
+func convertPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = fmt.Sprintf("{%s}", part[1:])
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func pathParameters(path string) []Parameter {
+	params := []Parameter{}
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			params = append(params, Parameter{Name: part[1 : len(part)-1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return params
+}
+
+func operationID(method string, path string) string {
+	return strings.Trim(reOperationID.ReplaceAllString(strings.ToLower(method)+"_"+path, "_"), "_")
+}
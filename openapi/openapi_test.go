@@ -0,0 +1,85 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/widgets/action"
+)
+
+func TestGenerate(t *testing.T) {
+	err := engine.Load(config.Conf, engine.LoadOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Unload()
+
+	doc := Generate(Option{Servers: []string{"http://127.0.0.1:5099"}})
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, "http://127.0.0.1:5099", doc.Servers[0].URL)
+	assert.NotNil(t, doc.Components.Schemas["pet"])
+
+	search := doc.Paths["/api/__yao/table/pet/search"]["get"]
+	if assert.NotNil(t, search) {
+		assert.Equal(t, "#/components/schemas/pet", search.Responses["200"].Content["application/json"].Schema.Properties["data"].Items.Ref)
+		assert.Contains(t, search.Security[0], "bearer-jwt")
+	}
+}
+
+func TestAddPath(t *testing.T) {
+	doc := New()
+	doc.Components.Schemas["pet"] = &Schema{Type: "object"}
+	doc.AddPath("pet", "/api/pet/{id}", api.Path{
+		Label:   "Update",
+		Method:  "POST",
+		Process: "models.pet.Update",
+		In:      []interface{}{"$param.id", ":payload", "$query.lang"},
+		Out:     api.Out{Status: 200, Type: "application/json"},
+	}, "bearer-jwt,cross-origin", "", "")
+
+	op := doc.Paths["/api/pet/{id}"]["post"]
+	assert.Equal(t, "post_api_pet_id", op.OperationID)
+	assert.Equal(t, "id", op.Parameters[0].Name)
+	assert.Equal(t, "path", op.Parameters[0].In)
+	assert.Equal(t, "lang", op.Parameters[1].Name)
+	assert.Equal(t, "#/components/schemas/pet", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, []map[string][]string{{"bearer-jwt": {}}}, op.Security)
+	assert.Contains(t, doc.Components.SecuritySchemes, "bearer-jwt")
+
+	// The processes and the guards are not shown by default
+	assert.Empty(t, op.Process)
+	assert.Empty(t, op.Guard)
+	doc.extensions = true
+	doc.AddPath("pet", "/api/pet/{id}/extensions", api.Path{Method: "POST", Process: "models.pet.Update"}, "bearer-jwt", "", "")
+	assert.Equal(t, "models.pet.Update", doc.Paths["/api/pet/{id}/extensions"]["post"].Process)
+	assert.Equal(t, "bearer-jwt", doc.Paths["/api/pet/{id}/extensions"]["post"].Guard)
+
+	doc.AddPath("pet", "/api/pet/public", api.Path{Method: "GET"}, "-", "", "")
+	assert.Equal(t, []map[string][]string{{}}, doc.Paths["/api/pet/public"]["get"].Security)
+
+	doc.AddRoute("GET", "/api/__yao/neo/chats/:id")
+	assert.Equal(t, "neo", doc.Paths["/api/__yao/neo/chats/{id}"]["get"].Tags[0])
+}
+
+func TestActionOf(t *testing.T) {
+	actions := &struct {
+		Search   *action.Process `json:"search,omitempty"`
+		UpdateIn *action.Process `json:"update-in,omitempty"`
+		Data     *action.Process `json:"-"`
+	}{
+		Search:   &action.Process{Guard: "bearer-jwt"},
+		UpdateIn: &action.Process{Guard: "-"},
+		Data:     &action.Process{},
+	}
+
+	assert.Equal(t, "update-in", actionKey("/:id/update/in"))
+	assert.Equal(t, "find", actionKey("/:id/find/:primary"))
+	assert.Equal(t, "bearer-jwt", actionOf(actions, "search").Guard)
+	assert.Equal(t, "-", actionOf(actions, "update-in").Guard)
+	assert.NotNil(t, actionOf(actions, "data"))
+	assert.Nil(t, actionOf(actions, "delete"))
+	assert.Equal(t, "/api/{id}/files/{path}", convertPath("/api/:id/files/*path"))
+}
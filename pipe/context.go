@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/telemetry"
 )
 
 var contexts = sync.Map{}
@@ -115,15 +116,58 @@ func (ctx *Context) Exec(args ...any) (any, error) {
 // Exec and return error
 func (ctx *Context) exec(node *Node, input Input) (output any, err error) {
 
-	var out any
-	switch node.Type {
+	out, pause, err := ctx.run(node, input)
+	if err != nil {
+		return nil, err
+	}
 
-	case "process":
-		out, err = node.YaoProcess(ctx, input)
+	// Pause the pipe waiting for user input
+	if pause {
+		return out, nil
+	}
+
+	// Execute the next node
+	next, eof, err := ctx.next()
+	if err != nil {
+		return nil, err
+	}
+
+	// End of the pipe
+	if eof {
+		defer Close(ctx.id)
+		output, err := ctx.parseOutput()
 		if err != nil {
 			return nil, err
 		}
 
+		return output, nil
+	}
+
+	// Execute the next node
+	return ctx.exec(next, anyToInput(out))
+}
+
+// run execute the node, each node execution is traced
+func (ctx *Context) run(node *Node, input Input) (out any, pause bool, err error) {
+
+	// The nodes are executed one by one, the node span is the parent of the processes and the AI requests of the node
+	parent := ctx.context
+	spanCtx, span := telemetry.Start(parent, fmt.Sprintf("pipe.node %s", node.Name), telemetry.SpanKindInternal)
+	span.SetAttribute("pipe.name", ctx.Name)
+	span.SetAttribute("pipe.node", node.Name)
+	span.SetAttribute("pipe.type", node.Type)
+	ctx.context = spanCtx
+	defer func() {
+		ctx.context = parent
+		span.SetError(err)
+		span.Finish()
+	}()
+
+	switch node.Type {
+
+	case "process":
+		out, err = node.YaoProcess(ctx, input)
+
 	// case "request":
 	// 	err := node.ExecRequest(ctx, args)
 	// 	if err != nil {
@@ -132,51 +176,21 @@ func (ctx *Context) exec(node *Node, input Input) (output any, err error) {
 
 	case "ai":
 		out, err = node.AI(ctx, input)
-		if err != nil {
-			return nil, err
-		}
 
 	case "switch":
 		out, err = node.Case(ctx, input)
-		if err != nil {
-			return nil, err
-		}
 
 	case "user-input":
-		var pause bool = false
 		out, pause, err = node.Render(ctx, input)
-		if err != nil {
-			return nil, err
-		}
-
-		// Pause the pipe waiting for user input
-		if pause {
-			return out, nil
-		}
 
 	default:
-		return nil, node.Errorf(ctx, "type '%s' not support", node.Type)
+		err = node.Errorf(ctx, "type '%s' not support", node.Type)
 	}
 
-	// Execute the next node
-	next, eof, err := ctx.next()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-
-	// End of the pipe
-	if eof {
-		defer Close(ctx.id)
-		output, err := ctx.parseOutput()
-		if err != nil {
-			return nil, err
-		}
-
-		return output, nil
-	}
-
-	// Execute the next node
-	return ctx.exec(next, anyToInput(out))
+	return out, pause, nil
 }
 
 // Next the next node
@@ -344,6 +358,7 @@ func (ctx *Context) inheritance(parent *Context) *Context {
 	ctx.history = parent.history
 	ctx.global = parent.global
 	ctx.sid = parent.sid
+	ctx.context = parent.context
 	ctx.parent = parent
 	return ctx
 }
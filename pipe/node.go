@@ -1,13 +1,15 @@
 package pipe
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/process"
-	"github.com/yaoapp/yao/openai"
+	"github.com/yaoapp/yao/llm"
 	"github.com/yaoapp/yao/pipe/ui/cli"
+	"github.com/yaoapp/yao/usage"
 )
 
 // Case Execute the user input
@@ -86,6 +88,10 @@ func (node *Node) YaoProcess(ctx *Context, input Input) (any, error) {
 		return nil, node.Errorf(ctx, err.Error())
 	}
 
+	if ctx.context != nil {
+		process.WithContext(ctx.context)
+	}
+
 	res, err := process.WithGlobal(ctx.global).WithSID(ctx.sid).Exec()
 	if err != nil {
 		return nil, node.Errorf(ctx, err.Error())
@@ -133,14 +139,26 @@ func (node *Node) AI(ctx *Context, input Input) (any, error) {
 
 func (node *Node) chatCompletions(ctx *Context, prompts []Prompt, options map[string]interface{}) (any, error) {
 	// moapi call
-	ai, err := openai.NewMoapi(node.Model)
+	connector := "moapi:" + node.Model
+	if node.Connector != "" {
+		connector = node.Connector
+	}
+
+	ai, err := llm.New(connector)
 	if err != nil {
 		return nil, err
 	}
 
+	// the token usage is recorded as the pipes.<id> assistant
+	parent := ctx.context
+	if parent == nil {
+		parent = context.Background()
+	}
+	parent = usage.WithMeta(parent, usage.Meta{Sid: ctx.sid, Assistant: "pipes." + ctx.Pipe.ID, Source: "pipe"})
+
 	response := []string{}
 	content := []string{}
-	_, ex := ai.ChatCompletions(promptsToMap(prompts), node.Options, func(data []byte) int {
+	_, ex := ai.ChatCompletionsWith(parent, promptsToMap(prompts), node.Options, func(data []byte) int {
 
 		// Prograss Hook
 
@@ -25,7 +25,7 @@ func processPipes(process *process.Process) interface{} {
 		exception.New("pipes.%s not loaded", 404, process.ID).Throw()
 		return nil
 	}
-	ctx := pipe.Create().WithGlobal(process.Global).WithSid(process.Sid)
+	ctx := pipe.Create().WithGlobal(process.Global).WithSid(process.Sid).With(process.Context)
 	return ctx.Run(process.Args...)
 }
 
@@ -43,7 +43,7 @@ func processCreate(process *process.Process) interface{} {
 		exception.New(err.Error(), 500).Throw()
 	}
 
-	ctx := pipe.Create().WithGlobal(process.Global).WithSid(process.Sid)
+	ctx := pipe.Create().WithGlobal(process.Global).WithSid(process.Sid).With(process.Context)
 	return ctx.Run(args...)
 }
 
@@ -78,7 +78,7 @@ func processCreateWith(process *process.Process) interface{} {
 		exception.New(err.Error(), 500).Throw()
 	}
 
-	ctx := pipe.Create().WithGlobal(data).WithSid(process.Sid)
+	ctx := pipe.Create().WithGlobal(data).WithSid(process.Sid).With(process.Context)
 	return ctx.Run(args...)
 }
 
@@ -95,7 +95,7 @@ func processRun(process *process.Process) interface{} {
 		exception.New("pipes.%s not loaded", 404, process.ID).Throw()
 	}
 
-	ctx := pipe.Create().WithGlobal(process.Global).WithSid(process.Sid)
+	ctx := pipe.Create().WithGlobal(process.Global).WithSid(process.Sid).With(process.Context)
 	return ctx.Run(args...)
 }
 
@@ -116,6 +116,7 @@ func processResume(process *process.Process) interface{} {
 	return ctx.
 		WithGlobal(process.Global).
 		WithSid(process.Sid).
+		With(process.Context).
 		Resume(id, args...)
 }
 
@@ -154,6 +155,7 @@ func processResumeWith(process *process.Process) interface{} {
 	return ctx.
 		WithGlobal(data).
 		WithSid(process.Sid).
+		With(process.Context).
 		Resume(id, args...)
 }
 
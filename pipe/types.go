@@ -47,20 +47,21 @@ type Hooks struct {
 
 // Node the pip node
 type Node struct {
-	Name     string           `json:"name"`
-	Type     string           `json:"type,omitempty"`     // user-input, ai, process, switch, request
-	Label    string           `json:"label,omitempty"`    // Display
-	Process  *Process         `json:"process,omitempty"`  // Yao Process
-	Prompts  []Prompt         `json:"prompts,omitempty"`  // AI prompts
-	Model    string           `json:"model,omitempty"`    // AI model name (optional)
-	Options  map[string]any   `json:"options,omitempty"`  // AI or Request options (optional)
-	Request  *Request         `json:"request,omitempty"`  // Http Request
-	UI       string           `json:"ui,omitempty"`       // The User Interface cli, web, app, wxapp ...
-	AutoFill *AutoFill        `json:"autofill,omitempty"` // Autofill the user input with the expression
-	Switch   map[string]*Pipe `json:"case,omitempty"`     // Switch
-	Input    Input            `json:"input,omitempty"`    // the node input expression
-	Output   any              `json:"output,omitempty"`   // the node output expression
-	Goto     string           `json:"goto,omitempty"`     // goto node name / EOF
+	Name      string           `json:"name"`
+	Type      string           `json:"type,omitempty"`      // user-input, ai, process, switch, request
+	Label     string           `json:"label,omitempty"`     // Display
+	Process   *Process         `json:"process,omitempty"`   // Yao Process
+	Prompts   []Prompt         `json:"prompts,omitempty"`   // AI prompts
+	Model     string           `json:"model,omitempty"`     // AI model name (optional)
+	Connector string           `json:"connector,omitempty"` // AI connector (optional), the moapi is used if empty
+	Options   map[string]any   `json:"options,omitempty"`   // AI or Request options (optional)
+	Request   *Request         `json:"request,omitempty"`   // Http Request
+	UI        string           `json:"ui,omitempty"`        // The User Interface cli, web, app, wxapp ...
+	AutoFill  *AutoFill        `json:"autofill,omitempty"`  // Autofill the user input with the expression
+	Switch    map[string]*Pipe `json:"case,omitempty"`      // Switch
+	Input     Input            `json:"input,omitempty"`     // the node input expression
+	Output    any              `json:"output,omitempty"`    // the node output expression
+	Goto      string           `json:"goto,omitempty"`      // goto node name / EOF
 
 	index int // the index of the node
 }
@@ -0,0 +1,452 @@
+package prompt
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Template the prompt template
+//
+//	Hello {{ customer.name }}!
+//	{{ if customer.vip }}Thank you for being a VIP.{{ else if customer.orders > 0 }}Welcome back.{{ else }}Welcome.{{ end }}
+//	{{- for i, item in items }}
+//	{{ i + 1 }}. {{ item.name }} x {{ item.quantity }}
+//	{{- end }}
+//
+// The expressions are the expr-lang expressions, {{- and -}} trim the spaces before and after the tag.
+type Template struct {
+	Source string
+	nodes  []*node
+}
+
+type node struct {
+	kind        int // text, output, if, for
+	text        string
+	stmt        string
+	program     *vm.Program
+	identifiers []string
+	branches    []*branch // if
+	key, value  string    // for
+	body        []*node   // for
+}
+
+type branch struct {
+	stmt        string
+	program     *vm.Program // nil: else
+	identifiers []string
+	body        []*node
+}
+
+const (
+	kindText = iota
+	kindOutput
+	kindIf
+	kindFor
+)
+
+var tagRe = regexp.MustCompile(`(?s)\{\{(-?)(.*?)(-?)\}\}`)
+var forRe = regexp.MustCompile(`(?s)^for\s+([A-Za-z_]\w*)(?:\s*,\s*([A-Za-z_]\w*))?\s+in\s+(.+)$`)
+
+// IsTemplate check if the prompt is a template
+func IsTemplate(source string) bool {
+	return tagRe.MatchString(source)
+}
+
+// Parse the template
+func Parse(source string) (*Template, error) {
+	type frame struct {
+		node   *node
+		branch *branch
+	}
+
+	root := []*node{}
+	stack := []frame{}
+	add := func(n *node) {
+		if len(stack) == 0 {
+			root = append(root, n)
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.branch != nil {
+			top.branch.body = append(top.branch.body, n)
+			return
+		}
+		top.node.body = append(top.node.body, n)
+	}
+
+	pos := 0
+	trimNext := false
+	for _, loc := range tagRe.FindAllStringSubmatchIndex(source, -1) {
+		text := source[pos:loc[0]]
+		if trimNext {
+			text = strings.TrimLeft(text, " \t\r\n")
+		}
+		if loc[3] > loc[2] {
+			text = strings.TrimRight(text, " \t\r\n")
+		}
+		if text != "" {
+			add(&node{kind: kindText, text: text})
+		}
+		trimNext = loc[7] > loc[6]
+		pos = loc[1]
+
+		stmt := strings.TrimSpace(source[loc[4]:loc[5]])
+		line := strings.Count(source[:loc[0]], "\n") + 1
+		switch {
+		case stmt == "end":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected {{ end }}", line)
+			}
+			stack = stack[:len(stack)-1]
+
+		case stmt == "else" || strings.HasPrefix(stmt, "else if "):
+			if len(stack) == 0 || stack[len(stack)-1].node.kind != kindIf {
+				return nil, fmt.Errorf("line %d: unexpected {{ %s }}", line, stmt)
+			}
+
+			top := &stack[len(stack)-1]
+			if top.branch.program == nil {
+				return nil, fmt.Errorf("line %d: unexpected {{ %s }} after {{ else }}", line, stmt)
+			}
+
+			b := &branch{}
+			if stmt != "else" {
+				cond, err := compile(strings.TrimSpace(strings.TrimPrefix(stmt, "else if ")), line)
+				if err != nil {
+					return nil, err
+				}
+				b = cond
+			}
+			top.node.branches = append(top.node.branches, b)
+			top.branch = b
+
+		case strings.HasPrefix(stmt, "if "):
+			b, err := compile(strings.TrimSpace(strings.TrimPrefix(stmt, "if ")), line)
+			if err != nil {
+				return nil, err
+			}
+			n := &node{kind: kindIf, branches: []*branch{b}}
+			add(n)
+			stack = append(stack, frame{node: n, branch: b})
+
+		case strings.HasPrefix(stmt, "for "):
+			match := forRe.FindStringSubmatch(stmt)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: {{ %s }} should be {{ for item in list }} or {{ for key, item in list }}", line, stmt)
+			}
+
+			list, err := compile(match[3], line)
+			if err != nil {
+				return nil, err
+			}
+
+			n := &node{kind: kindFor, stmt: list.stmt, program: list.program, identifiers: list.identifiers, value: match[1]}
+			if match[2] != "" {
+				n.key, n.value = match[1], match[2]
+			}
+			add(n)
+			stack = append(stack, frame{node: n})
+
+		default:
+			out, err := compile(stmt, line)
+			if err != nil {
+				return nil, err
+			}
+			add(&node{kind: kindOutput, stmt: out.stmt, program: out.program, identifiers: out.identifiers})
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("{{ %s }} is not closed, {{ end }} is required", stack[len(stack)-1].node.kindName())
+	}
+
+	text := source[pos:]
+	if trimNext {
+		text = strings.TrimLeft(text, " \t\r\n")
+	}
+	if text != "" {
+		add(&node{kind: kindText, text: text})
+	}
+
+	return &Template{Source: source, nodes: root}, nil
+}
+
+// Identifiers the variables the template uses, the loop variables are excluded
+func (tmpl *Template) Identifiers() []string {
+	found := map[string]bool{}
+	identifiers(tmpl.nodes, map[string]bool{}, found)
+
+	names := []string{}
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Uses check if the template uses the variable
+func (tmpl *Template) Uses(name string) bool {
+	for _, identifier := range tmpl.Identifiers() {
+		if identifier == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Render the template with the data
+func (tmpl *Template) Render(data map[string]interface{}) (string, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	var res strings.Builder
+	err := render(&res, tmpl.nodes, data)
+	if err != nil {
+		return "", err
+	}
+	return res.String(), nil
+}
+
+func render(res *strings.Builder, nodes []*node, data map[string]interface{}) error {
+	for _, n := range nodes {
+		switch n.kind {
+		case kindText:
+			res.WriteString(n.text)
+
+		case kindOutput:
+			value, err := expr.Run(n.program, data)
+			if err != nil {
+				return fmt.Errorf("{{ %s }} %s", n.stmt, err.Error())
+			}
+			res.WriteString(String(value))
+
+		case kindIf:
+			for _, b := range n.branches {
+				if b.program != nil {
+					value, err := expr.Run(b.program, data)
+					if err != nil {
+						return fmt.Errorf("{{ if %s }} %s", b.stmt, err.Error())
+					}
+					if !truthy(value) {
+						continue
+					}
+				}
+
+				if err := render(res, b.body, data); err != nil {
+					return err
+				}
+				break
+			}
+
+		case kindFor:
+			value, err := expr.Run(n.program, data)
+			if err != nil {
+				return fmt.Errorf("{{ for %s }} %s", n.stmt, err.Error())
+			}
+
+			err = each(value, func(key interface{}, item interface{}) error {
+				scope := make(map[string]interface{}, len(data)+2)
+				for name, v := range data {
+					scope[name] = v
+				}
+				scope[n.value] = item
+				if n.key != "" {
+					scope[n.key] = key
+				}
+				return render(res, n.body, scope)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// each iterate the items of the list, the keys of the map are sorted
+func each(value interface{}, fn func(key interface{}, item interface{}) error) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+
+	case []interface{}:
+		for i, item := range v {
+			if err := fn(i, item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := fn(key, v[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := fn(i, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%v is not a list", value)
+}
+
+// String the text of the value, the objects and the arrays are JSON
+func String(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case bool, int, int64, int32, uint, uint64, uint32:
+		return fmt.Sprintf("%v", v)
+	}
+
+	data, err := jsoniter.MarshalToString(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return data
+}
+
+// truthy false, nil, 0, "" and the empty lists are false
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0
+	case reflect.Float32:
+		return rv.Float() != 0
+	}
+	return true
+}
+
+// compile the expression and read the variables it uses
+func compile(stmt string, line int) (*branch, error) {
+	if stmt == "" {
+		return nil, fmt.Errorf("line %d: the expression is empty", line)
+	}
+
+	tree, err := parser.Parse(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: {{ %s }} %s", line, stmt, err.Error())
+	}
+
+	program, err := expr.Compile(stmt, expr.AllowUndefinedVariables())
+	if err != nil {
+		return nil, fmt.Errorf("line %d: {{ %s }} %s", line, stmt, err.Error())
+	}
+
+	v := &visitor{declared: map[string]bool{}}
+	ast.Walk(&tree.Node, v)
+
+	names := []string{}
+	for _, name := range v.identifiers {
+		if !v.declared[name] {
+			names = append(names, name)
+		}
+	}
+	return &branch{stmt: stmt, program: program, identifiers: names}, nil
+}
+
+func identifiers(nodes []*node, scope map[string]bool, found map[string]bool) {
+	add := func(names []string) {
+		for _, name := range names {
+			if !scope[name] {
+				found[name] = true
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		switch n.kind {
+		case kindOutput:
+			add(n.identifiers)
+
+		case kindIf:
+			for _, b := range n.branches {
+				add(b.identifiers)
+				identifiers(b.body, scope, found)
+			}
+
+		case kindFor:
+			add(n.identifiers)
+			inner := map[string]bool{n.value: true}
+			if n.key != "" {
+				inner[n.key] = true
+			}
+			for name := range scope {
+				inner[name] = true
+			}
+			identifiers(n.body, inner, found)
+		}
+	}
+}
+
+func (n *node) kindName() string {
+	if n.kind == kindFor {
+		return "for " + n.stmt
+	}
+	return "if " + n.branches[0].stmt
+}
+
+type visitor struct {
+	identifiers []string
+	declared    map[string]bool
+}
+
+// Visit collect the identifiers and the variables declared by let
+func (v *visitor) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.VariableDeclaratorNode:
+		v.declared[n.Name] = true
+
+	case *ast.IdentifierNode:
+		v.identifiers = append(v.identifiers, n.Value)
+	}
+}
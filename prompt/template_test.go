@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse("Hello {{ customer.name }}!\n" +
+		"{{ if customer.vip }}Thank you for being a VIP.{{ else if customer.orders > 0 }}Welcome back.{{ else }}Welcome.{{ end }}\n" +
+		"{{- for i, item in items }}\n{{ i + 1 }}. {{ item.name }} x {{ item.quantity }}\n{{- end }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"customer", "items"}, tmpl.Identifiers())
+	assert.True(t, tmpl.Uses("items"))
+	assert.False(t, tmpl.Uses("item"))
+
+	text, err := tmpl.Render(map[string]interface{}{
+		"customer": map[string]interface{}{"name": "Max", "vip": false, "orders": float64(3)},
+		"items": []interface{}{
+			map[string]interface{}{"name": "Apple", "quantity": float64(2)},
+			map[string]interface{}{"name": "Banana", "quantity": 1.5},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Hello Max!\nWelcome back.\n1. Apple x 2\n2. Banana x 1.5", text)
+
+	text, err = tmpl.Render(map[string]interface{}{"customer": map[string]interface{}{"name": "Ada", "vip": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Hello Ada!\nThank you for being a VIP.", text)
+
+	tmpl, err = Parse(`{{ for key, value in options }}{{ key }}={{ value }};{{ end }}{{ tags }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err = tmpl.Render(map[string]interface{}{"options": map[string]interface{}{"b": 2, "a": "x"}, "tags": []interface{}{"red"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `a=x;b=2;["red"]`, text)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(`{{ if vip }}VIP`)
+	assert.Contains(t, err.Error(), "{{ if vip }} is not closed")
+
+	_, err = Parse("Hello\n{{ end }}")
+	assert.Contains(t, err.Error(), "line 2: unexpected {{ end }}")
+
+	_, err = Parse(`{{ for items }}{{ end }}`)
+	assert.Contains(t, err.Error(), "{{ for item in list }}")
+
+	_, err = Parse(`{{ if a }}{{ else }}{{ else }}{{ end }}`)
+	assert.Contains(t, err.Error(), "after {{ else }}")
+
+	_, err = Parse(`{{ customer. }}`)
+	assert.NotNil(t, err)
+
+	templates, err := ParseAll("Static prompt", "Hello {{ name }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, templates[0])
+	assert.NotNil(t, templates[1])
+}
+
+func TestVariables(t *testing.T) {
+	vars := Variables{
+		"customer": {Type: "object", Required: true},
+		"language": {Type: "string", Default: "English"},
+		"count":    {Type: "integer"},
+	}
+
+	templates, err := ParseAll("Reply in {{ language }} to {{ customer.name }}, {{ content }}", "{{ for item in items }}{{ item }}{{ end }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vars.Check(templates, "content")
+	assert.Equal(t, "the variables items (prompts[1]) are not declared", err.Error())
+	assert.Nil(t, vars.Check(templates[:1], "content"))
+	assert.NotNil(t, Variables{"name": {Type: "text"}}.Check(nil))
+	assert.NotNil(t, Variables{"name": {Type: "integer", Default: "one"}}.Check(nil))
+
+	data, err := vars.Bind(map[string]interface{}{"customer": map[string]interface{}{"name": "Max"}, "count": 2, "content": "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "English", data["language"])
+	assert.Equal(t, float64(2), data["count"])
+	assert.Equal(t, "Hi", data["content"])
+
+	_, err = vars.Bind(map[string]interface{}{})
+	assert.Equal(t, "the variable customer is required", err.Error())
+
+	_, err = vars.Bind(map[string]interface{}{"customer": map[string]interface{}{}, "count": 1.5})
+	assert.Equal(t, "the variable count should be integer", err.Error())
+}
@@ -0,0 +1,176 @@
+package prompt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/log"
+)
+
+// Variable the typed input variable of the prompt templates
+//
+//	variables:
+//	  customer: { type: object, required: true, description: "The customer, {name, vip, orders}" }
+//	  language: { type: string, default: "English" }
+type Variable struct {
+	Type        string      `json:"type,omitempty" yaml:"type,omitempty"` // string|number|integer|boolean|object|array, any type if it is empty
+	Required    bool        `json:"required,omitempty" yaml:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Variables the input variables of the prompt templates
+type Variables map[string]Variable
+
+var variableTypes = map[string]bool{"": true, "string": true, "number": true, "integer": true, "boolean": true, "object": true, "array": true}
+
+// Check the types of the variables and the variables the templates use, the builtin variables are given by the caller.
+// e.g. content, session and formdata
+func (vars Variables) Check(templates []*Template, builtins ...string) error {
+	for name, v := range vars {
+		if !variableTypes[v.Type] {
+			return fmt.Errorf("the type %s of the variable %s is invalid", v.Type, name)
+		}
+		if v.Default != nil && !isType(normalize(v.Default), v.Type) {
+			return fmt.Errorf("the default value of the variable %s should be %s", name, v.Type)
+		}
+	}
+
+	known := map[string]bool{}
+	for _, name := range builtins {
+		known[name] = true
+	}
+
+	missing := []string{}
+	for i, tmpl := range templates {
+		if tmpl == nil {
+			continue
+		}
+		for _, name := range tmpl.Identifiers() {
+			if _, has := vars[name]; !has && !known[name] {
+				missing = append(missing, fmt.Sprintf("%s (prompts[%d])", name, i))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("the variables %s are not declared", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Bind the values of the variables, the defaults are used if the values are missing.
+// The values are checked with the types, the values not declared are kept.
+func (vars Variables) Bind(values map[string]interface{}) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for name, value := range values {
+		data[name] = value
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v := vars[name]
+		value, has := data[name]
+		if !has || value == nil {
+			if v.Default == nil {
+				if v.Required {
+					return nil, fmt.Errorf("the variable %s is required", name)
+				}
+				continue
+			}
+			value = v.Default
+		}
+
+		value = normalize(value)
+		if !isType(value, v.Type) {
+			return nil, fmt.Errorf("the variable %s should be %s", name, v.Type)
+		}
+		data[name] = value
+	}
+	return data, nil
+}
+
+// Session the data of the session, the empty map returns if the sid is empty
+func Session(sid string) map[string]interface{} {
+	if sid == "" {
+		return map[string]interface{}{}
+	}
+
+	data, err := session.Global().ID(sid).Dump()
+	if err != nil {
+		log.Warn("[Prompt] the session %s %s", sid, err.Error())
+		return map[string]interface{}{}
+	}
+	return data
+}
+
+// ParseAll parse the prompts, the template is nil if the prompt is not a template
+func ParseAll(prompts ...string) ([]*Template, error) {
+	templates := make([]*Template, len(prompts))
+	for i, source := range prompts {
+		if !IsTemplate(source) {
+			continue
+		}
+
+		tmpl, err := Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("prompts[%d] %s", i, err.Error())
+		}
+		templates[i] = tmpl
+	}
+	return templates, nil
+}
+
+// normalize the value to the JSON types, e.g. the integers are float64 and the structs are maps
+func normalize(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, float64, map[string]interface{}, []interface{}:
+		return value
+	}
+
+	data, err := jsoniter.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var res interface{}
+	if err := jsoniter.Unmarshal(data, &res); err != nil {
+		return value
+	}
+	return res
+}
+
+func isType(value interface{}, typ string) bool {
+	switch typ {
+	case "":
+		return true
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return false
+}
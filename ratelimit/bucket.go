@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend the counter store, the gou store satisfies it
+type Backend interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration) error
+}
+
+// Bucket the token bucket, refills the Rate tokens per second up to the Size
+type Bucket struct {
+	Size float64
+	Rate float64
+}
+
+// memory the local memory backend
+type memory struct {
+	values  map[string]memoryValue
+	mutex   sync.Mutex
+	cleaned time.Time
+}
+
+type memoryValue struct {
+	value   interface{}
+	expired time.Time
+}
+
+// NewMemory create a local memory backend, the expired values are removed periodically
+func NewMemory() Backend {
+	return &memory{values: map[string]memoryValue{}, cleaned: time.Now()}
+}
+
+// Get get the value
+func (m *memory) Get(key string) (interface{}, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, has := m.values[key]
+	if !has || time.Now().After(v.expired) {
+		return nil, false
+	}
+	return v.value, true
+}
+
+// Set set the value with the ttl
+func (m *memory) Set(key string, value interface{}, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	m.values[key] = memoryValue{value: value, expired: now.Add(ttl)}
+
+	if now.Sub(m.cleaned) > time.Minute {
+		for k, v := range m.values {
+			if now.After(v.expired) {
+				delete(m.values, k)
+			}
+		}
+		m.cleaned = now
+	}
+	return nil
+}
+
+// Take take a token from the bucket, the state is the value of the counter, empty for a full bucket.
+// It returns the new state, the remaining tokens and the time to wait if the token is not available.
+func (bucket Bucket) Take(state string, now time.Time) (string, int, time.Duration) {
+	tokens := bucket.Size
+	if state != "" {
+		t, l, err := parseState(state)
+		if err == nil {
+			elapsed := now.Sub(l).Seconds()
+			if elapsed < 0 {
+				elapsed = 0
+			}
+			tokens = math.Min(bucket.Size, t+elapsed*bucket.Rate)
+		}
+	}
+
+	if tokens < 1 {
+		wait := time.Duration((1 - tokens) / bucket.Rate * float64(time.Second))
+		return formatState(tokens, now), 0, wait
+	}
+
+	tokens--
+	return formatState(tokens, now), int(tokens), 0
+}
+
+// TTL the time of refilling the empty bucket
+func (bucket Bucket) TTL() time.Duration {
+	return time.Duration(bucket.Size/bucket.Rate*float64(time.Second)) + time.Second
+}
+
+func formatState(tokens float64, now time.Time) string {
+	return fmt.Sprintf("%s:%d", strconv.FormatFloat(tokens, 'f', 6, 64), now.UnixNano())
+}
+
+func parseState(state string) (float64, time.Time, error) {
+	parts := strings.SplitN(state, ":", 2)
+	if len(parts) != 2 {
+		return 0, time.Time{}, fmt.Errorf("invalid state %s", state)
+	}
+
+	tokens, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	nano, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return tokens, time.Unix(0, nano), nil
+}
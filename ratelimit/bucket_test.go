@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketTake(t *testing.T) {
+	bucket := Bucket{Size: 2, Rate: 1} // 1 token per second
+	now := time.Now()
+
+	state, remaining, wait := bucket.Take("", now)
+	assert.Equal(t, 1, remaining)
+	assert.Equal(t, time.Duration(0), wait)
+
+	state, remaining, wait = bucket.Take(state, now)
+	assert.Equal(t, 0, remaining)
+	assert.Equal(t, time.Duration(0), wait)
+
+	// Empty
+	state, remaining, wait = bucket.Take(state, now.Add(500*time.Millisecond))
+	assert.Equal(t, 0, remaining)
+	assert.Equal(t, 500*time.Millisecond, wait.Round(time.Millisecond))
+
+	// Refilled
+	state, _, wait = bucket.Take(state, now.Add(1500*time.Millisecond))
+	assert.Equal(t, time.Duration(0), wait)
+
+	// The bucket size is the max
+	_, remaining, _ = bucket.Take(state, now.Add(time.Hour))
+	assert.Equal(t, 1, remaining)
+
+	// The invalid state is the full bucket
+	_, remaining, _ = bucket.Take("invalid", now)
+	assert.Equal(t, 1, remaining)
+	assert.Equal(t, 3*time.Second, bucket.TTL())
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	m.Set("foo", "bar", 50*time.Millisecond)
+	value, has := m.Get("foo")
+	assert.True(t, has)
+	assert.Equal(t, "bar", value)
+
+	time.Sleep(60 * time.Millisecond)
+	_, has = m.Get("foo")
+	assert.False(t, has)
+}
@@ -0,0 +1,372 @@
+package ratelimit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/yao/helper"
+	"github.com/yaoapp/yao/share"
+)
+
+// Limiter the rate limiter of a rule
+type Limiter struct {
+	Name   string
+	Rule   share.LimitRule
+	bucket Bucket
+}
+
+var backend Backend = NewMemory()
+var global *Limiter
+var paths = []*Limiter{}
+var guards = map[string]*Limiter{}
+var quota *share.LimitQuota
+
+// keyMutexes serialize the read-modify-write of the counters with the same key, the keys are spread over the mutexes,
+// so a slow store blocks the requests of the same key only. The counters of the shared store are approximate in the cluster
+var keyMutexes [64]sync.Mutex
+var configMutex sync.RWMutex
+
+// Setup load the limits, it should be called after the stores are loaded
+func Setup(limits share.Limits) error {
+	var b Backend = NewMemory()
+	if limits.Store != "" {
+		s, has := store.Pools[limits.Store]
+		if !has {
+			return fmt.Errorf("limits store %s does not load", limits.Store)
+		}
+		b = s
+	}
+
+	var g *Limiter
+	var err error
+	if limits.Global != nil {
+		g, err = NewLimiter("global", *limits.Global)
+		if err != nil {
+			return err
+		}
+	}
+
+	p := []*Limiter{}
+	for _, rule := range limits.Paths {
+		if rule.Path == "" {
+			return fmt.Errorf("limits paths the path is required")
+		}
+
+		limiter, err := NewLimiter(fmt.Sprintf("path:%s:%s", strings.ToUpper(rule.Method), rule.Path), rule)
+		if err != nil {
+			return err
+		}
+		p = append(p, limiter)
+	}
+
+	gs := map[string]*Limiter{}
+	for name, rule := range limits.Guards {
+		limiter, err := NewLimiter(fmt.Sprintf("guard:%s", name), rule)
+		if err != nil {
+			return err
+		}
+		gs[name] = limiter
+	}
+
+	if limits.Neo != nil && limits.Neo.Daily <= 0 {
+		return fmt.Errorf("limits neo the daily quota should be greater than 0")
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	backend = b
+	global = g
+	paths = p
+	guards = gs
+	quota = limits.Neo
+	return nil
+}
+
+// NewLimiter create a limiter of the rule
+func NewLimiter(name string, rule share.LimitRule) (*Limiter, error) {
+	if rule.Limit <= 0 {
+		return nil, fmt.Errorf("limits %s the limit should be greater than 0", name)
+	}
+
+	period := time.Minute
+	if rule.Period != "" {
+		var err error
+		period, err = time.ParseDuration(rule.Period)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("limits %s the period %s is invalid", name, rule.Period)
+		}
+	}
+
+	if rule.Key == "process" && rule.Process == "" {
+		return nil, fmt.Errorf("limits %s the process is required", name)
+	}
+
+	size := rule.Burst
+	if size <= 0 {
+		size = rule.Limit
+	}
+
+	bucket := Bucket{Size: float64(size), Rate: float64(rule.Limit) / period.Seconds()}
+	return &Limiter{Name: name, Rule: rule, bucket: bucket}, nil
+}
+
+// Enabled check if the global or the path limits are set
+func Enabled() bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return global != nil || len(paths) > 0
+}
+
+// Middleware the gin middleware checks the global and the path limits
+func Middleware(c *gin.Context) {
+	configMutex.RLock()
+	limiters := []*Limiter{}
+	if global != nil {
+		limiters = append(limiters, global)
+	}
+	for _, limiter := range paths {
+		if limiter.Match(c.Request.Method, c.Request.URL.Path) {
+			limiters = append(limiters, limiter)
+		}
+	}
+	configMutex.RUnlock()
+
+	for _, limiter := range limiters {
+		if !limiter.Check(c) {
+			return
+		}
+	}
+	c.Next()
+}
+
+// Guards wrap the guards, the guard limits are checked after the guard passed
+// It returns the guards as is when the guard limits are not set.
+func Guards(handlers map[string]gin.HandlerFunc) map[string]gin.HandlerFunc {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	if len(guards) == 0 {
+		return handlers
+	}
+
+	res := map[string]gin.HandlerFunc{}
+	for name, handler := range handlers {
+		res[name] = handler
+		if limiter, has := guards[name]; has {
+			res[name] = guard(handler, limiter)
+		}
+	}
+	return res
+}
+
+func guard(handler gin.HandlerFunc, limiter *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler(c)
+		if c.IsAborted() || c.Writer.Written() { // the guard responded or executed the handlers with c.Next
+			return
+		}
+		limiter.Check(c)
+	}
+}
+
+// NeoQuota the gin handler checks the daily quota of the Neo chats
+func NeoQuota(c *gin.Context) {
+	configMutex.RLock()
+	q := quota
+	b := backend
+	configMutex.RUnlock()
+
+	if q == nil {
+		return
+	}
+
+	keyType := q.Key
+	if keyType == "" {
+		keyType = "user"
+	}
+
+	now := time.Now()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	key := fmt.Sprintf("quota:neo:%s:%s", keyOf(c, keyType, q.Process), now.Format("20060102"))
+
+	unlock := lockKey(key)
+	used := 0
+	if value, has := b.Get(key); has {
+		used, _ = strconv.Atoi(fmt.Sprintf("%v", value))
+	}
+
+	if used >= q.Daily {
+		unlock()
+		message := q.Message
+		if message == "" {
+			message = fmt.Sprintf("The daily quota of %d chats is exceeded", q.Daily)
+		}
+		c.Header("X-Quota-Limit", fmt.Sprintf("%d", q.Daily))
+		c.Header("X-Quota-Remaining", "0")
+		reject(c, tomorrow.Sub(now), message)
+		return
+	}
+
+	err := b.Set(key, fmt.Sprintf("%d", used+1), tomorrow.Sub(now)+time.Hour)
+	unlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		c.Abort()
+		return
+	}
+
+	c.Header("X-Quota-Limit", fmt.Sprintf("%d", q.Daily))
+	c.Header("X-Quota-Remaining", fmt.Sprintf("%d", q.Daily-used-1))
+}
+
+// Match check if the rule matches the request
+func (limiter *Limiter) Match(method string, path string) bool {
+	if limiter.Rule.Method != "" && !strings.EqualFold(limiter.Rule.Method, method) {
+		return false
+	}
+
+	if strings.HasSuffix(limiter.Rule.Path, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(limiter.Rule.Path, "*"))
+	}
+	return path == limiter.Rule.Path
+}
+
+// Check take a token of the request, responses 429 if the limit is exceeded
+func (limiter *Limiter) Check(c *gin.Context) bool {
+	keyType := limiter.Rule.Key
+	if keyType == "" {
+		keyType = "ip"
+	}
+
+	remaining, wait, err := limiter.Take(keyOf(c, keyType, limiter.Rule.Process))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		c.Abort()
+		return false
+	}
+
+	c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limiter.Rule.Limit))
+	c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
+	if wait > 0 {
+		message := limiter.Rule.Message
+		if message == "" {
+			message = "Too many requests, please try again later"
+		}
+		reject(c, wait, message)
+		return false
+	}
+	return true
+}
+
+// Take take a token of the key, returns the remaining tokens and the time to wait if the limit is exceeded
+func (limiter *Limiter) Take(key string) (int, time.Duration, error) {
+	configMutex.RLock()
+	b := backend
+	configMutex.RUnlock()
+
+	key = fmt.Sprintf("limit:%s:%s", limiter.Name, key)
+	unlock := lockKey(key)
+	defer unlock()
+
+	state := ""
+	if value, has := b.Get(key); has && value != nil {
+		state = fmt.Sprintf("%v", value)
+	}
+
+	state, remaining, wait := limiter.bucket.Take(state, time.Now())
+	err := b.Set(key, state, limiter.bucket.TTL())
+	if err != nil {
+		return 0, 0, err
+	}
+	return remaining, wait, nil
+}
+
+// lockKey lock the counter of the key, returns the unlock function
+func lockKey(key string) func() {
+	m := keyMutex(key)
+	m.Lock()
+	return m.Unlock
+}
+
+func keyMutex(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &keyMutexes[h.Sum32()%uint32(len(keyMutexes))]
+}
+
+func reject(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"code": http.StatusTooManyRequests, "message": message})
+	c.Abort()
+}
+
+// keyOf the counter key of the request, the anonymous requests are counted by the client ip
+func keyOf(c *gin.Context, keyType string, processName string) string {
+	ip := fmt.Sprintf("ip:%s", c.ClientIP())
+	switch keyType {
+	case "sid":
+		if sid := sidOf(c); sid != "" {
+			return fmt.Sprintf("sid:%s", sid)
+		}
+
+	case "user":
+		sid := sidOf(c)
+		if sid == "" {
+			return ip
+		}
+
+		id, err := session.Global().ID(sid).Get("user_id")
+		if err == nil && id != nil {
+			return fmt.Sprintf("user:%v", id)
+		}
+		return fmt.Sprintf("sid:%s", sid)
+
+	case "process":
+		res, err := process.NewWithContext(c.Request.Context(), processName, c.ClientIP(), c.Request.URL.Path, sidOf(c)).Exec()
+		if err == nil && res != nil && fmt.Sprintf("%v", res) != "" {
+			return fmt.Sprintf("process:%v", res)
+		}
+	}
+	return ip
+}
+
+// sidOf the session id of the request, it is read from the JWT token if the guards have not run
+func sidOf(c *gin.Context) (sid string) {
+	if sid = c.GetString("__sid"); sid != "" {
+		return sid
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if token == "" {
+		token = c.Query("__tk")
+	}
+
+	if token == "" {
+		token = strings.TrimSpace(strings.TrimPrefix(c.Query("token"), "Bearer "))
+	}
+
+	if token == "" {
+		token, _ = c.Cookie("__tk")
+	}
+
+	if token == "" {
+		sid, _ = c.Cookie("sid")
+		return sid
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			sid = ""
+		}
+	}()
+	return helper.JwtValidate(token).SID
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/share"
+)
+
+func TestMiddleware(t *testing.T) {
+	err := Setup(share.Limits{
+		Global: &share.LimitRule{Limit: 100, Period: "1s"},
+		Paths: []share.LimitRule{
+			{Path: "/api/__yao/neo*", Limit: 2, Period: "1m", Message: "Slow down"},
+			{Path: "/api/pets", Method: "POST", Limit: 1, Period: "1h"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(share.Limits{})
+	assert.True(t, Enabled())
+
+	router := testRouter(map[string]gin.HandlerFunc{})
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/__yao/neo/chats").Code)
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/__yao/neo/chats").Code)
+
+	w := testRequest(router, "GET", "/api/__yao/neo/chats")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Contains(t, w.Body.String(), "Slow down")
+
+	// The method does not match
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/pets").Code)
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/pets").Code)
+	assert.Equal(t, 200, testRequest(router, "POST", "/api/pets").Code)
+	assert.Equal(t, http.StatusTooManyRequests, testRequest(router, "POST", "/api/pets").Code)
+}
+
+func TestGuards(t *testing.T) {
+	err := Setup(share.Limits{Guards: map[string]share.LimitRule{"bearer-jwt": {Limit: 1, Key: "sid"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(share.Limits{})
+	assert.False(t, Enabled())
+
+	guards := Guards(map[string]gin.HandlerFunc{
+		"bearer-jwt": func(c *gin.Context) { c.Set("__sid", c.GetHeader("X-Sid")) },
+		"other":      func(c *gin.Context) {},
+	})
+
+	router := testRouter(guards)
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/guard", "X-Sid", "s1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, testRequest(router, "GET", "/api/guard", "X-Sid", "s1").Code)
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/guard", "X-Sid", "s2").Code)
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/other").Code)
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/other").Code)
+}
+
+func TestNeoQuota(t *testing.T) {
+	err := Setup(share.Limits{Neo: &share.LimitQuota{Daily: 2, Key: "ip"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(share.Limits{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/__yao/neo", NeoQuota, func(c *gin.Context) { c.String(200, "ok") })
+
+	w := testRequest(router, "GET", "/api/__yao/neo")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Quota-Remaining"))
+	assert.Equal(t, 200, testRequest(router, "GET", "/api/__yao/neo").Code)
+
+	w = testRequest(router, "GET", "/api/__yao/neo")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("X-Quota-Remaining"))
+}
+
+func TestTakeSlowStore(t *testing.T) {
+	limiter, err := NewLimiter("slow", share.LimitRule{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slow := &slowBackend{Backend: NewMemory(), block: make(chan bool)}
+	configMutex.Lock()
+	backend = slow
+	configMutex.Unlock()
+	defer Setup(share.Limits{})
+
+	// The keys of the different mutexes
+	fast := "fast"
+	for i := 0; keyMutex("limit:slow:"+fast) == keyMutex("limit:slow:slow"); i++ {
+		fast = fmt.Sprintf("fast-%d", i)
+	}
+
+	done := make(chan bool)
+	go func() {
+		limiter.Take("slow")
+		done <- true
+	}()
+
+	// The request of the other key is not blocked by the slow store
+	taken := make(chan bool)
+	go func() {
+		limiter.Take(fast)
+		taken <- true
+	}()
+
+	select {
+	case <-taken:
+	case <-time.After(time.Second):
+		t.Fatal("the request of the other key is blocked")
+	}
+
+	close(slow.block)
+	<-done
+}
+
+type slowBackend struct {
+	Backend
+	block chan bool
+}
+
+func (b *slowBackend) Get(key string) (interface{}, bool) {
+	if strings.HasSuffix(key, ":slow") {
+		<-b.block
+	}
+	return b.Backend.Get(key)
+}
+
+func TestSetupError(t *testing.T) {
+	assert.Error(t, Setup(share.Limits{Store: "not-found"}))
+	assert.Error(t, Setup(share.Limits{Global: &share.LimitRule{Limit: 0}}))
+	assert.Error(t, Setup(share.Limits{Global: &share.LimitRule{Limit: 1, Period: "invalid"}}))
+	assert.Error(t, Setup(share.Limits{Paths: []share.LimitRule{{Limit: 1}}}))
+	assert.Error(t, Setup(share.Limits{Global: &share.LimitRule{Limit: 1, Key: "process"}}))
+	assert.Error(t, Setup(share.Limits{Neo: &share.LimitQuota{}}))
+}
+
+func testRouter(guards map[string]gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware)
+
+	ok := func(c *gin.Context) { c.String(200, "ok") }
+	router.GET("/api/__yao/neo/chats", ok)
+	router.GET("/api/pets", ok)
+	router.POST("/api/pets", ok)
+	if guard, has := guards["bearer-jwt"]; has {
+		router.GET("/api/guard", guard, ok)
+	}
+	if guard, has := guards["other"]; has {
+		router.GET("/api/other", guard, ok)
+	}
+	return router
+}
+
+func testRequest(router *gin.Engine, method string, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// PoolStats the stats of the V8 runtime pool
+type PoolStats struct {
+	MinSize   int `json:"min_size"`
+	MaxSize   int `json:"max_size"`
+	InUse     int `json:"in_use"`    // The isolates held by the script contexts
+	Available int `json:"available"` // The isolates can be acquired without waiting
+}
+
+var inUse int64
+var poolSize = [2]int64{}
+
+// the processes executed in a script context, each execution holds an isolate of the pool
+var scriptProcesses = []string{"scripts.", "studio."}
+
+// Acquire count an isolate of the pool as in use, call the returned function when the script context is closed.
+// One script context holds one isolate of the pool until it is closed.
+func Acquire() func() {
+	atomic.AddInt64(&inUse, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { atomic.AddInt64(&inUse, -1) })
+	}
+}
+
+// Pool the stats of the V8 runtime pool
+func Pool() PoolStats {
+	stats := PoolStats{
+		MinSize: int(atomic.LoadInt64(&poolSize[0])),
+		MaxSize: int(atomic.LoadInt64(&poolSize[1])),
+		InUse:   int(atomic.LoadInt64(&inUse)),
+	}
+
+	if stats.MaxSize > stats.InUse {
+		stats.Available = stats.MaxSize - stats.InUse
+	}
+	return stats
+}
+
+// trackPool set the pool size and count the isolates held by the script processes
+func trackPool(cfg config.Config) {
+	atomic.StoreInt64(&poolSize[0], int64(cfg.Runtime.MinSize))
+	atomic.StoreInt64(&poolSize[1], int64(cfg.Runtime.MaxSize))
+	share.UseProcessHook("runtime", func(handler process.Handler) process.Handler {
+		return func(p *process.Process) interface{} {
+			name := strings.ToLower(p.Name)
+			for _, prefix := range scriptProcesses {
+				if strings.HasPrefix(name, prefix) {
+					release := Acquire()
+					defer release()
+					break
+				}
+			}
+			return handler(p)
+		}
+	})
+}
@@ -54,6 +54,8 @@ func Start(cfg config.Config) error {
 		return err
 	}
 
+	trackPool(cfg)
+
 	return nil
 }
 
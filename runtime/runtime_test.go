@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/yao/config"
 )
@@ -34,3 +35,24 @@ func testPrepare(t *testing.T, rootEnv ...string) {
 	}
 	application.Load(app)
 }
+
+func TestPool(t *testing.T) {
+	cfg := config.Conf
+	cfg.Runtime.MinSize = 2
+	cfg.Runtime.MaxSize = 3
+	trackPool(cfg)
+
+	before := Pool().InUse
+	release := Acquire()
+	second := Acquire()
+	stats := Pool()
+	assert.Equal(t, 2, stats.MinSize)
+	assert.Equal(t, before+2, stats.InUse)
+	assert.Equal(t, 3-stats.InUse, stats.Available)
+
+	// Release once only
+	release()
+	release()
+	second()
+	assert.Equal(t, before, Pool().InUse)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/telemetry"
+)
+
+// RequestIDHeader the header of the request id
+const RequestIDHeader = "X-Request-ID"
+
+// the redacted value of the headers and the query
+const redacted = "[REDACTED]"
+
+// withAccessLog the access log middleware, writes a record per request to the log.
+// The request id is generated if the X-Request-ID header is not valid, it is set to the response header,
+// the "__request_id" of the gin context, the "__request_id" of the process global data and the request context.
+func withAccessLog(c *gin.Context) {
+	start := time.Now()
+	id := c.GetHeader(RequestIDHeader)
+	if !validRequestID(id) {
+		id = uuid.NewString()
+		c.Request.Header.Set(RequestIDHeader, id)
+	}
+
+	c.Set("__request_id", id)
+	share.MergeGlobal(c, map[string]interface{}{"__request_id": id})
+	c.Request = c.Request.WithContext(share.WithRequestID(c.Request.Context(), id))
+	c.Header(RequestIDHeader, id)
+	telemetry.SpanFromContext(c.Request.Context()).SetAttribute("http.request_id", id)
+
+	c.Next()
+
+	cfg := config.Conf.AccessLog
+	if cfg.Disable {
+		return
+	}
+
+	size := c.Writer.Size()
+	if size < 0 {
+		size = 0
+	}
+
+	fields := AccessFields(c)
+	fields["type"] = "access"
+	fields["method"] = c.Request.Method
+	fields["path"] = c.Request.URL.Path
+	fields["status"] = c.Writer.Status()
+	fields["latency"] = float64(time.Since(start).Microseconds()) / 1000 // milliseconds
+	fields["bytes"] = size
+	fields["ip"] = c.ClientIP()
+	fields["user_agent"] = c.Request.UserAgent()
+
+	if c.Request.URL.RawQuery != "" {
+		fields["query"] = redactQuery(c.Request.URL.RawQuery, cfg.RedactQuery)
+	}
+
+	if cfg.Headers {
+		fields["headers"] = redactHeaders(c, cfg.RedactHeaders)
+	}
+
+	if len(c.Errors) > 0 {
+		fields["error"] = c.Errors.String()
+	}
+
+	sid := c.GetString("__sid")
+	if sid != "" {
+		if id, err := session.Global().ID(sid).Get("user_id"); err == nil && id != nil {
+			fields["user_id"] = id
+		}
+	}
+
+	message := fmt.Sprintf("[Access] %s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+	if c.Writer.Status() >= 500 {
+		log.With(fields).Error(message)
+		return
+	}
+	log.With(fields).Info(message)
+}
+
+// AccessFields the log fields of the request, use it to write the logs with the request id.
+// e.g. log.With(service.AccessFields(c)).Error("...")
+func AccessFields(c *gin.Context) log.F {
+	fields := share.LogFields(c.Request.Context())
+	if _, has := fields["request_id"]; !has {
+		fields["request_id"] = c.GetString("__request_id")
+	}
+	if sid := c.GetString("__sid"); sid != "" {
+		fields["sid"] = sid
+	}
+	return fields
+}
+
+// validRequestID the request id from the client should be printable and less than 128 characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func redactQuery(raw string, keys []string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return redacted
+	}
+
+	for _, key := range keys {
+		for name := range values {
+			if strings.EqualFold(name, strings.TrimSpace(key)) {
+				values[name] = []string{redacted}
+			}
+		}
+	}
+	return values.Encode()
+}
+
+func redactHeaders(c *gin.Context, names []string) map[string]string {
+	headers := map[string]string{}
+	for name, values := range c.Request.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	for _, name := range names {
+		for key := range headers {
+			if strings.EqualFold(key, strings.TrimSpace(name)) {
+				headers[key] = redacted
+			}
+		}
+	}
+	return headers
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+func TestAccessLog(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	log.SetFormatter(log.JSON)
+	defer log.SetOutput(os.Stdout)
+	defer log.SetFormatter(log.TEXT)
+
+	cfg := config.Conf.AccessLog
+	config.Conf.AccessLog.Headers = true
+	config.Conf.AccessLog.RedactHeaders = []string{"Authorization"}
+	config.Conf.AccessLog.RedactQuery = []string{"token"}
+	defer func() { config.Conf.AccessLog = cfg }()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(withAccessLog)
+	router.Use(func(c *gin.Context) { share.MergeGlobal(c, map[string]interface{}{"role": "admin"}) }) // e.g. the guards
+	router.GET("/api/pets", func(c *gin.Context) {
+		global := c.GetStringMap("__global")
+		assert.Equal(t, c.GetString("__request_id"), global["__request_id"])
+		assert.Equal(t, "admin", global["role"])
+		assert.Equal(t, c.GetString("__request_id"), share.RequestID(c.Request.Context()))
+		log.With(share.LogFields(c.Request.Context())).Info("unit-test-handler")
+		c.String(200, "ok")
+	})
+
+	// Generate the request id
+	req := httptest.NewRequest("GET", "/api/pets?token=secret&page=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	id := w.Header().Get(RequestIDHeader)
+	assert.Len(t, id, 36)
+	assert.Contains(t, output.String(), id)
+	assert.Contains(t, output.String(), `"status":200`)
+	assert.Contains(t, output.String(), `"path":"/api/pets"`)
+	assert.NotContains(t, output.String(), "secret")
+	assert.Contains(t, output.String(), "REDACTED")
+
+	// Propagate the request id
+	output.Reset()
+	req = httptest.NewRequest("GET", "/api/pets", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "req-123", w.Header().Get(RequestIDHeader))
+	assert.Contains(t, output.String(), `"request_id":"req-123"`)
+
+	// The logs of the handler carry the request id
+	for _, line := range strings.Split(output.String(), "\n") {
+		if strings.Contains(line, "unit-test-handler") {
+			assert.Contains(t, line, `"request_id":"req-123"`)
+		}
+	}
+
+	// Disabled
+	output.Reset()
+	config.Conf.AccessLog.Disable = true
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/pets", nil))
+	assert.Empty(t, output.String())
+}
+
+func TestAccessRedact(t *testing.T) {
+	assert.Equal(t, "page=1&token=%5BREDACTED%5D", redactQuery("token=xxx&page=1", []string{"TOKEN"}))
+	assert.False(t, validRequestID("has space"))
+	assert.False(t, validRequestID(""))
+	assert.True(t, validRequestID("0af7651916cd43dd8448eb211c80319c"))
+}
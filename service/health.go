@@ -0,0 +1,12 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/yao/health"
+)
+
+// setupHealth register the liveness and the readiness endpoints, they are registered before the middlewares for the probes
+func setupHealth(router *gin.Engine) {
+	router.GET("/healthz", health.LiveHandler)
+	router.GET("/readyz", health.ReadyHandler)
+}
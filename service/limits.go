@@ -0,0 +1,24 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/ratelimit"
+	"github.com/yaoapp/yao/share"
+)
+
+// startLimits load the rate limits of the app.yao, the counters are kept when the routes are rebuilt
+func startLimits() {
+	err := ratelimit.Setup(share.App.Limits)
+	if err != nil {
+		log.Error("[Limits] %s", err.Error())
+	}
+}
+
+// setupLimits register the limit middleware, returns the guards with the limits
+func setupLimits(router *gin.Engine, guards map[string]gin.HandlerFunc) map[string]gin.HandlerFunc {
+	if ratelimit.Enabled() {
+		router.Use(ratelimit.Middleware)
+	}
+	return ratelimit.Guards(guards)
+}
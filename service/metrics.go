@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/metrics"
+)
+
+// metricsPath the path of the metrics endpoint, empty when the metrics is disabled
+var metricsPath = ""
+
+// startMetrics instrument the process handlers and the schedules, the collectors are shared by the routes
+func startMetrics(cfg config.Config) {
+	if cfg.Metrics.Enable {
+		metrics.Start(cfg)
+	}
+}
+
+// setupMetrics register the metrics middleware and the endpoint
+func setupMetrics(router *gin.Engine, cfg config.Config) {
+	if !cfg.Metrics.Enable {
+		metricsPath = ""
+		return
+	}
+
+	metricsPath = cfg.Metrics.Path
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+
+	handlers := []gin.HandlerFunc{}
+	for _, name := range strings.Split(cfg.Metrics.Guard, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		guard, has := Guards[name]
+		if !has {
+			log.Error("[Metrics] the guard %s does not exist, ignored", name)
+			continue
+		}
+		handlers = append(handlers, guard)
+	}
+
+	if len(handlers) == 0 && cfg.Metrics.Token != "" {
+		handlers = append(handlers, metrics.TokenGuard(cfg.Metrics.Token))
+	}
+
+	if len(handlers) == 0 {
+		log.Warn("[Metrics] %s is not protected, set the YAO_METRICS_GUARD or YAO_METRICS_TOKEN", metricsPath)
+	}
+
+	router.Use(metrics.Middleware)
+	router.GET(metricsPath, append(handlers, metrics.Handler)...)
+}
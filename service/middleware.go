@@ -16,13 +16,19 @@ import (
 
 // Middlewares the middlewares
 var Middlewares = []gin.HandlerFunc{
-	gin.Logger(),
+	withAccessLog,
 	withStaticFileServer,
 }
 
 // withStaticFileServer static file server
 func withStaticFileServer(c *gin.Context) {
 
+	// Handle metrics
+	if metricsPath != "" && c.Request.URL.Path == metricsPath {
+		c.Next()
+		return
+	}
+
 	// Handle API & websocket
 	length := len(c.Request.URL.Path)
 	if (length >= 5 && c.Request.URL.Path[0:5] == "/api/") ||
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/yao/openapi"
+	"github.com/yaoapp/yao/share"
+)
+
+// setupOpenAPI register the OpenAPI document endpoint, the document is generated from the current routes.
+// The endpoint is registered only if the openapi is set in the app.yao, it is guarded by bearer-jwt by default.
+func setupOpenAPI(router *gin.Engine, guards map[string]gin.HandlerFunc) {
+	setting := share.App.OpenAPI
+	if setting == nil {
+		return
+	}
+
+	guard := setting.Guard
+	if guard == "" {
+		guard = "bearer-jwt"
+	}
+
+	handlers := []gin.HandlerFunc{}
+	for _, name := range strings.Split(guard, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if guard, has := guards[name]; has {
+			handlers = append(handlers, guard)
+			continue
+		}
+		handlers = append(handlers, api.ProcessGuard(name))
+	}
+
+	handlers = append(handlers, func(c *gin.Context) {
+		option := openapi.Option{Servers: setting.Servers, Extensions: setting.Extensions}
+		if current := routes.Load(); current != nil {
+			option.Routes = current.Routes()
+		}
+		c.JSON(http.StatusOK, openapi.Generate(option))
+	})
+
+	router.GET("/api/__yao/openapi.json", handlers...)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,8 +10,12 @@ import (
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/neo"
 	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/telemetry"
 )
 
+// the routes of the service, swapped when the APIs changed
+var routes atomic.Pointer[gin.Engine]
+
 // Start the yao service
 func Start(cfg config.Config) (*http.Server, error) {
 
@@ -23,10 +28,12 @@ func Start(cfg config.Config) (*http.Server, error) {
 		return nil, err
 	}
 
+	// The root router serves the requests with the current routes
 	router := gin.New()
-	router.Use(Middlewares...)
-	api.SetGuards(Guards)
-	api.SetRoutes(router, "/api", cfg.AllowFrom...)
+	router.NoRoute(serve)
+	start(cfg)
+	routes.Store(newRoutes(cfg))
+
 	srv := http.New(router, http.Option{
 		Host:    cfg.Host,
 		Port:    cfg.Port,
@@ -35,11 +42,6 @@ func Start(cfg config.Config) (*http.Server, error) {
 		Timeout: 5 * time.Second,
 	})
 
-	// Neo API
-	if neo.Neo != nil {
-		neo.Neo.API(router, "/api/__yao/neo")
-	}
-
 	go func() {
 		err = srv.Start()
 	}()
@@ -47,14 +49,44 @@ func Start(cfg config.Config) (*http.Server, error) {
 	return srv, nil
 }
 
-// Restart the yao service
+// Restart rebuild the routes of the yao service, the in-flight requests are served by the previous routes
 func Restart(srv *http.Server, cfg config.Config) error {
+	if cfg.AllowFrom == nil {
+		cfg.AllowFrom = []string{}
+	}
+	routes.Store(newRoutes(cfg))
+	return nil
+}
+
+// start the tracing, the metrics and the limits, they are shared by the routes and not restarted when the routes are rebuilt
+func start(cfg config.Config) {
+	startTrace(cfg)
+	startMetrics(cfg)
+	startLimits()
+}
+
+// newRoutes create the router with the middlewares, the APIs and the Neo API
+func newRoutes(cfg config.Config) *gin.Engine {
 	router := gin.New()
+	setupHealth(router)
+	guards := setupTrace(router, Guards)
+	setupMetrics(router, cfg)
+	guards = setupLimits(router, guards)
 	router.Use(Middlewares...)
-	api.SetGuards(Guards)
+	api.SetGuards(guards)
+	setupOpenAPI(router, guards)
 	api.SetRoutes(router, "/api", cfg.AllowFrom...)
-	srv.Reset(router)
-	return srv.Restart()
+
+	// Neo API
+	if neo.Neo != nil {
+		neo.Neo.API(router, "/api/__yao/neo")
+	}
+	return router
+}
+
+// serve the request with the current routes
+func serve(c *gin.Context) {
+	routes.Load().ServeHTTP(c.Writer, c.Request)
 }
 
 // Stop the yao service
@@ -64,6 +96,7 @@ func Stop(srv *http.Server) error {
 		return err
 	}
 	<-srv.Event()
+	telemetry.Stop()
 	return nil
 }
 
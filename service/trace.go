@@ -0,0 +1,26 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/telemetry"
+)
+
+// startTrace start the tracing, the exporter is started once and shared by the routes
+func startTrace(cfg config.Config) {
+	err := telemetry.Setup(cfg)
+	if err != nil {
+		log.Error("[Trace] %s", err.Error())
+	}
+}
+
+// setupTrace register the trace middleware, returns the traced guards
+func setupTrace(router *gin.Engine, guards map[string]gin.HandlerFunc) map[string]gin.HandlerFunc {
+	if !telemetry.Enabled() {
+		return guards
+	}
+
+	router.Use(telemetry.Middleware)
+	return telemetry.Guards(guards)
+}
@@ -23,27 +23,40 @@ func Watch(srv *http.Server, interrupt chan uint8) (err error) {
 			return
 		}
 
-		// Reload
-		err = engine.Reload(config.Conf, engine.LoadOption{Action: "watch"})
+		// Reload the changed DSL and its dependents
+		res, err := engine.ReloadFile(config.Conf, name)
 		if err != nil {
 			fmt.Println(color.RedString("[Watch] Reload: %s", err.Error()))
 			return
 		}
-		fmt.Println(color.GreenString("[Watch] Reload Completed"))
 
-		// Model
-		if strings.HasPrefix(name, "/models") {
-			fmt.Println(color.GreenString("[Watch] Model: %s changed (Please run yao migrate manually)", name))
+		// The app.yao or the connectors changed, reload the limits and the schedules of the metrics
+		if res.Full {
+			startMetrics(config.Conf)
+			startLimits()
+			fmt.Println(color.GreenString("[Watch] Reload Completed"))
 		}
 
-		// Restart
-		if strings.HasPrefix(name, "/apis") {
+		for _, file := range res.Reloaded {
+			fmt.Println(color.GreenString("[Watch] Reload: %s", file))
+		}
+
+		for _, id := range res.Removed {
+			fmt.Println(color.GreenString("[Watch] Remove: %s", id))
+		}
+
+		for _, message := range res.Messages {
+			fmt.Println(color.GreenString("[Watch] %s", message))
+		}
+
+		// Rebuild the routes
+		if res.Routes {
 			err = Restart(srv, config.Conf)
 			if err != nil {
 				fmt.Println(color.RedString("[Watch] Restart: %s", err.Error()))
 				return
 			}
-			fmt.Println(color.GreenString("[Watch] Restart Completed"))
+			fmt.Println(color.GreenString("[Watch] Routes Updated"))
 		}
 
 	}, interrupt)
@@ -0,0 +1,53 @@
+package share
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context with the request id, the logs written with LogFields carry it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID the request id of the context
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// LogFields the log fields of the request context. e.g. log.With(share.LogFields(ctx)).Error("...")
+func LogFields(ctx context.Context) log.F {
+	fields := log.F{}
+	if id := RequestID(ctx); id != "" {
+		fields["request_id"] = id
+	}
+	return fields
+}
+
+// MergeGlobal merge the values into the "__global" of the gin context, the existing values are overwritten
+func MergeGlobal(c *gin.Context, values map[string]interface{}) {
+	global := map[string]interface{}{}
+	if v, has := c.Get("__global"); has {
+		if v, ok := v.(map[string]interface{}); ok {
+			for key, value := range v {
+				global[key] = value
+			}
+		}
+	}
+
+	for key, value := range values {
+		global[key] = value
+	}
+	c.Set("__global", global)
+}
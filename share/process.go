@@ -0,0 +1,85 @@
+package share
+
+import (
+	"sync"
+
+	"github.com/yaoapp/gou/process"
+)
+
+// ProcessHook wrap the process handler. e.g. the metrics and the tracing
+type ProcessHook func(handler process.Handler) process.Handler
+
+type processHook struct {
+	name string
+	hook ProcessHook
+}
+
+var processHooks = []processHook{}
+
+// the original handlers of the hooked processes, the key is the process name
+var hookedProcesses = map[string]process.Handler{}
+var processHooksMutex sync.RWMutex
+
+// UseProcessHook register the hook, the hook with the same name is replaced.
+// The hooks are executed in the order of registration, the first one is the outermost.
+// The hooked handlers execute the hooks registered at the time of the call, so they are not wrapped again.
+func UseProcessHook(name string, hook ProcessHook) {
+	processHooksMutex.Lock()
+	defer processHooksMutex.Unlock()
+
+	for i := range processHooks {
+		if processHooks[i].name == name {
+			processHooks[i].hook = hook
+			return
+		}
+	}
+
+	processHooks = append(processHooks, processHook{name: name, hook: hook})
+	hookProcesses()
+}
+
+// HookProcesses hook the process handlers not hooked yet.
+// It writes the process handlers, call it when the handlers are registered. e.g. after the engine is loaded.
+func HookProcesses() {
+	processHooksMutex.Lock()
+	defer processHooksMutex.Unlock()
+	hookProcesses()
+}
+
+// UnhookProcesses restore the original handlers, call it before the handlers are registered again. e.g. before the engine is reloaded.
+// The handlers registered during the reload replace the originals, the others are hooked again by HookProcesses.
+func UnhookProcesses() {
+	processHooksMutex.Lock()
+	defer processHooksMutex.Unlock()
+	for name, handler := range hookedProcesses {
+		process.Handlers[name] = handler
+	}
+	hookedProcesses = map[string]process.Handler{}
+}
+
+func hookProcesses() {
+	if len(processHooks) == 0 {
+		return
+	}
+
+	for name, handler := range process.Handlers {
+		if _, has := hookedProcesses[name]; has || handler == nil {
+			continue
+		}
+		hookedProcesses[name] = handler
+		process.Handlers[name] = hookProcess(handler)
+	}
+}
+
+// hookProcess the handler executes the hooks registered at the time of the call
+func hookProcess(handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		processHooksMutex.RLock()
+		next := handler
+		for i := len(processHooks) - 1; i >= 0; i-- {
+			next = processHooks[i].hook(next)
+		}
+		processHooksMutex.RUnlock()
+		return next(p)
+	}
+}
@@ -0,0 +1,60 @@
+package share
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestProcessHooks(t *testing.T) {
+	defer func() {
+		UnhookProcesses()
+		processHooks = []processHook{}
+		delete(process.Handlers, "unit.test.hook")
+	}()
+
+	calls := []string{}
+	counter := func(name string) ProcessHook {
+		return func(handler process.Handler) process.Handler {
+			return func(p *process.Process) interface{} {
+				calls = append(calls, name)
+				return handler(p)
+			}
+		}
+	}
+
+	process.Handlers["unit.test.hook"] = func(p *process.Process) interface{} { return "v1" }
+	UseProcessHook("metrics", counter("metrics"))
+	UseProcessHook("metrics", counter("metrics"))
+	HookProcesses()
+
+	res := process.Handlers["unit.test.hook"](&process.Process{Name: "unit.test.hook"})
+	assert.Equal(t, "v1", res)
+	assert.Equal(t, []string{"metrics"}, calls)
+
+	// The hook registered later is applied to the hooked handlers
+	calls = []string{}
+	UseProcessHook("trace", counter("trace"))
+	process.Handlers["unit.test.hook"](&process.Process{Name: "unit.test.hook"})
+	assert.Equal(t, []string{"metrics", "trace"}, calls)
+
+	// The handler is hooked once
+	calls = []string{}
+	HookProcesses()
+	process.Handlers["unit.test.hook"](&process.Process{Name: "unit.test.hook"})
+	assert.Equal(t, []string{"metrics", "trace"}, calls)
+
+	// The reloaded handler with the same name is hooked again
+	calls = []string{}
+	UnhookProcesses()
+	res = process.Handlers["unit.test.hook"](&process.Process{Name: "unit.test.hook"})
+	assert.Equal(t, "v1", res)
+	assert.Empty(t, calls)
+
+	process.Handlers["unit.test.hook"] = func(p *process.Process) interface{} { return "v2" }
+	HookProcesses()
+	res = process.Handlers["unit.test.hook"](&process.Process{Name: "unit.test.hook"})
+	assert.Equal(t, "v2", res)
+	assert.Equal(t, []string{"metrics", "trace"}, calls)
+}
@@ -86,6 +86,86 @@ type AppInfo struct {
 	Moapi        Moapi                  `json:"moapi,omitempty"`
 	AfterLoad    string                 `json:"afterLoad,omitempty"`    // Process executed after the app is loaded
 	AfterMigrate string                 `json:"afterMigrate,omitempty"` // Process executed after the app is migrated
+	Health       map[string]string      `json:"health,omitempty"`       // The readiness checks, the key is the check name and the value is the process
+	Limits       Limits                 `json:"limits,omitempty"`       // The rate limits of the HTTP APIs
+	OpenAPI      *OpenAPI               `json:"openapi,omitempty"`      // The OpenAPI document endpoint, it is disabled if it is nil
+	Usage        *Usage                 `json:"usage,omitempty"`        // The token usage accounting of the LLM calls, it is disabled if it is nil
+	LLMCache     *LLMCache              `json:"llmCache,omitempty"`     // The response cache of the LLM calls, it is disabled if it is nil
+}
+
+// LLMCache the response cache of the LLM calls, the identical calls are replayed from the store
+type LLMCache struct {
+	Store      string   `json:"store"`                // The store name of the cached responses, use a redis store in the cluster
+	TTL        string   `json:"ttl,omitempty"`        // The time to live of the responses, the default value is 24h
+	All        bool     `json:"all,omitempty"`        // Cache all the calls, only the calls with temperature 0 are cached by default
+	Connectors []string `json:"connectors,omitempty"` // The connectors of the cached calls, all the connectors if it is empty
+}
+
+// Usage the token usage accounting of the LLM calls
+type Usage struct {
+	Disable   bool                  `json:"disable,omitempty"`   // Disable the recording and the budgets
+	Connector string                `json:"connector,omitempty"` // The database connector of the usage table, the default value is default
+	Table     string                `json:"table,omitempty"`     // The usage table, the default value is yao_llm_usage
+	Prices    map[string]UsagePrice `json:"prices,omitempty"`    // The prices per million tokens, the key is the model or the connector id
+	Budgets   UsageBudgets          `json:"budgets,omitempty"`   // The budgets of the users and the assistants
+}
+
+// UsagePrice the prices per million tokens
+type UsagePrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// UsageBudgets the budgets of the users and the assistants, the calls are refused with 402 once the budget is spent
+type UsageBudgets struct {
+	Period     string                 `json:"period,omitempty"`     // day|month, the default value is month
+	User       *UsageBudget           `json:"user,omitempty"`       // The budget of each user
+	Assistant  *UsageBudget           `json:"assistant,omitempty"`  // The budget of each assistant
+	Users      map[string]UsageBudget `json:"users,omitempty"`      // The budgets of the given users, the key is the user id
+	Assistants map[string]UsageBudget `json:"assistants,omitempty"` // The budgets of the given assistants, the key is the assistant id
+}
+
+// UsageBudget the budget in the period, the tokens or the cost, both are checked if they are set
+type UsageBudget struct {
+	Tokens  int     `json:"tokens,omitempty"`  // The max total tokens
+	Cost    float64 `json:"cost,omitempty"`    // The max cost, the cost is counted by the prices
+	Message string  `json:"message,omitempty"` // The message of the 402 error
+}
+
+// OpenAPI the OpenAPI document endpoint /api/__yao/openapi.json
+type OpenAPI struct {
+	Guard      string   `json:"guard,omitempty"`      // The guards of the endpoint, separated by commas, the default is bearer-jwt. "-" for the public document
+	Servers    []string `json:"servers,omitempty"`    // The server urls of the document
+	Extensions bool     `json:"extensions,omitempty"` // Add the x-yao-process and x-yao-guard extensions to the operations
+}
+
+// Limits the rate limits and the quotas of the HTTP APIs
+type Limits struct {
+	Store  string               `json:"store,omitempty"`  // The store name of the counters, use a redis store in the cluster. The local memory is used if it is empty
+	Global *LimitRule           `json:"global,omitempty"` // The limit of all the requests
+	Paths  []LimitRule          `json:"paths,omitempty"`  // The limits of the API paths
+	Guards map[string]LimitRule `json:"guards,omitempty"` // The limits of the requests passed the guard, the key is the guard name
+	Neo    *LimitQuota          `json:"neo,omitempty"`    // The daily quota of the Neo chats
+}
+
+// LimitRule the token bucket rate limit, the bucket refills the limit tokens in the period
+type LimitRule struct {
+	Path    string `json:"path,omitempty"`    // The API path, the "*" suffix matches the prefix. e.g. /api/__yao/neo*
+	Method  string `json:"method,omitempty"`  // The HTTP method, all the methods if it is empty
+	Limit   int    `json:"limit"`             // The max requests in the period
+	Period  string `json:"period,omitempty"`  // The period, the default value is 1m. e.g. 1s, 1m, 1h
+	Burst   int    `json:"burst,omitempty"`   // The bucket size, the default value is the limit
+	Key     string `json:"key,omitempty"`     // The counter key ip|sid|user|process, the default value is ip
+	Process string `json:"process,omitempty"` // The process returns the counter key when the key is process, the args are the ip, path and sid
+	Message string `json:"message,omitempty"` // The message of the 429 response
+}
+
+// LimitQuota the daily quota
+type LimitQuota struct {
+	Daily   int    `json:"daily"`             // The max requests per day
+	Key     string `json:"key,omitempty"`     // The counter key ip|sid|user|process, the default value is user
+	Process string `json:"process,omitempty"` // The process returns the counter key when the key is process
+	Message string `json:"message,omitempty"` // The message of the 429 response
 }
 
 // Moapi AIGC App Store API
@@ -12,6 +12,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	v8 "github.com/yaoapp/gou/runtime/v8"
 	"github.com/yaoapp/yao/neo"
+	"github.com/yaoapp/yao/runtime"
 )
 
 var regExcp = regexp.MustCompile(`Exception\|(\d+):(.*)`)
@@ -225,6 +226,7 @@ func setRouter(router *gin.Engine) {
 			return
 		}
 		defer ctx.Close()
+		defer runtime.Acquire()()
 
 		res, err := ctx.Call(fun.Method, fun.Args...)
 		if err != nil {
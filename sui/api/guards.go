@@ -15,6 +15,8 @@ import (
 	"github.com/yaoapp/gou/runtime/v8/bridge"
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/yao/helper"
+	"github.com/yaoapp/yao/runtime"
+	"github.com/yaoapp/yao/share"
 	"rogchap.com/v8go"
 )
 
@@ -184,6 +186,7 @@ func (r *Request) scriptGuardExec(c *gin.Context, name string, args []interface{
 		return err
 	}
 	defer ctx.Close()
+	defer runtime.Acquire()()
 
 	// Should be refector after the runtime refector
 	// Add the context object
@@ -216,7 +219,7 @@ func (r *Request) scriptGuardExec(c *gin.Context, name string, args []interface{
 		}
 
 		if global, ok := global.(map[string]interface{}); ok {
-			c.Set("__global", global)
+			share.MergeGlobal(c, global)
 		}
 
 		return v8go.Undefined(info.Context().Isolate())
@@ -435,6 +438,8 @@ func (r *Request) processGuardExec(c *gin.Context, name string, args []interface
 		}
 	}
 
+	process.WithContext(c.Request.Context())
+
 	if global, has := c.Get("__global"); has { // 设定全局变量
 		if global, ok := global.(map[string]interface{}); ok {
 			process.WithGlobal(global)
@@ -453,7 +458,7 @@ func (r *Request) processGuardExec(c *gin.Context, name string, args []interface
 		}
 
 		if global, ok := data["__global"].(map[string]interface{}); ok {
-			c.Set("__global", global)
+			share.MergeGlobal(c, global)
 		}
 	}
 
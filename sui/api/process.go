@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
 	"github.com/yaoapp/gou/types"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/yao/sui/core"
@@ -51,6 +52,11 @@ func init() {
 		"page.remove":    PageRemove,
 		"page.exist":     PageExist,
 		"page.asset":     PageAsset,
+		"page.history":   PageHistory,
+		"page.version":   PageVersion,
+		"page.diff":      PageDiff,
+		"page.rollback":  PageRollback,
+		"page.publish":   PagePublish,
 
 		"editor.render":              EditorRender,
 		"editor.source":              EditorSource,
@@ -524,6 +530,10 @@ func PageSave(process *process.Process) interface{} {
 		exception.New("the source is required", 400).Throw()
 	}
 
+	if source.User == "" {
+		source.User = author(process)
+	}
+
 	err = page.Save(source)
 	if err != nil {
 		exception.New(err.Error(), 500).Throw()
@@ -747,6 +757,96 @@ func PageExist(process *process.Process) interface{} {
 	return tmpl.PageExist(route)
 }
 
+// PageHistory get the versions of the page, the latest first
+func PageHistory(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	page := getPage(process)
+	history, err := page.History()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return history
+}
+
+// PageVersion get the version of the page with the source files
+func PageVersion(process *process.Process) interface{} {
+	process.ValidateArgNums(4)
+	page := getPage(process)
+	version, err := page.Version(process.ArgsString(3))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+	return version
+}
+
+// PageDiff compare the versions of the page
+// sui.page.diff <sui> <template> <route> [from] [to]
+// The empty from is the published version, the empty to is the current source.
+func PageDiff(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	page := getPage(process)
+	from := ""
+	if process.NumOfArgs() > 3 {
+		from = process.ArgsString(3)
+	}
+
+	to := ""
+	if process.NumOfArgs() > 4 {
+		to = process.ArgsString(4)
+	}
+
+	diffs, err := page.Diff(from, to)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return diffs
+}
+
+// PageRollback restore the page source to the version, the restored source is saved as a new draft
+func PageRollback(process *process.Process) interface{} {
+	process.ValidateArgNums(4)
+	page := getPage(process)
+	version, err := page.Rollback(process.ArgsString(3), author(process))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	Reload()
+	return version
+}
+
+// PagePublish publish the version of the page and build it, the current source is published if the version is not given
+// sui.page.publish <sui> <template> <route> [option] [data], option: {"version": "...", "ssr": true, "asset_root": "..."}
+func PagePublish(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	page := getPage(process)
+	option := process.ArgsMap(3, map[string]interface{}{})
+	ssr := true
+	if v, ok := option["ssr"].(bool); ok {
+		ssr = v
+	}
+
+	assetRoot := ""
+	if v, ok := option["asset_root"].(string); ok {
+		assetRoot = v
+	}
+
+	id := ""
+	if v, ok := option["version"].(string); ok {
+		id = v
+	}
+
+	data := process.ArgsMap(4, map[string]interface{}{})
+	version, warnings, err := page.Publish(id, author(process), &core.BuildOption{SSR: ssr, AssetRoot: assetRoot, Data: data})
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	Reload()
+
+	return map[string]interface{}{"version": version, "warnings": warnings}
+}
+
 // PageAsset handle the find Template request
 func PageAsset(process *process.Process) interface{} {
 	process.ValidateArgNums(3)
@@ -1119,6 +1219,40 @@ func get(process *process.Process) core.SUI {
 	return sui
 }
 
+func getPage(process *process.Process) core.IPage {
+	sui := get(process)
+	templateID := process.ArgsString(1)
+	route := route(process, 2)
+
+	tmpl, err := sui.GetTemplate(templateID)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	if !tmpl.PageExist(route) {
+		exception.New("the page %s does not exist", 404, route).Throw()
+	}
+
+	page, err := tmpl.Page(route)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return page
+}
+
+// author get the user id of the session as the author of the page version
+func author(process *process.Process) string {
+	if process.Sid == "" {
+		return ""
+	}
+
+	id, err := session.Global().ID(process.Sid).Get("user_id")
+	if err != nil || id == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", id)
+}
+
 func route(process *process.Process, i int) string {
 	route := process.ArgsString(i)
 	if route == "" {
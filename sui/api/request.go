@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
@@ -16,6 +17,7 @@ import (
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/yao/sui/core"
+	"github.com/yaoapp/yao/telemetry"
 )
 
 // Request is the request for the page API.
@@ -86,6 +88,25 @@ func NewRequestContext(c *gin.Context) (*Request, int, error) {
 // Render is the response for the page API.
 func (r *Request) Render() (string, int, error) {
 
+	parent := context.Background()
+	if r.context != nil {
+		parent = r.context.Request.Context()
+	}
+
+	ctx, span := telemetry.Start(parent, "sui.render", telemetry.SpanKindInternal)
+	span.SetAttribute("sui.route", r.Request.URL.Path)
+	span.SetAttribute("sui.file", r.File)
+	r.Request.Context = ctx
+
+	html, status, err := r.render()
+	span.SetAttribute("sui.status", status)
+	span.SetError(err)
+	span.Finish()
+	return html, status, err
+}
+
+func (r *Request) render() (string, int, error) {
+
 	// Read content from cache
 	var c *core.Cache = nil
 	if !r.Request.DisableCache() {
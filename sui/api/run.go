@@ -11,6 +11,8 @@ import (
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/runtime"
+	"github.com/yaoapp/yao/share"
 	"github.com/yaoapp/yao/sui/core"
 )
 
@@ -86,7 +88,7 @@ func Run(process *process.Process) interface{} {
 	if cfg != nil {
 		_, err := r.apiGuard(method, cfg.API)
 		if err != nil {
-			log.Error("Guard error: %s", err.Error())
+			log.With(share.LogFields(r.context.Request.Context())).Error("Guard error: %s", err.Error())
 			r.context.Done()
 			return nil
 		}
@@ -113,6 +115,7 @@ func Run(process *process.Process) interface{} {
 		return nil
 	}
 	defer scriptCtx.Close()
+	defer runtime.Acquire()()
 
 	global := scriptCtx.Global()
 	if !global.Has(prefix + method) {
@@ -13,9 +13,9 @@ import (
 
 var slotRe = regexp.MustCompile(`\[\{([^\}]+)\}\]`)
 var cssRe = regexp.MustCompile(`([\.a-z0-9A-Z-:# ]+)\{`)
-var transStmtReSingle = regexp.MustCompile(`'::([^:']+)'`)
-var transStmtReDouble = regexp.MustCompile(`"::([^:"]+)"`)
-var transFuncRe = regexp.MustCompile(`__m\s*\(\s*["'](.*?)["']\s*\)`)
+var transStmtReSingle = regexp.MustCompile(`'::([^:'][^']*)'`)
+var transStmtReDouble = regexp.MustCompile(`"::([^:"][^"]*)"`)
+var transFuncRe = regexp.MustCompile("__m\\s*\\(\\s*(?:\"([^\"]*)\"|'([^']*)'|`([^`]*)`)\\s*[,)]")
 
 // Build build the page
 func (page *Page) Build(ctx *BuildContext, option *BuildOption) (*goquery.Document, []string, error) {
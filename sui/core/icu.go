@@ -0,0 +1,546 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+var icuArgRe = regexp.MustCompile(`\{\s*[\p{L}\p{N}_$.]+\s*(,|\})`)
+
+// ICU argument types
+const (
+	icuText = iota
+	icuArg
+	icuPound
+)
+
+// icuNode is the node of the parsed ICU message
+type icuNode struct {
+	kind    int
+	text    string
+	name    string
+	typ     string // ENUM: '', 'number', 'date', 'time', 'plural', 'selectordinal', 'select'
+	style   string
+	offset  float64
+	options map[string][]icuNode
+}
+
+// icuContext is the context of the ICU message formatting
+type icuContext struct {
+	tag      language.Tag
+	printer  *message.Printer
+	location *time.Location
+	values   map[string]interface{}
+	pound    *float64
+}
+
+// HasICU check if the message is an ICU message with arguments, e.g. "{count, plural, one {# item} other {# items} }"
+func HasICU(message string) bool {
+	return icuArgRe.MatchString(message)
+}
+
+// FormatICU format the ICU MessageFormat message with the given values
+// Support the simple argument {name}, number, date, time, plural, selectordinal and select.
+// The timezone is the Locale.Timezone, an offset like "+08:00" or an IANA name like "Asia/Shanghai".
+func FormatICU(msg string, locale string, timezone string, values map[string]interface{}) (string, error) {
+	nodes, err := parseICU(msg)
+	if err != nil {
+		return msg, err
+	}
+
+	tag := language.Make(locale)
+	if locale == "" {
+		tag = language.English
+	}
+
+	ctx := &icuContext{
+		tag:      tag,
+		printer:  message.NewPrinter(tag),
+		location: icuLocation(timezone),
+		values:   values,
+	}
+
+	var sb strings.Builder
+	err = ctx.format(&sb, nodes)
+	if err != nil {
+		return msg, err
+	}
+	return sb.String(), nil
+}
+
+// FmtICU format the ICU message with the locale, the message is returned as it is if the format fails
+func (locale *Locale) FmtICU(message string, values map[string]interface{}) string {
+	if !HasICU(message) {
+		return message
+	}
+
+	name := "en"
+	timezone := ""
+	if locale != nil {
+		name = locale.Name
+		timezone = locale.Timezone
+	}
+
+	res, err := FormatICU(message, name, timezone, values)
+	if err != nil {
+		return message
+	}
+	return res
+}
+
+func (ctx *icuContext) format(sb *strings.Builder, nodes []icuNode) error {
+	for _, node := range nodes {
+		switch node.kind {
+		case icuText:
+			sb.WriteString(node.text)
+
+		case icuPound:
+			if ctx.pound == nil {
+				sb.WriteString("#")
+				continue
+			}
+			sb.WriteString(ctx.printer.Sprint(number.Decimal(*ctx.pound)))
+
+		case icuArg:
+			err := ctx.formatArg(sb, node)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ctx *icuContext) formatArg(sb *strings.Builder, node icuNode) error {
+	value, has := icuValue(ctx.values, node.name)
+
+	switch node.typ {
+	case "":
+		if !has {
+			sb.WriteString("{" + node.name + "}")
+			return nil
+		}
+		if n, ok := icuNumber(value); ok && !isString(value) {
+			sb.WriteString(ctx.printer.Sprint(number.Decimal(n)))
+			return nil
+		}
+		sb.WriteString(fmt.Sprintf("%v", value))
+		return nil
+
+	case "number":
+		n, ok := icuNumber(value)
+		if !ok {
+			return fmt.Errorf("the argument %s should be a number", node.name)
+		}
+		switch node.style {
+		case "integer":
+			sb.WriteString(ctx.printer.Sprint(number.Decimal(math.Round(n), number.MaxFractionDigits(0))))
+		case "percent":
+			sb.WriteString(ctx.printer.Sprint(number.Percent(n)))
+		default:
+			sb.WriteString(ctx.printer.Sprint(number.Decimal(n)))
+		}
+		return nil
+
+	case "date", "time":
+		t, ok := icuTime(value)
+		if !ok {
+			return fmt.Errorf("the argument %s should be a date", node.name)
+		}
+		if ctx.location != nil {
+			t = t.In(ctx.location)
+		}
+		sb.WriteString(icuFormatTime(ctx.tag, node.typ, node.style, t))
+		return nil
+
+	case "select":
+		key := ""
+		if has && value != nil {
+			key = fmt.Sprintf("%v", value)
+		}
+		options, has := node.options[key]
+		if !has {
+			options, has = node.options["other"]
+		}
+		if !has {
+			return fmt.Errorf("the select argument %s requires the other option", node.name)
+		}
+		return ctx.format(sb, options)
+
+	case "plural", "selectordinal":
+		n, ok := icuNumber(value)
+		if !ok {
+			return fmt.Errorf("the argument %s should be a number", node.name)
+		}
+
+		options, has := node.options[fmt.Sprintf("=%s", strconv.FormatFloat(n, 'f', -1, 64))]
+		if !has {
+			rules := plural.Cardinal
+			if node.typ == "selectordinal" {
+				rules = plural.Ordinal
+			}
+			options, has = node.options[icuPluralForm(rules, ctx.tag, n-node.offset)]
+		}
+		if !has {
+			options, has = node.options["other"]
+		}
+		if !has {
+			return fmt.Errorf("the %s argument %s requires the other option", node.typ, node.name)
+		}
+
+		pound := ctx.pound
+		value := n - node.offset
+		ctx.pound = &value
+		err := ctx.format(sb, options)
+		ctx.pound = pound
+		return err
+	}
+
+	return fmt.Errorf("the argument type %s is not supported", node.typ)
+}
+
+// icuParser the ICU message parser
+type icuParser struct {
+	runes []rune
+	pos   int
+}
+
+func parseICU(message string) ([]icuNode, error) {
+	parser := &icuParser{runes: []rune(message)}
+	nodes, err := parser.message(false)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.runes) {
+		return nil, fmt.Errorf("unexpected } at %d", parser.pos)
+	}
+	return nodes, nil
+}
+
+// message parse the (sub)message until the } or the end
+func (parser *icuParser) message(inPlural bool) ([]icuNode, error) {
+	nodes := []icuNode{}
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, icuNode{kind: icuText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for parser.pos < len(parser.runes) {
+		ch := parser.runes[parser.pos]
+		switch {
+		case ch == '\'':
+			parser.pos++
+			if parser.pos < len(parser.runes) && parser.runes[parser.pos] == '\'' {
+				text.WriteRune('\'')
+				parser.pos++
+				continue
+			}
+
+			// Quoted literal, starts with a special character
+			if parser.pos < len(parser.runes) && strings.ContainsRune("{}#|", parser.runes[parser.pos]) {
+				for parser.pos < len(parser.runes) {
+					if parser.runes[parser.pos] == '\'' {
+						if parser.pos+1 < len(parser.runes) && parser.runes[parser.pos+1] == '\'' {
+							text.WriteRune('\'')
+							parser.pos += 2
+							continue
+						}
+						parser.pos++
+						break
+					}
+					text.WriteRune(parser.runes[parser.pos])
+					parser.pos++
+				}
+				continue
+			}
+			text.WriteRune('\'')
+
+		case ch == '{':
+			flush()
+			parser.pos++
+			node, err := parser.argument()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+
+		case ch == '}':
+			flush()
+			return nodes, nil
+
+		case ch == '#' && inPlural:
+			flush()
+			nodes = append(nodes, icuNode{kind: icuPound})
+			parser.pos++
+
+		default:
+			text.WriteRune(ch)
+			parser.pos++
+		}
+	}
+
+	flush()
+	return nodes, nil
+}
+
+// argument parse the argument after the {
+func (parser *icuParser) argument() (icuNode, error) {
+	node := icuNode{kind: icuArg}
+	name, end := parser.until(",}")
+	node.name = strings.TrimSpace(name)
+	if node.name == "" {
+		return node, fmt.Errorf("the argument name is required at %d", parser.pos)
+	}
+
+	if end == '}' {
+		return node, nil
+	}
+	if end != ',' {
+		return node, fmt.Errorf("the argument %s is not closed", node.name)
+	}
+
+	typ, end := parser.until(",}")
+	node.typ = strings.TrimSpace(typ)
+	switch node.typ {
+	case "number", "date", "time":
+		if end == ',' {
+			style, close := parser.until("}")
+			if close != '}' {
+				return node, fmt.Errorf("the argument %s is not closed", node.name)
+			}
+			node.style = strings.TrimSpace(style)
+			return node, nil
+		}
+		if end != '}' {
+			return node, fmt.Errorf("the argument %s is not closed", node.name)
+		}
+		return node, nil
+
+	case "plural", "selectordinal", "select":
+		if end != ',' {
+			return node, fmt.Errorf("the %s argument %s requires options", node.typ, node.name)
+		}
+		return node, parser.options(&node)
+	}
+
+	return node, fmt.Errorf("the argument type %s of %s is not supported", node.typ, node.name)
+}
+
+// options parse the options of the plural, selectordinal and select argument
+func (parser *icuParser) options(node *icuNode) error {
+	node.options = map[string][]icuNode{}
+	for {
+		parser.spaces()
+		if parser.pos >= len(parser.runes) {
+			return fmt.Errorf("the argument %s is not closed", node.name)
+		}
+
+		if parser.runes[parser.pos] == '}' {
+			parser.pos++
+			break
+		}
+
+		start := parser.pos
+		for parser.pos < len(parser.runes) && !strings.ContainsRune(" \t\r\n{}", parser.runes[parser.pos]) {
+			parser.pos++
+		}
+		selector := string(parser.runes[start:parser.pos])
+
+		if strings.HasPrefix(selector, "offset:") && node.typ != "select" {
+			offset, err := strconv.ParseFloat(strings.TrimPrefix(selector, "offset:"), 64)
+			if err != nil {
+				return fmt.Errorf("the offset of %s is not a number", node.name)
+			}
+			node.offset = offset
+			continue
+		}
+
+		parser.spaces()
+		if selector == "" || parser.pos >= len(parser.runes) || parser.runes[parser.pos] != '{' {
+			return fmt.Errorf("the option %s of %s should be followed by a {message}", selector, node.name)
+		}
+		parser.pos++
+
+		// The # of a select nested in a plural refers to the plural value
+		message, err := parser.message(true)
+		if err != nil {
+			return err
+		}
+		if parser.pos >= len(parser.runes) || parser.runes[parser.pos] != '}' {
+			return fmt.Errorf("the option %s of %s is not closed", selector, node.name)
+		}
+		parser.pos++
+		node.options[selector] = message
+	}
+
+	if _, has := node.options["other"]; !has {
+		return fmt.Errorf("the %s argument %s requires the other option", node.typ, node.name)
+	}
+	return nil
+}
+
+func (parser *icuParser) until(chars string) (string, rune) {
+	start := parser.pos
+	for parser.pos < len(parser.runes) {
+		ch := parser.runes[parser.pos]
+		if strings.ContainsRune(chars, ch) {
+			parser.pos++
+			return string(parser.runes[start : parser.pos-1]), ch
+		}
+		parser.pos++
+	}
+	return string(parser.runes[start:]), 0
+}
+
+func (parser *icuParser) spaces() {
+	for parser.pos < len(parser.runes) && strings.ContainsRune(" \t\r\n", parser.runes[parser.pos]) {
+		parser.pos++
+	}
+}
+
+func icuValue(values map[string]interface{}, name string) (interface{}, bool) {
+	if values == nil {
+		return nil, false
+	}
+
+	if value, has := values[name]; has {
+		return value, true
+	}
+
+	// The nested value, e.g. {user.name}
+	var current interface{} = values
+	for _, key := range strings.Split(name, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			value, has := v[key]
+			if !has {
+				return nil, false
+			}
+			current = value
+		case Data:
+			value, has := v[key]
+			if !has {
+				return nil, false
+			}
+			current = value
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func icuNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func icuTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	// Unix timestamp in milliseconds, the same as the JavaScript Date
+	if n, ok := icuNumber(value); ok {
+		return time.UnixMilli(int64(n)), true
+	}
+	return time.Time{}, false
+}
+
+func icuLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return nil
+	}
+
+	if loc, err := time.LoadLocation(timezone); err == nil {
+		return loc
+	}
+
+	// The offset, e.g. +08:00
+	t, err := time.Parse("-07:00", timezone)
+	if err != nil {
+		return nil
+	}
+	_, offset := t.Zone()
+	return time.FixedZone(timezone, offset)
+}
+
+func icuPluralForm(rules *plural.Rules, tag language.Tag, n float64) string {
+	n = math.Abs(n)
+	raw := strconv.FormatFloat(n, 'f', -1, 64)
+	integer, fraction, _ := strings.Cut(raw, ".")
+	i, _ := strconv.Atoi(integer)
+	f := 0
+	if fraction != "" {
+		f, _ = strconv.Atoi(fraction)
+	}
+
+	v := len(fraction)
+	form := rules.MatchPlural(tag, i, v, v, f, f)
+	switch form {
+	case plural.Zero:
+		return "zero"
+	case plural.One:
+		return "one"
+	case plural.Two:
+		return "two"
+	case plural.Few:
+		return "few"
+	case plural.Many:
+		return "many"
+	}
+	return "other"
+}
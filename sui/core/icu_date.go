@@ -0,0 +1,113 @@
+package core
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// icuDateFormat the date layouts and the names of the months and the weekdays of a language.
+// The layouts are the Go layouts, MMMM, MMM and EEEE are replaced with the month, the short month and the weekday names.
+type icuDateFormat struct {
+	layouts     map[string]string // short, medium, long, full
+	months      []string
+	shortMonths []string
+	weekdays    []string // Sunday first
+}
+
+// icuTimeLayouts the time styles, the times are 24-hour in all languages
+var icuTimeLayouts = map[string]string{"": "15:04", "short": "15:04", "medium": "15:04:05", "long": "15:04:05 -07:00", "full": "15:04:05 -07:00"}
+
+var icuDateFormats = map[string]icuDateFormat{
+	"en": {
+		layouts:     map[string]string{"short": "2006-01-02", "medium": "MMM 2, 2006", "long": "MMMM 2, 2006", "full": "EEEE, MMMM 2, 2006"},
+		months:      []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		shortMonths: []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		weekdays:    []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	},
+	"zh": {
+		layouts:  map[string]string{"short": "2006/1/2", "medium": "2006年1月2日", "long": "2006年1月2日", "full": "2006年1月2日EEEE"},
+		weekdays: []string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+	},
+	"ja": {
+		layouts:  map[string]string{"short": "2006/01/02", "medium": "2006/01/02", "long": "2006年1月2日", "full": "2006年1月2日EEEE"},
+		weekdays: []string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+	},
+	"ko": {
+		layouts:  map[string]string{"short": "06. 1. 2.", "medium": "2006. 1. 2.", "long": "2006년 1월 2일", "full": "2006년 1월 2일 EEEE"},
+		weekdays: []string{"일요일", "월요일", "화요일", "수요일", "목요일", "금요일", "토요일"},
+	},
+	"de": {
+		layouts:     map[string]string{"short": "02.01.06", "medium": "02.01.2006", "long": "2. MMMM 2006", "full": "EEEE, 2. MMMM 2006"},
+		months:      []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: []string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		weekdays:    []string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	},
+	"fr": {
+		layouts:     map[string]string{"short": "02/01/2006", "medium": "2 MMM 2006", "long": "2 MMMM 2006", "full": "EEEE 2 MMMM 2006"},
+		months:      []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		weekdays:    []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	},
+	"es": {
+		layouts:     map[string]string{"short": "2/1/06", "medium": "2 MMM 2006", "long": "2 de MMMM de 2006", "full": "EEEE, 2 de MMMM de 2006"},
+		months:      []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: []string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		weekdays:    []string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	},
+	"pt": {
+		layouts:     map[string]string{"short": "02/01/2006", "medium": "2 de MMM de 2006", "long": "2 de MMMM de 2006", "full": "EEEE, 2 de MMMM de 2006"},
+		months:      []string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: []string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		weekdays:    []string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+	},
+	"it": {
+		layouts:     map[string]string{"short": "02/01/06", "medium": "2 MMM 2006", "long": "2 MMMM 2006", "full": "EEEE 2 MMMM 2006"},
+		months:      []string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: []string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		weekdays:    []string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+	},
+	"ru": {
+		layouts:     map[string]string{"short": "02.01.2006", "medium": "2 MMM 2006 г.", "long": "2 MMMM 2006 г.", "full": "EEEE, 2 MMMM 2006 г."},
+		months:      []string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		shortMonths: []string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.", "июл.", "авг.", "сент.", "окт.", "нояб.", "дек."},
+		weekdays:    []string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+	},
+}
+
+// icuFormatTime format the date or the time with the style of the language, the English style is used if the language is not supported.
+// The style is a Go layout if it is not short, medium, long or full. e.g. {day, date, 2006/01/02}
+func icuFormatTime(tag language.Tag, typ string, style string, t time.Time) string {
+	if typ == "time" {
+		if layout, has := icuTimeLayouts[style]; has {
+			return t.Format(layout)
+		}
+		return t.Format(style)
+	}
+
+	base, _ := tag.Base()
+	format, has := icuDateFormats[base.String()]
+	if !has {
+		format = icuDateFormats["en"]
+	}
+
+	if style == "" {
+		style = "short"
+	}
+
+	layout, has := format.layouts[style]
+	if !has {
+		return t.Format(style)
+	}
+
+	res := t.Format(layout)
+	if format.weekdays != nil {
+		res = strings.Replace(res, "EEEE", format.weekdays[t.Weekday()], 1)
+	}
+	if format.months != nil {
+		res = strings.Replace(res, "MMMM", format.months[t.Month()-1], 1)
+		res = strings.Replace(res, "MMM", format.shortMonths[t.Month()-1], 1)
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatICU(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		locale   string
+		timezone string
+		values   map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "Simple argument",
+			message:  "Hello {name}",
+			values:   map[string]interface{}{"name": "Yao"},
+			expected: "Hello Yao",
+		},
+		{
+			name:     "Nested argument",
+			message:  "Hello {user.name}",
+			values:   map[string]interface{}{"user": map[string]interface{}{"name": "Yao"}},
+			expected: "Hello Yao",
+		},
+		{
+			name:     "Plural one",
+			message:  "{count, plural, =0 {No items} one {# item} other {# items} }",
+			values:   map[string]interface{}{"count": 1},
+			expected: "1 item",
+		},
+		{
+			name:     "Plural other",
+			message:  "{count, plural, =0 {No items} one {# item} other {# items}}",
+			values:   map[string]interface{}{"count": 1200},
+			expected: "1,200 items",
+		},
+		{
+			name:     "Plural exact",
+			message:  "{count, plural, =0 {No items} one {# item} other {# items}}",
+			values:   map[string]interface{}{"count": 0},
+			expected: "No items",
+		},
+		{
+			name:     "Plural offset",
+			message:  "{count, plural, offset:1 =1 {Only you} one {You and # other} other {You and # others}}",
+			values:   map[string]interface{}{"count": 3},
+			expected: "You and 2 others",
+		},
+		{
+			name:     "Plural russian",
+			message:  "{count, plural, one {# товар} few {# товара} many {# товаров} other {# товара}}",
+			locale:   "ru",
+			values:   map[string]interface{}{"count": 5},
+			expected: "5 товаров",
+		},
+		{
+			name:     "Select",
+			message:  "{gender, select, male {He} female {She} other {They}} liked it",
+			values:   map[string]interface{}{"gender": "female"},
+			expected: "She liked it",
+		},
+		{
+			name:     "Select other",
+			message:  "{gender, select, male {He} female {She} other {They}} liked it",
+			values:   map[string]interface{}{},
+			expected: "They liked it",
+		},
+		{
+			name:     "Select ordinal",
+			message:  "{place, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}",
+			values:   map[string]interface{}{"place": 23},
+			expected: "23rd",
+		},
+		{
+			name:     "Number percent",
+			message:  "{ratio, number, percent}",
+			values:   map[string]interface{}{"ratio": 0.25},
+			expected: "25%",
+		},
+		{
+			name:     "Date with timezone",
+			message:  "{day, date, short} {day, time, short}",
+			timezone: "+08:00",
+			values:   map[string]interface{}{"day": "2024-01-01T20:30:00Z"},
+			expected: "2024-01-02 04:30",
+		},
+		{
+			name:     "Date medium",
+			message:  "{day, date, medium}",
+			values:   map[string]interface{}{"day": "2024-01-01T08:00:00Z"},
+			expected: "Jan 1, 2024",
+		},
+		{
+			name:     "Date zh full",
+			message:  "{day, date, full}",
+			locale:   "zh-CN",
+			values:   map[string]interface{}{"day": "2024-01-01T08:00:00Z"},
+			expected: "2024年1月1日星期一",
+		},
+		{
+			name:     "Date de long",
+			message:  "{day, date, long}",
+			locale:   "de-DE",
+			values:   map[string]interface{}{"day": "2024-03-01T08:00:00Z"},
+			expected: "1. März 2024",
+		},
+		{
+			name:     "Date fr medium",
+			message:  "{day, date, medium}",
+			locale:   "fr",
+			values:   map[string]interface{}{"day": "2024-01-01T08:00:00Z"},
+			expected: "1 janv. 2024",
+		},
+		{
+			name:     "Date unknown language",
+			message:  "{day, date, full}",
+			locale:   "sw",
+			values:   map[string]interface{}{"day": "2024-01-01T08:00:00Z"},
+			expected: "Monday, January 1, 2024",
+		},
+		{
+			name:     "Quoted",
+			message:  "'{name}' is {name}, it''s",
+			values:   map[string]interface{}{"name": "Yao"},
+			expected: "{name} is Yao, it's",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := FormatICU(test.message, test.locale, test.timezone, test.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
+
+func TestFormatICUError(t *testing.T) {
+	_, err := FormatICU("{count, plural, one {# item}}", "en", "", map[string]interface{}{"count": 1})
+	assert.Error(t, err)
+
+	_, err = FormatICU("{count, unknown}", "en", "", nil)
+	assert.Error(t, err)
+
+	assert.True(t, HasICU("{count, plural, one {# item} other {# items}}"))
+	assert.True(t, HasICU("Hello {name}"))
+	assert.False(t, HasICU("Hello World"))
+
+	locale := &Locale{Name: "en"}
+	assert.Equal(t, "{count, plural, one {# item}}", locale.FmtICU("{count, plural, one {# item}}", nil))
+}
+
+func TestICUExtraction(t *testing.T) {
+	matches := transFuncRe.FindAllStringSubmatch(`__m("{count, plural, one {# item} other {# items}}", { count: 2 }); __m('Hello')`, -1)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, "{count, plural, one {# item} other {# items}}", matches[0][1])
+	assert.Equal(t, "Hello", matches[1][2])
+
+	stmt := transStmtReSingle.FindAllStringSubmatch(`'::{count, plural, offset:1 one {# item} other {# items} }'`, -1)
+	assert.Len(t, stmt, 1)
+	assert.Equal(t, "{count, plural, offset:1 one {# item} other {# items} }", stmt[0][1])
+	assert.Len(t, transStmtReSingle.FindAllStringSubmatch(`':::escaped'`, -1), 0)
+}
@@ -73,12 +73,169 @@ const i118nScriptTmpl = `
 		__sui_locale = %s;
 	} catch (e) { __sui_locale = {}  }
 
+	const __sui_locale_name = %s;
+	const __sui_locale_timezone = %s;
+
 	function __m(message, fmt) {
 		if (fmt && typeof fmt === "function") {
 			return fmt(message, __sui_locale);
 		}
+		if (fmt && typeof fmt === "object") {
+			return __sui_icu(__sui_locale[message] || message, fmt);
+		}
 		return __sui_locale[message] || message;
 	}
+
+	function __sui_icu(message, values, locale, timezone) {
+		locale = locale || __sui_locale_name || document.documentElement.lang || "en";
+		timezone = timezone || __sui_locale_timezone || undefined;
+		values = values || {};
+		const src = String(message);
+		let pos = 0;
+
+		const value = function (name) {
+			if (name in values) return values[name];
+			return name.split(".").reduce(function (v, k) { return v === undefined || v === null ? undefined : v[k]; }, values);
+		};
+
+		const until = function (chars) {
+			const start = pos;
+			while (pos < src.length && chars.indexOf(src[pos]) === -1) pos++;
+			const end = src[pos];
+			pos++;
+			return [src.slice(start, pos - 1), end];
+		};
+
+		const spaces = function () {
+			while (pos < src.length && " \t\r\n".indexOf(src[pos]) !== -1) pos++;
+		};
+
+		const parse = function (inPlural) {
+			const nodes = [];
+			let text = "";
+			while (pos < src.length) {
+				const ch = src[pos];
+				if (ch === "'") {
+					pos++;
+					if (src[pos] === "'") { text += "'"; pos++; continue; }
+					if (pos < src.length && "{}#|".indexOf(src[pos]) !== -1) {
+						while (pos < src.length) {
+							if (src[pos] === "'") {
+								if (src[pos + 1] === "'") { text += "'"; pos += 2; continue; }
+								pos++;
+								break;
+							}
+							text += src[pos];
+							pos++;
+						}
+						continue;
+					}
+					text += "'";
+					continue;
+				}
+				if (ch === "{") {
+					if (text) { nodes.push(text); text = ""; }
+					pos++;
+					nodes.push(argument());
+					continue;
+				}
+				if (ch === "}") break;
+				if (ch === "#" && inPlural) {
+					if (text) { nodes.push(text); text = ""; }
+					nodes.push({ pound: true });
+					pos++;
+					continue;
+				}
+				text += ch;
+				pos++;
+			}
+			if (text) nodes.push(text);
+			return nodes;
+		};
+
+		const argument = function () {
+			let r = until(",}");
+			const node = { name: r[0].trim(), type: "", style: "", offset: 0, options: {} };
+			if (r[1] === "}") return node;
+			r = until(",}");
+			node.type = r[0].trim();
+			if (node.type === "number" || node.type === "date" || node.type === "time") {
+				if (r[1] === ",") node.style = until("}")[0].trim();
+				return node;
+			}
+			if (r[1] !== ",") throw new Error("the argument " + node.name + " requires options");
+			for (;;) {
+				spaces();
+				if (pos >= src.length) throw new Error("the argument " + node.name + " is not closed");
+				if (src[pos] === "}") { pos++; break; }
+				const start = pos;
+				while (pos < src.length && " \t\r\n{}".indexOf(src[pos]) === -1) pos++;
+				const selector = src.slice(start, pos);
+				if (selector.indexOf("offset:") === 0) { node.offset = Number(selector.slice(7)); continue; }
+				spaces();
+				if (src[pos] !== "{") throw new Error("the option " + selector + " of " + node.name + " should be followed by a {message}");
+				pos++;
+				node.options[selector] = parse(true);
+				if (src[pos] !== "}") throw new Error("the option " + selector + " of " + node.name + " is not closed");
+				pos++;
+			}
+			if (!node.options.other) throw new Error("the argument " + node.name + " requires the other option");
+			return node;
+		};
+
+		const datetime = function (v, type, style) {
+			const date = v instanceof Date ? v : new Date(v);
+			const option = {};
+			option[type === "time" ? "timeStyle" : "dateStyle"] = style || "short";
+			try {
+				return new Intl.DateTimeFormat(locale, Object.assign({ timeZone: timezone }, option)).format(date);
+			} catch (e) {
+				return new Intl.DateTimeFormat(locale, option).format(date);
+			}
+		};
+
+		const format = function (nodes, pound) {
+			return nodes.map(function (node) {
+				if (typeof node === "string") return node;
+				if (node.pound) return pound === null ? "#" : new Intl.NumberFormat(locale).format(pound);
+				const v = value(node.name);
+				switch (node.type) {
+					case "": {
+						if (v === undefined) return "{" + node.name + "}";
+						return typeof v === "number" ? new Intl.NumberFormat(locale).format(v) : String(v);
+					}
+					case "number": {
+						const option = node.style === "percent" ? { style: "percent" } : node.style === "integer" ? { maximumFractionDigits: 0 } : {};
+						return new Intl.NumberFormat(locale, option).format(Number(v));
+					}
+					case "date":
+					case "time":
+						return datetime(v, node.type, node.style);
+					case "select":
+						return format(node.options[String(v)] || node.options.other, pound);
+					case "plural":
+					case "selectordinal": {
+						const n = Number(v);
+						let option = node.options["=" + n];
+						if (!option) {
+							const rules = new Intl.PluralRules(locale, { type: node.type === "plural" ? "cardinal" : "ordinal" });
+							option = node.options[rules.select(n - node.offset)] || node.options.other;
+						}
+						return format(option, n - node.offset);
+					}
+				}
+				throw new Error("the argument type " + node.type + " is not supported");
+			}).join("");
+		};
+
+		try {
+			const nodes = parse(false);
+			return format(nodes, null);
+		} catch (e) {
+			console.warn("[SUI] ICU message " + src + ": " + (e.message || e));
+			return src;
+		}
+	}
 `
 
 const pageEventScriptTmpl = `
@@ -180,8 +337,8 @@ func bodyInjectionScript(jsonRaw string, debug bool) string {
 	return fmt.Sprintf(`<script type="text/javascript">`+initScriptTmpl+`</script>`, jsonRaw, jsPrintData)
 }
 
-func headInjectionScript(jsonRaw string) string {
-	return fmt.Sprintf(`<script type="text/javascript">`+i118nScriptTmpl+`</script>`, jsonRaw)
+func headInjectionScript(jsonRaw string, locale string, timezone string) string {
+	return fmt.Sprintf(`<script type="text/javascript">`+i118nScriptTmpl+`</script>`, jsonRaw, locale, timezone)
 }
 
 func pageEventInjectScript(eventID, eventName, dataKeys, jsonKeys, handler string) string {
@@ -0,0 +1,486 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LocaleStateInitial the message is not translated yet
+	LocaleStateInitial = "initial"
+
+	// LocaleStateTranslated the message is translated
+	LocaleStateTranslated = "translated"
+
+	// LocaleStateDraft the message is translated by the machine and should be reviewed
+	LocaleStateDraft = "draft"
+
+	// LocaleStateStale the message is not used by the page any more
+	LocaleStateStale = "stale"
+)
+
+// LocaleEntry is the struct for a translatable message of a page
+type LocaleEntry struct {
+	ID     string   `json:"id"`
+	Route  string   `json:"route"`
+	Type   string   `json:"type,omitempty"` // ENUM: 'text', 'html', 'attr', 'script'
+	Keys   []string `json:"keys,omitempty"`
+	Source string   `json:"source"`
+	Target string   `json:"target,omitempty"`
+	State  string   `json:"state,omitempty"`
+}
+
+// LocaleCatalog is the struct for the messages of a template in a locale
+type LocaleCatalog struct {
+	Template string        `json:"template,omitempty"`
+	Source   string        `json:"source,omitempty"`
+	Target   string        `json:"target"`
+	Entries  []LocaleEntry `json:"entries"`
+	Stale    []LocaleEntry `json:"stale,omitempty"`
+}
+
+// LocaleReport is the struct for the missing and stale messages report
+type LocaleReport struct {
+	Locale     string        `json:"locale"`
+	Total      int           `json:"total"`
+	Translated int           `json:"translated"`
+	Missing    []LocaleEntry `json:"missing"`
+	Drafts     []LocaleEntry `json:"drafts"`
+	Stale      []LocaleEntry `json:"stale"`
+}
+
+// LocaleEntries get the entries of a page from the extracted translations and the locale source
+func LocaleEntries(route string, translations []Translation, locale Locale) []LocaleEntry {
+	entries := []LocaleEntry{}
+	index := map[string]int{}
+	for _, t := range translations {
+		if t.Message == "" {
+			continue
+		}
+
+		if i, has := index[t.Message]; has {
+			entries[i].Keys = append(entries[i].Keys, t.Key)
+			continue
+		}
+
+		entry := LocaleEntry{
+			ID:     t.Key,
+			Route:  route,
+			Type:   t.Type,
+			Keys:   []string{t.Key},
+			Source: t.Message,
+			State:  LocaleStateInitial,
+		}
+
+		// The script messages are used if the message is not in the messages
+		target, has := locale.Messages[t.Message]
+		if !has && t.Type == "script" {
+			target, has = locale.ScriptMessages[t.Message]
+		}
+
+		if has && target != "" && target != t.Message {
+			entry.Target = target
+			entry.State = LocaleStateTranslated
+			if draft, has := locale.Drafts[t.Message]; has && draft == target {
+				entry.State = LocaleStateDraft
+			}
+		} else if target, has := locale.Keys[t.Key]; has && target != "" && target != t.Message {
+			entry.Target = target
+			entry.State = LocaleStateTranslated
+		}
+
+		index[t.Message] = len(entries)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// StaleLocaleEntries get the messages of the locale source which are not used by the page any more
+func StaleLocaleEntries(route string, translations []Translation, locale Locale) []LocaleEntry {
+	used := map[string]bool{}
+	for _, t := range translations {
+		used[t.Message] = true
+	}
+
+	stale := []LocaleEntry{}
+	for source, target := range locale.Messages {
+		if used[source] {
+			continue
+		}
+		stale = append(stale, LocaleEntry{Route: route, Source: source, Target: target, State: LocaleStateStale})
+	}
+
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Source < stale[j].Source })
+	return stale
+}
+
+// Report get the missing and stale messages report of the catalog
+func (catalog *LocaleCatalog) Report() LocaleReport {
+	report := LocaleReport{
+		Locale:  catalog.Target,
+		Total:   len(catalog.Entries),
+		Missing: []LocaleEntry{},
+		Drafts:  []LocaleEntry{},
+		Stale:   []LocaleEntry{},
+	}
+
+	for _, entry := range catalog.Entries {
+		switch entry.State {
+		case LocaleStateTranslated:
+			report.Translated++
+		case LocaleStateDraft:
+			report.Drafts = append(report.Drafts, entry)
+		default:
+			report.Missing = append(report.Missing, entry)
+		}
+	}
+
+	if catalog.Stale != nil {
+		report.Stale = append(report.Stale, catalog.Stale...)
+	}
+	return report
+}
+
+// Routes get the entries grouped by route
+func (catalog *LocaleCatalog) Routes() ([]string, map[string][]LocaleEntry) {
+	routes := []string{}
+	groups := map[string][]LocaleEntry{}
+	for _, entry := range catalog.Entries {
+		if _, has := groups[entry.Route]; !has {
+			routes = append(routes, entry.Route)
+			groups[entry.Route] = []LocaleEntry{}
+		}
+		groups[entry.Route] = append(groups[entry.Route], entry)
+	}
+	return routes, groups
+}
+
+// Export export the catalog to the given format ( xliff, po )
+func (catalog *LocaleCatalog) Export(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "xliff", "xlf":
+		return catalog.XLIFF()
+	case "po":
+		return catalog.PO()
+	}
+	return nil, fmt.Errorf("The format %s is not supported, should be xliff or po", format)
+}
+
+// ParseLocaleCatalog parse the catalog from the given format ( xliff, po )
+func ParseLocaleCatalog(format string, data []byte) (*LocaleCatalog, error) {
+	switch strings.ToLower(format) {
+	case "xliff", "xlf":
+		return ParseXLIFF(data)
+	case "po":
+		return ParsePO(data)
+	}
+	return nil, fmt.Errorf("The format %s is not supported, should be xliff or po", format)
+}
+
+// xliffSubStateDraft the sub state of the machine translated segment
+const xliffSubStateDraft = "yao:draft"
+
+// XLIFF 2.0 document
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr,omitempty"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID       string      `xml:"id,attr"`
+	Original string      `xml:"original,attr,omitempty"`
+	Units    []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID      string       `xml:"id,attr"`
+	Notes   []xliffNote  `xml:"notes>note,omitempty"`
+	Segment xliffSegment `xml:"segment"`
+}
+
+type xliffNote struct {
+	Category string `xml:"category,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type xliffSegment struct {
+	State    string `xml:"state,attr,omitempty"`
+	SubState string `xml:"subState,attr,omitempty"`
+	Source   string `xml:"source"`
+	Target   string `xml:"target,omitempty"`
+}
+
+// XLIFF export the catalog to XLIFF 2.0
+func (catalog *LocaleCatalog) XLIFF() ([]byte, error) {
+	source := catalog.Source
+	if source == "" {
+		source = "en"
+	}
+
+	doc := xliffDocument{Version: "2.0", SrcLang: source, TrgLang: catalog.Target, Files: []xliffFile{}}
+	routes, groups := catalog.Routes()
+	for _, route := range routes {
+		file := xliffFile{ID: TranslationKeyPrefix(route), Original: route, Units: []xliffUnit{}}
+		for _, entry := range groups[route] {
+			segment := xliffSegment{State: entry.State, Source: entry.Source, Target: entry.Target}
+			if entry.State == LocaleStateDraft {
+				segment.State = LocaleStateTranslated
+				segment.SubState = xliffSubStateDraft
+			}
+
+			unit := xliffUnit{
+				ID: entry.ID,
+				Notes: []xliffNote{
+					{Category: "route", Value: entry.Route},
+					{Category: "type", Value: entry.Type},
+					{Category: "keys", Value: strings.Join(entry.Keys, ",")},
+				},
+				Segment: segment,
+			}
+			file.Units = append(file.Units, unit)
+		}
+		doc.Files = append(doc.Files, file)
+	}
+
+	raw, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), raw...), nil
+}
+
+// ParseXLIFF parse the XLIFF 2.0 document
+func ParseXLIFF(data []byte) (*LocaleCatalog, error) {
+	doc := xliffDocument{}
+	err := xml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("Parse the XLIFF error: %s", err.Error())
+	}
+
+	if !strings.HasPrefix(doc.Version, "2") {
+		return nil, fmt.Errorf("The XLIFF version %s is not supported, should be 2.0", doc.Version)
+	}
+
+	catalog := &LocaleCatalog{Source: doc.SrcLang, Target: doc.TrgLang, Entries: []LocaleEntry{}}
+	for _, file := range doc.Files {
+		for _, unit := range file.Units {
+			entry := LocaleEntry{
+				ID:     unit.ID,
+				Route:  file.Original,
+				Source: unit.Segment.Source,
+				Target: unit.Segment.Target,
+				State:  unit.Segment.State,
+			}
+
+			if unit.Segment.SubState == xliffSubStateDraft {
+				entry.State = LocaleStateDraft
+			}
+
+			for _, note := range unit.Notes {
+				switch note.Category {
+				case "route":
+					entry.Route = note.Value
+				case "type":
+					entry.Type = note.Value
+				case "keys":
+					if note.Value != "" {
+						entry.Keys = strings.Split(note.Value, ",")
+					}
+				}
+			}
+			catalog.Entries = append(catalog.Entries, entry)
+		}
+	}
+	return catalog, nil
+}
+
+// PO export the catalog to gettext PO
+func (catalog *LocaleCatalog) PO() ([]byte, error) {
+	var buf bytes.Buffer
+	source := catalog.Source
+	if source == "" {
+		source = "en"
+	}
+
+	buf.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	buf.WriteString(poQuote(fmt.Sprintf("Language: %s\n", catalog.Target)) + "\n")
+	buf.WriteString(poQuote("MIME-Version: 1.0\n") + "\n")
+	buf.WriteString(poQuote("Content-Type: text/plain; charset=UTF-8\n") + "\n")
+	buf.WriteString(poQuote("Content-Transfer-Encoding: 8bit\n") + "\n")
+	buf.WriteString(poQuote(fmt.Sprintf("X-Source-Language: %s\n", source)) + "\n")
+	if catalog.Template != "" {
+		buf.WriteString(poQuote(fmt.Sprintf("X-Template: %s\n", catalog.Template)) + "\n")
+	}
+
+	for _, entry := range catalog.Entries {
+		buf.WriteString("\n")
+		buf.WriteString(fmt.Sprintf("#. id: %s\n", entry.ID))
+		buf.WriteString(fmt.Sprintf("#. type: %s\n", entry.Type))
+		if len(entry.Keys) > 0 {
+			buf.WriteString(fmt.Sprintf("#. keys: %s\n", strings.Join(entry.Keys, ",")))
+		}
+		buf.WriteString(fmt.Sprintf("#: %s\n", entry.Route))
+		if entry.State == LocaleStateDraft {
+			buf.WriteString("#, fuzzy\n")
+		}
+		buf.WriteString(fmt.Sprintf("msgctxt %s\n", poQuote(entry.Route)))
+		buf.WriteString(fmt.Sprintf("msgid %s\n", poQuote(entry.Source)))
+		buf.WriteString(fmt.Sprintf("msgstr %s\n", poQuote(entry.Target)))
+	}
+	return buf.Bytes(), nil
+}
+
+// ParsePO parse the gettext PO file
+func ParsePO(data []byte) (*LocaleCatalog, error) {
+	catalog := &LocaleCatalog{Entries: []LocaleEntry{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	entry := LocaleEntry{}
+	field := ""
+	values := map[string]string{}
+	fuzzy := false
+	line := 0
+
+	flush := func() error {
+		if _, has := values["msgid"]; !has {
+			entry = LocaleEntry{}
+			values = map[string]string{}
+			return nil
+		}
+
+		// The header
+		if values["msgid"] == "" {
+			for _, header := range strings.Split(values["msgstr"], "\n") {
+				name, value, ok := strings.Cut(header, ":")
+				if !ok {
+					continue
+				}
+				value = strings.TrimSpace(value)
+				switch strings.TrimSpace(name) {
+				case "Language":
+					catalog.Target = value
+				case "X-Source-Language":
+					catalog.Source = value
+				case "X-Template":
+					catalog.Template = value
+				}
+			}
+			entry = LocaleEntry{}
+			values = map[string]string{}
+			fuzzy = false
+			return nil
+		}
+
+		if ctx, has := values["msgctxt"]; has && ctx != "" {
+			entry.Route = ctx
+		}
+		entry.Source = values["msgid"]
+		entry.Target = values["msgstr"]
+		entry.State = LocaleStateInitial
+		if entry.Target != "" {
+			entry.State = LocaleStateTranslated
+			if fuzzy {
+				entry.State = LocaleStateDraft
+			}
+		}
+		if entry.Route == "" {
+			return fmt.Errorf("Parse the PO error: line %d the route (msgctxt) of %q is required", line, entry.Source)
+		}
+		catalog.Entries = append(catalog.Entries, entry)
+		entry = LocaleEntry{}
+		values = map[string]string{}
+		fuzzy = false
+		return nil
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		// The previous entry is completed
+		if _, has := values["msgstr"]; has && (strings.HasPrefix(text, "#") || strings.HasPrefix(text, "msgctxt ") || strings.HasPrefix(text, "msgid ")) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			field = ""
+		}
+
+		switch {
+		case text == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			field = ""
+
+		case strings.HasPrefix(text, "#."):
+			comment := strings.TrimSpace(strings.TrimPrefix(text, "#."))
+			name, value, _ := strings.Cut(comment, ":")
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(name) {
+			case "id":
+				entry.ID = value
+			case "type":
+				entry.Type = value
+			case "keys":
+				if value != "" {
+					entry.Keys = strings.Split(value, ",")
+				}
+			}
+
+		case strings.HasPrefix(text, "#:"):
+			entry.Route = strings.TrimSpace(strings.TrimPrefix(text, "#:"))
+
+		case strings.HasPrefix(text, "#,"):
+			fuzzy = strings.Contains(text, "fuzzy")
+
+		case strings.HasPrefix(text, "#"):
+			continue
+
+		case strings.HasPrefix(text, "\""):
+			if field == "" {
+				return nil, fmt.Errorf("Parse the PO error: line %d unexpected string", line)
+			}
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("Parse the PO error: line %d %s", line, err.Error())
+			}
+			values[field] = values[field] + value
+
+		default:
+			name, raw, ok := strings.Cut(text, " ")
+			if !ok {
+				return nil, fmt.Errorf("Parse the PO error: line %d %s", line, text)
+			}
+
+			value, err := strconv.Unquote(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, fmt.Errorf("Parse the PO error: line %d %s", line, err.Error())
+			}
+			field = name
+			values[field] = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+func poQuote(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t", "\r", "\\r")
+	return "\"" + replacer.Replace(value) + "\""
+}
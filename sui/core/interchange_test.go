@@ -0,0 +1,100 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleEntries(t *testing.T) {
+	translations := []Translation{
+		{Key: "trans_index_0", Message: "Hello", Type: "text"},
+		{Key: "trans_index_1", Message: "World", Type: "attr"},
+		{Key: "trans_index_2", Message: "Hello", Type: "text"},
+		{Key: "trans_index_3", Message: "Submit", Type: "script"},
+		{Key: "trans_index_4", Message: "Cancel", Type: "script"},
+	}
+
+	locale := Locale{
+		Keys:           map[string]string{"trans_index_3": "提交"},
+		Messages:       map[string]string{"Hello": "你好", "World": "World", "Removed": "已删除"},
+		ScriptMessages: map[string]string{"Cancel": "取消"},
+	}
+
+	entries := LocaleEntries("/index", translations, locale)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, []string{"trans_index_0", "trans_index_2"}, entries[0].Keys)
+	assert.Equal(t, "你好", entries[0].Target)
+	assert.Equal(t, LocaleStateTranslated, entries[0].State)
+	assert.Equal(t, LocaleStateInitial, entries[1].State)
+	assert.Equal(t, "提交", entries[2].Target)
+	assert.Equal(t, "取消", entries[3].Target)
+	assert.Equal(t, LocaleStateTranslated, entries[3].State)
+
+	stale := StaleLocaleEntries("/index", translations, locale)
+	assert.Len(t, stale, 1)
+	assert.Equal(t, "Removed", stale[0].Source)
+
+	catalog := LocaleCatalog{Target: "zh-cn", Entries: entries, Stale: stale}
+	report := catalog.Report()
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 3, report.Translated)
+	assert.Len(t, report.Missing, 1)
+	assert.Equal(t, "World", report.Missing[0].Source)
+	assert.Len(t, report.Stale, 1)
+}
+
+func TestLocaleCatalogXLIFF(t *testing.T) {
+	catalog := testLocaleCatalog()
+	raw, err := catalog.Export("xliff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(raw), `urn:oasis:names:tc:xliff:document:2.0`)
+	assert.Contains(t, string(raw), `trgLang="zh-cn"`)
+	assert.Contains(t, string(raw), `subState="yao:draft"`)
+
+	parsed, err := ParseLocaleCatalog("xliff", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "en", parsed.Source)
+	assert.Equal(t, "zh-cn", parsed.Target)
+	assert.Equal(t, catalog.Entries, parsed.Entries)
+}
+
+func TestLocaleCatalogPO(t *testing.T) {
+	catalog := testLocaleCatalog()
+	raw, err := catalog.Export("po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(raw), `msgctxt "/index"`)
+	assert.Contains(t, string(raw), "#, fuzzy")
+
+	parsed, err := ParseLocaleCatalog("po", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "en", parsed.Source)
+	assert.Equal(t, "zh-cn", parsed.Target)
+	assert.Equal(t, "default", parsed.Template)
+	assert.Equal(t, catalog.Entries, parsed.Entries)
+
+	_, err = ParseLocaleCatalog("csv", raw)
+	assert.Error(t, err)
+}
+
+func testLocaleCatalog() LocaleCatalog {
+	return LocaleCatalog{
+		Template: "default",
+		Source:   "en",
+		Target:   "zh-cn",
+		Entries: []LocaleEntry{
+			{ID: "trans_index_0", Route: "/index", Type: "text", Keys: []string{"trans_index_0"}, Source: "Hello \"Yao\"", Target: "你好 \"Yao\"", State: LocaleStateTranslated},
+			{ID: "trans_index_1", Route: "/index", Type: "html", Keys: []string{"trans_index_1", "trans_index_2"}, Source: "Line 1\nLine 2 {{ name }}", State: LocaleStateInitial},
+			{ID: "trans_about_0", Route: "/about", Type: "script", Keys: []string{"trans_about_0"}, Source: "<b>About</b>", Target: "<b>关于</b>", State: LocaleStateTranslated},
+			{ID: "trans_about_1", Route: "/about", Type: "text", Keys: []string{"trans_about_1"}, Source: "Contact", Target: "联系", State: LocaleStateDraft},
+		},
+	}
+}
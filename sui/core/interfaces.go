@@ -69,6 +69,8 @@ type ITemplate interface {
 	ExecAfterBuildScripts() []TemplateScirptResult
 
 	Trans(option *BuildOption) ([]string, error)
+	LocaleCatalog(name string, option *BuildOption) (*LocaleCatalog, error)
+	ImportLocaleCatalog(catalog *LocaleCatalog) ([]string, error)
 }
 
 // IPage is the interface for the page
@@ -100,6 +102,12 @@ type IPage interface {
 	BuildAsComponent(globalCtx *GlobalBuildContext, option *BuildOption) ([]string, error)
 
 	Trans(globalCtx *GlobalBuildContext, option *BuildOption) ([]string, error)
+
+	History() ([]PageVersion, error)
+	Version(id string) (*PageVersion, error)
+	Diff(from string, to string) ([]PageFileDiff, error)
+	Rollback(version string, author string) (*PageVersion, error)
+	Publish(version string, author string, option *BuildOption) (*PageVersion, []string, error)
 }
 
 // IBlock is the interface for the block
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/llm"
+)
+
+// MachineTranslateBatchSize the default number of messages sent in one request
+const MachineTranslateBatchSize = 20
+
+var machineTokenRe = regexp.MustCompile(`\{\{[\s\S]*?\}\}|<[^<>]+>|&[a-zA-Z0-9#]+;`)
+
+// TranslatorAI the AI interface used by the machine translator
+type TranslatorAI interface {
+	ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception)
+	GetContent(response interface{}) (string, *exception.Exception)
+}
+
+// MachineTranslator translate the locale messages through an AI connector
+type MachineTranslator struct {
+	AI        TranslatorAI
+	BatchSize int
+	Source    string
+}
+
+// NewMachineTranslator create a new machine translator by the connector id
+func NewMachineTranslator(connector string) (*MachineTranslator, error) {
+	ai, err := llm.New(connector)
+	if err != nil {
+		return nil, err
+	}
+	return &MachineTranslator{AI: ai, BatchSize: MachineTranslateBatchSize}, nil
+}
+
+// TranslateLocale translate the untranslated messages of the locale, the results are saved as drafts.
+// The missing messages are not in the locale file before merging the translations. see Locale.Missing
+// A message is sent if it is missing, its target is empty or it is still a draft.
+// The human-edited messages are never overwritten.
+func (translator *MachineTranslator) TranslateLocale(locale *Locale, missing []string) ([]string, error) {
+
+	if locale.Messages == nil {
+		locale.Messages = map[string]string{}
+	}
+
+	if locale.Drafts == nil {
+		locale.Drafts = map[string]string{}
+	}
+
+	// Collect the untranslated messages
+	pending := map[string]bool{}
+	for _, source := range missing {
+		pending[source] = true
+	}
+
+	sources := []string{}
+	for source := range locale.Messages {
+		sources = append(sources, source)
+	}
+	for source := range locale.ScriptMessages {
+		sources = append(sources, source)
+	}
+
+	for _, source := range sources {
+		if locale.target(source) == "" || locale.isDraft(source) {
+			pending[source] = true
+		}
+	}
+
+	if len(pending) == 0 {
+		return []string{}, nil
+	}
+
+	messages := []string{}
+	for source := range pending {
+		messages = append(messages, source)
+	}
+	sort.Strings(messages)
+
+	results, warnings, err := translator.Translate(locale.Name, messages)
+	if err != nil {
+		return warnings, err
+	}
+
+	for source, target := range results {
+		locale.Messages[source] = target
+		locale.Drafts[source] = target
+	}
+	return warnings, nil
+}
+
+// Missing the messages of the translations not in the locale yet, call it before merging the translations
+func (locale *Locale) Missing(translations []Translation, prefix string) []string {
+	var reg *regexp.Regexp = nil
+	if prefix != "" {
+		reg = regexp.MustCompile(fmt.Sprintf(`^%s_([0-9]+)$`, prefix))
+	}
+
+	missing := []string{}
+	for _, t := range translations {
+		if t.Message == "" || (reg != nil && !reg.MatchString(t.Key)) {
+			continue
+		}
+
+		if _, has := locale.Messages[t.Message]; has {
+			continue
+		}
+
+		if _, has := locale.ScriptMessages[t.Message]; has && t.Type == "script" {
+			continue
+		}
+		missing = append(missing, t.Message)
+	}
+	return missing
+}
+
+// target the translation of the message, the script messages are used if it is not in the messages
+func (locale *Locale) target(source string) string {
+	if target, has := locale.Messages[source]; has {
+		return target
+	}
+	return locale.ScriptMessages[source]
+}
+
+// isDraft the target of the message is the machine translation not reviewed yet
+func (locale *Locale) isDraft(source string) bool {
+	draft, has := locale.Drafts[source]
+	return has && draft == locale.target(source)
+}
+
+// Translate translate the messages to the target locale in batches, the placeholders and HTML tags are kept
+func (translator *MachineTranslator) Translate(target string, messages []string) (map[string]string, []string, error) {
+	results := map[string]string{}
+	warnings := []string{}
+
+	size := translator.BatchSize
+	if size <= 0 {
+		size = MachineTranslateBatchSize
+	}
+
+	for start := 0; start < len(messages); start += size {
+		end := start + size
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		batch := messages[start:end]
+		masked := make([]string, len(batch))
+		tokens := make([][]string, len(batch))
+		for i, message := range batch {
+			masked[i], tokens[i] = maskMachineTokens(message)
+		}
+
+		translated, err := translator.request(target, masked)
+		if err != nil {
+			return results, warnings, err
+		}
+
+		if len(translated) != len(batch) {
+			warnings = append(warnings, fmt.Sprintf("[%s] The translator returns %d messages, %d expected, the batch is ignored", target, len(translated), len(batch)))
+			continue
+		}
+
+		for i, message := range batch {
+			text, err := unmaskMachineTokens(translated[i], tokens[i])
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("[%s] %q %s, ignored", target, message, err.Error()))
+				continue
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			results[message] = text
+		}
+	}
+
+	return results, warnings, nil
+}
+
+func (translator *MachineTranslator) request(target string, messages []string) ([]string, error) {
+	input, err := jsoniter.MarshalToString(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	source := "the detected source language"
+	if translator.Source != "" {
+		source = translator.Source
+	}
+
+	prompt := fmt.Sprintf(
+		"You are a professional software localization translator. "+
+			"Translate each string of the JSON array from %s to the locale %s. "+
+			"Keep the tokens like ⟦0⟧ unchanged, they are placeholders and HTML tags. "+
+			"Keep the ICU MessageFormat syntax like {count, plural, one {# item} other {# items}}, translate only the text of the options. "+
+			"Reply with a JSON array of the translated strings only, in the same order and the same length, without any explanation.",
+		source, target,
+	)
+
+	res, ex := translator.AI.ChatCompletions([]map[string]interface{}{
+		{"role": "system", "content": prompt},
+		{"role": "user", "content": input},
+	}, map[string]interface{}{"temperature": 0}, nil)
+	if ex != nil {
+		return nil, fmt.Errorf("Machine translate %s error: %s", target, ex.Message)
+	}
+
+	content, ex := translator.AI.GetContent(res)
+	if ex != nil {
+		return nil, fmt.Errorf("Machine translate %s error: %s", target, ex.Message)
+	}
+
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	translated := []string{}
+	err = jsoniter.UnmarshalFromString(strings.TrimSpace(content), &translated)
+	if err != nil {
+		return nil, fmt.Errorf("Machine translate %s error: the response is not a JSON array of strings %s", target, err.Error())
+	}
+	return translated, nil
+}
+
+// maskMachineTokens replace the placeholders and HTML tags with the tokens like ⟦0⟧
+func maskMachineTokens(message string) (string, []string) {
+	tokens := []string{}
+	masked := machineTokenRe.ReplaceAllStringFunc(message, func(token string) string {
+		tokens = append(tokens, token)
+		return fmt.Sprintf("⟦%d⟧", len(tokens)-1)
+	})
+	return masked, tokens
+}
+
+// unmaskMachineTokens restore the placeholders and HTML tags, every token should be kept once
+func unmaskMachineTokens(message string, tokens []string) (string, error) {
+	for i, token := range tokens {
+		mark := fmt.Sprintf("⟦%d⟧", i)
+		if strings.Count(message, mark) != 1 {
+			return "", fmt.Errorf("the placeholder %s is lost", token)
+		}
+		message = strings.Replace(message, mark, token, 1)
+	}
+
+	if strings.Contains(message, "⟦") {
+		return "", fmt.Errorf("unknown placeholders")
+	}
+	return message, nil
+}
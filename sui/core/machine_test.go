@@ -0,0 +1,115 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/exception"
+)
+
+type stubTranslatorAI struct {
+	requests int
+	reply    func(messages []string) []string
+}
+
+func (ai *stubTranslatorAI) ChatCompletions(messages []map[string]interface{}, option map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	ai.requests++
+	input := []string{}
+	err := jsoniter.UnmarshalFromString(messages[len(messages)-1]["content"].(string), &input)
+	if err != nil {
+		return nil, exception.New(err.Error(), 400)
+	}
+
+	content, _ := jsoniter.MarshalToString(ai.reply(input))
+	return content, nil
+}
+
+func (ai *stubTranslatorAI) GetContent(response interface{}) (string, *exception.Exception) {
+	return "```json\n" + response.(string) + "\n```", nil
+}
+
+func TestMachineTranslatorTranslateLocale(t *testing.T) {
+	ai := &stubTranslatorAI{reply: func(messages []string) []string {
+		res := []string{}
+		for _, message := range messages {
+			res = append(res, "ZH:"+message)
+		}
+		return res
+	}}
+
+	translator := &MachineTranslator{AI: ai, BatchSize: 2}
+	locale := &Locale{
+		Name: "zh-cn",
+		Messages: map[string]string{
+			"OK":                   "OK",
+			"<b>Bold</b> &amp; Co": "",
+			"Edited":               "人工翻译",
+			"Draft":                "ZH:old",
+			"Reviewed":             "已审阅",
+		},
+		ScriptMessages: map[string]string{"Submit": "提交"},
+		Drafts:         map[string]string{"Draft": "ZH:old", "Reviewed": "ZH:Reviewed"},
+	}
+
+	missing := locale.Missing([]Translation{
+		{Key: "trans_index_0", Message: "OK", Type: "text"},
+		{Key: "trans_index_1", Message: "Hello {{ name }}", Type: "text"},
+		{Key: "trans_index_2", Message: "Submit", Type: "script"},
+		{Key: "trans_index_3", Message: "Cancel", Type: "script"},
+		{Key: "trans_other_0", Message: "Other", Type: "text"},
+	}, "trans_index")
+	assert.Equal(t, []string{"Hello {{ name }}", "Cancel"}, missing)
+
+	warnings, err := translator.TranslateLocale(locale, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The missing, the empty and the draft messages are sent
+	assert.Len(t, warnings, 0)
+	assert.Equal(t, 2, ai.requests)
+	assert.Equal(t, "ZH:Hello {{ name }}", locale.Messages["Hello {{ name }}"])
+	assert.Equal(t, "ZH:<b>Bold</b> &amp; Co", locale.Messages["<b>Bold</b> &amp; Co"])
+	assert.Equal(t, "ZH:Cancel", locale.Messages["Cancel"])
+	assert.Equal(t, "ZH:Draft", locale.Messages["Draft"])
+	assert.Equal(t, "ZH:Draft", locale.Drafts["Draft"])
+
+	// The human-edited messages are kept, even if the target is the same as the source
+	assert.Equal(t, "OK", locale.Messages["OK"])
+	assert.Equal(t, "人工翻译", locale.Messages["Edited"])
+	assert.Equal(t, "已审阅", locale.Messages["Reviewed"])
+	assert.NotContains(t, locale.Messages, "Submit")
+	assert.NotContains(t, locale.Drafts, "Edited")
+	assert.NotContains(t, locale.Drafts, "OK")
+
+	// The reviewed messages are not sent again
+	locale.Drafts = map[string]string{}
+	ai.requests = 0
+	_, err = translator.TranslateLocale(locale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, ai.requests)
+}
+
+func TestMachineTranslatorPlaceholders(t *testing.T) {
+	ai := &stubTranslatorAI{reply: func(messages []string) []string {
+		res := []string{}
+		for _, message := range messages {
+			res = append(res, strings.ReplaceAll(message, "⟦0⟧", ""))
+		}
+		return res
+	}}
+
+	translator := &MachineTranslator{AI: ai}
+	results, warnings, err := translator.Translate("zh-cn", []string{"Hello {{ name }}", "Plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, warnings, 1)
+	assert.NotContains(t, results, "Hello {{ name }}")
+	assert.Equal(t, "Plain", results["Plain"])
+}
@@ -150,7 +150,14 @@ func (parser *TemplateParser) Render(html string) (string, error) {
 			data = "{}"
 		}
 
-		head.AppendHtml(headInjectionScript(data))
+		// The locale name and timezone for the ICU messages
+		localeName, timezone := `""`, `""`
+		if parser.locale != nil {
+			localeName, _ = jsoniter.MarshalToString(parser.locale.Name)
+			timezone, _ = jsoniter.MarshalToString(parser.locale.Timezone)
+		}
+
+		head.AppendHtml(headInjectionScript(data, localeName, timezone))
 		parser.addScripts(head, parser.filterScripts("head", parser.scripts))
 		parser.addStyles(head, parser.styles)
 
@@ -461,18 +468,18 @@ func (parser *TemplateParser) escape(value string) string {
 func (parser *TemplateParser) transNode(key string, message string) string {
 
 	if parser.locale == nil {
-		return message
+		return parser.icu(message)
 	}
 
 	if lcMessage, has := parser.locale.Keys[key]; has && lcMessage != message {
-		return lcMessage
+		return parser.icu(lcMessage)
 	}
 
 	if lcMessage, has := parser.locale.Messages[message]; has {
-		return lcMessage
+		return parser.icu(lcMessage)
 	}
 
-	return message
+	return parser.icu(message)
 }
 
 func (parser *TemplateParser) transText(content string, keys []string) string {
@@ -487,8 +494,10 @@ func (parser *TemplateParser) transText(content string, keys []string) string {
 			continue
 		}
 
+		quote := "'"
 		transMatches := transStmtReSingle.FindAllStringSubmatch(text, -1)
 		if len(transMatches) == 0 {
+			quote = "\""
 			transMatches = transStmtReDouble.FindAllStringSubmatch(text, -1)
 		}
 		if len(transMatches) > len(keys) {
@@ -499,28 +508,44 @@ func (parser *TemplateParser) transText(content string, keys []string) string {
 			message := strings.TrimSpace(transMatch[1])
 
 			if parser.locale == nil {
-				newContent = strings.Replace(newContent, "::"+message, message, 1)
+				newContent = strings.Replace(newContent, "::"+message, parser.icuLiteral(message, quote), 1)
 				continue
 			}
 
 			key := keys[i]
 			if lcMessage, has := parser.locale.Keys[key]; has && lcMessage != message {
-				newContent = strings.Replace(newContent, "::"+message, lcMessage, 1)
+				newContent = strings.Replace(newContent, "::"+message, parser.icuLiteral(lcMessage, quote), 1)
 
 				continue
 			}
 
 			if lcMessage, has := parser.locale.Messages[message]; has {
-				newContent = strings.Replace(newContent, "::"+message, lcMessage, 1)
+				newContent = strings.Replace(newContent, "::"+message, parser.icuLiteral(lcMessage, quote), 1)
 				continue
 			}
 
-			newContent = strings.Replace(newContent, "::"+message, message, 1)
+			newContent = strings.Replace(newContent, "::"+message, parser.icuLiteral(message, quote), 1)
 		}
 	}
 	return newContent
 }
 
+// icu format the ICU message with the page data, e.g. {count, plural, one {# item} other {# items} }
+func (parser *TemplateParser) icu(message string) string {
+	if !HasICU(message) {
+		return message
+	}
+	return parser.locale.FmtICU(message, parser.data)
+}
+
+// icuLiteral format the ICU message in a string literal of the statement
+func (parser *TemplateParser) icuLiteral(message string, quote string) string {
+	if !HasICU(message) {
+		return message
+	}
+	return strings.ReplaceAll(parser.icu(message), quote, "\\"+quote)
+}
+
 // Remove the tag and replace it with the children
 func (parser *TemplateParser) removeWrapper(sel *goquery.Selection) {
 	children := sel.Children()
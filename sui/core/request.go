@@ -308,6 +308,10 @@ func (r *Request) call(p interface{}) (interface{}, error) {
 		process.WithSID(r.Sid)
 	}
 
+	if r.Context != nil {
+		process.WithContext(r.Context)
+	}
+
 	v, err := process.Exec()
 	if err != nil {
 		log.Error("[Request] process %s %s", processName, err.Error())
@@ -11,6 +11,7 @@ import (
 	"github.com/yaoapp/gou/application"
 	v8 "github.com/yaoapp/gou/runtime/v8"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
+	"github.com/yaoapp/yao/runtime"
 	"github.com/yaoapp/yao/share"
 )
 
@@ -97,6 +98,7 @@ func (script *Script) Call(r *Request, method string, args ...any) (interface{},
 		return nil, err
 	}
 	defer ctx.Close()
+	defer runtime.Acquire()()
 	if args == nil {
 		args = []any{}
 	}
@@ -119,6 +121,7 @@ func (script *Script) BeforeRender(r *Request, props map[string]interface{}) (Da
 		return nil, err
 	}
 	defer ctx.Close()
+	defer runtime.Acquire()()
 
 	if !ctx.Global().Has("BeforeRender") {
 		return nil, nil
@@ -160,6 +163,7 @@ func (script *Script) Constants() (map[string]interface{}, error) {
 		return nil, err
 	}
 	defer ctx.Close()
+	defer runtime.Acquire()()
 
 	global := ctx.Global()
 	if global == nil {
@@ -197,6 +201,7 @@ func (script *Script) Helpers() ([]string, error) {
 		return nil, err
 	}
 	defer ctx.Close()
+	defer runtime.Acquire()()
 
 	global := ctx.Global()
 	if global == nil {
@@ -192,10 +192,20 @@ func (page *Page) translateScript(code string) ([]Translation, []string, error)
 	}
 	matches := transFuncRe.FindAllStringSubmatch(code, -1)
 	for _, match := range matches {
+
+		// The message is quoted by ", ' or `, the ICU arguments are kept as they are
+		message := match[1]
+		if message == "" {
+			message = match[2]
+		}
+		if message == "" {
+			message = match[3]
+		}
+
 		key := TranslationKey(page.Route, page.transCtx.sequence)
 		translations = append(translations, Translation{
 			Key:     key,
-			Message: match[1],
+			Message: message,
 			Type:    "script",
 		})
 		page.transCtx.sequence = page.transCtx.sequence + 1
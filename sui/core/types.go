@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"net/url"
 	"regexp"
 
@@ -126,6 +127,7 @@ type Locale struct {
 	Keys           map[string]string `json:"keys,omitempty" yaml:"keys,omitempty"`
 	Messages       map[string]string `json:"messages,omitempty" yaml:"messages,omitempty"`
 	ScriptMessages map[string]string `json:"script_messages,omitempty" yaml:"script_messages,omitempty"`
+	Drafts         map[string]string `json:"drafts,omitempty" yaml:"drafts,omitempty"` // The machine translations to be reviewed
 	Direction      string            `json:"direction,omitempty" yaml:"direction,omitempty"`
 	Timezone       string            `json:"timezone,omitempty" yaml:"timezone,omitempty"`
 }
@@ -274,6 +276,7 @@ type BuildOption struct {
 	StyleMinify     bool                   `json:"styleminify,omitempty"`
 	ExecScripts     bool                   `json:"exec_scripts,omitempty"`
 	Locales         []string               `json:"locales,omitempty"`
+	Translator      *MachineTranslator     `json:"-"`
 }
 
 // Request is the struct for the request
@@ -291,6 +294,7 @@ type Request struct {
 	Theme     any                    `json:"theme,omitempty"`
 	Locale    any                    `json:"locale,omitempty"`
 	Script    *Script                `json:"-"`
+	Context   context.Context        `json:"-"` // The context of the data processes, it carries the trace span
 }
 
 // RequestSource is the struct for the request
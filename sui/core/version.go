@@ -0,0 +1,264 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The status of the page version
+const (
+	PageVersionDraft     = "draft"
+	PageVersionPublished = "published"
+	PageVersionArchived  = "archived"
+)
+
+// diffContext the number of the context lines of the patch
+const diffContext = 3
+
+// diffMaxCells the max size of the LCS table, the larger files are diffed as a whole replacement
+const diffMaxCells = 4000000
+
+// PageVersion the version of the page source
+type PageVersion struct {
+	ID      string            `json:"id"`
+	Route   string            `json:"route"`
+	Author  string            `json:"author,omitempty"`
+	Time    int64             `json:"time"` // unix milliseconds
+	Message string            `json:"message,omitempty"`
+	Parent  string            `json:"parent,omitempty"`
+	Status  string            `json:"status"`
+	Added   int               `json:"added"`
+	Removed int               `json:"removed"`
+	Files   map[string]string `json:"files,omitempty"`
+}
+
+// PageFileDiff the diff of a page source file
+type PageFileDiff struct {
+	File    string `json:"file"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Patch   string `json:"patch"`
+}
+
+type diffLine struct {
+	op   byte // ' ', '-', '+'
+	text string
+}
+
+// DiffFiles compare the page source files, the unchanged files are ignored
+func DiffFiles(from, to map[string]string) []PageFileDiff {
+	names := map[string]bool{}
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+
+	files := []string{}
+	for name := range names {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	diffs := []PageFileDiff{}
+	for _, name := range files {
+		if from[name] == to[name] {
+			continue
+		}
+		diffs = append(diffs, DiffText(name, from[name], to[name]))
+	}
+	return diffs
+}
+
+// DiffText compare the text line by line, returns the unified patch
+func DiffText(name, from, to string) PageFileDiff {
+	diff := PageFileDiff{File: name}
+	lines := diffLines(splitLines(from), splitLines(to))
+	for _, line := range lines {
+		switch line.op {
+		case '+':
+			diff.Added++
+		case '-':
+			diff.Removed++
+		}
+	}
+
+	if diff.Added == 0 && diff.Removed == 0 {
+		return diff
+	}
+
+	diff.Patch = fmt.Sprintf("--- a/%s\n+++ b/%s\n%s", name, name, diffHunks(lines))
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines the longest common subsequence line diff
+func diffLines(a, b []string) []diffLine {
+
+	// Trim the common prefix and suffix
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := []diffLine{}
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+
+	x := a[prefix : len(a)-suffix]
+	y := b[prefix : len(b)-suffix]
+	if len(x)*len(y) > diffMaxCells {
+		for _, text := range x {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range y {
+			lines = append(lines, diffLine{'+', text})
+		}
+
+	} else {
+		lcs := make([][]int, len(x)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(y)+1)
+		}
+
+		for i := len(x) - 1; i >= 0; i-- {
+			for j := len(y) - 1; j >= 0; j-- {
+				if x[i] == y[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(x) && j < len(y) {
+			if x[i] == y[j] {
+				lines = append(lines, diffLine{' ', x[i]})
+				i++
+				j++
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lines = append(lines, diffLine{'-', x[i]})
+				i++
+			} else {
+				lines = append(lines, diffLine{'+', y[j]})
+				j++
+			}
+		}
+
+		for ; i < len(x); i++ {
+			lines = append(lines, diffLine{'-', x[i]})
+		}
+
+		for ; j < len(y); j++ {
+			lines = append(lines, diffLine{'+', y[j]})
+		}
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+// diffHunks format the diff lines as the unified diff hunks
+func diffHunks(lines []diffLine) string {
+	var sb strings.Builder
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(lines); {
+
+		// Find the next change
+		change := start
+		for change < len(lines) && lines[change].op == ' ' {
+			change++
+		}
+
+		if change == len(lines) {
+			break
+		}
+
+		// The hunk begins with the context lines before the change
+		begin := change - diffContext
+		if begin < start {
+			begin = start
+		}
+
+		for k := start; k < begin; k++ {
+			oldLine++
+			newLine++
+		}
+
+		// The hunk ends when the unchanged lines are more than twice the context
+		end := change
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+
+			if next == len(lines) || next-end > diffContext*2 {
+				end = end + diffContext
+				if end > next {
+					end = next
+				}
+				break
+			}
+			end = next
+		}
+
+		oldCount, newCount := 0, 0
+		for _, line := range lines[begin:end] {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount)))
+		for _, line := range lines[begin:end] {
+			sb.WriteByte(line.op)
+			sb.WriteString(line.text)
+			sb.WriteByte('\n')
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		start = end
+	}
+
+	return sb.String()
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffText(t *testing.T) {
+	from := "<div>\n  <h1>Hello</h1>\n  <p>1</p>\n  <p>2</p>\n  <p>3</p>\n  <p>4</p>\n  <p>5</p>\n  <p>6</p>\n  <p>7</p>\n  <p>8</p>\n</div>\n"
+	to := "<div>\n  <h1>Hello World</h1>\n  <p>1</p>\n  <p>2</p>\n  <p>3</p>\n  <p>4</p>\n  <p>5</p>\n  <p>6</p>\n  <p>7</p>\n  <p>8</p>\n  <p>9</p>\n</div>\n"
+
+	diff := DiffText("index.html", from, to)
+	assert.Equal(t, 2, diff.Added)
+	assert.Equal(t, 1, diff.Removed)
+	assert.Equal(t, "--- a/index.html\n+++ b/index.html\n"+
+		"@@ -1,5 +1,5 @@\n <div>\n-  <h1>Hello</h1>\n+  <h1>Hello World</h1>\n   <p>1</p>\n   <p>2</p>\n   <p>3</p>\n"+
+		"@@ -8,4 +8,5 @@\n   <p>6</p>\n   <p>7</p>\n   <p>8</p>\n+  <p>9</p>\n </div>\n", diff.Patch)
+
+	diff = DiffText("index.css", "", "a\nb\n")
+	assert.Equal(t, "--- a/index.css\n+++ b/index.css\n@@ -0,0 +1,2 @@\n+a\n+b\n", diff.Patch)
+
+	diff = DiffText("index.css", "a\n", "a\n")
+	assert.Equal(t, "", diff.Patch)
+}
+
+func TestDiffFiles(t *testing.T) {
+	diffs := DiffFiles(
+		map[string]string{"index.html": "<div></div>", "index.css": "a{}", "index.ts": "old"},
+		map[string]string{"index.html": "<div></div>", "index.css": "b{}", "index.json": "{}"},
+	)
+	assert.Len(t, diffs, 3)
+	assert.Equal(t, "index.css", diffs[0].File)
+	assert.Equal(t, "index.json", diffs[1].File)
+	assert.Equal(t, 1, diffs[1].Added)
+	assert.Equal(t, "index.ts", diffs[2].File)
+	assert.Equal(t, 1, diffs[2].Removed)
+}
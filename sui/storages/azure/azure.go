@@ -2,57 +2,371 @@ package azure
 
 import (
 	"fmt"
-	"net/url"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/fs/system"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/sui/core"
+	"github.com/yaoapp/yao/sui/storages/local"
 )
 
 // Azure is the struct for the azure sui
+// The templates are stored in the Azure Blob container and cached in the local file system,
+// the local sui works on the cache, the changes are written through to the container.
 type Azure struct {
-	url url.URL
-	*core.DSL
+	storage  Storage
+	prefix   string            // the key prefix of the sui in the container
+	cache    string            // the cache root
+	manifest map[string]string // the cached objects key => ETag
+	mutex    sync.Mutex
+	*local.Local
 }
 
-// new create a new azure sui
-func new() (*Azure, error) {
-	return nil, fmt.Errorf("Azure does not support yet")
+// Template is the struct for the azure sui template
+type Template struct {
+	azure *Azure
+	*local.Template
 }
 
 // New create a new azure sui
+//
+//	"storage": {
+//		"driver": "azure",
+//		"option": {
+//			"host": "http://127.0.0.1:10000/devstoreaccount1", // optional, https://<account>.blob.core.windows.net by default
+//			"account": "$ENV.AZURE_STORAGE_ACCOUNT",
+//			"key": "$ENV.AZURE_STORAGE_KEY", // or "sas"
+//			"container": "sui",
+//			"prefix": "website", // optional
+//			"cache": "/data/sui/cache" // optional, <data root>/.sui/<id> by default
+//		}
+//	}
 func New(dsl *core.DSL) (*Azure, error) {
 
-	// if dsl.Storage.Option == nil {
-	// 	return nil, fmt.Errorf("option.host is required")
-	// }
+	if dsl.Storage.Option == nil {
+		return nil, fmt.Errorf("option.account is required")
+	}
 
-	// if dsl.Storage.Option["host"] == nil {
-	// 	return nil, fmt.Errorf("option.host is required")
-	// }
+	blob, err := NewBlob(
+		option(dsl, "host"),
+		option(dsl, "account"),
+		option(dsl, "key"),
+		option(dsl, "sas"),
+		option(dsl, "container"),
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	// host, ok := dsl.Storage.Option["host"].(string)
-	// if !ok {
-	// 	return nil, fmt.Errorf("option.host %s is not a valid string", host)
-	// }
+	if blob.Key != nil {
+		err = blob.CreateContainer()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// u, err := url.Parse(host)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("option.host %s is not a valid url", host)
-	// }
+	return NewWithStorage(dsl, blob)
+}
+
+// NewWithStorage create a new remote sui with the given object storage
+func NewWithStorage(dsl *core.DSL, storage Storage) (*Azure, error) {
+
+	cache := option(dsl, "cache")
+	if cache == "" {
+		cache = filepath.Join(config.Conf.DataRoot, ".sui", dsl.ID)
+	}
+
+	cache, err := filepath.Abs(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Join(cache, "templates"), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Trim(option(dsl, "prefix"), "/")
+	if prefix != "" {
+		prefix = prefix + "/"
+	}
+
+	azure := &Azure{
+		storage:  storage,
+		prefix:   prefix,
+		cache:    cache,
+		manifest: map[string]string{},
+	}
+
+	cacheFS := &FileSystem{FileSystem: system.New(filepath.Join(cache, "templates")), azure: azure}
+	azure.Local, err = local.NewWithFS(dsl, cacheFS, "/")
+	if err != nil {
+		return nil, err
+	}
 
-	return new()
+	azure.loadManifest()
+	err = azure.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	return azure, nil
 }
 
 // GetTemplates get the templates
 func (azure *Azure) GetTemplates() ([]core.ITemplate, error) {
-	return nil, nil
+
+	err := azure.Sync()
+	if err != nil {
+		log.Error("[SUI] Azure sync error: %s, use the cache", err.Error())
+	}
+
+	templates, err := azure.Local.GetTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tmpl := range templates {
+		templates[i] = &Template{azure: azure, Template: tmpl.(*local.Template)}
+	}
+	return templates, nil
 }
 
 // GetTemplate get the template
 func (azure *Azure) GetTemplate(name string) (core.ITemplate, error) {
-	return nil, nil
+	tmpl, err := azure.Local.GetTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{azure: azure, Template: tmpl.(*local.Template)}, nil
 }
 
-// UploadTemplate upload the template
+// UploadTemplate upload the template, the src is a directory of the local file system
 func (azure *Azure) UploadTemplate(src string, dst string) (core.ITemplate, error) {
-	return nil, nil
+
+	root := filepath.Join(azure.cache, "templates", dst)
+	err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(root, name)
+		err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(target, content, 0644)
+		if err != nil {
+			return err
+		}
+
+		return azure.upload(path.Join(dst, filepath.ToSlash(name)))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return azure.GetTemplate(dst)
+}
+
+// Sync download the changed templates from the container to the cache, the removed objects are removed from the cache.
+// The files which are not in the container and never synced are kept.
+func (azure *Azure) Sync() error {
+
+	azure.mutex.Lock()
+	defer azure.mutex.Unlock()
+
+	prefix := azure.prefix + "templates/"
+	objects, err := azure.storage.List(prefix)
+	if err != nil {
+		return err
+	}
+
+	remote := map[string]bool{}
+	for _, object := range objects {
+		remote[object.Key] = true
+		file := azure.cacheFile(strings.TrimPrefix(object.Key, prefix))
+		if etag, has := azure.manifest[object.Key]; has && etag == object.ETag {
+			if _, err := os.Stat(file); err == nil {
+				continue
+			}
+		}
+
+		content, err := azure.storage.Get(object.Key)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(file, content, 0644)
+		if err != nil {
+			return err
+		}
+		azure.manifest[object.Key] = object.ETag
+	}
+
+	for key := range azure.manifest {
+		if remote[key] {
+			continue
+		}
+		os.Remove(azure.cacheFile(strings.TrimPrefix(key, prefix)))
+		delete(azure.manifest, key)
+	}
+
+	return azure.saveManifest()
+}
+
+// upload upload the cache file to the container, the name is the path of the file in the cache templates root
+func (azure *Azure) upload(name string) error {
+	content, err := os.ReadFile(azure.cacheFile(name))
+	if err != nil {
+		return err
+	}
+
+	key := azure.key(name)
+	etag, err := azure.storage.Put(key, content, mime.TypeByExtension(filepath.Ext(name)))
+	if err != nil {
+		return err
+	}
+
+	azure.mutex.Lock()
+	defer azure.mutex.Unlock()
+	azure.manifest[key] = etag
+	return azure.saveManifest()
+}
+
+// uploadAll upload the file or all the files of the directory to the container
+func (azure *Azure) uploadAll(name string) error {
+	root := azure.cacheFile(name)
+	return filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(filepath.Join(azure.cache, "templates"), file)
+		if err != nil {
+			return err
+		}
+		return azure.upload(filepath.ToSlash(rel))
+	})
+}
+
+// remove remove the object or all the objects with the prefix from the container
+func (azure *Azure) remove(name string) error {
+	key := azure.key(name)
+	objects, err := azure.storage.List(key)
+	if err != nil {
+		return err
+	}
+
+	azure.mutex.Lock()
+	defer azure.mutex.Unlock()
+	for _, object := range objects {
+		if object.Key != key && !strings.HasPrefix(object.Key, key+"/") {
+			continue
+		}
+
+		err := azure.storage.Delete(object.Key)
+		if err != nil {
+			return err
+		}
+		delete(azure.manifest, object.Key)
+	}
+	return azure.saveManifest()
+}
+
+// publish upload the build output of the public root to the container
+func (azure *Azure) publish(root string) error {
+	public := filepath.Join(application.App.Root(), "public")
+	dir := filepath.Join(public, root)
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(public, file)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		_, err = azure.storage.Put(azure.prefix+"public/"+filepath.ToSlash(rel), content, mime.TypeByExtension(filepath.Ext(file)))
+		return err
+	})
+}
+
+func (azure *Azure) key(name string) string {
+	return azure.prefix + "templates/" + strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+}
+
+func (azure *Azure) cacheFile(name string) string {
+	return filepath.Join(azure.cache, "templates", filepath.FromSlash(name))
+}
+
+func (azure *Azure) loadManifest() {
+	content, err := os.ReadFile(filepath.Join(azure.cache, "manifest.json"))
+	if err != nil {
+		return
+	}
+
+	err = jsoniter.Unmarshal(content, &azure.manifest)
+	if err != nil {
+		log.Error("[SUI] Azure manifest %s error: %s", azure.cache, err.Error())
+		azure.manifest = map[string]string{}
+	}
+}
+
+func (azure *Azure) saveManifest() error {
+	content, err := jsoniter.Marshal(azure.manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(azure.cache, "manifest.json"), content, 0644)
+}
+
+func option(dsl *core.DSL, name string) string {
+	if dsl.Storage == nil || dsl.Storage.Option == nil {
+		return ""
+	}
+
+	value, ok := dsl.Storage.Option[name].(string)
+	if !ok {
+		return ""
+	}
+	return value
 }
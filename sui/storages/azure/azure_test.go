@@ -0,0 +1,191 @@
+package azure
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/sui/core"
+)
+
+// The Azurite well-known development account
+// docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0
+// YAO_TEST_AZURITE=http://127.0.0.1:10000/devstoreaccount1
+const azuriteAccount = "devstoreaccount1"
+const azuriteKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+func TestAzureSync(t *testing.T) {
+	storage := newMemoryStorage()
+	storage.Put("site/templates/demo/template.json", []byte(`{"name":"Demo"}`), "application/json")
+	storage.Put("site/templates/demo/index/index.html", []byte(`<div>Hello</div>`), "text/html")
+
+	azure := prepare(t, storage)
+	tmpls, err := azure.GetTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, tmpls, 1)
+	assert.Equal(t, "demo", tmpls[0].(*Template).ID)
+	assert.Equal(t, "Demo", tmpls[0].(*Template).Name)
+	assert.True(t, tmpls[0].PageExist("/index"))
+
+	// The changed objects are downloaded again, the removed objects are removed from the cache
+	storage.Put("site/templates/demo/index/index.html", []byte(`<div>World</div>`), "text/html")
+	storage.Delete("site/templates/demo/template.json")
+	err = azure.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(azure.cacheFile("demo/index/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<div>World</div>", string(content))
+	assert.NoFileExists(t, azure.cacheFile("demo/template.json"))
+}
+
+func TestAzureWriteThrough(t *testing.T) {
+	storage := newMemoryStorage()
+	storage.Put("site/templates/demo/index/index.html", []byte(`<div>Hello</div>`), "text/html")
+
+	azure := prepare(t, storage)
+	tmpl, err := azure.GetTemplate("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := tmpl.AssetUpload(strings.NewReader("PNG"), "logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "site/templates/demo/__assets/" + strings.TrimPrefix(file, "/")
+	assert.Equal(t, "PNG", string(storage.objects[key]))
+	assert.Equal(t, "image/png", storage.types[key])
+
+	res, err := tmpl.MediaSearch(url.Values{}, 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, res.Total)
+
+	err = tmpl.RemovePage("/index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, storage.objects, "site/templates/demo/index/index.html")
+
+	// A new instance with another cache gets the uploaded asset
+	another := prepare(t, storage)
+	assert.FileExists(t, another.cacheFile("demo/__assets/"+strings.TrimPrefix(file, "/")))
+}
+
+func TestBlob(t *testing.T) {
+	host := os.Getenv("YAO_TEST_AZURITE")
+	if host == "" {
+		t.Skip("YAO_TEST_AZURITE is not set")
+	}
+
+	blob, err := NewBlob(host, azuriteAccount, azuriteKey, "", "yao-sui-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = blob.CreateContainer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	etag, err := blob.Put("templates/demo/index/index page.html", []byte("<div>Hello</div>"), "text/html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, etag)
+
+	objects, err := blob.List("templates/demo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "templates/demo/index/index page.html", objects[0].Key)
+	assert.Equal(t, etag, objects[0].ETag)
+
+	content, err := blob.Get("templates/demo/index/index page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<div>Hello</div>", string(content))
+
+	err = blob.Delete("templates/demo/index/index page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = blob.Get("templates/demo/index/index page.html")
+	assert.Error(t, err)
+}
+
+func prepare(t *testing.T, storage Storage) *Azure {
+	azure, err := NewWithStorage(&core.DSL{
+		ID:      "test",
+		Storage: &core.Storage{Driver: "azure", Option: map[string]interface{}{"prefix": "site", "cache": t.TempDir()}},
+	}, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return azure
+}
+
+type memoryStorage struct {
+	objects map[string][]byte
+	types   map[string]string
+	mutex   sync.Mutex
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (storage *memoryStorage) List(prefix string) ([]Object, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	objects := []Object{}
+	for key, content := range storage.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, ETag: fmt.Sprintf("%x", content), Size: int64(len(content))})
+		}
+	}
+	return objects, nil
+}
+
+func (storage *memoryStorage) Get(key string) ([]byte, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	content, has := storage.objects[key]
+	if !has {
+		return nil, fmt.Errorf("%s not found", key)
+	}
+	return content, nil
+}
+
+func (storage *memoryStorage) Put(key string, data []byte, contentType string) (string, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	storage.objects[key] = data
+	storage.types[key] = contentType
+	return fmt.Sprintf("%x", data), nil
+}
+
+func (storage *memoryStorage) Delete(key string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	delete(storage.objects, key)
+	delete(storage.types, key)
+	return nil
+}
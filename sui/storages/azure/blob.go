@@ -0,0 +1,332 @@
+package azure
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlobAPIVersion the version of the Azure Blob REST API
+const BlobAPIVersion = "2020-10-02"
+
+// Storage the object storage interface used by the remote sui
+type Storage interface {
+	List(prefix string) ([]Object, error)
+	Get(key string) ([]byte, error)
+	Put(key string, data []byte, contentType string) (string, error)
+	Delete(key string) error
+}
+
+// Object the object of the storage
+type Object struct {
+	Key         string
+	ETag        string
+	Size        int64
+	ContentType string
+}
+
+// Blob the Azure Blob storage client, works with Azurite too
+type Blob struct {
+	Host      string // https://<account>.blob.core.windows.net or http://127.0.0.1:10000/devstoreaccount1
+	Account   string
+	Key       []byte // the shared key
+	SAS       string // the shared access signature, used when the key is empty
+	Container string
+	client    *http.Client
+}
+
+type blobList struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ETag          string `xml:"Etag"`
+			ContentLength int64  `xml:"Content-Length"`
+			ContentType   string `xml:"Content-Type"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// NewBlob create a new Azure Blob storage client
+func NewBlob(host, account, key, sas, container string) (*Blob, error) {
+
+	if account == "" {
+		return nil, fmt.Errorf("option.account is required")
+	}
+
+	if container == "" {
+		return nil, fmt.Errorf("option.container is required")
+	}
+
+	if host == "" {
+		host = fmt.Sprintf("https://%s.blob.core.windows.net", account)
+	}
+
+	if _, err := url.Parse(host); err != nil {
+		return nil, fmt.Errorf("option.host %s is not a valid url", host)
+	}
+
+	blob := &Blob{
+		Host:      strings.TrimSuffix(host, "/"),
+		Account:   account,
+		SAS:       strings.TrimPrefix(sas, "?"),
+		Container: container,
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}
+
+	if key != "" {
+		secret, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("option.key is not a valid base64 string")
+		}
+		blob.Key = secret
+	}
+
+	if blob.Key == nil && blob.SAS == "" {
+		return nil, fmt.Errorf("option.key or option.sas is required")
+	}
+
+	return blob, nil
+}
+
+// CreateContainer create the container if it does not exist
+func (blob *Blob) CreateContainer() error {
+	res, err := blob.do("PUT", "", url.Values{"restype": {"container"}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusCreated || res.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return blob.error(res)
+}
+
+// List list the objects with the prefix
+func (blob *Blob) List(prefix string) ([]Object, error) {
+
+	objects := []Object{}
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		res, err := blob.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusOK {
+			err = blob.error(res)
+			res.Body.Close()
+			return nil, err
+		}
+
+		list := blobList{}
+		err = xml.NewDecoder(res.Body).Decode(&list)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range list.Blobs {
+			objects = append(objects, Object{
+				Key:         item.Name,
+				ETag:        item.Properties.ETag,
+				Size:        item.Properties.ContentLength,
+				ContentType: item.Properties.ContentType,
+			})
+		}
+
+		if list.NextMarker == "" {
+			break
+		}
+		marker = list.NextMarker
+	}
+
+	return objects, nil
+}
+
+// Get get the content of the object
+func (blob *Blob) Get(key string) ([]byte, error) {
+	res, err := blob.do("GET", key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, blob.error(res)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Put upload the object, returns the ETag
+func (blob *Blob) Put(key string, data []byte, contentType string) (string, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("x-ms-blob-type", "BlockBlob")
+	res, err := blob.do("PUT", key, nil, header, data)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return "", blob.error(res)
+	}
+	return res.Header.Get("ETag"), nil
+}
+
+// Delete delete the object, the missing object is ignored
+func (blob *Blob) Delete(key string) error {
+	res, err := blob.do("DELETE", key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusAccepted || res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return blob.error(res)
+}
+
+func (blob *Blob) do(method string, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+
+	path := "/" + url.PathEscape(blob.Container)
+	if key != "" {
+		segments := strings.Split(key, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		path = path + "/" + strings.Join(segments, "/")
+	}
+
+	u, err := url.Parse(blob.Host + path)
+	if err != nil {
+		return nil, err
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+
+	rawQuery := query.Encode()
+	if blob.Key == nil && blob.SAS != "" {
+		if rawQuery != "" {
+			rawQuery = rawQuery + "&"
+		}
+		rawQuery = rawQuery + blob.SAS
+	}
+	u.RawQuery = rawQuery
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", BlobAPIVersion)
+	if blob.Key != nil {
+		req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", blob.Account, blob.sign(req, query)))
+	}
+
+	return blob.client.Do(req)
+}
+
+// sign sign the request with the shared key
+// https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (blob *Blob) sign(req *http.Request, query url.Values) string {
+
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	headers := []string{}
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			headers = append(headers, name)
+		}
+	}
+	sort.Strings(headers)
+
+	canonicalized := ""
+	for _, name := range headers {
+		canonicalized = canonicalized + fmt.Sprintf("%s:%s\n", name, strings.TrimSpace(req.Header.Get(name)))
+	}
+
+	resource := fmt.Sprintf("/%s%s", blob.Account, req.URL.EscapedPath())
+	names := []string{}
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		resource = resource + fmt.Sprintf("\n%s:%s", strings.ToLower(name), strings.Join(values, ","))
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, the x-ms-date is used
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}, "\n") + "\n" + canonicalized + resource
+
+	mac := hmac.New(sha256.New, blob.Key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (blob *Blob) error(res *http.Response) error {
+	body, _ := io.ReadAll(res.Body)
+	message := res.Status
+	content := struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}{}
+	if xml.Unmarshal(body, &content) == nil && content.Code != "" {
+		message = fmt.Sprintf("%s %s", content.Code, strings.Split(content.Message, "\n")[0])
+	}
+	return fmt.Errorf("Azure blob %s %s: %s", res.Request.Method, res.Request.URL.Path, message)
+}
@@ -0,0 +1,72 @@
+package azure
+
+import (
+	"io"
+
+	"github.com/yaoapp/gou/fs"
+)
+
+// FileSystem is the local cache of the remote templates, the changes are written through to the container
+type FileSystem struct {
+	azure *Azure
+	fs.FileSystem
+}
+
+// WriteFile write the file to the cache and upload it
+func (cache *FileSystem) WriteFile(file string, data []byte, perm uint32) (int, error) {
+	n, err := cache.FileSystem.WriteFile(file, data, perm)
+	if err != nil {
+		return n, err
+	}
+	return n, cache.azure.upload(file)
+}
+
+// Write write the content of the reader to the cache and upload it
+func (cache *FileSystem) Write(file string, reader io.Reader, perm uint32) (int, error) {
+	n, err := cache.FileSystem.Write(file, reader, perm)
+	if err != nil {
+		return n, err
+	}
+	return n, cache.azure.upload(file)
+}
+
+// Remove remove the file from the cache and the container
+func (cache *FileSystem) Remove(name string) error {
+	err := cache.FileSystem.Remove(name)
+	if err != nil {
+		return err
+	}
+	return cache.azure.remove(name)
+}
+
+// RemoveAll remove the directory from the cache and the container
+func (cache *FileSystem) RemoveAll(name string) error {
+	err := cache.FileSystem.RemoveAll(name)
+	if err != nil {
+		return err
+	}
+	return cache.azure.remove(name)
+}
+
+// Copy copy the file or the directory and upload the copies
+func (cache *FileSystem) Copy(src string, dest string) error {
+	err := cache.FileSystem.Copy(src, dest)
+	if err != nil {
+		return err
+	}
+	return cache.azure.uploadAll(dest)
+}
+
+// Move move the file or the directory, the objects are moved in the container too
+func (cache *FileSystem) Move(oldpath string, newpath string) error {
+	err := cache.FileSystem.Move(oldpath, newpath)
+	if err != nil {
+		return err
+	}
+
+	err = cache.azure.remove(oldpath)
+	if err != nil {
+		return err
+	}
+	return cache.azure.uploadAll(newpath)
+}
@@ -0,0 +1,55 @@
+package azure
+
+import (
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/sui/core"
+)
+
+// Build build the template and publish the build output to the container
+func (tmpl *Template) Build(option *core.BuildOption) ([]string, error) {
+	warnings, err := tmpl.Template.Build(option)
+	if err != nil {
+		return warnings, err
+	}
+	return warnings, tmpl.publish(option)
+}
+
+// Trans translate the template and publish the locale files to the container
+func (tmpl *Template) Trans(option *core.BuildOption) ([]string, error) {
+	warnings, err := tmpl.Template.Trans(option)
+	if err != nil {
+		return warnings, err
+	}
+	return warnings, tmpl.publish(option)
+}
+
+// SyncAssets sync the assets and publish them to the container
+func (tmpl *Template) SyncAssets(option *core.BuildOption) error {
+	err := tmpl.Template.SyncAssets(option)
+	if err != nil {
+		return err
+	}
+	return tmpl.publish(option)
+}
+
+// Reload sync the templates from the container and reload the template
+func (tmpl *Template) Reload() error {
+	err := tmpl.azure.Sync()
+	if err != nil {
+		log.Error("[SUI] Azure sync error: %s, use the cache", err.Error())
+	}
+	return tmpl.Template.Reload()
+}
+
+func (tmpl *Template) publish(option *core.BuildOption) error {
+	var data map[string]interface{}
+	if option != nil {
+		data = option.Data
+	}
+
+	root, err := tmpl.azure.DSL.PublicRoot(data)
+	if err != nil {
+		root = tmpl.azure.DSL.Public.Root
+	}
+	return tmpl.azure.publish(root)
+}
@@ -593,8 +593,22 @@ func (page *Page) writeLocaleSource(ctx *core.BuildContext, option *core.BuildOp
 		}
 
 		locale := page.tmpl.getLocale(lc.Value, page.Route, true)
+		locale.Name = lc.Value
+		missing := locale.Missing(translations, prefix)
 		locale.MergeTranslations(translations, prefix)
 
+		// Machine translate the untranslated messages
+		if option.Translator != nil {
+			messages, err := option.Translator.TranslateLocale(&locale, missing)
+			if err != nil {
+				return err
+			}
+			for _, message := range messages {
+				log.Warn("[SUI] Machine translate %s: %s", page.Route, message)
+			}
+			locale.MergeTranslations(translations, prefix)
+		}
+
 		// Call the hook
 		var keys any = locale.Keys
 		var messages any = locale.Messages
@@ -624,10 +638,15 @@ func (page *Page) writeLocaleSource(ctx *core.BuildContext, option *core.BuildOp
 
 		// Save to file
 		file := filepath.Join(page.tmpl.Root, "__locales", lc.Value, fmt.Sprintf("%s.yml", page.Route))
-		content, err := yaml.Marshal(map[string]interface{}{
+		source := map[string]interface{}{
 			"keys":     keys,
 			"messages": messages,
-		})
+		}
+		if len(locale.Drafts) > 0 {
+			source["drafts"] = locale.Drafts
+		}
+
+		content, err := yaml.Marshal(source)
 		if err != nil {
 			return err
 		}
@@ -667,6 +686,7 @@ func (page *Page) writeLocaleFiles(ctx *core.BuildContext, data map[string]inter
 
 		// Remove messages
 		locale.Messages = map[string]string{}
+		locale.Drafts = nil
 		raw, err := yaml.Marshal(locale)
 		if err != nil {
 			log.Error(`[SUI] Marshal the locale file error: %s`, err.Error())
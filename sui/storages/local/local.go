@@ -22,6 +22,18 @@ func New(dsl *sui.DSL) (*Local, error) {
 		templateRoot = dsl.Storage.Option["root"].(string)
 	}
 
+	dataFS, err := fs.Get("system")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithFS(dsl, dataFS, templateRoot)
+}
+
+// NewWithFS create a new local sui with the given file system, the templates are stored in the templateRoot of the file system
+// It is used by the remote storages, the file system is the local cache of the remote templates.
+func NewWithFS(dsl *sui.DSL, dataFS fs.FileSystem, templateRoot string) (*Local, error) {
+
 	root := "/"
 	host := "/"
 	index := "/index"
@@ -44,11 +56,6 @@ func New(dsl *sui.DSL) (*Local, error) {
 		}
 	}
 
-	dataFS, err := fs.Get("system")
-	if err != nil {
-		return nil, err
-	}
-
 	dsl.Public = &sui.Public{
 		Host:    host,
 		Root:    root,
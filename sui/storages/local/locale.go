@@ -0,0 +1,169 @@
+package local
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/sui/core"
+	"gopkg.in/yaml.v3"
+)
+
+// LocaleCatalog get the translatable messages of the template for the given locale
+func (tmpl *Template) LocaleCatalog(name string, option *core.BuildOption) (*core.LocaleCatalog, error) {
+
+	catalog := &core.LocaleCatalog{
+		Template: tmpl.ID,
+		Source:   tmpl.defaultLocale(),
+		Target:   name,
+		Entries:  []core.LocaleEntry{},
+		Stale:    []core.LocaleEntry{},
+	}
+
+	ctx := core.NewGlobalBuildContext()
+	pages, err := tmpl.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ipage := range pages {
+		page, ok := ipage.(*Page)
+		if !ok {
+			continue
+		}
+
+		err := page.Load()
+		if err != nil {
+			return nil, err
+		}
+
+		translations, _, err := page.translations(ctx, option)
+		if err != nil {
+			return nil, err
+		}
+
+		locale := tmpl.getLocale(name, page.Route, true)
+		catalog.Entries = append(catalog.Entries, core.LocaleEntries(page.Route, translations, locale)...)
+
+		// The global messages are shared by all pages, only the page locale file could be stale
+		file := filepath.Join(tmpl.Root, "__locales", name, fmt.Sprintf("%s.yml", page.Route))
+		if exists, _ := tmpl.local.fs.Exists(file); exists {
+			catalog.Stale = append(catalog.Stale, core.StaleLocaleEntries(page.Route, translations, locale)...)
+		}
+	}
+
+	return catalog, nil
+}
+
+// ImportLocaleCatalog write the translated messages of the catalog back to the locale files
+func (tmpl *Template) ImportLocaleCatalog(catalog *core.LocaleCatalog) ([]string, error) {
+	warnings := []string{}
+	if catalog.Target == "" {
+		return warnings, fmt.Errorf("The target locale of the catalog is required")
+	}
+
+	name := strings.ToLower(catalog.Target)
+	routes, groups := catalog.Routes()
+	for _, route := range routes {
+		if !tmpl.PageExist(route) {
+			warnings = append(warnings, fmt.Sprintf("The page %s does not exist, ignored", route))
+			continue
+		}
+
+		// Read the page locale file itself, the global messages and settings are not written back
+		file := filepath.Join(tmpl.Root, "__locales", name, fmt.Sprintf("%s.yml", route))
+		locale := core.Locale{}
+		if exists, _ := tmpl.local.fs.Exists(file); exists {
+			raw, err := tmpl.local.fs.ReadFile(file)
+			if err != nil {
+				return warnings, err
+			}
+
+			err = yaml.Unmarshal(raw, &locale)
+			if err != nil {
+				return warnings, fmt.Errorf("Parse the locale file %s error: %s", file, err.Error())
+			}
+		}
+
+		if locale.Keys == nil {
+			locale.Keys = map[string]string{}
+		}
+		if locale.Messages == nil {
+			locale.Messages = map[string]string{}
+		}
+		if locale.ScriptMessages == nil {
+			locale.ScriptMessages = map[string]string{}
+		}
+		if locale.Drafts == nil {
+			locale.Drafts = map[string]string{}
+		}
+
+		for _, entry := range groups[route] {
+			if entry.Target == "" {
+				continue
+			}
+
+			// The script messages are used by the __m function in the browser, they are merged from the messages on build
+			locale.Messages[entry.Source] = entry.Target
+			if entry.Type == "script" {
+				locale.ScriptMessages[entry.Source] = entry.Target
+			}
+
+			for _, key := range entry.Keys {
+				locale.Keys[key] = entry.Target
+			}
+
+			// The reviewed message is not a draft any more
+			delete(locale.Drafts, entry.Source)
+			if entry.State == core.LocaleStateDraft {
+				locale.Drafts[entry.Source] = entry.Target
+			}
+		}
+
+		content, err := yaml.Marshal(locale)
+		if err != nil {
+			return warnings, err
+		}
+
+		_, err = tmpl.local.fs.WriteFile(file, content, 0644)
+		if err != nil {
+			return warnings, err
+		}
+	}
+
+	return warnings, nil
+}
+
+func (tmpl *Template) defaultLocale() string {
+	for _, locale := range tmpl.Locales() {
+		if locale.Default {
+			return locale.Value
+		}
+	}
+	return ""
+}
+
+// translations get the translations of the page itself
+func (page *Page) translations(globalCtx *core.GlobalBuildContext, option *core.BuildOption) ([]core.Translation, []string, error) {
+	ctx := core.NewBuildContext(globalCtx)
+	_, _, warnings, err := page.Page.Compile(ctx, option)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	reg := regexp.MustCompile(fmt.Sprintf(`^%s_([0-9]+)$`, regexp.QuoteMeta(core.TranslationKeyPrefix(page.Route))))
+	translations := []core.Translation{}
+	for _, t := range ctx.GetTranslations() {
+		if !reg.MatchString(t.Key) {
+			continue
+		}
+		translations = append(translations, t)
+	}
+
+	if len(warnings) > 0 {
+		log.Warn("[SUI] Extract the translations of %s: %s", page.Route, strings.Join(warnings, ";"))
+	}
+	return translations, warnings, nil
+}
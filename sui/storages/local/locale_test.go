@@ -0,0 +1,115 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/yao/sui/core"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplateLocaleCatalog(t *testing.T) {
+	tests := prepare(t)
+	defer clean()
+
+	tmpl, err := tests.Test.GetTemplate("advanced")
+	if err != nil {
+		t.Fatalf("GetTemplate error: %v", err)
+	}
+
+	root := application.App.Root()
+	path := filepath.Join(root, "data", tmpl.GetRoot(), "__locales")
+	err = os.RemoveAll(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+
+	_, err = tmpl.Trans(&core.BuildOption{SSR: true})
+	if err != nil {
+		t.Fatalf("Trans error: %v", err)
+	}
+
+	catalog, err := tmpl.LocaleCatalog("zh-cn", &core.BuildOption{SSR: true})
+	if err != nil {
+		t.Fatalf("LocaleCatalog error: %v", err)
+	}
+
+	assert.Equal(t, "zh-cn", catalog.Target)
+	assert.NotEmpty(t, catalog.Entries)
+
+	report := catalog.Report()
+	assert.Equal(t, len(catalog.Entries), report.Total)
+	assert.Equal(t, report.Total, report.Translated+len(report.Missing))
+}
+
+func TestTemplateImportLocaleCatalog(t *testing.T) {
+	tests := prepare(t)
+	defer clean()
+
+	tmpl, err := tests.Test.GetTemplate("advanced")
+	if err != nil {
+		t.Fatalf("GetTemplate error: %v", err)
+	}
+
+	root := application.App.Root()
+	path := filepath.Join(root, "data", tmpl.GetRoot(), "__locales")
+	err = os.RemoveAll(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+
+	catalog, err := tmpl.LocaleCatalog("zh-cn", &core.BuildOption{SSR: true})
+	if err != nil {
+		t.Fatalf("LocaleCatalog error: %v", err)
+	}
+
+	if len(catalog.Entries) == 0 {
+		t.Fatalf("LocaleCatalog error: no entries")
+	}
+
+	index := 0
+	for i, e := range catalog.Entries {
+		if e.Type != "script" {
+			index = i
+			break
+		}
+	}
+	entry := catalog.Entries[index]
+	catalog.Entries[index].Target = "Unit Test Translation"
+
+	// The settings of the page locale file are kept
+	file := filepath.Join(path, "zh-cn", entry.Route+".yml")
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	err = os.WriteFile(file, []byte("direction: rtl\ntimezone: Asia/Shanghai\nscript_messages:\n  Unit Test: 单元测试\n"), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	warnings, err := tmpl.ImportLocaleCatalog(catalog)
+	if err != nil {
+		t.Fatalf("ImportLocaleCatalog error: %v", err)
+	}
+	assert.Len(t, warnings, 0)
+	assert.FileExists(t, file)
+
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+
+	locale := core.Locale{}
+	err = yaml.Unmarshal(raw, &locale)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	assert.Equal(t, "Unit Test Translation", locale.Messages[entry.Source])
+	assert.Equal(t, "rtl", locale.Direction)
+	assert.Equal(t, "Asia/Shanghai", locale.Timezone)
+	assert.Equal(t, "单元测试", locale.ScriptMessages["Unit Test"])
+}
@@ -19,7 +19,7 @@ func (tmpl *Template) Pages() ([]core.IPage, error) {
 	tmpl.local.fs.Walk(tmpl.Root, func(root, file string, isdir bool) error {
 		name := filepath.Base(file)
 		if isdir {
-			if strings.HasPrefix(name, "__") || name == ".tmp" {
+			if strings.HasPrefix(name, "__") || name == ".tmp" || name == versionDir {
 				return filepath.SkipDir
 			}
 			return nil
@@ -59,7 +59,7 @@ func (tmpl *Template) PageTree(route string) ([]*core.PageTreeNode, error) {
 		log.Debug("[PageTree] Walk | file: %s isdir: %v name: %v", relPath, isdir, name)
 
 		if isdir {
-			if strings.HasPrefix(name, "__") || name == ".tmp" {
+			if strings.HasPrefix(name, "__") || name == ".tmp" || name == versionDir {
 				return filepath.SkipDir
 			}
 
@@ -200,12 +200,14 @@ func (tmpl *Template) RemovePage(route string) error {
 		return err
 	}
 
-	// Remove .tmp directory
-	tmpPath := filepath.Join(tmpl.Root, route, ".tmp")
-	if exist, _ := tmpl.local.fs.Exists(tmpPath); exist {
-		err = tmpl.local.fs.RemoveAll(tmpPath)
-		if err != nil {
-			return err
+	// Remove .tmp and .versions directory
+	for _, dir := range []string{".tmp", versionDir} {
+		tmpPath := filepath.Join(tmpl.Root, route, dir)
+		if exist, _ := tmpl.local.fs.Exists(tmpPath); exist {
+			err = tmpl.local.fs.RemoveAll(tmpPath)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -503,6 +505,12 @@ func (page *Page) Save(request *core.RequestSource) error {
 		return err
 	}
 
+	// Save the version of the page
+	_, err = page.commit(request.User, "")
+	if err != nil {
+		return err
+	}
+
 	// Remove the temp file
 	tempPath := filepath.Join(page.Path, ".tmp", request.UID)
 	if exist, _ := page.tmpl.local.fs.Exists(tempPath); exist {
@@ -0,0 +1,347 @@
+package local
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/yao/sui/core"
+)
+
+// versionDir the directory of the page versions, in the page path
+const versionDir = ".versions"
+
+// versionIndex the published version of the page
+type versionIndex struct {
+	Published   string `json:"published,omitempty"`
+	PublishedAt int64  `json:"published_at,omitempty"`
+	PublishedBy string `json:"published_by,omitempty"`
+}
+
+// History get the versions of the page, the latest first. The source files are not included.
+func (page *Page) History() ([]core.PageVersion, error) {
+	versions, err := page.versions()
+	if err != nil {
+		return nil, err
+	}
+
+	index := page.versionIndex()
+	history := []core.PageVersion{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		version.Files = nil
+		version.Status = versionStatus(version.ID, index.Published)
+		history = append(history, version)
+	}
+	return history, nil
+}
+
+// Version get the version of the page with the source files
+func (page *Page) Version(id string) (*core.PageVersion, error) {
+	file := filepath.Join(page.Path, versionDir, fmt.Sprintf("%s.json", id))
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("Page %s version %s not found", page.Route, id)
+	}
+
+	if exist, _ := page.tmpl.local.fs.Exists(file); !exist {
+		return nil, fmt.Errorf("Page %s version %s not found", page.Route, id)
+	}
+
+	content, err := page.tmpl.local.fs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	version := core.PageVersion{}
+	err = jsoniter.Unmarshal(content, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	version.Status = versionStatus(version.ID, page.versionIndex().Published)
+	return &version, nil
+}
+
+// Diff compare the versions of the page.
+// The empty from is the published version, the empty to is the current source.
+func (page *Page) Diff(from string, to string) ([]core.PageFileDiff, error) {
+
+	fromFiles := map[string]string{}
+	if from == "" {
+		from = page.versionIndex().Published
+	}
+
+	if from != "" {
+		version, err := page.Version(from)
+		if err != nil {
+			return nil, err
+		}
+		fromFiles = version.Files
+	}
+
+	toFiles := map[string]string{}
+	if to == "" {
+		files, err := page.sourceFiles()
+		if err != nil {
+			return nil, err
+		}
+		toFiles = files
+
+	} else {
+		version, err := page.Version(to)
+		if err != nil {
+			return nil, err
+		}
+		toFiles = version.Files
+	}
+
+	return core.DiffFiles(fromFiles, toFiles), nil
+}
+
+// Rollback restore the page source to the version, a new draft version is created
+func (page *Page) Rollback(id string, author string) (*core.PageVersion, error) {
+	version, err := page.Version(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = page.restore(version)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err = page.commit(author, fmt.Sprintf("Rollback to %s", id))
+	if err != nil {
+		return nil, err
+	}
+
+	version.Files = nil
+	return version, nil
+}
+
+// Publish promote the version as the published version and build the page.
+// The current source is published if the id is empty, otherwise the source of the version is restored before building,
+// the current source is saved as a version first, so nothing is lost.
+func (page *Page) Publish(id string, author string, option *core.BuildOption) (*core.PageVersion, []string, error) {
+	version, err := page.commit(author, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if id != "" && id != version.ID {
+		version, err = page.Version(id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = page.restore(version)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = page.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings, err := page.Build(nil, option)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	index := versionIndex{Published: version.ID, PublishedAt: time.Now().UnixMilli(), PublishedBy: author}
+	content, err := jsoniter.Marshal(index)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	_, err = page.tmpl.local.fs.WriteFile(filepath.Join(page.Path, versionDir, "index.json"), content, 0644)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	version.Files = nil
+	version.Status = core.PageVersionPublished
+	return version, warnings, nil
+}
+
+// restore write the source files of the version to the page, the files not in the version are removed
+func (page *Page) restore(version *core.PageVersion) error {
+	current, err := page.sourceFiles()
+	if err != nil {
+		return err
+	}
+
+	for name := range current {
+		if _, has := version.Files[name]; has {
+			continue
+		}
+
+		err = page.tmpl.local.fs.Remove(filepath.Join(page.Path, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, content := range version.Files {
+		if current[name] == content {
+			continue
+		}
+
+		_, err = page.tmpl.local.fs.WriteFile(filepath.Join(page.Path, name), []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit save the current source as a new version, nothing changes if the source is the same as the latest version
+func (page *Page) commit(author string, message string) (*core.PageVersion, error) {
+	files, err := page.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := page.versions()
+	if err != nil {
+		return nil, err
+	}
+
+	version := core.PageVersion{Route: page.Route, Author: author, Message: message, Files: files}
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		diffs := core.DiffFiles(latest.Files, files)
+		if len(diffs) == 0 {
+			latest.Status = versionStatus(latest.ID, page.versionIndex().Published)
+			return &latest, nil
+		}
+
+		version.Parent = latest.ID
+		for _, diff := range diffs {
+			version.Added += diff.Added
+			version.Removed += diff.Removed
+		}
+
+	} else {
+		for _, diff := range core.DiffFiles(map[string]string{}, files) {
+			version.Added += diff.Added
+		}
+	}
+
+	now := time.Now()
+	version.ID = now.UTC().Format("20060102150405.000000")
+	version.Time = now.UnixMilli()
+	version.Status = core.PageVersionDraft
+
+	content, err := jsoniter.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = page.tmpl.local.fs.WriteFile(filepath.Join(page.Path, versionDir, fmt.Sprintf("%s.json", version.ID)), content, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// versions get the versions of the page, the oldest first
+func (page *Page) versions() ([]core.PageVersion, error) {
+	dir := filepath.Join(page.Path, versionDir)
+	if exist, _ := page.tmpl.local.fs.Exists(dir); !exist {
+		return []core.PageVersion{}, nil
+	}
+
+	files, err := page.tmpl.local.fs.ReadDir(dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []core.PageVersion{}
+	for _, file := range files {
+		if filepath.Ext(file) != ".json" || filepath.Base(file) == "index.json" {
+			continue
+		}
+
+		content, err := page.tmpl.local.fs.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		version := core.PageVersion{}
+		err = jsoniter.Unmarshal(content, &version)
+		if err != nil {
+			return nil, fmt.Errorf("Page %s version %s %s", page.Route, filepath.Base(file), err.Error())
+		}
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID < versions[j].ID })
+	return versions, nil
+}
+
+func (page *Page) versionIndex() versionIndex {
+	index := versionIndex{}
+	file := filepath.Join(page.Path, versionDir, "index.json")
+	if exist, _ := page.tmpl.local.fs.Exists(file); !exist {
+		return index
+	}
+
+	content, err := page.tmpl.local.fs.ReadFile(file)
+	if err != nil {
+		return index
+	}
+
+	jsoniter.Unmarshal(content, &index)
+	return index
+}
+
+// sourceFiles get the source files of the page
+func (page *Page) sourceFiles() (map[string]string, error) {
+	names := []string{
+		page.Codes.HTML.File,
+		page.Codes.CSS.File,
+		page.Codes.LESS.File,
+		page.Codes.JS.File,
+		page.Codes.TS.File,
+		page.Codes.DATA.File,
+		page.Codes.CONF.File,
+		fmt.Sprintf("%s.backend.ts", page.Name),
+		fmt.Sprintf("%s.backend.js", page.Name),
+	}
+
+	files := map[string]string{}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		file := filepath.Join(page.Path, name)
+		if exist, _ := page.tmpl.local.fs.Exists(file); !exist {
+			continue
+		}
+
+		content, err := page.tmpl.local.fs.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = string(content)
+	}
+	return files, nil
+}
+
+func versionStatus(id string, published string) string {
+	if published == "" || id > published {
+		return core.PageVersionDraft
+	}
+
+	if id == published {
+		return core.PageVersionPublished
+	}
+	return core.PageVersionArchived
+}
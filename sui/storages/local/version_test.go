@@ -0,0 +1,130 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/sui/core"
+)
+
+func TestPageVersions(t *testing.T) {
+	tests := prepare(t)
+	defer clean()
+
+	tmpl, err := tests.Test.GetTemplate("advanced")
+	if err != nil {
+		t.Fatalf("GetTemplate error: %v", err)
+	}
+
+	route := "/unit-test-versions"
+	tmpl.RemovePage(route)
+	defer tmpl.RemovePage(route)
+
+	page, err := tmpl.CreateEmptyPage(route, &core.PageSetting{Title: "Versions"})
+	if err != nil {
+		t.Fatalf("CreateEmptyPage error: %v", err)
+	}
+
+	source := &core.RequestSource{
+		UID:        "unit-test",
+		User:       "1",
+		Page:       &core.SourceData{Source: "<div>Versions</div>\n<p>Changed</p>", Language: "html"},
+		NeedToSave: core.ReqeustSourceNeedToSave{Page: true},
+	}
+
+	// The same source is saved twice, only one version is created
+	for i := 0; i < 2; i++ {
+		err = page.Save(source)
+		if err != nil {
+			t.Fatalf("Save error: %v", err)
+		}
+	}
+
+	history, err := page.History()
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+
+	assert.Len(t, history, 2)
+	assert.Equal(t, "1", history[0].Author)
+	assert.Equal(t, history[1].ID, history[0].Parent)
+	assert.Equal(t, core.PageVersionDraft, history[0].Status)
+	assert.Equal(t, 1, history[0].Added)
+	assert.Nil(t, history[0].Files)
+
+	diffs, err := page.Diff(history[1].ID, history[0].ID)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	assert.Len(t, diffs, 1)
+	assert.Equal(t, "unit-test-versions.html", diffs[0].File)
+	assert.Contains(t, diffs[0].Patch, "+<p>Changed</p>")
+
+	// Rollback creates a new draft with the source of the first version
+	version, err := page.Rollback(history[1].ID, "2")
+	if err != nil {
+		t.Fatalf("Rollback error: %v", err)
+	}
+	assert.Equal(t, "2", version.Author)
+	assert.Equal(t, history[0].ID, version.Parent)
+
+	first, err := page.Version(history[1].ID)
+	if err != nil {
+		t.Fatalf("Version error: %v", err)
+	}
+
+	diffs, err = page.Diff(first.ID, "")
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	assert.Len(t, diffs, 0)
+
+	// Publish
+	version, _, err = page.Publish("", "1", &core.BuildOption{SSR: true})
+	if err != nil {
+		t.Fatalf("Publish error: %v", err)
+	}
+	assert.Equal(t, core.PageVersionPublished, version.Status)
+
+	history, err = page.History()
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+	assert.Len(t, history, 3)
+	assert.Equal(t, core.PageVersionPublished, history[0].Status)
+	assert.Equal(t, core.PageVersionArchived, history[1].Status)
+
+	// Publish the version, the current source is saved as a draft and the source of the version is restored
+	published := version.ID
+	source.Page.Source = "<div>Versions</div>\n<p>Draft</p>"
+	err = page.Save(source)
+	if err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	version, _, err = page.Publish(published, "2", &core.BuildOption{SSR: true})
+	if err != nil {
+		t.Fatalf("Publish error: %v", err)
+	}
+	assert.Equal(t, published, version.ID)
+
+	history, err = page.History()
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+	assert.Len(t, history, 4)
+	assert.Equal(t, core.PageVersionDraft, history[0].Status)
+	assert.Equal(t, core.PageVersionPublished, history[1].Status)
+
+	diffs, err = page.Diff(published, "")
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	assert.Len(t, diffs, 0)
+
+	_, _, err = page.Publish("not-found", "1", &core.BuildOption{SSR: true})
+	assert.Error(t, err)
+
+	_, err = page.Version("../../template")
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// OTLPExporter export the spans to the OpenTelemetry collector with the OTLP/HTTP JSON protocol
+type OTLPExporter struct {
+	Endpoint string
+	Service  string
+	Headers  map[string]string
+	client   *http.Client
+}
+
+// FileExporter write the spans to the file, one OTLP JSON request per line.
+// The file can be read by the otlpjsonfile receiver of the OpenTelemetry collector.
+type FileExporter struct {
+	Service string
+	file    *os.File
+	mutex   sync.Mutex
+}
+
+// NewOTLPExporter create a new OTLP/HTTP exporter, the spans are posted to the {endpoint}/v1/traces
+func NewOTLPExporter(endpoint string, service string, headers map[string]string) *OTLPExporter {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint = endpoint + "/v1/traces"
+	}
+	return &OTLPExporter{Endpoint: endpoint, Service: service, Headers: headers, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Export post the spans to the collector
+func (exporter *OTLPExporter) Export(spans []*Span) error {
+	body, err := Encode(exporter.Service, spans)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", exporter.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range exporter.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := exporter.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %d %s", exporter.Endpoint, resp.StatusCode, string(message))
+	}
+	return nil
+}
+
+// Close nothing to close
+func (exporter *OTLPExporter) Close() error {
+	return nil
+}
+
+// NewFileExporter create a new file exporter, the spans are appended to the file
+func NewFileExporter(name string, service string) (*FileExporter, error) {
+	err := os.MkdirAll(filepath.Dir(name), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{Service: service, file: file}, nil
+}
+
+// Export append the spans to the file
+func (exporter *FileExporter) Export(spans []*Span) error {
+	body, err := Encode(exporter.Service, spans)
+	if err != nil {
+		return err
+	}
+
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	_, err = exporter.file.Write(append(body, '\n'))
+	return err
+}
+
+// Close close the file
+func (exporter *FileExporter) Close() error {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	return exporter.file.Close()
+}
+
+// Encode encode the spans as the OTLP JSON ExportTraceServiceRequest
+func Encode(service string, spans []*Span) ([]byte, error) {
+	items := []map[string]interface{}{}
+	for _, span := range spans {
+		span.mutex.Lock()
+		item := map[string]interface{}{
+			"traceId":           hex.EncodeToString(span.TraceID[:]),
+			"spanId":            hex.EncodeToString(span.SpanID[:]),
+			"name":              span.Name,
+			"kind":              span.Kind,
+			"startTimeUnixNano": fmt.Sprintf("%d", span.Start.UnixNano()),
+			"endTimeUnixNano":   fmt.Sprintf("%d", span.End.UnixNano()),
+			"attributes":        encodeAttributes(span.Attributes),
+			"status":            map[string]interface{}{"code": span.Status, "message": span.StatusMessage},
+		}
+
+		if span.ParentID != [8]byte{} {
+			item["parentSpanId"] = hex.EncodeToString(span.ParentID[:])
+		}
+		span.mutex.Unlock()
+		items = append(items, item)
+	}
+
+	return jsoniter.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": encodeAttributes(map[string]interface{}{"service.name": service}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/yaoapp/yao/telemetry"},
+						"spans": items,
+					},
+				},
+			},
+		},
+	})
+}
+
+func encodeAttributes(attributes map[string]interface{}) []interface{} {
+	keys := []string{}
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	res := []interface{}{}
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch v := attributes[key].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": fmt.Sprintf("%d", v)}
+		case int64:
+			value = map[string]interface{}{"intValue": fmt.Sprintf("%d", v)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+		}
+		res = append(res, map[string]interface{}{"key": key, "value": value})
+	}
+	return res
+}
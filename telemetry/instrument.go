@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/process"
+)
+
+// Middleware the gin middleware traces the HTTP requests, the traceparent header of the request is the parent
+func Middleware(c *gin.Context) {
+	ctx := Extract(c.Request.Context(), c.Request.Header)
+	ctx, span := Start(ctx, fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL.Path), SpanKindServer)
+	if span == nil {
+		c.Next()
+		return
+	}
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	route := c.FullPath()
+	if route != "" {
+		span.SetName(fmt.Sprintf("%s %s", c.Request.Method, route))
+		span.SetAttribute("http.route", route)
+	}
+
+	span.SetAttribute("http.method", c.Request.Method)
+	span.SetAttribute("http.target", c.Request.URL.Path)
+	span.SetAttribute("http.status_code", status)
+	if status >= 500 {
+		span.SetError(fmt.Sprintf("HTTP %d", status))
+	}
+
+	if len(c.Errors) > 0 {
+		span.SetError(c.Errors.String())
+	}
+	span.Finish()
+}
+
+// Guards wrap the guards, the guard executions are traced.
+// It returns the guards as is when the tracing is disabled.
+func Guards(guards map[string]gin.HandlerFunc) map[string]gin.HandlerFunc {
+	if !Enabled() {
+		return guards
+	}
+
+	res := map[string]gin.HandlerFunc{}
+	for name, guard := range guards {
+		res[name] = traceGuard(name, guard)
+	}
+	return res
+}
+
+func traceGuard(name string, guard gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), fmt.Sprintf("guard %s", name), SpanKindInternal)
+		guard(c)
+		if c.IsAborted() {
+			span.SetAttribute("guard.aborted", true)
+		}
+		span.Finish()
+	}
+}
+
+// traceProcess each process execution is a span.
+// The parent is the span of the process context, the process context carries the span to the handler.
+// The nested processes created with the context of the caller are the children. e.g. utils.flow.*, the process of the AIGC.
+// The processes created without a context start new traces. e.g. the Process() calls of the scripts and the nodes of the flows,
+// gou does not pass the context of the caller to them.
+func traceProcess(handler process.Handler) process.Handler {
+	return func(p *process.Process) (res interface{}) {
+		ctx, span := Start(p.Context, fmt.Sprintf("process %s", strings.ToLower(p.Name)), SpanKindInternal)
+		if span == nil {
+			return handler(p)
+		}
+
+		span.SetAttribute("process.name", strings.ToLower(p.Name))
+		p.WithContext(ctx)
+		defer func() {
+			if r := recover(); r != nil {
+				span.SetError(r)
+				span.Finish()
+				panic(r)
+			}
+
+			if err, ok := res.(error); ok {
+				span.SetError(err)
+			}
+			span.Finish()
+		}()
+
+		return handler(p)
+	}
+}
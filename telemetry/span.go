@@ -0,0 +1,201 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The span kinds, the same as the OTLP SpanKind
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+)
+
+// The span status codes, the same as the OTLP StatusCode
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// TraceParentHeader the W3C trace context header
+const TraceParentHeader = "traceparent"
+
+// Span a timed operation of a trace, all the methods are safe on a nil span
+type Span struct {
+	TraceID       [16]byte
+	SpanID        [8]byte
+	ParentID      [8]byte
+	Name          string
+	Kind          int
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	Status        int
+	StatusMessage string
+	sampled       bool
+	remote        bool
+	ended         bool
+	mutex         sync.Mutex
+}
+
+type spanKey struct{}
+
+// Start start a new span, the parent is the span of the context.
+// It returns a nil span when the tracing is disabled or the trace is not sampled.
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	p := current()
+	if p == nil {
+		return ctx, nil
+	}
+
+	span := &Span{Name: name, Kind: kind, Start: time.Now(), Attributes: map[string]interface{}{}}
+	rand.Read(span.SpanID[:])
+
+	parent := SpanFromContext(ctx)
+	if parent != nil {
+		if !parent.sampled {
+			return ctx, nil
+		}
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+
+	} else {
+		rand.Read(span.TraceID[:])
+		if !p.sample(span.TraceID) {
+			return ctx, nil
+		}
+	}
+
+	span.sampled = true
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext get the span of the context, nil if not found
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+
+	span, ok := ctx.Value(spanKey{}).(*Span)
+	if !ok {
+		return nil
+	}
+	return span
+}
+
+// SetAttribute set the attribute of the span, the value should be a string, bool, int, int64 or float64
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Attributes[key] = value
+}
+
+// SetName rename the span
+func (span *Span) SetName(name string) {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Name = name
+}
+
+// SetError mark the span as failed
+func (span *Span) SetError(err interface{}) {
+	if span == nil || err == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.Status = StatusError
+	span.StatusMessage = fmt.Sprintf("%v", err)
+}
+
+// Finish end the span and send it to the exporter, only the first call works
+func (span *Span) Finish() {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	if span.ended || span.remote {
+		span.mutex.Unlock()
+		return
+	}
+	span.ended = true
+	span.End = time.Now()
+	span.mutex.Unlock()
+
+	if p := current(); p != nil {
+		p.enqueue(span)
+	}
+}
+
+// TraceParent the W3C traceparent value of the span
+func (span *Span) TraceParent() string {
+	if span == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(span.TraceID[:]), hex.EncodeToString(span.SpanID[:]))
+}
+
+// Inject set the traceparent header of the span of the context, use it for the outgoing requests
+func Inject(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil || header == nil {
+		return
+	}
+	header[TraceParentHeader] = []string{span.TraceParent()}
+}
+
+// Extract read the traceparent header, returns the context with the remote parent span
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	value := header.Get(TraceParentHeader)
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+
+	span := &Span{remote: true}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return ctx
+	}
+
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return ctx
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return ctx
+	}
+
+	copy(span.TraceID[:], traceID)
+	copy(span.SpanID[:], spanID)
+	span.sampled = flags[0]&0x01 == 0x01
+	if span.TraceID == [16]byte{} || span.SpanID == [8]byte{} {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
@@ -0,0 +1,220 @@
+package telemetry
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// BatchSize the max number of the spans exported at once
+var BatchSize = 512
+
+// QueueSize the max number of the queued spans, the spans are dropped when the queue is full
+var QueueSize = 4096
+
+// FlushInterval the interval of exporting the queued spans
+var FlushInterval = 5 * time.Second
+
+// Exporter export the ended spans
+type Exporter interface {
+	Export(spans []*Span) error
+	Close() error
+}
+
+type provider struct {
+	exporter Exporter
+	ratio    float64
+	queue    chan *Span
+	flush    chan chan bool
+	done     chan bool
+}
+
+var active *provider
+var activeMutex sync.RWMutex
+
+// Setup start the tracing with the config, nothing changes if the exporter is not set.
+// It should be called after the application is loaded, the process handlers are instrumented.
+func Setup(cfg config.Config) error {
+	if cfg.Trace.Exporter == "" {
+		return nil
+	}
+
+	var exporter Exporter
+	var err error
+	switch strings.ToLower(cfg.Trace.Exporter) {
+	case "otlp":
+		exporter = NewOTLPExporter(cfg.Trace.Endpoint, cfg.Trace.Service, parseHeaders(cfg.Trace.Headers))
+
+	case "file":
+		file := cfg.Trace.File
+		if file == "" {
+			file = filepath.Join(cfg.Root, "logs", "trace.jsonl")
+		} else if !filepath.IsAbs(file) {
+			file = filepath.Join(cfg.Root, file)
+		}
+
+		exporter, err = NewFileExporter(file, cfg.Trace.Service)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("Trace exporter %s does not support, the exporter should be otlp or file", cfg.Trace.Exporter)
+	}
+
+	ratio := cfg.Trace.Sample
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	Use(exporter, ratio)
+	share.UseProcessHook("trace", traceProcess)
+	log.Info("[Trace] %s exporter started, sample ratio %v", cfg.Trace.Exporter, ratio)
+	return nil
+}
+
+// Use start the tracing with the exporter, the previous exporter is stopped
+func Use(exporter Exporter, ratio float64) {
+	Stop()
+
+	p := &provider{
+		exporter: exporter,
+		ratio:    ratio,
+		queue:    make(chan *Span, QueueSize),
+		flush:    make(chan chan bool),
+		done:     make(chan bool),
+	}
+
+	activeMutex.Lock()
+	active = p
+	activeMutex.Unlock()
+	go p.run()
+}
+
+// Enabled check if the tracing is enabled
+func Enabled() bool {
+	return current() != nil
+}
+
+// Flush export the queued spans immediately
+func Flush() {
+	p := current()
+	if p == nil {
+		return
+	}
+
+	res := make(chan bool)
+	select {
+	case p.flush <- res:
+		<-res
+	case <-p.done:
+	}
+}
+
+// Stop export the queued spans and stop the tracing
+func Stop() {
+	activeMutex.Lock()
+	p := active
+	active = nil
+	activeMutex.Unlock()
+
+	if p == nil {
+		return
+	}
+
+	close(p.queue)
+	<-p.done
+	err := p.exporter.Close()
+	if err != nil {
+		log.Error("[Trace] close the exporter %s", err.Error())
+	}
+}
+
+func current() *provider {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+	return active
+}
+
+// sample the trace by the ratio, the decision is made by the trace id, so that it is the same for all the services
+func (p *provider) sample(traceID [16]byte) bool {
+	if p.ratio >= 1 {
+		return true
+	}
+	value := binary.BigEndian.Uint64(traceID[8:]) >> 1
+	return float64(value) < p.ratio*float64(uint64(1)<<63)
+}
+
+func (p *provider) enqueue(span *Span) {
+	defer func() { recover() }() // the queue is closed after stopping
+	select {
+	case p.queue <- span:
+	default:
+		log.Warn("[Trace] the queue is full, span %s dropped", span.Name)
+	}
+}
+
+func (p *provider) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	batch := []*Span{}
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := p.exporter.Export(batch)
+		if err != nil {
+			log.Error("[Trace] export %d spans %s", len(batch), err.Error())
+		}
+		batch = []*Span{}
+	}
+
+	for {
+		select {
+		case span, ok := <-p.queue:
+			if !ok {
+				export()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= BatchSize {
+				export()
+			}
+
+		case res := <-p.flush:
+			for len(p.queue) > 0 {
+				span, ok := <-p.queue
+				if !ok {
+					break
+				}
+				batch = append(batch, span)
+			}
+			export()
+			res <- true
+
+		case <-ticker.C:
+			export()
+		}
+	}
+}
+
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers
+}
@@ -0,0 +1,220 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestStartDisabled(t *testing.T) {
+	Stop()
+	ctx, span := Start(context.Background(), "test", SpanKindInternal)
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+
+	// The methods are safe on the nil span
+	span.SetAttribute("foo", "bar")
+	span.SetError(fmt.Errorf("error"))
+	span.Finish()
+	assert.Equal(t, "", span.TraceParent())
+}
+
+func TestFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.jsonl")
+	exporter, err := NewFileExporter(file, "yao-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Use(exporter, 1)
+	defer Stop()
+
+	ctx, root := Start(context.Background(), "GET /api/pets", SpanKindServer)
+	root.SetAttribute("http.status_code", 200)
+
+	_, child := Start(ctx, "process models.pet.get", SpanKindInternal)
+	child.SetError(fmt.Errorf("not found"))
+	child.Finish()
+	root.Finish()
+	root.Finish() // only the first call works
+	Flush()
+
+	spans := readSpans(t, file)
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	assert.Equal(t, "process models.pet.get", spans[0]["name"])
+	assert.Equal(t, spans[1]["spanId"], spans[0]["parentSpanId"])
+	assert.Equal(t, spans[1]["traceId"], spans[0]["traceId"])
+	assert.Equal(t, float64(StatusError), spans[0]["status"].(map[string]interface{})["code"])
+	assert.Equal(t, "not found", spans[0]["status"].(map[string]interface{})["message"])
+
+	assert.Equal(t, "GET /api/pets", spans[1]["name"])
+	assert.Nil(t, spans[1]["parentSpanId"])
+	assert.Equal(t, float64(SpanKindServer), spans[1]["kind"])
+	attributes := spans[1]["attributes"].([]interface{})
+	assert.Equal(t, "http.status_code", attributes[0].(map[string]interface{})["key"])
+	assert.Equal(t, map[string]interface{}{"intValue": "200"}, attributes[0].(map[string]interface{})["value"])
+}
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		data := map[string]interface{}{}
+		jsoniter.Unmarshal(body, &data)
+		bodies <- data
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	Use(NewOTLPExporter(server.URL, "yao-test", parseHeaders("Authorization=Bearer token")), 1)
+	defer Stop()
+
+	_, span := Start(context.Background(), "neo.chat", SpanKindInternal)
+	span.Finish()
+	Flush()
+
+	data := <-bodies
+	resource := data["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	attributes := resource["resource"].(map[string]interface{})["attributes"].([]interface{})
+	assert.Equal(t, "service.name", attributes[0].(map[string]interface{})["key"])
+	assert.Equal(t, map[string]interface{}{"stringValue": "yao-test"}, attributes[0].(map[string]interface{})["value"])
+
+	spans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "neo.chat", spans[0].(map[string]interface{})["name"])
+}
+
+func TestPropagation(t *testing.T) {
+	Use(&memoryExporter{}, 1)
+	defer Stop()
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), header)
+	ctx, span := Start(ctx, "GET /", SpanKindServer)
+	if !assert.NotNil(t, span) {
+		return
+	}
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fmt.Sprintf("%x", span.TraceID))
+	assert.Equal(t, "00f067aa0ba902b7", fmt.Sprintf("%x", span.ParentID))
+
+	out := map[string][]string{}
+	Inject(ctx, out)
+	assert.Equal(t, []string{span.TraceParent()}, out["traceparent"])
+	assert.Regexp(t, "^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$", out["traceparent"][0])
+
+	// Not sampled by the caller
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span = Start(Extract(context.Background(), header), "GET /", SpanKindServer)
+	assert.Nil(t, span)
+
+	// Invalid header
+	header.Set("traceparent", "invalid")
+	assert.Nil(t, SpanFromContext(Extract(context.Background(), header)))
+}
+
+func TestTraceProcess(t *testing.T) {
+	Use(&memoryExporter{}, 1)
+	defer Stop()
+
+	handler := traceProcess(func(p *process.Process) interface{} {
+		return SpanFromContext(p.Context)
+	})
+
+	// The concurrent requests of the same session keep their own parent spans
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, span := Start(context.Background(), "GET /", SpanKindServer)
+			child, ok := handler(&process.Process{Name: "scripts.test", Sid: "sid-1", Context: ctx}).(*Span)
+			assert.True(t, ok)
+			assert.Equal(t, span.SpanID, child.ParentID)
+			assert.Equal(t, span.TraceID, child.TraceID)
+		}()
+	}
+	wg.Wait()
+
+	// The nested process created with the context of the caller is the child, the one created without a context starts a new trace
+	nested := traceProcess(func(p *process.Process) interface{} {
+		child := handler(&process.Process{Name: "scripts.child", Context: p.Context}).(*Span)
+		root := handler(&process.Process{Name: "scripts.root"}).(*Span)
+		return []*Span{SpanFromContext(p.Context), child, root}
+	})
+
+	ctx, _ := Start(context.Background(), "GET /", SpanKindServer)
+	spans := nested(&process.Process{Name: "flows.test", Context: ctx}).([]*Span)
+	assert.Equal(t, spans[0].SpanID, spans[1].ParentID)
+	assert.Equal(t, spans[0].TraceID, spans[1].TraceID)
+	assert.Equal(t, [8]byte{}, spans[2].ParentID)
+	assert.NotEqual(t, spans[0].TraceID, spans[2].TraceID)
+}
+
+func TestSample(t *testing.T) {
+	exporter := &memoryExporter{}
+	Use(exporter, 0.000001)
+	defer Stop()
+
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		_, span := Start(context.Background(), "test", SpanKindInternal)
+		if span != nil {
+			sampled++
+		}
+	}
+	assert.Less(t, sampled, 10)
+}
+
+type memoryExporter struct{ spans []*Span }
+
+func (exporter *memoryExporter) Export(spans []*Span) error {
+	exporter.spans = append(exporter.spans, spans...)
+	return nil
+}
+
+func (exporter *memoryExporter) Close() error { return nil }
+
+func readSpans(t *testing.T, file string) []map[string]interface{} {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	spans := []map[string]interface{}{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		data := map[string]interface{}{}
+		err := jsoniter.Unmarshal(scanner.Bytes(), &data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, resource := range data["resourceSpans"].([]interface{}) {
+			for _, scope := range resource.(map[string]interface{})["scopeSpans"].([]interface{}) {
+				for _, span := range scope.(map[string]interface{})["spans"].([]interface{}) {
+					spans = append(spans, span.(map[string]interface{}))
+				}
+			}
+		}
+	}
+	return spans
+}
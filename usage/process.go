@@ -0,0 +1,66 @@
+package usage
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("usage", map[string]process.Handler{
+		"summary":   ProcessSummary,
+		"records":   ProcessRecords,
+		"remaining": ProcessRemaining,
+	})
+}
+
+// ProcessSummary usage.Summary the sum of the tokens and the cost
+// args[0] the filter. e.g. {"group_by": ["user", "model"], "from": "2024-11-01", "to": "2024-12-01", "assistant": "neo"}
+func ProcessSummary(process *process.Process) interface{} {
+	filter := filterOf(process)
+	res, err := Summary(filter)
+	if err != nil {
+		exception.New("Usage summary error: %s", 400, err).Throw()
+	}
+	return res
+}
+
+// ProcessRecords usage.Records the usage records, the latest first
+// args[0] the filter. e.g. {"user": "1", "page": 1, "pagesize": 20}
+func ProcessRecords(process *process.Process) interface{} {
+	filter := filterOf(process)
+	res, err := Records(filter)
+	if err != nil {
+		exception.New("Usage records error: %s", 400, err).Throw()
+	}
+	return res
+}
+
+// ProcessRemaining usage.Remaining the budget and the spent of the user or the assistant in the current period
+// args[0] user|assistant, args[1] the user id or the assistant id
+func ProcessRemaining(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	res, err := Remaining(process.ArgsString(0), process.ArgsString(1))
+	if err != nil {
+		exception.New("Usage remaining error: %s", 400, err).Throw()
+	}
+	return res
+}
+
+func filterOf(process *process.Process) Filter {
+	filter := Filter{}
+	if process.NumOfArgs() == 0 || process.Args[0] == nil {
+		return filter
+	}
+
+	raw, err := jsoniter.Marshal(process.Args[0])
+	if err != nil {
+		exception.New("Usage the filter is invalid: %s", 400, err).Throw()
+	}
+
+	err = jsoniter.Unmarshal(raw, &filter)
+	if err != nil {
+		exception.New("Usage the filter is invalid: %s", 400, err).Throw()
+	}
+	return filter
+}
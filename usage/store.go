@@ -0,0 +1,577 @@
+package usage
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// Store the usage table
+type Store struct {
+	query  query.Query
+	schema schema.Schema
+	table  string
+}
+
+// Filter the filter of the usage queries
+type Filter struct {
+	User      string   `json:"user,omitempty"`
+	Assistant string   `json:"assistant,omitempty"`
+	Connector string   `json:"connector,omitempty"`
+	Model     string   `json:"model,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	From      string   `json:"from,omitempty"`     // The start time, 2006-01-02 or RFC3339
+	To        string   `json:"to,omitempty"`       // The end time (exclusive), 2006-01-02 or RFC3339
+	GroupBy   []string `json:"group_by,omitempty"` // user|assistant|connector|model|source, the summary of all the records if it is empty
+	Page      int      `json:"page,omitempty"`
+	PageSize  int      `json:"pagesize,omitempty"`
+}
+
+// spent the tokens and the cost spent in the period
+type spent struct {
+	tokens int
+	cost   float64
+	start  time.Time
+	loaded time.Time
+}
+
+// columns the filter and the group by fields
+var columns = map[string]string{
+	"user":      "user_id",
+	"assistant": "assistant",
+	"connector": "connector",
+	"model":     "model",
+	"source":    "source",
+}
+
+var setting share.Usage
+var store *Store
+var counters = map[string]*spent{}
+var mutex sync.Mutex
+
+// Refresh the spent tokens of the budgets are reloaded from the table after the duration, the other nodes of the cluster write the same table
+var Refresh = time.Minute
+
+// ModelID the id of the usage model, the usage table is migrated, dumped and restored with the models of the app
+const ModelID = "__yao.llm_usage"
+
+// Load load the usage model, the prices and the budgets of the app.yao, the usage accounting is disabled if it is not set
+func Load(cfg config.Config) error {
+	if share.App.Usage == nil {
+		return Setup(share.Usage{Disable: true})
+	}
+	return Setup(*share.App.Usage)
+}
+
+// Setup set the usage accounting, the usage model is loaded and the table is created if it does not exist
+func Setup(option share.Usage) error {
+	switch option.Budgets.Period {
+	case "", "day", "month":
+	default:
+		return fmt.Errorf("usage budgets the period %s is invalid, day or month", option.Budgets.Period)
+	}
+
+	var s *Store = nil
+	if !option.Disable {
+		var err error
+		s, err = NewStore(option.Connector, option.Table)
+		if err != nil {
+			return err
+		}
+	} else {
+		delete(model.Models, ModelID)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	setting = option
+	store = s
+	counters = map[string]*spent{}
+	return nil
+}
+
+// NewStore create the usage store of the database connector, the usage model is loaded and the table is created if it does not exist
+func NewStore(conn string, table string) (*Store, error) {
+	if table == "" {
+		table = "yao_llm_usage"
+	}
+
+	s := &Store{table: table}
+	if conn == "" || conn == "default" {
+		s.query = capsule.Global.Query()
+		s.schema = capsule.Global.Schema()
+	} else {
+		c, err := connector.Select(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		s.query, err = c.Query()
+		if err != nil {
+			return nil, err
+		}
+
+		s.schema, err = c.Schema()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.initialize(conn)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// initialize load the usage model, the table is migrated by the model if it does not exist
+func (s *Store) initialize(conn string) error {
+	source, err := usageModel(conn, s.table)
+	if err != nil {
+		return err
+	}
+
+	mod, err := model.LoadSource(source, ModelID, "<usage>.mod.yao")
+	if err != nil {
+		return err
+	}
+
+	has, err := s.schema.HasTable(s.table)
+	if err != nil {
+		return err
+	}
+
+	if has {
+		return nil
+	}
+
+	err = mod.Migrate(false)
+	if err != nil {
+		return err
+	}
+
+	log.Trace("[Usage] the table %s is created", s.table)
+	return nil
+}
+
+// usageModel the source of the usage model
+func usageModel(conn string, table string) ([]byte, error) {
+	column := func(name string, typ string, option map[string]interface{}) map[string]interface{} {
+		col := map[string]interface{}{"name": name, "type": typ}
+		for key, value := range option {
+			col[key] = value
+		}
+		return col
+	}
+
+	dsl := map[string]interface{}{
+		"name":  "LLM Usage",
+		"table": map[string]interface{}{"name": table, "comment": "The token usage of the LLM calls"},
+		"columns": []interface{}{
+			column("id", "ID", nil),
+			column("sid", "string", map[string]interface{}{"length": 255, "nullable": true, "index": true}),
+			column("user_id", "string", map[string]interface{}{"length": 255, "nullable": true, "index": true}),
+			column("assistant", "string", map[string]interface{}{"length": 200, "nullable": true, "index": true}),
+			column("connector", "string", map[string]interface{}{"length": 200, "nullable": true, "index": true}),
+			column("model", "string", map[string]interface{}{"length": 200, "nullable": true, "index": true}),
+			column("source", "string", map[string]interface{}{"length": 50, "nullable": true, "index": true}),
+			column("prompt_tokens", "integer", map[string]interface{}{"default": 0}),
+			column("completion_tokens", "integer", map[string]interface{}{"default": 0}),
+			column("total_tokens", "integer", map[string]interface{}{"default": 0}),
+			column("estimated", "boolean", map[string]interface{}{"default": false}),
+			column("cost", "decimal", map[string]interface{}{"precision": 20, "scale": 8, "default": 0}),
+			column("created_at", "timestampTz", map[string]interface{}{"default_raw": "NOW()", "index": true}),
+		},
+		"option": map[string]interface{}{"timestamps": false, "soft_deletes": false},
+	}
+
+	if conn != "" && conn != "default" {
+		dsl["connector"] = conn
+	}
+	return jsoniter.Marshal(dsl)
+}
+
+// Save save the usage record, the cost is counted by the prices
+func Save(record *Record) {
+	mutex.Lock()
+	s := store
+	price, has := setting.Prices[record.Model]
+	if !has {
+		price, has = setting.Prices[record.Connector]
+	}
+	mutex.Unlock()
+
+	if has {
+		record.Cost = (float64(record.PromptTokens)*price.Prompt + float64(record.CompletionTokens)*price.Completion) / 1000000
+	}
+
+	if s == nil {
+		return
+	}
+
+	err := s.query.New().Table(s.table).Insert(map[string]interface{}{
+		"sid":               record.Sid,
+		"user_id":           record.User,
+		"assistant":         record.Assistant,
+		"connector":         record.Connector,
+		"model":             record.Model,
+		"source":            record.Source,
+		"prompt_tokens":     record.PromptTokens,
+		"completion_tokens": record.CompletionTokens,
+		"total_tokens":      record.TotalTokens,
+		"estimated":         record.Estimated,
+		"cost":              record.Cost,
+		"created_at":        record.CreatedAt,
+	})
+	if err != nil {
+		log.Error("[Usage] save the usage of %s %s", record.Connector, err.Error())
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, key := range []string{"user:" + record.User, "assistant:" + record.Assistant} {
+		if c, has := counters[key]; has && !record.CreatedAt.Before(c.start) {
+			c.tokens = c.tokens + record.TotalTokens
+			c.cost = c.cost + record.Cost
+		}
+	}
+}
+
+// Summary the sum of the tokens and the cost grouped by the fields of the filter
+func Summary(filter Filter) ([]map[string]interface{}, error) {
+	s, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	groups := []interface{}{}
+	for _, name := range filter.GroupBy {
+		column, has := columns[name]
+		if !has {
+			return nil, fmt.Errorf("usage the group by field %s is invalid, user, assistant, connector, model or source", name)
+		}
+		fields = append(fields, column)
+		groups = append(groups, column)
+	}
+
+	qb, err := s.where(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	qb.SelectRaw(strings.Join(append(fields,
+		"COUNT(*) AS calls",
+		"SUM(prompt_tokens) AS prompt_tokens",
+		"SUM(completion_tokens) AS completion_tokens",
+		"SUM(total_tokens) AS total_tokens",
+		"SUM(cost) AS cost",
+	), ", "))
+
+	if len(groups) > 0 {
+		qb.GroupBy(groups...)
+	}
+
+	rows, err := qb.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []map[string]interface{}{}
+	for _, row := range rows {
+		item := map[string]interface{}{
+			"calls":             toNumber(row.Get("calls")),
+			"prompt_tokens":     toNumber(row.Get("prompt_tokens")),
+			"completion_tokens": toNumber(row.Get("completion_tokens")),
+			"total_tokens":      toNumber(row.Get("total_tokens")),
+			"cost":              toNumber(row.Get("cost")),
+		}
+		for _, name := range filter.GroupBy {
+			item[name] = row.Get(columns[name])
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// Records the usage records of the filter, the latest first
+func Records(filter Filter) (map[string]interface{}, error) {
+	s, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	qb, err := s.where(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := qb.Clone().Count()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := qb.OrderBy("id", "desc").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		data[i] = row
+	}
+
+	return map[string]interface{}{
+		"data":     data,
+		"page":     filter.Page,
+		"pagesize": filter.PageSize,
+		"pagecnt":  int(math.Ceil(float64(total) / float64(filter.PageSize))),
+		"total":    total,
+	}, nil
+}
+
+// Remaining the budgets and the spent of the user or the assistant in the current period
+func Remaining(kind string, id string) (map[string]interface{}, error) {
+	if kind != "user" && kind != "assistant" {
+		return nil, fmt.Errorf("usage the budget kind %s is invalid, user or assistant", kind)
+	}
+
+	budget := budgetOf(kind, id)
+	c, err := spentOf(kind, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]interface{}{
+		kind:     id,
+		"period": period(),
+		"start":  c.start,
+		"tokens": c.tokens,
+		"cost":   c.cost,
+	}
+
+	if budget != nil {
+		res["budget"] = map[string]interface{}{"tokens": budget.Tokens, "cost": budget.Cost}
+		if budget.Tokens > 0 {
+			res["remaining_tokens"] = budget.Tokens - c.tokens
+		}
+		if budget.Cost > 0 {
+			res["remaining_cost"] = budget.Cost - c.cost
+		}
+	}
+	return res, nil
+}
+
+// check the budgets of the user and the assistant
+func check(meta Meta) *exception.Exception {
+	mutex.Lock()
+	s := store
+	mutex.Unlock()
+	if s == nil {
+		return nil
+	}
+
+	for _, kind := range []string{"user", "assistant"} {
+		id := meta.User
+		if kind == "assistant" {
+			id = meta.Assistant
+		}
+
+		if id == "" {
+			continue
+		}
+
+		budget := budgetOf(kind, id)
+		if budget == nil {
+			continue
+		}
+
+		c, err := spentOf(kind, id)
+		if err != nil {
+			log.Error("[Usage] the spent of the %s %s %s", kind, id, err.Error())
+			continue
+		}
+
+		if (budget.Tokens > 0 && c.tokens >= budget.Tokens) || (budget.Cost > 0 && c.cost >= budget.Cost) {
+			message := budget.Message
+			if message == "" {
+				message = fmt.Sprintf("The %s budget of the %s %s is exceeded", period(), kind, id)
+			}
+			return exception.New(message, 402)
+		}
+	}
+	return nil
+}
+
+// budgetOf the budget of the given user or assistant, or the budget of each user or assistant
+func budgetOf(kind string, id string) *share.UsageBudget {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	budgets := setting.Budgets
+	named, each := budgets.Users, budgets.User
+	if kind == "assistant" {
+		named, each = budgets.Assistants, budgets.Assistant
+	}
+
+	if budget, has := named[id]; has {
+		return &budget
+	}
+	return each
+}
+
+// spentOf the tokens and the cost spent in the current period, they are loaded from the table after the refresh duration
+func spentOf(kind string, id string) (spent, error) {
+	start := periodStart(time.Now())
+	key := kind + ":" + id
+
+	mutex.Lock()
+	c, has := counters[key]
+	s := store
+	mutex.Unlock()
+
+	if has && c.start.Equal(start) && time.Since(c.loaded) < Refresh {
+		return *c, nil
+	}
+
+	if s == nil {
+		return spent{start: start}, nil
+	}
+
+	row, err := s.query.New().Table(s.table).
+		SelectRaw("SUM(total_tokens) AS tokens, SUM(cost) AS cost").
+		Where(columns[kind], id).
+		Where("created_at", ">=", start).
+		First()
+	if err != nil {
+		return spent{}, err
+	}
+
+	c = &spent{
+		tokens: int(toNumber(row.Get("tokens"))),
+		cost:   toNumber(row.Get("cost")),
+		start:  start,
+		loaded: time.Now(),
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	counters[key] = c
+	return *c, nil
+}
+
+// where the query of the filter
+func (s *Store) where(filter Filter) (query.Query, error) {
+	qb := s.query.New().Table(s.table)
+	for name, value := range map[string]string{
+		"user":      filter.User,
+		"assistant": filter.Assistant,
+		"connector": filter.Connector,
+		"model":     filter.Model,
+		"source":    filter.Source,
+	} {
+		if value != "" {
+			qb.Where(columns[name], value)
+		}
+	}
+
+	if filter.From != "" {
+		from, err := parseTime(filter.From)
+		if err != nil {
+			return nil, err
+		}
+		qb.Where("created_at", ">=", from)
+	}
+
+	if filter.To != "" {
+		to, err := parseTime(filter.To)
+		if err != nil {
+			return nil, err
+		}
+		qb.Where("created_at", "<", to)
+	}
+	return qb, nil
+}
+
+// current the store, the error returns if the usage accounting is disabled
+func current() (*Store, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if store == nil {
+		return nil, fmt.Errorf("usage the usage accounting is disabled or not loaded")
+	}
+	return store, nil
+}
+
+func period() string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if setting.Budgets.Period == "day" {
+		return "day"
+	}
+	return "month"
+}
+
+// periodStart the start of the current day or month
+func periodStart(now time.Time) time.Time {
+	if period() == "day" {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("usage the time %s is invalid, 2006-01-02 or RFC3339", value)
+	}
+	return t, nil
+}
+
+// toNumber the number of the aggregate column, the drivers return the sums as the numbers, the strings or the bytes
+func toNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	case []byte:
+		n, _ := strconv.ParseFloat(string(v), 64)
+		return n
+	}
+	return 0
+}
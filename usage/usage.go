@@ -0,0 +1,278 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+)
+
+// Usage the token usage of an LLM call
+type Usage struct {
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	Estimated        bool `json:"estimated,omitempty"` // The tokens are counted with tiktoken, the provider did not return the usage
+}
+
+// Meta the caller of the LLM call, it is passed by the context
+type Meta struct {
+	Sid       string `json:"sid,omitempty"`
+	User      string `json:"user,omitempty"`      // The user id, it is read from the session (user_id) if it is empty
+	Assistant string `json:"assistant,omitempty"` // The Neo assistant id, aigcs.<id> or pipes.<id>
+	Source    string `json:"source,omitempty"`    // openai|neo|aigc|pipe
+}
+
+// Record the usage record of an LLM call
+type Record struct {
+	Meta
+	Usage
+	Connector string    `json:"connector"`
+	Model     string    `json:"model"`
+	Cost      float64   `json:"cost"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Call the metering of an LLM call
+type Call struct {
+	record   Record
+	messages []map[string]interface{}
+	counter  func(input string) (int, error)
+	text     strings.Builder
+	usage    *Usage
+	mutex    sync.Mutex
+}
+
+type metaKey struct{}
+
+// WithMeta returns a copy of the context with the caller of the LLM calls, the empty fields are inherited from the parent context
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	parent := MetaOf(ctx)
+	if meta.Sid == "" {
+		meta.Sid = parent.Sid
+	}
+	if meta.User == "" {
+		meta.User = parent.User
+	}
+	if meta.Assistant == "" {
+		meta.Assistant = parent.Assistant
+	}
+	if meta.Source == "" {
+		meta.Source = parent.Source
+	}
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// MetaOf the caller of the LLM calls
+func MetaOf(ctx context.Context) Meta {
+	if ctx == nil {
+		return Meta{}
+	}
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
+
+// Begin start metering the LLM call, the 402 exception returns if the budget of the user or the assistant is spent.
+// The counter counts the tokens of the model, it is used to estimate the usage when the provider does not return it.
+func Begin(ctx context.Context, connector, model string, messages []map[string]interface{}, counter func(input string) (int, error)) (*Call, *exception.Exception) {
+	meta := MetaOf(ctx)
+	if meta.User == "" && meta.Sid != "" {
+		meta.User = userOf(meta.Sid)
+	}
+
+	call := &Call{
+		record:   Record{Meta: meta, Connector: connector, Model: model},
+		messages: messages,
+		counter:  counter,
+	}
+
+	if ex := check(meta); ex != nil {
+		return nil, ex
+	}
+	return call, nil
+}
+
+// Stream wrap the stream callback, the chunks are metered before they are sent to the cb
+func (call *Call) Stream(cb func(data []byte) int) func(data []byte) int {
+	return func(data []byte) int {
+		call.chunk(data)
+		return cb(data)
+	}
+}
+
+// End record the usage of the call. The usage of the response is used if it is present,
+// otherwise the tokens of the messages and the streamed text are estimated. Nothing is recorded if the call returns nothing.
+func (call *Call) End(response interface{}) {
+	call.mutex.Lock()
+	defer call.mutex.Unlock()
+
+	usage := call.usage
+	if response != nil {
+		if u := Parse(response); u != nil {
+			usage = u
+		}
+	}
+
+	if usage == nil {
+		if (response == nil && call.text.Len() == 0) || call.counter == nil {
+			return
+		}
+		usage = call.estimate()
+	}
+
+	record := call.record
+	record.Usage = *usage
+	record.CreatedAt = time.Now()
+	Save(&record)
+}
+
+// chunk read the usage or the delta text of the OpenAI chunk line
+func (call *Call) chunk(data []byte) {
+	if !bytes.HasPrefix(data, []byte("data:")) {
+		return
+	}
+
+	body := bytes.TrimSpace(data[5:])
+	if len(body) == 0 || body[0] != '{' {
+		return // [DONE]
+	}
+
+	call.mutex.Lock()
+	defer call.mutex.Unlock()
+
+	// The last chunk of the stream_options.include_usage, or the last chunk of the anthropic and ollama adapters
+	if bytes.Contains(body, []byte(`"usage"`)) {
+		var res map[string]interface{}
+		if err := jsoniter.Unmarshal(body, &res); err == nil {
+			if u := Parse(res); u != nil {
+				call.usage = u
+			}
+		}
+	}
+
+	delta := jsoniter.Get(body, "choices", 0, "delta", "content")
+	if delta.ValueType() == jsoniter.StringValue {
+		call.text.WriteString(delta.ToString())
+	}
+}
+
+// estimate count the tokens with the counter, each message takes 4 more tokens and the reply is primed with 3 tokens
+func (call *Call) estimate() *Usage {
+	prompt := 3
+	for _, message := range call.messages {
+		prompt = prompt + 4 + call.count(text(message["content"]))
+	}
+
+	completion := call.count(call.text.String())
+	return &Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion, Estimated: true}
+}
+
+func (call *Call) count(input string) int {
+	if input == "" {
+		return 0
+	}
+
+	n, err := call.counter(input)
+	if err != nil {
+		return len(input)/4 + 1
+	}
+	return n
+}
+
+// Parse read the usage of the response.
+// The OpenAI usage (prompt_tokens, completion_tokens), the Anthropic usage (input_tokens, output_tokens)
+// and the Ollama counts (prompt_eval_count, eval_count) are supported. nil returns if there is no usage.
+func Parse(response interface{}) *Usage {
+	data, ok := response.(map[string]interface{})
+	if !ok {
+		raw, err := jsoniter.Marshal(response)
+		if err != nil {
+			return nil
+		}
+		if err := jsoniter.Unmarshal(raw, &data); err != nil {
+			return nil
+		}
+	}
+
+	if u, ok := data["usage"].(map[string]interface{}); ok {
+		usage := &Usage{}
+		if _, has := u["input_tokens"]; has {
+			usage.PromptTokens = toInt(u["input_tokens"])
+			usage.CompletionTokens = toInt(u["output_tokens"])
+		} else {
+			usage.PromptTokens = toInt(u["prompt_tokens"])
+			usage.CompletionTokens = toInt(u["completion_tokens"])
+		}
+
+		usage.TotalTokens = toInt(u["total_tokens"])
+		if usage.TotalTokens == 0 {
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		}
+		return usage
+	}
+
+	if _, has := data["prompt_eval_count"]; has {
+		prompt := toInt(data["prompt_eval_count"])
+		completion := toInt(data["eval_count"])
+		return &Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+	}
+	return nil
+}
+
+// userOf the user id of the session
+func userOf(sid string) string {
+	id, err := session.Global().ID(sid).Get("user_id")
+	if err != nil {
+		log.Warn("[Usage] the user of the session %s %s", sid, err.Error())
+		return ""
+	}
+
+	if id == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", id)
+}
+
+// text the text of the message content, the text parts are joined if the content is an array
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		texts := []string{}
+		for _, part := range v {
+			if p, ok := part.(map[string]interface{}); ok {
+				if text, ok := p["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
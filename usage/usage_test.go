@@ -0,0 +1,145 @@
+package usage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestParse(t *testing.T) {
+	u := Parse(map[string]interface{}{"usage": map[string]interface{}{"prompt_tokens": float64(9), "completion_tokens": float64(12), "total_tokens": float64(21)}})
+	assert.Equal(t, &Usage{PromptTokens: 9, CompletionTokens: 12, TotalTokens: 21}, u)
+
+	// anthropic
+	u = Parse(map[string]interface{}{"usage": map[string]interface{}{"input_tokens": float64(25), "output_tokens": float64(3)}})
+	assert.Equal(t, &Usage{PromptTokens: 25, CompletionTokens: 3, TotalTokens: 28}, u)
+
+	// ollama
+	u = Parse(map[string]interface{}{"done": true, "prompt_eval_count": float64(26), "eval_count": float64(3)})
+	assert.Equal(t, &Usage{PromptTokens: 26, CompletionTokens: 3, TotalTokens: 29}, u)
+
+	assert.Nil(t, Parse(map[string]interface{}{"choices": []interface{}{}}))
+	assert.Nil(t, Parse("Hello"))
+}
+
+func TestCallStream(t *testing.T) {
+	messages := []map[string]interface{}{{"role": "user", "content": "Say hello"}}
+	counter := func(input string) (int, error) { return len(input), nil }
+
+	call, ex := Begin(context.Background(), "gpt-4o", "gpt-4o", messages, counter)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+
+	text := ""
+	cb := call.Stream(func(data []byte) int {
+		text = text + string(data)
+		return 1
+	})
+	cb([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Hello"}}]}`))
+	cb([]byte(`data: {"choices":[{"index":0,"delta":{"content":"!"},"finish_reason":"stop"}]}`))
+	cb([]byte(`data: [DONE]`))
+
+	assert.Contains(t, text, "Hello")
+	assert.Nil(t, call.usage)
+	assert.Equal(t, &Usage{PromptTokens: 3 + 4 + 9, CompletionTokens: 6, TotalTokens: 22, Estimated: true}, call.estimate())
+
+	// The usage of the last chunk
+	cb([]byte(`data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`))
+	assert.Equal(t, &Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}, call.usage)
+}
+
+func TestWithMeta(t *testing.T) {
+	ctx := WithMeta(context.Background(), Meta{Sid: "sid-1", Source: "openai"})
+	ctx = WithMeta(ctx, Meta{Assistant: "aigcs.translate", Source: "aigc"})
+	assert.Equal(t, Meta{Sid: "sid-1", Assistant: "aigcs.translate", Source: "aigc"}, MetaOf(ctx))
+	assert.Equal(t, Meta{}, MetaOf(context.Background()))
+}
+
+func TestBudgets(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	err := Setup(share.Usage{
+		Table:  "yao_llm_usage_test",
+		Prices: map[string]share.UsagePrice{"gpt-4o": {Prompt: 2.5, Completion: 10}},
+		Budgets: share.UsageBudgets{
+			User:  &share.UsageBudget{Tokens: 100},
+			Users: map[string]share.UsageBudget{"vip": {Tokens: 1000}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		store.schema.DropTableIfExists("yao_llm_usage_test")
+		Setup(share.Usage{Disable: true})
+		assert.NotContains(t, model.Models, ModelID)
+	}()
+
+	// The usage table is a model, it is migrated, dumped and restored with the models of the app
+	mod, has := model.Models[ModelID]
+	assert.True(t, has)
+	assert.Equal(t, "yao_llm_usage_test", mod.MetaData.Table.Name)
+
+	ctx := WithMeta(context.Background(), Meta{User: "u1", Assistant: "neo", Source: "neo"})
+	call, ex := Begin(ctx, "gpt-4o", "gpt-4o", nil, nil)
+	if ex != nil {
+		t.Fatal(ex.Message)
+	}
+	call.End(map[string]interface{}{"usage": map[string]interface{}{"prompt_tokens": float64(60), "completion_tokens": float64(50)}})
+
+	// The budget of the user is spent
+	_, ex = Begin(ctx, "gpt-4o", "gpt-4o", nil, nil)
+	assert.NotNil(t, ex)
+	assert.Equal(t, 402, ex.Code)
+
+	_, ex = Begin(WithMeta(context.Background(), Meta{User: "vip"}), "gpt-4o", "gpt-4o", nil, nil)
+	assert.Nil(t, ex)
+
+	summary, err := Summary(Filter{GroupBy: []string{"user", "model"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, summary, 1)
+	assert.Equal(t, float64(110), summary[0]["total_tokens"])
+	assert.InDelta(t, 0.00065, summary[0]["cost"], 0.0000001)
+	assert.Equal(t, "u1", summary[0]["user"])
+
+	remaining, err := Remaining("user", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, -10, remaining["remaining_tokens"])
+
+	records, err := Records(Filter{Assistant: "neo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, records["data"], 1)
+}
+
+func TestLoadNotConfigured(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	usage := share.App.Usage
+	share.App.Usage = nil
+	defer func() { share.App.Usage = usage }()
+
+	err := Load(config.Conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The usage accounting is disabled, the usage model is not loaded
+	assert.NotContains(t, model.Models, ModelID)
+	_, err = current()
+	assert.NotNil(t, err)
+}
@@ -135,7 +135,11 @@ func (widget *DSL) handler(processName string) process.Handler {
 		args = append(args, p.Args...)
 		args = append(args, instance.(*Instance).dsl)
 
-		return process.New(processName, args...).Run()
+		nested := process.New(processName, args...)
+		if p.Context != nil {
+			nested.WithContext(p.Context)
+		}
+		return nested.Run()
 	}
 }
 
@@ -20,6 +20,7 @@ import (
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/data"
 	"github.com/yaoapp/yao/i18n"
+	"github.com/yaoapp/yao/runtime"
 	"github.com/yaoapp/yao/share"
 	"github.com/yaoapp/yao/widgets/login"
 )
@@ -306,6 +307,7 @@ func processService(process *process.Process) interface{} {
 		return nil
 	}
 	defer v8ctx.Close()
+	defer runtime.Acquire()()
 
 	res, err := v8ctx.CallWith(ctx, method, args...)
 	if err != nil {
@@ -19,7 +19,9 @@ func Guard(c *gin.Context) {
 		return
 	}
 
+	lock.RLock()
 	chart, has := Charts[id]
+	lock.RUnlock()
 	if !has {
 		abort(c, 404, fmt.Sprintf("the chart widget %s does not exist", id))
 		return
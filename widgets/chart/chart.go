@@ -3,6 +3,7 @@ package chart
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/application"
@@ -34,6 +35,9 @@ import (
 // Charts the loaded chart widgets
 var Charts map[string]*DSL = map[string]*DSL{}
 
+// lock the charts are loaded and unloaded with the lock, and read with the read lock
+var lock sync.RWMutex
+
 // New create a new DSL
 func New(id string) *DSL {
 	return &DSL{
@@ -75,6 +79,25 @@ func Load(cfg config.Config) error {
 	return err
 }
 
+// Unload unload the chart
+func Unload(id string) {
+	delete(Charts, id)
+}
+
+// UnloadSync unload the chart
+func UnloadSync(id string) {
+	lock.Lock()
+	defer lock.Unlock()
+	Unload(id)
+}
+
+// LoadFileSync load chart dsl by file
+func LoadFileSync(root string, file string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	return LoadFile(root, file)
+}
+
 // LoadFile load table dsl by file
 func LoadFile(root string, file string) error {
 
@@ -138,7 +161,9 @@ func Get(chart interface{}) (*DSL, error) {
 		return nil, fmt.Errorf("%v type does not support", chart)
 	}
 
+	lock.RLock()
 	t, has := Charts[id]
+	lock.RUnlock()
 	if !has {
 		return nil, fmt.Errorf("%s does not exist", id)
 	}
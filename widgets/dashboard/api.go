@@ -19,7 +19,9 @@ func Guard(c *gin.Context) {
 		return
 	}
 
+	lock.RLock()
 	dashboard, has := Dashboards[id]
+	lock.RUnlock()
 	if !has {
 		abort(c, 404, fmt.Sprintf("the dashboard widget %s does not exist", id))
 		return
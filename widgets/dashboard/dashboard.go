@@ -3,6 +3,7 @@ package dashboard
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/application"
@@ -34,6 +35,9 @@ import (
 // Dashboards the loaded dashboard widgets
 var Dashboards map[string]*DSL = map[string]*DSL{}
 
+// lock the dashboards are loaded and unloaded with the lock, and read with the read lock
+var lock sync.RWMutex
+
 // New create a new DSL
 func New(id string) *DSL {
 	return &DSL{
@@ -75,6 +79,25 @@ func Load(cfg config.Config) error {
 	return err
 }
 
+// Unload unload the dashboard
+func Unload(id string) {
+	delete(Dashboards, id)
+}
+
+// UnloadSync unload the dashboard
+func UnloadSync(id string) {
+	lock.Lock()
+	defer lock.Unlock()
+	Unload(id)
+}
+
+// LoadFileSync load dashboard dsl by file
+func LoadFileSync(root string, file string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	return LoadFile(root, file)
+}
+
 // LoadFile load table dsl by file
 func LoadFile(root string, file string) error {
 
@@ -138,7 +161,9 @@ func Get(dashboard interface{}) (*DSL, error) {
 		return nil, fmt.Errorf("%v type does not support", dashboard)
 	}
 
+	lock.RLock()
 	t, has := Dashboards[id]
+	lock.RUnlock()
 	if !has {
 		return nil, fmt.Errorf("%s does not exist", id)
 	}
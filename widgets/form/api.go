@@ -19,7 +19,9 @@ func Guard(c *gin.Context) {
 		return
 	}
 
+	lock.RLock()
 	form, has := Forms[id]
+	lock.RUnlock()
 	if !has {
 		abort(c, 404, fmt.Sprintf("the form widget %s does not exist", id))
 		return
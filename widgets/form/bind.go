@@ -60,13 +60,14 @@ func (dsl *DSL) bindForm() error {
 		}
 	}
 
-	form, err := Get(id)
-	if err != nil {
-		return err
+	// the lock of the loading is held, the form is read without the read lock
+	form, has := Forms[id]
+	if !has {
+		return fmt.Errorf("form %s does not exist", id)
 	}
 
 	// Bind Fields
-	err = dsl.Fields.BindForm(form)
+	err := dsl.Fields.BindForm(form)
 	if err != nil {
 		return err
 	}
@@ -90,7 +91,7 @@ func (dsl *DSL) bindTable() error {
 	id := dsl.Action.Bind.Table
 
 	// Load table
-	if _, has := table.Tables[id]; !has {
+	if !table.Exists(id) {
 		if err := table.LoadID(id); err != nil {
 			return err
 		}
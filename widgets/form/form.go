@@ -52,7 +52,9 @@ import (
 
 // Forms the loaded form widgets
 var Forms map[string]*DSL = map[string]*DSL{}
-var lock sync.Mutex
+
+// lock the forms are loaded and unloaded with the lock, and read with the read lock
+var lock sync.RWMutex
 
 // New create a new DSL
 func New(id string, file string, source []byte) *DSL {
@@ -103,6 +105,13 @@ func Unload(id string) {
 	delete(Forms, id)
 }
 
+// UnloadSync unload the form
+func UnloadSync(id string) {
+	lock.Lock()
+	defer lock.Unlock()
+	Unload(id)
+}
+
 // LoadFileSync load form dsl by file
 func LoadFileSync(root string, file string) error {
 	lock.Lock()
@@ -227,7 +236,9 @@ func Get(form interface{}) (*DSL, error) {
 		return nil, fmt.Errorf("%v type does not support", form)
 	}
 
+	lock.RLock()
 	t, has := Forms[id]
+	lock.RUnlock()
 	if !has {
 		return nil, fmt.Errorf("%s does not exist", id)
 	}
@@ -363,6 +374,8 @@ func (dsl *DSL) Read() []byte {
 
 // Exists check the form exists
 func Exists(id string) bool {
+	lock.RLock()
 	_, has := Forms[id]
+	lock.RUnlock()
 	return has
 }
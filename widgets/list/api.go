@@ -19,7 +19,9 @@ func Guard(c *gin.Context) {
 		return
 	}
 
+	lock.RLock()
 	list, has := Lists[id]
+	lock.RUnlock()
 	if !has {
 		abort(c, 404, fmt.Sprintf("the list widget %s does not exist", id))
 		return
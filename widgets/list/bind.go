@@ -51,7 +51,7 @@ func (dsl *DSL) bindTable() error {
 	id := dsl.Action.Bind.Table
 
 	// Load table
-	if _, has := table.Tables[id]; !has {
+	if !table.Exists(id) {
 		if err := table.LoadID(id); err != nil {
 			return err
 		}
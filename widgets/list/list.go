@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/application"
@@ -46,6 +47,9 @@ import (
 // Lists the loaded list widgets
 var Lists map[string]*DSL = map[string]*DSL{}
 
+// lock the lists are loaded and unloaded with the lock, and read with the read lock
+var lock sync.RWMutex
+
 // New create a new DSL
 func New(id string) *DSL {
 	return &DSL{
@@ -88,6 +92,25 @@ func Load(cfg config.Config) error {
 	return err
 }
 
+// Unload unload the list
+func Unload(id string) {
+	delete(Lists, id)
+}
+
+// UnloadSync unload the list
+func UnloadSync(id string) {
+	lock.Lock()
+	defer lock.Unlock()
+	Unload(id)
+}
+
+// LoadFileSync load list dsl by file
+func LoadFileSync(root string, file string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	return LoadFile(root, file)
+}
+
 // LoadFile load table dsl by file
 func LoadFile(root string, file string) error {
 
@@ -182,7 +205,9 @@ func Get(list interface{}) (*DSL, error) {
 		return nil, fmt.Errorf("%v type does not support", list)
 	}
 
+	lock.RLock()
 	t, has := Lists[id]
+	lock.RUnlock()
 	if !has {
 		return nil, fmt.Errorf("%s does not exist", id)
 	}
@@ -19,7 +19,9 @@ func Guard(c *gin.Context) {
 		return
 	}
 
+	lock.RLock()
 	tab, has := Tables[id]
+	lock.RUnlock()
 	if !has {
 		abort(c, 404, fmt.Sprintf("the table widget %s does not exist", id))
 		return
@@ -69,13 +69,14 @@ func (dsl *DSL) bindTable() error {
 		}
 	}
 
-	tab, err := Get(id)
-	if err != nil {
-		return err
+	// the lock of the loading is held, the table is read without the read lock
+	tab, has := Tables[id]
+	if !has {
+		return fmt.Errorf("table %s does not exist", id)
 	}
 
 	// Bind Fields
-	err = dsl.Fields.BindTable(tab)
+	err := dsl.Fields.BindTable(tab)
 	if err != nil {
 		return err
 	}
@@ -82,7 +82,9 @@ import (
 
 // Tables the loaded table widgets
 var Tables map[string]*DSL = map[string]*DSL{}
-var lock sync.Mutex
+
+// lock the tables are loaded and unloaded with the lock, and read with the read lock
+var lock sync.RWMutex
 
 // New create a new DSL
 func New(id string, file string, source []byte) *DSL {
@@ -132,6 +134,13 @@ func Unload(id string) {
 	delete(Tables, id)
 }
 
+// UnloadSync unload the table
+func UnloadSync(id string) {
+	lock.Lock()
+	defer lock.Unlock()
+	Unload(id)
+}
+
 // LoadID load table dsl by id
 func LoadID(id string) error {
 
@@ -266,7 +275,9 @@ func Get(table interface{}) (*DSL, error) {
 		return nil, fmt.Errorf("%v type does not support", table)
 	}
 
+	lock.RLock()
 	t, has := Tables[id]
+	lock.RUnlock()
 	if !has {
 		return nil, fmt.Errorf("%s does not exist", id)
 	}
@@ -423,6 +434,8 @@ func (dsl *DSL) Read() []byte {
 
 // Exists check the table exists
 func Exists(id string) bool {
+	lock.RLock()
 	_, has := Tables[id]
+	lock.RUnlock()
 	return has
 }